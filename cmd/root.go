@@ -2,20 +2,30 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"slices"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/fatih/color"
 	"github.com/miekg/dns"
+	"github.com/tantalor93/dnspyre/v3/pkg/dashboard"
 	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
 	"github.com/tantalor93/dnspyre/v3/pkg/printutils"
 	"github.com/tantalor93/dnspyre/v3/pkg/reporter"
+	"github.com/tantalor93/dnspyre/v3/pkg/tui"
 )
 
 var (
@@ -31,6 +41,39 @@ var (
 	benchmark = dnsbench.Benchmark{}
 
 	failConditions []string
+
+	tuiEnabled bool
+
+	dashboardAddr string
+
+	saveResultsPath string
+
+	benchmarkCmd = pApp.Command("benchmark", "Runs the DNS benchmark, this is the default command.").Default()
+
+	reportCmd = pApp.Command("report", "Generates report from the results file saved using --save-results without re-running the benchmark. "+
+		"The saved benchmark configuration is used, only the report options (--json, --silent, --color, --distribution, --min, --max, --precision, "+
+		"--percentile, --csv, --html, --plot, --plotf, --plot-chart, --domain-stats-top, --domain-stats-csv and --fail) are taken from the flags, "+
+		"other flags are rejected.")
+
+	// reportFlags are the flags accepted by the report command, the other flags configure the benchmark, which is taken from the results file.
+	reportFlags = []string{"json", "silent", "color", "distribution", "min", "max", "precision", "percentile", "csv", "html", "plot", "plotf",
+		"plot-chart", "domain-stats-top", "domain-stats-csv", "fail"}
+
+	reportResultsPath string
+
+	compareCmd = pApp.Command("compare", "Compares the candidate results file with the baseline results file, both saved using --save-results. "+
+		"Deltas of QPS, latency percentiles, error rates and response codes are printed together with significance tests of the differences "+
+		"(Welch's t-test of per-iteration median latencies, when both files contain repeated iterations, otherwise Mann-Whitney U test "+
+		"of latencies, chi-square tests of IO error rates and response code distributions) and the verdict, whether the candidate is better, "+
+		"worse, changed or there is no significant change. Only the --json, --color and --percentile flags are taken into account, other benchmark flags are rejected.")
+
+	// compareFlags are the flags accepted by the compare command.
+	compareFlags = []string{"json", "color", "percentile", "significance", "min-effect"}
+
+	compareBaselinePath  string
+	compareCandidatePath string
+	compareSignificance  float64
+	compareMinEffect     float64
 )
 
 const (
@@ -38,8 +81,18 @@ const (
 	negativeFailCondition   = "negative"
 	errorFailCondition      = "error"
 	idmismatchFailCondition = "idmismatch"
+	mismatchFailCondition   = "mismatch"
 )
 
+// failConditionOptions returns all supported fail conditions, these are the predefined conditions and the IO error types.
+func failConditionOptions() []string {
+	options := []string{ioerrorFailCondition, negativeFailCondition, errorFailCondition, idmismatchFailCondition, mismatchFailCondition}
+	for _, t := range dnsbench.ErrorTypes {
+		options = append(options, string(t))
+	}
+	return options
+}
+
 func init() {
 	pApp.Flag("server", "Server represents (plain DNS, DoT, DoH or DoQ) server, which will be benchmarked. "+
 		"Format depends on the DNS protocol, that should be used for DNS benchmark. "+
@@ -64,6 +117,26 @@ func init() {
 	pApp.Flag("rate-limit-worker", "Apply a questions / second rate limit for each concurrent worker specified by --concurrency option.").
 		Default("0").IntVar(&benchmark.RateLimitWorker)
 
+	pApp.Flag("rate-limit-burst", "Maximum number of questions sent at once by --rate-limit and --rate-limit-worker rate limits, after the questions were not sent "+
+		"at the limited rate for a while, for example because the server was slow to respond. With burst of 1 the questions are evenly spaced.").
+		Default(fmt.Sprintf("%d", dnsbench.DefaultRateBurst)).IntVar(&benchmark.RateBurst)
+
+	pApp.Flag("target-latency", "Enable adaptive rate control, the global rate limit is adjusted every --interval (or every second) to keep "+
+		"the --target-percentile latency at or below this target, for example 20ms. The rate starts at --rate-limit, or at "+
+		fmt.Sprintf("%d", dnsbench.DefaultAdaptiveRate)+" QPS when the rate is not limited. Disabled by default.").
+		PlaceHolder("20ms").DurationVar(&benchmark.TargetLatency)
+
+	pApp.Flag("target-percentile", "Latency percentile kept at or below --target-latency by the adaptive rate control.").
+		PlaceHolder(fmt.Sprintf("%g", dnsbench.DefaultTargetPercentile)).Float64Var(&benchmark.TargetPercentile)
+
+	pApp.Flag("target-error-rate", "Maximum ratio of IO errors and error responses to all questions tolerated by the adaptive rate control, "+
+		"the rate is decreased in intervals exceeding it.").
+		PlaceHolder(fmt.Sprintf("%g", dnsbench.DefaultTargetErrorRate)).Float64Var(&benchmark.TargetErrorRate)
+
+	pApp.Flag("adaptive-step", "Number of questions / second added to the rate by the adaptive rate control after each interval meeting the targets. "+
+		"Defaults to one tenth of the starting rate.").
+		PlaceHolder("10").IntVar(&benchmark.AdaptiveStep)
+
 	pApp.Flag("query-per-conn", "Queries on a connection before creating a new one. 0: unlimited. Applicable for plain DNS and DoT, this option is not considered for DoH or DoQ.").
 		Default("0").Int64Var(&benchmark.QperConn)
 
@@ -117,12 +190,28 @@ func init() {
 	pApp.Flag("precision", "Significant figure for histogram precision.").
 		Default(fmt.Sprintf("%d", dnsbench.DefaultHistPrecision)).IntVar(&benchmark.HistPre)
 
+	pApp.Flag("percentile", "Latency percentile reported in the DNS timings, for example 99.9. Repeatable flag. "+
+		"By default p99, p95, p90, p75 and p50 are reported.").
+		PlaceHolder("PERCENTILE").Float64ListVar(&benchmark.Percentiles)
+
+	pApp.Flag("raw-samples", "Maximum number of raw latency and error datapoints kept by each worker for plotting latency distribution, "+
+		"the datapoints are uniformly sampled from all responses and errors. Negative value disables keeping the datapoints.").
+		Default(fmt.Sprintf("%d", dnsbench.DefaultRawSamples)).IntVar(&benchmark.RawSamples)
+
 	pApp.Flag("distribution", "Display distribution histogram of timings to stdout. Enabled by default.").
 		Default("true").BoolVar(&benchmark.HistDisplay)
 
 	pApp.Flag("csv", "Export distribution to CSV.").
 		PlaceHolder("PATH_TO_FILE").StringVar(&benchmark.Csv)
 
+	pApp.Flag("html", "Export self-contained HTML report with the run configuration, results and charts (see --plot-chart), "+
+		"which can be shared as a single file.").
+		PlaceHolder("PATH_TO_FILE").StringVar(&benchmark.HTML)
+
+	pApp.Flag("save-results", "Save the benchmark configuration and results to the file, the report can be generated again from the file "+
+		"with different report options using 'dnspyre report <file>'.").
+		PlaceHolder("PATH_TO_FILE").StringVar(&saveResultsPath)
+
 	pApp.Flag("json", "Report benchmark results as JSON.").BoolVar(&benchmark.JSON)
 
 	pApp.Flag("silent", "Disable stdout.").BoolVar(&benchmark.Silent)
@@ -136,6 +225,10 @@ func init() {
 	pApp.Flag("plotf", "Format of graphs. Supported formats: svg, png and jpg.").
 		Default(dnsbench.DefaultPlotFormat).EnumVar(&benchmark.PlotFormat, "svg", "png", "jpg")
 
+	pApp.Flag("plot-chart", "Chart generated by --plot, all charts are generated by default. Repeatable flag. "+
+		"Supported charts: "+strings.Join(dnsbench.PlotCharts, ", ")+".").
+		PlaceHolder("CHART").EnumsVar(&benchmark.PlotCharts, dnsbench.PlotCharts...)
+
 	pApp.Flag("doh-method", "HTTP method to use for DoH requests. Supported values: get, post.").
 		PlaceHolder(dnsbench.PostHTTPMethod).EnumVar(&benchmark.DohMethod, dnsbench.GetHTTPMethod, dnsbench.PostHTTPMethod)
 
@@ -155,9 +248,12 @@ func init() {
 
 	pApp.Flag("fail", "Controls conditions upon which the dnspyre will exit with a non-zero exit code. Repeatable flag. "+
 		"Supported options are 'ioerror' (fail if there is at least 1 IO error), 'negative' (fail if there is at least 1 negative DNS answer), "+
-		"'error' (fail if there is at least 1 error DNS response), 'idmismatch' (fail there is at least 1 ID mismatch between DNS request and response).").
+		"'error' (fail if there is at least 1 error DNS response), 'idmismatch' (fail there is at least 1 ID mismatch between DNS request and response), "+
+		"'mismatch' (fail if there is at least 1 answer of server specified by --compare-server differing from the answer of benchmarked server). "+
+		"Any IO error type ('timeout', 'connection_refused', 'connection_reset', 'eof', 'unreachable', 'resolve', 'tls_alert', 'certificate', 'tls', "+
+		"'http_status', 'id_mismatch', 'malformed_response', 'other') can be used as well to fail if there is at least 1 IO error of the type.").
 		PlaceHolder("CONDITION").
-		EnumsVar(&failConditions, ioerrorFailCondition, negativeFailCondition, errorFailCondition, idmismatchFailCondition)
+		EnumsVar(&failConditions, failConditionOptions()...)
 
 	pApp.Flag("log-requests", "Controls whether the Benchmark requests are logged. Requests are logged into the file specified by --log-requests-path flag. Disabled by default.").
 		BoolVar(&benchmark.RequestLogEnabled)
@@ -175,20 +271,144 @@ func init() {
 		"two durations <GO duration>-<GO duration> (e.g. 1s-2s, 500ms-2s, etc.), where the actual delay is random value from the interval that "+
 		"is randomized after each request.").Default("0s").StringVar(&benchmark.RequestDelay)
 
+	pApp.Flag("warmup", "Warm-up phase at the start of the benchmark, during which the queries are sent normally, but their results are reported separately "+
+		"and are not part of the results and QPS. Warm-up is configured either as a duration <GO duration> (e.g. 10s) or as a number of queries (e.g. 1000). "+
+		"The warm-up is part of the benchmark configured by --number or --duration. Disabled by default.").
+		PlaceHolder("10s").StringVar(&benchmark.Warmup)
+
+	pApp.Flag("iterations", "Repeat the benchmark the specified number of times. The report contains merged results of all iterations and mean, standard deviation "+
+		"and 95% confidence interval of QPS and latency percentiles of the iterations.").
+		Default("1").IntVar(&benchmark.Iterations)
+
+	pApp.Flag("iteration-pause", "Pause between the iterations of the benchmark repeated using --iterations.").
+		PlaceHolder("5s").DurationVar(&benchmark.IterationPause)
+
+	pApp.Flag("shuffle", "Shuffle the order of the queries, when repeated using --iterations, the order is shuffled for each iteration.").
+		BoolVar(&benchmark.Shuffle)
+
+	pApp.Flag("cv-threshold", "Maximum coefficient of variation (standard deviation divided by mean) of QPS and latency percentiles of the iterations, "+
+		"above which the results are reported as unstable.").
+		Default(fmt.Sprintf("%g", dnsbench.DefaultCVThreshold)).Float64Var(&benchmark.CVThreshold)
+
 	pApp.Flag("prometheus", "Enables Prometheus metrics endpoint on the specified address. For example :8080 or localhost:8080. The endpoint is available at /metrics path.").
 		PlaceHolder("ADDRESS").StringVar(&benchmark.PrometheusMetricsAddr)
 
+	pApp.Flag("push-gateway", "Pushes Prometheus metrics of the benchmark to the Prometheus Pushgateway at the specified URL. For example http://localhost:9091. "+
+		"The metrics are grouped by job, instance and run_id labels, the pushed metrics are also labeled by server and transport.").
+		PlaceHolder("URL").StringVar(&benchmark.PushgatewayURL)
+
+	pApp.Flag("remote-write", "Pushes Prometheus metrics of the benchmark to the Prometheus remote-write endpoint at the specified URL. "+
+		"For example http://localhost:9090/api/v1/write. The series are labeled by job, instance, server, protocol and run_id labels.").
+		PlaceHolder("URL").StringVar(&benchmark.RemoteWriteURL)
+
+	pApp.Flag("push-job", "Job label of the metrics pushed by --push-gateway and --remote-write. Default is \"dnspyre\".").
+		PlaceHolder("JOB").StringVar(&benchmark.PushJob)
+
+	pApp.Flag("push-instance", "Instance label of the metrics pushed by --push-gateway and --remote-write. Default is hostname of the machine running the benchmark.").
+		PlaceHolder("INSTANCE").StringVar(&benchmark.PushInstance)
+
+	pApp.Flag("push-interval", "Controls how often the metrics are pushed by --push-gateway and --remote-write during the benchmark run, <GO duration> (e.g. 10s, 1m). "+
+		"The metrics are always pushed after the benchmark finishes. By default, the metrics are pushed only after the benchmark finishes.").
+		PlaceHolder("10s").DurationVar(&benchmark.PushInterval)
+
+	pApp.Flag("run-id", "Identifier of the benchmark run used as run_id label of the metrics pushed by --push-gateway and --remote-write. "+
+		"By default, the identifier is generated.").
+		PlaceHolder("ID").StringVar(&benchmark.RunID)
+
+	pApp.Flag("otlp-endpoint", "Exports metrics of the benchmark and sampled spans of the queries to the OpenTelemetry collector at the specified URL using OTLP. "+
+		"For example http://localhost:4317 for gRPC or http://localhost:4318 for HTTP, https scheme enables TLS.").
+		PlaceHolder("URL").StringVar(&benchmark.OTLPEndpoint)
+
+	pApp.Flag("otlp-protocol", "Protocol used by --otlp-endpoint. Supported values: grpc, http.").
+		PlaceHolder(dnsbench.OTLPGRPCProtocol).EnumVar(&benchmark.OTLPProtocol, dnsbench.OTLPGRPCProtocol, dnsbench.OTLPHTTPProtocol)
+
+	pApp.Flag("otlp-trace-sample-rate", "Ratio (from 0 to 1) of the queries, for which a span is exported to --otlp-endpoint. "+
+		"By default, no spans are exported, only metrics.").
+		PlaceHolder("0.01").Float64Var(&benchmark.OTLPTraceSampleRate)
+
 	pApp.Flag("pprof", "Enables Go pprof profiling HTTP endpoint on the specified address. For example :6060 or localhost:6060. "+
 		"The profiling information (heap, goroutine, CPU profiles, etc.) is available at /debug/pprof/ path.").
 		PlaceHolder("ADDRESS").StringVar(&benchmark.PprofAddr)
 
-	pApp.Arg("queries", "Queries to issue. It can be a local file referenced using @<file-path>, for example @data/2-domains. "+
+	pApp.Flag("compare-server", "Server, which will receive every question sent to the benchmarked server. Answers of this server are compared with the answers "+
+		"of the benchmarked server (rcode, answer RRset and AD flag) and the found differences are reported. Repeatable flag. The format is the same as for --server flag. "+
+		"The compared servers are queried in the background, these queries are not counted in --rate-limit and do not slow down the benchmark. "+
+		"When the compared servers cannot keep up with the benchmarked server, the comparisons are skipped.").
+		PlaceHolder("SERVER").StringsVar(&benchmark.CompareServers)
+
+	pApp.Flag("compare-ignore-order", "Ignore order of records in the answer section when comparing answers of --compare-server servers.").
+		BoolVar(&benchmark.CompareIgnoreOrder)
+
+	pApp.Flag("compare-ttl", "Compare TTLs of the answer records when comparing answers of --compare-server servers.").
+		BoolVar(&benchmark.CompareTTL)
+
+	pApp.Flag("compare-ttl-tolerance", "Maximum difference of TTLs of matching answer records, which is not considered an answer difference. "+
+		"Applicable only with --compare-ttl flag. The tolerance is specified in GO duration format e.g. 5s, 1m.").
+		Default("0s").DurationVar(&benchmark.CompareTTLTolerance)
+
+	pApp.Flag("ttl-analysis", "Track answer TTLs for each question and report TTL distribution and caching behaviour of the benchmarked server "+
+		"(cache hits and misses inferred from TTL decrements and latency clusters, prefetches, stale answers and TTLs differing from authoritative TTL). Disabled by default.").
+		BoolVar(&benchmark.TTLAnalysis)
+
+	pApp.Flag("authoritative-ttl", "Authoritative TTL of all queried domains used by --ttl-analysis to detect TTLs differing from authoritative TTL, "+
+		"for example because of TTL clamping, when the benchmarked server does not answer authoritatively. The TTL of authoritative answers "+
+		"of the benchmarked server or of --compare-server takes precedence. For example 1h or 300s.").
+		PlaceHolder("1h").DurationVar(&benchmark.AuthoritativeTTL)
+
+	pApp.Flag("domain-stats", "Aggregate latencies and outcomes for each domain and report the slowest domains by p95 latency "+
+		"and the domains with the most errors. Disabled by default.").BoolVar(&benchmark.DomainStats)
+
+	pApp.Flag("domain-stats-top", "Number of domains reported in the slowest domains and the most errors reports.").
+		Default(strconv.Itoa(dnsbench.DefaultDomainStatsTop)).IntVar(&benchmark.DomainStatsTop)
+
+	pApp.Flag("domain-stats-csv", "Export per-domain statistics (for each domain and query type) to CSV, enables --domain-stats.").
+		PlaceHolder("PATH_TO_FILE").StringVar(&benchmark.DomainStatsCsv)
+
+	pApp.Flag("worker-stats", "Report results of each worker and each connection separately and highlight the outlier workers and connections "+
+		"by the number of requests, error rate and p99 latency. Connections are tracked for plain DNS, DoT and DoH over HTTP/1.1 and HTTP/2. Disabled by default.").
+		BoolVar(&benchmark.WorkerStats)
+
+	pApp.Flag("interval", "Report results of the last interval periodically during the benchmark run, for example every 10s. "+
+		"Interval reports replace the progress bar. Disabled by default.").
+		PlaceHolder("10s").DurationVar(&benchmark.IntervalReport)
+
+	pApp.Flag("interval-format", "Format of interval reports, 'text' prints human-readable lines, 'ndjson' writes newline delimited JSON objects.").
+		PlaceHolder(dnsbench.TextIntervalFormat).EnumVar(&benchmark.IntervalReportFormat, dnsbench.TextIntervalFormat, dnsbench.NDJSONIntervalFormat)
+
+	pApp.Flag("interval-output", "Write interval reports to the file instead of stdout.").
+		PlaceHolder("PATH_TO_FILE").StringVar(&benchmark.IntervalReportPath)
+
+	pApp.Flag("tui", "Show live terminal dashboard with QPS and latency sparklines, rolling percentiles, response codes, top errors and worker statuses. "+
+		"The benchmark can be paused (p), stopped early with the report printed (q) and its rate limit adjusted (+/-, u) using keyboard. "+
+		"The dashboard replaces the progress bar.").BoolVar(&tuiEnabled)
+
+	pApp.Flag("dashboard", "Serves web dashboard with live charts of throughput, latency percentiles, error rate and response codes on the specified address. "+
+		"For example :8081 or localhost:8081. When the benchmark finishes, the dashboard shows the final report and it is served until interrupted.").
+		PlaceHolder("ADDRESS").StringVar(&dashboardAddr)
+
+	benchmarkCmd.Arg("queries", "Queries to issue. It can be a local file referenced using @<file-path>, for example @data/2-domains. "+
 		"It can also be resource accessible using HTTP, like https://raw.githubusercontent.com/Tantalor93/dnspyre/master/data/1000-domains, in that "+
 		"case, the file will be downloaded and saved in-memory. "+
 		"These data sources can be combined, for example \"google.com @data/2-domains https://raw.githubusercontent.com/Tantalor93/dnspyre/master/data/2-domains\". "+
 		"If not provided, default list of domains will be used.").
 		StringsVar(&benchmark.Queries)
 
+	reportCmd.Arg("file", "Results file saved using --save-results.").Required().StringVar(&reportResultsPath)
+	reportCmd.Action(allowFlags(reportFlags))
+
+	compareCmd.Flag("significance", "Significance level of the tests of the differences between the compared results, "+
+		"the differences with p-value lower than the significance level are considered significant.").
+		Default(strconv.FormatFloat(reporter.DefaultSignificance, 'f', -1, 64)).Float64Var(&compareSignificance)
+
+	compareCmd.Flag("min-effect", "Minimum relative change of the median latency, for example 0.05 means 5%, the significant differences of latencies "+
+		"with smaller change are not considered as better or worse latency. The latency samples of a single run are not independent, "+
+		"so even the runs with the same configuration often differ significantly.").
+		Default(strconv.FormatFloat(reporter.DefaultMinEffect, 'f', -1, 64)).Float64Var(&compareMinEffect)
+
+	compareCmd.Arg("baseline", "Baseline results file saved using --save-results.").Required().StringVar(&compareBaselinePath)
+	compareCmd.Arg("candidate", "Candidate results file saved using --save-results, it is compared with the baseline.").Required().StringVar(&compareCandidatePath)
+	compareCmd.Action(allowFlags(compareFlags))
+
 	info, ok := debug.ReadBuildInfo()
 	if ok && len(Version) == 0 {
 		Version = info.Main.Version
@@ -198,7 +418,27 @@ func init() {
 // Execute starts main logic of command.
 func Execute() {
 	pApp.Version(Version)
-	kingpin.MustParse(pApp.Parse(preprocessArgs(os.Args[1:])))
+	command := kingpin.MustParse(pApp.Parse(preprocessArgs(os.Args[1:])))
+	// the benchmark and the report are colored using their own settings, this controls the colors of messages printed by the command itself
+	color.NoColor = !benchmark.Color
+
+	if command == reportCmd.FullCommand() {
+		effective, res, err := report(reportResultsPath)
+		if err != nil {
+			printutils.ErrFprintf(os.Stderr, "There was an error while printing report: %s\n", err.Error())
+			os.Exit(1)
+		}
+		exitOnFailConditions(effective, res)
+		return
+	}
+
+	if command == compareCmd.FullCommand() {
+		if err := compare(compareBaselinePath, compareCandidatePath); err != nil {
+			printutils.ErrFprintf(os.Stderr, "There was an error while comparing results: %s\n", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 
 	sigsInt := make(chan os.Signal, 8)
 	signal.Notify(sigsInt, syscall.SIGINT)
@@ -219,9 +459,23 @@ func Execute() {
 		os.Exit(1)
 	}()
 
-	start := time.Now()
-	res, err := benchmark.Run(ctx)
-	end := time.Now()
+	if tuiEnabled || len(dashboardAddr) != 0 {
+		benchmark.Controller = dnsbench.NewController()
+	}
+	var webDashboard *dashboard.Server
+	var webDashboardURL string
+	if len(dashboardAddr) != 0 {
+		server, url, shutdown := startWebDashboard(ctx)
+		defer shutdown()
+		webDashboard, webDashboardURL = server, url
+	}
+	stopTUI := func() {}
+	if tuiEnabled {
+		stopTUI = startTUI(ctx)
+	}
+
+	results, err := runBenchmark(ctx)
+	stopTUI()
 
 	if err != nil {
 		printutils.ErrFprintf(os.Stderr, "There was an error while starting benchmark: %s\n", err.Error())
@@ -229,39 +483,298 @@ func Execute() {
 		os.Exit(1)
 	}
 
-	if err := reporter.PrintReport(&benchmark, res, start, end.Sub(start)); err != nil {
+	effective, iterations := results.Benchmark, results.Iterations
+	res := results.Stats
+	for _, it := range iterations {
+		res = append(res, it.Stats...)
+	}
+
+	if len(saveResultsPath) != 0 {
+		if err := saveResults(saveResultsPath, results); err != nil {
+			printutils.ErrFprintf(os.Stderr, "There was an error while saving results: %s\n", err.Error())
+		}
+	}
+
+	if iterations != nil {
+		err = reporter.PrintIterationsReport(effective, iterations)
+	} else {
+		err = reporter.PrintReport(effective, res, results.Start, results.Duration)
+	}
+	if err != nil {
 		printutils.ErrFprintf(os.Stderr, "There was an error while printing report: %s\n", err.Error())
 		close(sigsInt)
 		os.Exit(1)
 	}
 
+	if webDashboard != nil {
+		finishWebDashboard(ctx, webDashboard, webDashboardURL, effective, res, results.Duration)
+	}
+
 	close(sigsInt)
 
-	if len(failConditions) > 0 {
-		stats := reporter.Merge(&benchmark, res)
-		for _, f := range failConditions {
-			switch f {
-			case ioerrorFailCondition:
-				if stats.Counters.IOError > 0 {
-					os.Exit(1)
-				}
-			case negativeFailCondition:
-				if stats.Counters.Negative > 0 {
-					os.Exit(1)
-				}
-			case errorFailCondition:
-				if stats.Counters.Error > 0 {
-					os.Exit(1)
-				}
-			case idmismatchFailCondition:
-				if stats.Counters.IDmismatch > 0 {
-					os.Exit(1)
-				}
+	exitOnFailConditions(effective, res)
+}
+
+// runBenchmark runs the benchmark configured by the flags, the returned results hold the configuration with defaults used by the run.
+// The run identifier is resolved before the run, so the saved results share it with the pushed metrics and the exported telemetry.
+func runBenchmark(ctx context.Context) (dnsbench.Results, error) {
+	if len(benchmark.RunID) == 0 {
+		benchmark.RunID = dnsbench.NewRunID()
+	}
+
+	results := dnsbench.Results{Start: time.Now()}
+	var err error
+	if benchmark.Iterations > 1 {
+		results.Iterations, err = benchmark.RunIterations(ctx)
+	} else {
+		results.Stats, err = benchmark.Run(ctx)
+	}
+	results.Duration = time.Since(results.Start)
+	if err != nil {
+		return results, err
+	}
+
+	// the benchmark is not modified by the run, so the results are reported using the same configuration with defaults, that was used by the run
+	results.Benchmark, err = benchmark.WithDefaults()
+	return results, err
+}
+
+// exitOnFailConditions exits with non-zero exit code, when any of the fail conditions configured using --fail is met by the results.
+func exitOnFailConditions(b *dnsbench.Benchmark, res []*dnsbench.ResultStats) {
+	if len(failConditions) == 0 {
+		return
+	}
+	stats := reporter.Merge(b, res)
+	for _, f := range failConditions {
+		switch f {
+		case ioerrorFailCondition:
+			if stats.Counters.IOError > 0 {
+				os.Exit(1)
+			}
+		case negativeFailCondition:
+			if stats.Counters.Negative > 0 {
+				os.Exit(1)
+			}
+		case errorFailCondition:
+			if stats.Counters.Error > 0 {
+				os.Exit(1)
+			}
+		case idmismatchFailCondition:
+			if stats.Counters.IDmismatch > 0 {
+				os.Exit(1)
+			}
+		case mismatchFailCondition:
+			if stats.Consistency != nil && stats.Consistency.Mismatched > 0 {
+				os.Exit(1)
+			}
+		default:
+			if stats.ErrorsOfType(dnsbench.ErrorType(f)) > 0 {
+				os.Exit(1)
 			}
 		}
 	}
 }
 
+// saveResults saves the results to the file, so that the report can be generated again using report command.
+func saveResults(path string, results dnsbench.Results) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return dnsbench.WriteResults(f, results)
+}
+
+// report prints report of the results saved in the file using the report options configured by the flags,
+// it returns the benchmark configuration used for the report and the reported results.
+func report(path string) (*dnsbench.Benchmark, []*dnsbench.ResultStats, error) {
+	results, err := readResults(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := results.Benchmark
+	b.Writer = benchmark.Writer
+	b.ErrWriter = benchmark.ErrWriter
+	b.JSON = benchmark.JSON
+	b.Silent = benchmark.Silent
+	b.Color = benchmark.Color
+	b.HistDisplay = benchmark.HistDisplay
+	b.HistPre = benchmark.HistPre
+	if benchmark.HistMin != 0 {
+		b.HistMin = benchmark.HistMin
+	}
+	if benchmark.HistMax != 0 {
+		b.HistMax = benchmark.HistMax
+	}
+	if len(benchmark.Percentiles) != 0 {
+		b.Percentiles = benchmark.Percentiles
+	}
+	b.Csv = benchmark.Csv
+	b.HTML = benchmark.HTML
+	b.PlotDir = benchmark.PlotDir
+	b.PlotFormat = benchmark.PlotFormat
+	if len(benchmark.PlotCharts) != 0 {
+		b.PlotCharts = benchmark.PlotCharts
+	}
+	b.DomainStatsTop = benchmark.DomainStatsTop
+	b.DomainStatsCsv = benchmark.DomainStatsCsv
+
+	effective, err := b.WithDefaults()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stats := results.Stats
+	if results.Iterations != nil {
+		stats = nil
+		for _, it := range results.Iterations {
+			stats = append(stats, it.Stats...)
+		}
+		err = reporter.PrintIterationsReport(effective, results.Iterations)
+	} else {
+		err = reporter.PrintReport(effective, stats, results.Start, results.Duration)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return effective, stats, nil
+}
+
+// compare prints comparison of the candidate results with the baseline results saved in the files.
+func compare(baselinePath, candidatePath string) error {
+	if compareSignificance <= 0 || compareSignificance >= 1 {
+		return errors.New("--significance must have value greater than 0 and lower than 1")
+	}
+	if compareMinEffect < 0 {
+		return errors.New("--min-effect must not be negative")
+	}
+	for _, p := range benchmark.Percentiles {
+		if p <= 0 || p > 100 {
+			return errors.New("--percentile must have value greater than 0 and at most 100")
+		}
+	}
+	baseline, err := readResults(baselinePath)
+	if err != nil {
+		return err
+	}
+	candidate, err := readResults(candidatePath)
+	if err != nil {
+		return err
+	}
+
+	b := &dnsbench.Benchmark{
+		Writer:      benchmark.Writer,
+		JSON:        benchmark.JSON,
+		Color:       benchmark.Color,
+		Percentiles: benchmark.Percentiles,
+	}
+	if b.Writer == nil {
+		b.Writer = os.Stdout
+	}
+	return reporter.PrintComparison(b, baseline, candidate, reporter.CompareOptions{Significance: compareSignificance, MinEffect: compareMinEffect})
+}
+
+// readResults reads the results saved in the file using --save-results.
+func readResults(path string) (dnsbench.Results, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return dnsbench.Results{}, err
+	}
+	defer f.Close()
+	return dnsbench.ReadResults(f)
+}
+
+// startTUI shows live terminal dashboard controlling the benchmark, the returned function closes the dashboard and restores the terminal.
+func startTUI(ctx context.Context) func() {
+	if err := tui.CheckTerminal(os.Stdin, os.Stdout); err != nil {
+		printutils.ErrFprintf(os.Stderr, "Failed to show live dashboard: %s\n", err.Error())
+		os.Exit(1)
+	}
+	if benchmark.IntervalReport > 0 && len(benchmark.IntervalReportPath) == 0 {
+		printutils.ErrFprintf(os.Stderr, "Interval reports cannot be printed to stdout together with live dashboard, use --interval-output\n")
+		os.Exit(1)
+	}
+
+	benchmark.ProgressBar = false
+	// messages printed by the benchmark to the standard output would break the dashboard, they are discarded until the dashboard is closed
+	writer := benchmark.Writer
+	benchmark.Writer = io.Discard
+	dashboard := tui.New(benchmark.Controller, benchmark.Server)
+	benchmark.Controller.OnInterval(dashboard.Update)
+
+	dashboardCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := dashboard.Run(dashboardCtx, os.Stdin, os.Stdout); err != nil {
+			printutils.ErrFprintf(os.Stderr, "Failed to show live dashboard: %s\n", err.Error())
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+		benchmark.Writer = writer
+	}
+}
+
+// startWebDashboard serves web dashboard showing live results of the benchmark, the URL of the dashboard and function shutting down
+// the HTTP server are returned.
+func startWebDashboard(ctx context.Context) (*dashboard.Server, string, func()) {
+	listener, err := net.Listen("tcp", dashboardAddr)
+	if err != nil {
+		printutils.ErrFprintf(os.Stderr, "Failed to start web dashboard at %s: %s\n", dashboardAddr, err.Error())
+		os.Exit(1)
+	}
+
+	server := dashboard.New(benchmark.Controller, benchmark.Server)
+	// nolint:gosec
+	httpServer := http.Server{Handler: server.Handler()}
+	go func() {
+		if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			printutils.ErrFprintf(os.Stderr, "Failed to serve web dashboard at %s: %s\n", dashboardAddr, err.Error())
+		}
+	}()
+	url := dashboardURL(listener.Addr())
+	printutils.NeutralFprintf(os.Stderr, "Web dashboard is available at %s\n", printutils.HighlightSprint(url))
+
+	return server, url, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}
+}
+
+// finishWebDashboard publishes the final report to the web dashboard and keeps serving the dashboard until the benchmark is interrupted.
+func finishWebDashboard(ctx context.Context, server *dashboard.Server, url string, b *dnsbench.Benchmark, res []*dnsbench.ResultStats, benchDuration time.Duration) {
+	report := bytes.Buffer{}
+	if err := reporter.WriteJSONReport(&report, b, res, benchDuration); err != nil {
+		printutils.ErrFprintf(os.Stderr, "Failed to create report for web dashboard: %s\n", err.Error())
+		return
+	}
+	server.Finish(report.Bytes())
+
+	if ctx.Err() != nil {
+		// benchmark was interrupted, do not wait for another interrupt
+		return
+	}
+	printutils.NeutralFprintf(os.Stderr, "\nFinal report is available in web dashboard at %s, press Ctrl+C to exit\n",
+		printutils.HighlightSprint(url))
+	<-ctx.Done()
+}
+
+// dashboardURL returns URL of the web dashboard listening on the address, unspecified address is replaced with localhost.
+func dashboardURL(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "http://" + addr.String() + "/"
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/"
+}
+
 func getSupportedDNSTypes() []string {
 	keys := make([]string, 0, len(dns.StringToType))
 	for k := range dns.StringToType {
@@ -270,6 +783,22 @@ func getSupportedDNSTypes() []string {
 	return keys
 }
 
+// allowFlags returns action rejecting the flags, which are not allowed for the selected command. The benchmark flags are defined
+// on the application level, so they are parsed for all commands, but the report and compare commands take only some of them into account.
+func allowFlags(allowed []string) kingpin.Action {
+	return func(context *kingpin.ParseContext) error {
+		for _, element := range context.Elements {
+			flag, ok := element.Clause.(*kingpin.FlagClause)
+			if !ok || slices.Contains(allowed, flag.Model().Name) {
+				continue
+			}
+			return fmt.Errorf("flag '--%s' is not supported by '%s' command, supported flags: --%s", flag.Model().Name,
+				context.SelectedCommand.FullCommand(), strings.Join(allowed, ", --"))
+		}
+		return nil
+	}
+}
+
 // preprocessArgs transforms bare --edns0 (no value) into --edns0=<DefaultEdns0BufferSize>
 // so that kingpin's Uint16Var does not require an explicit value. When the token
 // immediately following --edns0 looks like a uint16, it is left alone and kingpin
@@ -1,12 +1,23 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+	"github.com/tantalor93/dnspyre/v3/pkg/reporter"
 )
 
 // parseArgs resets all package-level state and parses the given args through pApp.
@@ -16,6 +27,14 @@ func parseArgs(t *testing.T, args []string) {
 	t.Helper()
 	benchmark = dnsbench.Benchmark{}
 	failConditions = nil
+	tuiEnabled = false
+	dashboardAddr = ""
+	saveResultsPath = ""
+	reportResultsPath = ""
+	compareBaselinePath = ""
+	compareCandidatePath = ""
+	compareSignificance = 0
+	compareMinEffect = 0
 	_, err := pApp.Parse(preprocessArgs(args))
 	require.NoError(t, err)
 }
@@ -35,6 +54,10 @@ func defaultBenchmark(queries []string) dnsbench.Benchmark {
 		RequestTimeout: dnsbench.DefaultRequestTimeout,
 		Rcodes:         true,
 		HistPre:        dnsbench.DefaultHistPrecision,
+		RawSamples:     dnsbench.DefaultRawSamples,
+		RateBurst:      dnsbench.DefaultRateBurst,
+		Iterations:     1,
+		CVThreshold:    dnsbench.DefaultCVThreshold,
 		HistDisplay:    true,
 		Color:          true,
 		PlotFormat:     dnsbench.DefaultPlotFormat,
@@ -42,6 +65,7 @@ func defaultBenchmark(queries []string) dnsbench.Benchmark {
 		Queries:        queries,
 		RequestLogPath: dnsbench.DefaultRequestLogPath,
 		RequestDelay:   "0s",
+		DomainStatsTop: dnsbench.DefaultDomainStatsTop,
 	}
 }
 
@@ -51,6 +75,9 @@ func TestFlagParsing(t *testing.T) {
 		args                   []string
 		expected               dnsbench.Benchmark
 		expectedFailConditions []string
+		expectedTUI            bool
+		expectedDashboardAddr  string
+		expectedSaveResults    string
 	}{
 		// --- defaults ---
 		{
@@ -251,6 +278,27 @@ func TestFlagParsing(t *testing.T) {
 				return b
 			}(),
 		},
+		{
+			name: "rate-limit-burst flag",
+			args: []string{"--rate-limit-burst=10", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.RateBurst = 10
+				return b
+			}(),
+		},
+		{
+			name: "adaptive rate flags",
+			args: []string{"--target-latency=20ms", "--target-percentile=95", "--target-error-rate=0.05", "--adaptive-step=50", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.TargetLatency = 20 * time.Millisecond
+				b.TargetPercentile = 95
+				b.TargetErrorRate = 0.05
+				b.AdaptiveStep = 50
+				return b
+			}(),
+		},
 		{
 			name: "rate-limit-worker flag",
 			args: []string{"--rate-limit-worker=50", "google.com"},
@@ -308,6 +356,12 @@ func TestFlagParsing(t *testing.T) {
 			expected:               defaultBenchmark([]string{"google.com"}),
 			expectedFailConditions: []string{"ioerror", "negative", "error"},
 		},
+		{
+			name:                   "fail flag error type conditions",
+			args:                   []string{"--fail=timeout", "--fail=tls_alert", "google.com"},
+			expected:               defaultBenchmark([]string{"google.com"}),
+			expectedFailConditions: []string{"timeout", "tls_alert"},
+		},
 		{
 			name: "separate-worker-connections flag",
 			args: []string{"--separate-worker-connections", "google.com"},
@@ -326,6 +380,36 @@ func TestFlagParsing(t *testing.T) {
 				return b
 			}(),
 		},
+		{
+			name: "warmup duration",
+			args: []string{"--warmup=10s", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.Warmup = "10s"
+				return b
+			}(),
+		},
+		{
+			name: "warmup queries",
+			args: []string{"--warmup=1000", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.Warmup = "1000"
+				return b
+			}(),
+		},
+		{
+			name: "iterations flags",
+			args: []string{"--iterations=5", "--iteration-pause=5s", "--shuffle", "--cv-threshold=0.05", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.Iterations = 5
+				b.IterationPause = 5 * time.Second
+				b.Shuffle = true
+				b.CVThreshold = 0.05
+				return b
+			}(),
+		},
 		{
 			name: "request-delay randomized interval",
 			args: []string{"--request-delay=100ms-500ms", "google.com"},
@@ -371,6 +455,157 @@ func TestFlagParsing(t *testing.T) {
 				return b
 			}(),
 		},
+		{
+			name: "compare-server flags",
+			args: []string{"--compare-server=1.1.1.1", "--compare-server=https://1.1.1.1", "--compare-ignore-order", "--compare-ttl",
+				"--compare-ttl-tolerance=10s", "--fail=mismatch", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.CompareServers = []string{"1.1.1.1", "https://1.1.1.1"}
+				b.CompareIgnoreOrder = true
+				b.CompareTTL = true
+				b.CompareTTLTolerance = 10 * time.Second
+				return b
+			}(),
+			expectedFailConditions: []string{"mismatch"},
+		},
+		{
+			name: "ttl-analysis flag",
+			args: []string{"--ttl-analysis", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.TTLAnalysis = true
+				return b
+			}(),
+		},
+		{
+			name: "authoritative-ttl flag",
+			args: []string{"--ttl-analysis", "--authoritative-ttl", "1h", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.TTLAnalysis = true
+				b.AuthoritativeTTL = time.Hour
+				return b
+			}(),
+		},
+		{
+			name: "domain-stats flags",
+			args: []string{"--domain-stats", "--domain-stats-top", "5", "--domain-stats-csv", "/tmp/domains.csv", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.DomainStats = true
+				b.DomainStatsTop = 5
+				b.DomainStatsCsv = "/tmp/domains.csv"
+				return b
+			}(),
+		},
+		{
+			name: "worker-stats flag",
+			args: []string{"--worker-stats", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.WorkerStats = true
+				return b
+			}(),
+		},
+		{
+			name: "html flag",
+			args: []string{"--html", "/tmp/report.html", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.HTML = "/tmp/report.html"
+				return b
+			}(),
+		},
+		{
+			name: "plot flags",
+			args: []string{"--plot", "/tmp/graphs", "--plotf", "png", "--plot-chart", "latency-cdf", "--plot-chart", "qtype-boxplot", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.PlotDir = "/tmp/graphs"
+				b.PlotFormat = "png"
+				b.PlotCharts = []string{dnsbench.LatencyCDFChart, dnsbench.QtypeBoxPlotChart}
+				return b
+			}(),
+		},
+		{
+			name: "interval flags",
+			args: []string{"--interval", "10s", "--interval-format", "ndjson", "--interval-output", "/tmp/intervals.ndjson", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.IntervalReport = 10 * time.Second
+				b.IntervalReportFormat = dnsbench.NDJSONIntervalFormat
+				b.IntervalReportPath = "/tmp/intervals.ndjson"
+				return b
+			}(),
+		},
+		{
+			name: "push flags",
+			args: []string{"--push-gateway", "http://localhost:9091", "--remote-write", "http://localhost:9090/api/v1/write", "--push-job", "job",
+				"--push-instance", "instance", "--push-interval", "10s", "--run-id", "run", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.PushgatewayURL = "http://localhost:9091"
+				b.RemoteWriteURL = "http://localhost:9090/api/v1/write"
+				b.PushJob = "job"
+				b.PushInstance = "instance"
+				b.PushInterval = 10 * time.Second
+				b.RunID = "run"
+				return b
+			}(),
+		},
+		{
+			name: "otlp flags",
+			args: []string{"--otlp-endpoint", "http://localhost:4318", "--otlp-protocol", "http", "--otlp-trace-sample-rate", "0.1", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.OTLPEndpoint = "http://localhost:4318"
+				b.OTLPProtocol = dnsbench.OTLPHTTPProtocol
+				b.OTLPTraceSampleRate = 0.1
+				return b
+			}(),
+		},
+		{
+			name: "raw samples flag",
+			args: []string{"--raw-samples=-1", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.RawSamples = -1
+				return b
+			}(),
+		},
+		{
+			name:        "tui flag",
+			args:        []string{"--tui", "google.com"},
+			expected:    defaultBenchmark([]string{"google.com"}),
+			expectedTUI: true,
+		},
+		{
+			name:                  "dashboard flag",
+			args:                  []string{"--dashboard", "localhost:8081", "google.com"},
+			expected:              defaultBenchmark([]string{"google.com"}),
+			expectedDashboardAddr: "localhost:8081",
+		},
+		{
+			name: "percentile flag",
+			args: []string{"--percentile", "99.9", "--percentile", "50", "google.com"},
+			expected: func() dnsbench.Benchmark {
+				b := defaultBenchmark([]string{"google.com"})
+				b.Percentiles = []float64{99.9, 50}
+				return b
+			}(),
+		},
+		{
+			name:                "save results flag",
+			args:                []string{"--save-results", "/tmp/results.json", "google.com"},
+			expected:            defaultBenchmark([]string{"google.com"}),
+			expectedSaveResults: "/tmp/results.json",
+		},
+		{
+			name:     "explicit benchmark command",
+			args:     []string{"benchmark", "google.com"},
+			expected: defaultBenchmark([]string{"google.com"}),
+		},
 		{
 			name:     "queries positional argument",
 			args:     []string{"google.com", "cloudflare.com"},
@@ -383,6 +618,267 @@ func TestFlagParsing(t *testing.T) {
 			parseArgs(t, tt.args)
 			assert.Equal(t, tt.expected, benchmark)
 			assert.Equal(t, tt.expectedFailConditions, failConditions)
+			assert.Equal(t, tt.expectedTUI, tuiEnabled)
+			assert.Equal(t, tt.expectedDashboardAddr, dashboardAddr)
+			assert.Equal(t, tt.expectedSaveResults, saveResultsPath)
+		})
+	}
+}
+
+func TestReportCommandParsing(t *testing.T) {
+	parseArgs(t, []string{"report", "--json", "--percentile", "99.9", "results.json"})
+
+	assert.Equal(t, "results.json", reportResultsPath)
+	assert.True(t, benchmark.JSON)
+	assert.Equal(t, []float64{99.9}, benchmark.Percentiles)
+	assert.Empty(t, benchmark.Queries)
+}
+
+func TestUnsupportedCommandFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "report with benchmark flags",
+			args:    []string{"report", "--server", "1.2.3.4", "-n", "5", "results.json"},
+			wantErr: "flag '--server' is not supported by 'report' command",
+		},
+		{
+			name:    "benchmark flag before report command",
+			args:    []string{"--concurrency", "5", "report", "results.json"},
+			wantErr: "flag '--concurrency' is not supported by 'report' command",
+		},
+		{
+			name:    "compare with report flag",
+			args:    []string{"compare", "--html", "report.html", "baseline.json", "candidate.json"},
+			wantErr: "flag '--html' is not supported by 'compare' command",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pApp.Parse(preprocessArgs(tt.args))
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.json")
+
+	saved := dnsbench.Benchmark{Server: "8.8.8.8", Count: 1, Rcodes: true}
+	effective, err := saved.WithDefaults()
+	require.NoError(t, err)
+	rs := &dnsbench.ResultStats{
+		Codes:    map[int]int64{dns.RcodeSuccess: 2},
+		Qtypes:   map[string]int64{"A": 2},
+		Hist:     hdrhistogram.New(0, time.Second.Nanoseconds(), 3),
+		Counters: &dnsbench.Counters{Total: 2, Success: 2},
+	}
+	require.NoError(t, rs.Hist.RecordValue(time.Millisecond.Nanoseconds()))
+	require.NoError(t, rs.Hist.RecordValue(3*time.Millisecond.Nanoseconds()))
+	require.NoError(t, saveResults(path, dnsbench.Results{Benchmark: effective, Start: time.Now(), Duration: time.Second, Stats: []*dnsbench.ResultStats{rs}}))
+
+	parseArgs(t, []string{"report", "--json", "--percentile", "99.9", "--html", filepath.Join(dir, "report.html"), path})
+	buffer := bytes.Buffer{}
+	benchmark.Writer = &buffer
+
+	b, stats, err := report(reportResultsPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, "8.8.8.8:53", b.Server)
+	assert.True(t, b.JSON)
+	require.Len(t, stats, 1)
+	assert.EqualValues(t, 2, stats[0].Hist.TotalCount())
+	assert.Contains(t, buffer.String(), `"totalRequests":2`)
+	assert.Contains(t, buffer.String(), `"percentilesMs":{"p99.9":3}`)
+	assert.FileExists(t, filepath.Join(dir, "report.html"))
+}
+
+func TestRunBenchmark_run_id(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		_ = w.WriteMsg(ret)
+	})}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	defer server.Shutdown()
+
+	var mu sync.Mutex
+	var pushPaths []string
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		pushPaths = append(pushPaths, r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer pushgateway.Close()
+
+	path := filepath.Join(t.TempDir(), "results.json")
+	parseArgs(t, []string{"--server", pc.LocalAddr().String(), "-n", "1", "-c", "1", "--silent", "--no-progress",
+		"--push-gateway", pushgateway.URL, "--push-instance", "test", "--save-results", path, "example.org"})
+
+	results, err := runBenchmark(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, saveResults(saveResultsPath, results))
+	saved, err := readResults(path)
+	require.NoError(t, err)
+
+	require.NotEmpty(t, saved.Benchmark.RunID, "run identifier should be saved together with the results")
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, pushPaths, 1)
+	assert.True(t, strings.Contains(pushPaths[0]+"/", "/run_id/"+saved.Benchmark.RunID+"/"),
+		"pushed run identifier %s should match the saved run identifier %s", pushPaths[0], saved.Benchmark.RunID)
+}
+
+func TestReport_missing_file(t *testing.T) {
+	parseArgs(t, []string{"report", "non-existing-results.json"})
+
+	_, _, err := report(reportResultsPath)
+	require.Error(t, err)
+}
+
+func TestCompareCommandParsing(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             []string
+		wantSignificance float64
+		wantMinEffect    float64
+	}{
+		{
+			name:             "default options",
+			args:             []string{"compare", "baseline.json", "candidate.json"},
+			wantSignificance: reporter.DefaultSignificance,
+			wantMinEffect:    reporter.DefaultMinEffect,
+		},
+		{
+			name:             "significance flag",
+			args:             []string{"compare", "--significance", "0.01", "baseline.json", "candidate.json"},
+			wantSignificance: 0.01,
+			wantMinEffect:    reporter.DefaultMinEffect,
+		},
+		{
+			name:             "min effect flag",
+			args:             []string{"compare", "--min-effect", "0.1", "baseline.json", "candidate.json"},
+			wantSignificance: reporter.DefaultSignificance,
+			wantMinEffect:    0.1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parseArgs(t, tt.args)
+
+			assert.Equal(t, "baseline.json", compareBaselinePath)
+			assert.Equal(t, "candidate.json", compareCandidatePath)
+			assert.InDelta(t, tt.wantSignificance, compareSignificance, 1e-9)
+			assert.InDelta(t, tt.wantMinEffect, compareMinEffect, 1e-9)
+			assert.Empty(t, benchmark.Queries)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	dir := t.TempDir()
+	save := func(name string, latencies ...time.Duration) string {
+		saved := dnsbench.Benchmark{Server: "8.8.8.8", Count: 1, Rcodes: true}
+		effective, err := saved.WithDefaults()
+		require.NoError(t, err)
+		rs := &dnsbench.ResultStats{
+			Codes:    map[int]int64{dns.RcodeSuccess: int64(len(latencies))},
+			Qtypes:   map[string]int64{"A": int64(len(latencies))},
+			Hist:     hdrhistogram.New(0, time.Second.Nanoseconds(), 3),
+			Counters: &dnsbench.Counters{Total: int64(len(latencies)), Success: int64(len(latencies))},
+		}
+		for _, l := range latencies {
+			require.NoError(t, rs.Hist.RecordValue(l.Nanoseconds()))
+			rs.Timings = append(rs.Timings, dnsbench.Datapoint{Duration: l})
+		}
+		path := filepath.Join(dir, name)
+		require.NoError(t, saveResults(path, dnsbench.Results{Benchmark: effective, Start: time.Now(), Duration: time.Second, Stats: []*dnsbench.ResultStats{rs}}))
+		return path
+	}
+	var baselineLatencies, candidateLatencies []time.Duration
+	for i := range 20 {
+		baselineLatencies = append(baselineLatencies, time.Duration(10+i)*time.Millisecond)
+		candidateLatencies = append(candidateLatencies, time.Duration(5+i/2)*time.Millisecond)
+	}
+	baseline := save("baseline.json", baselineLatencies...)
+	candidate := save("candidate.json", candidateLatencies...)
+
+	parseArgs(t, []string{"compare", "--json", "--percentile", "50", baseline, candidate})
+	buffer := bytes.Buffer{}
+	benchmark.Writer = &buffer
+
+	require.NoError(t, compare(compareBaselinePath, compareCandidatePath))
+	assert.Contains(t, buffer.String(), `"verdict":"better"`)
+	assert.Contains(t, buffer.String(), `"latencyMs":{"p50":`)
+}
+
+func TestCompare_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "missing file",
+			args: []string{"compare", "non-existing-baseline.json", "non-existing-candidate.json"},
+		},
+		{
+			name: "invalid significance",
+			args: []string{"compare", "--significance", "1.5", "baseline.json", "candidate.json"},
+		},
+		{
+			name: "invalid min effect",
+			args: []string{"compare", "--min-effect=-0.1", "baseline.json", "candidate.json"},
+		},
+		{
+			name: "invalid percentile",
+			args: []string{"compare", "--percentile", "0", "baseline.json", "candidate.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parseArgs(t, tt.args)
+
+			require.Error(t, compare(compareBaselinePath, compareCandidatePath))
+		})
+	}
+}
+
+func TestDashboardURL(t *testing.T) {
+	tests := []struct {
+		name string
+		addr net.Addr
+		want string
+	}{
+		{
+			name: "unspecified IPv4 address",
+			addr: &net.TCPAddr{IP: net.IPv4zero, Port: 8081},
+			want: "http://localhost:8081/",
+		},
+		{
+			name: "unspecified IPv6 address",
+			addr: &net.TCPAddr{IP: net.IPv6unspecified, Port: 8081},
+			want: "http://localhost:8081/",
+		},
+		{
+			name: "specific address",
+			addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8081},
+			want: "http://127.0.0.1:8081/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, dashboardURL(tt.addr))
 		})
 	}
 }
@@ -0,0 +1,2 @@
+// Package dashboard provides embedded web dashboard showing live results of the running dnsbench.Benchmark and its final report.
+package dashboard
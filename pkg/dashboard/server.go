@@ -0,0 +1,246 @@
+package dashboard
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+
+	"github.com/miekg/dns"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+const (
+	// maxHistory limits number of the last intervals replayed to newly connected clients.
+	maxHistory = 3600
+
+	// subscriberBuffer is the number of interval events buffered for each connected client, events are dropped for clients not keeping up.
+	// One more slot is reserved in the buffer for the final report, so that the report is always delivered.
+	subscriberBuffer = 64
+
+	intervalEvent = "interval"
+	reportEvent   = "report"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+type event struct {
+	name string
+	data []byte
+}
+
+// jsonInterval represents results of single interval sent to the dashboard.
+type jsonInterval struct {
+	ElapsedSeconds   float64          `json:"elapsedSeconds"`
+	QueriesPerSecond float64          `json:"queriesPerSecond"`
+	TotalRequests    int64            `json:"totalRequests"`
+	Success          int64            `json:"successResponses"`
+	Negative         int64            `json:"negativeResponses"`
+	Error            int64            `json:"errorResponses"`
+	IOErrors         int64            `json:"ioErrors"`
+	ErrorRate        float64          `json:"errorRate"`
+	P50Ms            float64          `json:"p50Ms"`
+	P95Ms            float64          `json:"p95Ms"`
+	P99Ms            float64          `json:"p99Ms"`
+	ResponseRcodes   map[string]int64 `json:"responseRcodes"`
+}
+
+// Server serves the web dashboard. The live results are streamed to the browser as server-sent events, when the benchmark finishes,
+// the final report is shown.
+type Server struct {
+	title string
+
+	mu          sync.Mutex
+	history     []event
+	report      []byte
+	subscribers map[chan event]struct{}
+}
+
+// New creates new Server subscribed to the live results of the benchmark controlled by the controller, title is shown in the header of the dashboard.
+func New(controller *dnsbench.Controller, title string) *Server {
+	s := &Server{title: title, subscribers: make(map[chan event]struct{})}
+	controller.OnInterval(s.update)
+	return s
+}
+
+// Handler returns HTTP handler serving the dashboard page at / path, the stream of events at /events path
+// and the final report at /report path.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.index)
+	mux.HandleFunc("GET /events", s.events)
+	mux.HandleFunc("GET /report", s.finalReport)
+	return mux
+}
+
+// Finish publishes the final report of the benchmark formatted as JSON, see reporter.WriteJSONReport.
+func (s *Server) Finish(report []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report = report
+	for ch := range s.subscribers {
+		// the slot reserved for the final report is always free, see send
+		ch <- event{name: reportEvent, data: report}
+		close(ch)
+		delete(s.subscribers, ch)
+	}
+}
+
+func (s *Server) update(is dnsbench.IntervalStats) {
+	data, err := json.Marshal(newJSONInterval(is))
+	if err != nil {
+		return
+	}
+	e := event{name: intervalEvent, data: data}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = append(s.history, e)
+	if len(s.history) > maxHistory {
+		s.history = s.history[len(s.history)-maxHistory:]
+	}
+	for ch := range s.subscribers {
+		s.send(ch, e)
+	}
+}
+
+// send sends event to the subscriber without blocking, the caller must hold the lock. The last slot of the subscriber buffer
+// is kept free for the final report.
+func (s *Server) send(ch chan event, e event) {
+	if len(ch) >= subscriberBuffer {
+		// client is not keeping up, the event is dropped
+		return
+	}
+	ch <- e
+}
+
+// subscribe registers new client receiving the live events, the caller must hold the lock.
+func (s *Server) subscribe() chan event {
+	ch := make(chan event, subscriberBuffer+1)
+	s.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (s *Server) index(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+func (s *Server) finalReport(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	report := s.report
+	s.mu.Unlock()
+
+	if report == nil {
+		http.Error(w, "benchmark is still running", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(report)
+}
+
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	s.mu.Lock()
+	history := s.history
+	report := s.report
+	var ch chan event
+	if report == nil {
+		ch = s.subscribe()
+	}
+	s.mu.Unlock()
+
+	titleData, _ := json.Marshal(s.title)
+	if err := writeEvent(w, event{name: "title", data: titleData}); err != nil {
+		s.unsubscribe(ch)
+		return
+	}
+	for _, e := range history {
+		if err := writeEvent(w, e); err != nil {
+			s.unsubscribe(ch)
+			return
+		}
+	}
+	if report != nil {
+		writeEvent(w, event{name: reportEvent, data: report})
+		flusher.Flush()
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			s.unsubscribe(ch)
+			return
+		case e, ok := <-ch:
+			if !ok {
+				// the final report was sent
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				s.unsubscribe(ch)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) unsubscribe(ch chan event) {
+	if ch == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscribers, ch)
+}
+
+func writeEvent(w http.ResponseWriter, e event) error {
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, e.data)
+	return err
+}
+
+func newJSONInterval(is dnsbench.IntervalStats) jsonInterval {
+	rcodes := make(map[string]int64, len(is.Codes))
+	for k, v := range is.Codes {
+		name, ok := dns.RcodeToString[k]
+		if !ok {
+			name = fmt.Sprintf("Unknown(%d)", k)
+		}
+		rcodes[name] = v
+	}
+	var errorRate float64
+	if is.Counters.Total > 0 {
+		errorRate = float64(is.Counters.IOError+is.Counters.Error) / float64(is.Counters.Total)
+	}
+	return jsonInterval{
+		ElapsedSeconds:   math.Round(is.Elapsed.Seconds()*1000) / 1000,
+		QueriesPerSecond: math.Round(is.QPS()*100) / 100,
+		TotalRequests:    is.Counters.Total,
+		Success:          is.Counters.Success,
+		Negative:         is.Counters.Negative,
+		Error:            is.Counters.Error,
+		IOErrors:         is.Counters.IOError,
+		ErrorRate:        math.Round(errorRate*10000) / 10000,
+		P50Ms:            durationMs(is.Hist.ValueAtQuantile(50)),
+		P95Ms:            durationMs(is.Hist.ValueAtQuantile(95)),
+		P99Ms:            durationMs(is.Hist.ValueAtQuantile(99)),
+		ResponseRcodes:   rcodes,
+	}
+}
+
+func durationMs(ns int64) float64 {
+	return math.Round(float64(ns)/1e4) / 100
+}
@@ -0,0 +1,136 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+func TestServer_index(t *testing.T) {
+	s := New(dnsbench.NewController(), "127.0.0.1:53")
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "new EventSource(\"events\")")
+}
+
+func TestServer_report(t *testing.T) {
+	s := New(dnsbench.NewController(), "127.0.0.1:53")
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/report")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "report should not be available while the benchmark is running")
+
+	s.Finish([]byte(`{"totalRequests":2}`))
+
+	resp, err = http.Get(srv.URL + "/report")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"totalRequests":2}`, string(body))
+}
+
+func TestServer_events(t *testing.T) {
+	s := New(dnsbench.NewController(), "127.0.0.1:53")
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	s.update(testIntervalStats(time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	assert.Equal(t, "event: title\ndata: \"127.0.0.1:53\"\n\n", readEvent(t, reader))
+	interval := "event: interval\ndata: {\"elapsedSeconds\":1,\"queriesPerSecond\":4,\"totalRequests\":4,\"successResponses\":2," +
+		"\"negativeResponses\":0,\"errorResponses\":1,\"ioErrors\":1,\"errorRate\":0.5,\"p50Ms\":10,\"p95Ms\":20,\"p99Ms\":20," +
+		"\"responseRcodes\":{\"NOERROR\":2,\"SERVFAIL\":1}}\n\n"
+	assert.Equal(t, interval, readEvent(t, reader), "history should be replayed to the new client")
+
+	s.update(testIntervalStats(2 * time.Second))
+	assert.Equal(t, strings.Replace(interval, "\"elapsedSeconds\":1", "\"elapsedSeconds\":2", 1), readEvent(t, reader),
+		"live intervals should be streamed")
+
+	s.Finish([]byte(`{"totalRequests":8}`))
+	assert.Equal(t, "event: report\ndata: {\"totalRequests\":8}\n\n", readEvent(t, reader))
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF, "stream should end after the final report")
+}
+
+func TestServer_Finish_slow_client(t *testing.T) {
+	s := New(dnsbench.NewController(), "127.0.0.1:53")
+	s.mu.Lock()
+	ch := s.subscribe()
+	s.mu.Unlock()
+
+	for i := range 2 * subscriberBuffer {
+		s.update(testIntervalStats(time.Duration(i) * time.Second))
+	}
+	s.Finish([]byte(`{"totalRequests":8}`))
+
+	var events []event
+	for e := range ch {
+		events = append(events, e)
+	}
+	require.Len(t, events, subscriberBuffer+1, "intervals should be dropped for the client not keeping up")
+	assert.Equal(t, event{name: reportEvent, data: []byte(`{"totalRequests":8}`)}, events[len(events)-1],
+		"final report should be delivered to the client not keeping up")
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var sb strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		sb.WriteString(line)
+		if line == "\n" {
+			return sb.String()
+		}
+	}
+}
+
+func testIntervalStats(elapsed time.Duration) dnsbench.IntervalStats {
+	hist := hdrhistogram.New(0, time.Second.Nanoseconds(), 3)
+	hist.RecordValue((10 * time.Millisecond).Nanoseconds())
+	hist.RecordValue((10 * time.Millisecond).Nanoseconds())
+	hist.RecordValue((20 * time.Millisecond).Nanoseconds())
+	return dnsbench.IntervalStats{
+		Duration: time.Second,
+		Elapsed:  elapsed,
+		Counters: dnsbench.Counters{Total: 4, Success: 2, Error: 1, IOError: 1},
+		Hist:     hist,
+		Codes:    map[int]int64{dns.RcodeSuccess: 2, dns.RcodeServerFailure: 1},
+		Errors:   map[string]int64{"i/o timeout": 1},
+	}
+}
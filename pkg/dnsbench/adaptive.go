@@ -0,0 +1,92 @@
+package dnsbench
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// adaptiveDecreaseFactor is the factor, by which the rate is multiplied, when the observed latency or error rate exceeds the target.
+	adaptiveDecreaseFactor = 0.7
+	// adaptiveSaturation is the minimal ratio of the achieved and limited rate, below which the rate is not increased anymore,
+	// because the benchmark is not able to send queries at the limited rate (for example because of low concurrency).
+	adaptiveSaturation = 0.9
+)
+
+// RateAdjustment represents single adjustment of the global rate limit done by the adaptive rate control (see Benchmark.TargetLatency).
+type RateAdjustment struct {
+	// Time is the time of the adjustment.
+	Time time.Time
+	// Elapsed is the time elapsed since the start of the benchmark until the adjustment.
+	Elapsed time.Duration
+	// PreviousRate is the global rate limit of queries per second before the adjustment.
+	PreviousRate int
+	// Rate is the global rate limit of queries per second after the adjustment.
+	Rate int
+	// Latency is the latency percentile (Benchmark.TargetPercentile) observed within the interval preceding the adjustment.
+	Latency time.Duration
+	// ErrorRate is the ratio of IO errors and error responses to all requests within the interval preceding the adjustment.
+	ErrorRate float64
+	// QPS is the number of requests per second finished within the interval preceding the adjustment.
+	QPS float64
+}
+
+// adaptiveRate is a closed-loop controller of the global rate limit. It uses additive increase and multiplicative decrease (AIMD),
+// the rate is increased by Benchmark.AdaptiveStep after each interval meeting the targets and multiplied by adaptiveDecreaseFactor
+// after each interval exceeding the target latency or error rate.
+type adaptiveRate struct {
+	b       *Benchmark
+	limiter *RateLimiter
+	// out is a writer, where the adjustments are logged, nil means the adjustments are not logged.
+	out io.Writer
+	// adjustments are accessed only by the interval reporter and read after the reporter finished.
+	adjustments []RateAdjustment
+}
+
+func newAdaptiveRate(b *Benchmark, limiter *RateLimiter, out io.Writer) *adaptiveRate {
+	return &adaptiveRate{b: b, limiter: limiter, out: out}
+}
+
+// adjust adjusts the rate limit based on the results of the interval.
+func (a *adaptiveRate) adjust(is IntervalStats) error {
+	previous := a.limiter.Rate()
+	if is.Counters.Total == 0 || previous == 0 {
+		// nothing was observed or the rate limit was removed using the controller
+		return nil
+	}
+
+	latency := time.Duration(is.Hist.ValueAtQuantile(a.b.TargetPercentile))
+	errorRate := float64(is.Counters.IOError+is.Counters.Error) / float64(is.Counters.Total)
+	qps := is.QPS()
+
+	rate := previous
+	switch {
+	case latency > a.b.TargetLatency || errorRate > a.b.TargetErrorRate:
+		rate = max(1, int(float64(previous)*adaptiveDecreaseFactor))
+	case qps >= adaptiveSaturation*float64(previous):
+		rate = previous + a.b.AdaptiveStep
+	}
+	if rate == previous {
+		return nil
+	}
+
+	a.limiter.SetRate(rate)
+	adj := RateAdjustment{
+		Time:         is.Start.Add(is.Duration),
+		Elapsed:      is.Elapsed,
+		PreviousRate: previous,
+		Rate:         rate,
+		Latency:      latency,
+		ErrorRate:    errorRate,
+		QPS:          qps,
+	}
+	a.adjustments = append(a.adjustments, adj)
+
+	if a.out == nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(a.out, "[%s] rate adjusted from %d to %d QPS, %.1f QPS, p%g: %s, error rate: %.2f%%\n",
+		adj.Elapsed.Round(time.Second), adj.PreviousRate, adj.Rate, adj.QPS, a.b.TargetPercentile, roundLatency(int64(adj.Latency)), adj.ErrorRate*100)
+	return err
+}
@@ -0,0 +1,102 @@
+package dnsbench
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmark_init_adaptive_rate(t *testing.T) {
+	tests := []struct {
+		name      string
+		benchmark Benchmark
+		want      Benchmark
+	}{
+		{
+			name:      "defaults",
+			benchmark: Benchmark{TargetLatency: 20 * time.Millisecond},
+			want: Benchmark{TargetLatency: 20 * time.Millisecond, Rate: DefaultAdaptiveRate, TargetPercentile: DefaultTargetPercentile,
+				TargetErrorRate: DefaultTargetErrorRate, AdaptiveStep: DefaultAdaptiveRate / 10},
+		},
+		{
+			name:      "configured",
+			benchmark: Benchmark{TargetLatency: 20 * time.Millisecond, Rate: 5, TargetPercentile: 95, TargetErrorRate: 0.1},
+			want:      Benchmark{TargetLatency: 20 * time.Millisecond, Rate: 5, TargetPercentile: 95, TargetErrorRate: 0.1, AdaptiveStep: 1},
+		},
+		{
+			name:      "disabled",
+			benchmark: Benchmark{},
+			want:      Benchmark{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.benchmark.Server = "8.8.8.8"
+
+			require.NoError(t, tt.benchmark.init())
+
+			assert.Equal(t, tt.want.TargetLatency, tt.benchmark.TargetLatency)
+			assert.Equal(t, tt.want.Rate, tt.benchmark.Rate)
+			assert.Equal(t, tt.want.TargetPercentile, tt.benchmark.TargetPercentile)
+			assert.Equal(t, tt.want.TargetErrorRate, tt.benchmark.TargetErrorRate)
+			assert.Equal(t, tt.want.AdaptiveStep, tt.benchmark.AdaptiveStep)
+		})
+	}
+}
+
+func Test_adaptiveRate_adjust(t *testing.T) {
+	b := &Benchmark{TargetLatency: 20 * time.Millisecond, TargetPercentile: 99, TargetErrorRate: 0.01, AdaptiveStep: 10}
+	interval := func(total, errs int64, latency time.Duration) IntervalStats {
+		hist := hdrhistogram.New(0, int64(time.Second), 3)
+		for range total - errs {
+			hist.RecordValue(int64(latency))
+		}
+		return IntervalStats{
+			Start:    now,
+			Duration: time.Second,
+			Elapsed:  time.Second,
+			Counters: Counters{Total: total, Success: total - errs, IOError: errs},
+			Hist:     hist,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		rate     int
+		interval IntervalStats
+		wantRate int
+	}{
+		{name: "within targets", rate: 100, interval: interval(100, 0, 10*time.Millisecond), wantRate: 110},
+		{name: "latency exceeded", rate: 100, interval: interval(100, 0, 30*time.Millisecond), wantRate: 70},
+		{name: "error rate exceeded", rate: 100, interval: interval(100, 5, 10*time.Millisecond), wantRate: 70},
+		{name: "minimal rate", rate: 1, interval: interval(1, 0, 30*time.Millisecond), wantRate: 1},
+		{name: "rate not achieved", rate: 100, interval: interval(50, 0, 10*time.Millisecond), wantRate: 100},
+		{name: "empty interval", rate: 100, interval: interval(0, 0, 0), wantRate: 100},
+		{name: "rate not limited", rate: 0, interval: interval(100, 0, 10*time.Millisecond), wantRate: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(tt.rate, 1)
+			out := bytes.Buffer{}
+			a := newAdaptiveRate(b, limiter, &out)
+
+			require.NoError(t, a.adjust(tt.interval))
+
+			assert.Equal(t, tt.wantRate, limiter.Rate())
+			if tt.wantRate == tt.rate {
+				assert.Empty(t, a.adjustments)
+				assert.Empty(t, out.String())
+				return
+			}
+			require.Len(t, a.adjustments, 1)
+			assert.Equal(t, tt.rate, a.adjustments[0].PreviousRate)
+			assert.Equal(t, tt.wantRate, a.adjustments[0].Rate)
+			assert.Equal(t, now.Add(time.Second), a.adjustments[0].Time)
+			assert.Contains(t, out.String(), "rate adjusted from")
+		})
+	}
+}
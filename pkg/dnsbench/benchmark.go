@@ -12,7 +12,7 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
-	_ "net/http/pprof" // nolint:gosec // G108: pprof is intentionally exposed when --pprof flag is used
+	"net/http/pprof" // nolint:gosec // G108: pprof is intentionally exposed when --pprof flag is used
 	"net/url"
 	"os"
 	"regexp"
@@ -21,12 +21,10 @@ import (
 	"sync"
 	"time"
 
-	"github.com/fatih/color"
 	"github.com/miekg/dns"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/schollz/progressbar/v3"
 	"github.com/tantalor93/dnspyre/v3/pkg/printutils"
-	"go.uber.org/ratelimit"
 )
 
 var client = http.Client{
@@ -98,6 +96,23 @@ type Benchmark struct {
 	Rate int
 	// RateLimitWorker configures rate limit per worker for queries per second. This means that queries generated by each concurrent worker per second will not exceed this limit.
 	RateLimitWorker int
+	// RateBurst configures maximum number of queries, which can be sent at once by the global and per worker rate limits, after the queries were not sent
+	// at the limited rate for a while (for example because the server was slow to respond). With burst of 1 the queries are evenly spaced.
+	// Zero means DefaultRateBurst.
+	RateBurst int
+
+	// TargetLatency enables adaptive rate control, the global rate limit is adjusted every interval (Benchmark.IntervalReport or DefaultControllerInterval)
+	// to keep the latency percentile Benchmark.TargetPercentile at or below this target. The rate starts at Benchmark.Rate or DefaultAdaptiveRate,
+	// when the rate is not limited. When 0 is configured, which is default value, the rate is not adjusted.
+	TargetLatency time.Duration
+	// TargetPercentile is the latency percentile kept at or below Benchmark.TargetLatency by the adaptive rate control. Zero means DefaultTargetPercentile.
+	TargetPercentile float64
+	// TargetErrorRate is the maximum ratio of IO errors and error responses to all requests tolerated by the adaptive rate control,
+	// the rate is decreased in intervals exceeding it. Zero means DefaultTargetErrorRate.
+	TargetErrorRate float64
+	// AdaptiveStep is the number of queries per second, by which the adaptive rate control increases the rate after each interval meeting the targets.
+	// Zero means one tenth of the starting rate.
+	AdaptiveStep int
 
 	// QperConn configures how many queries are sent by each connection (socket) before closing it and creating a new one.
 	// This is considered only for plain DNS over UDP or TCP and DoT.
@@ -155,9 +170,16 @@ type Benchmark struct {
 	HistMax time.Duration
 	// HistPre controls precision of histogram printed by Benchmark.PrintReport.
 	HistPre int
+	// Percentiles are the latency percentiles reported in the DNS timings, when empty DefaultPercentiles are reported.
+	Percentiles []float64
+	// RawSamples limits number of raw datapoints of responses and IO errors (ResultStats.Timings and ResultStats.Errors) kept by each worker,
+	// the kept datapoints are uniformly sampled using reservoir sampling. Zero means DefaultRawSamples, negative value disables keeping the raw datapoints.
+	RawSamples int
 
 	// Csv path to file, where the Benchmark result distribution is written.
 	Csv string
+	// HTML path to file, where the self-contained HTML report with the run configuration, results and charts is written.
+	HTML string
 	// JSON controls whether the Benchmark.PrintReport prints the Benchmark results in JSON format (option is true).
 	JSON bool
 
@@ -170,6 +192,8 @@ type Benchmark struct {
 	PlotDir string
 	// PlotFormat controls the format of generated graphs. Supported values are "svg", "png" and "jpg".
 	PlotFormat string
+	// PlotCharts controls which charts are generated, see PlotCharts for supported values. If empty, which is default value, all charts are generated.
+	PlotCharts []string
 
 	// DohMethod controls HTTP method used for sending DoH requests. Supported values are "post" and "get". Default is "post".
 	DohMethod string
@@ -201,30 +225,140 @@ type Benchmark struct {
 	SeparateWorkerConnections bool
 
 	// Writer used for writing benchmark execution logs and results. Default is os.Stdout.
-	Writer io.Writer
+	Writer io.Writer `json:"-"`
 	// ErrWriter used for writing warnings and errors. Default is os.Stderr.
-	ErrWriter io.Writer
+	ErrWriter io.Writer `json:"-"`
 
 	// RequestDelay configures delay between each DNS request. Either constant delay can be configured (e.g. 2s) or randomized delay can be configured (e.g. 1s-2s).
 	RequestDelay string
 
+	// Warmup configures warm-up phase at the start of the benchmark run, either as a duration (e.g. 10s) or as a number of queries (e.g. 1000).
+	// The queries are sent normally during the warm-up, but their results are recorded separately to ResultStats.Warmup, so that the other results
+	// describe the steady state. The warm-up is part of the run configured by Benchmark.Count or Benchmark.Duration.
+	Warmup string
+
+	// Iterations configures how many times the benchmark is repeated by Benchmark.RunIterations. Zero means single iteration.
+	Iterations int
+	// IterationPause configures pause between the iterations executed by Benchmark.RunIterations.
+	IterationPause time.Duration
+	// Shuffle controls whether the order of the questions is shuffled with each run of the benchmark.
+	Shuffle bool
+	// CVThreshold is the maximum coefficient of variation (standard deviation divided by mean) of the per-iteration QPS and latency percentiles,
+	// above which the iterations are reported as unstable. Zero means DefaultCVThreshold.
+	CVThreshold float64
+
 	// PrometheusMetricsAddr configures address for Prometheus metrics endpoint.
 	PrometheusMetricsAddr string
+	// PrometheusRegisterer configures registerer, where the Prometheus metrics of the benchmark are registered in addition to the registry
+	// owned by the benchmark. This allows to expose the metrics of the benchmark embedded in another Go service. Multiple benchmarks can register
+	// the metrics on the same registerer, when they are distinguished by server and transport labels or by wrapping the registerer
+	// using prometheus.WrapRegistererWith. The metrics registered by the previous run of the benchmark are reused.
+	PrometheusRegisterer prometheus.Registerer `json:"-"`
+
+	// PushgatewayURL configures URL of Prometheus Pushgateway, where the Prometheus metrics of the benchmark are pushed.
+	// The metrics are grouped by job, instance, server, protocol and run_id labels.
+	PushgatewayURL string
+	// RemoteWriteURL configures URL of Prometheus remote-write endpoint, where the Prometheus metrics of the benchmark are pushed.
+	// The series are labeled with the same labels as the metrics pushed to Pushgateway.
+	RemoteWriteURL string
+	// PushJob configures job label of the pushed metrics. Default is "dnspyre".
+	PushJob string
+	// PushInstance configures instance label of the pushed metrics. Default is hostname of the machine running the benchmark.
+	PushInstance string
+	// PushInterval controls how often the metrics are pushed during the benchmark run. The metrics are always pushed after the benchmark finishes.
+	// When 0 is configured, which is default value, the metrics are pushed only after the benchmark finishes.
+	PushInterval time.Duration
+	// RunID configures run_id label of the pushed metrics and run_id resource attribute of the OTLP metrics and traces identifying the benchmark run.
+	// If empty, the identifier is generated by each Run and once for all iterations by RunIterations, it is not stored to the benchmark,
+	// set it using NewRunID, when the identifier needs to be known, for example to save it together with the results.
+	RunID string
+
+	// OTLPEndpoint configures URL of OpenTelemetry collector, where the metrics of the benchmark and sampled spans of the queries are exported
+	// using OTLP. For example http://localhost:4317 for gRPC or http://localhost:4318 for HTTP, https scheme enables TLS.
+	OTLPEndpoint string
+	// OTLPProtocol controls protocol used for exporting to Benchmark.OTLPEndpoint. Supported values are "grpc" and "http". Default is "grpc".
+	OTLPProtocol string
+	// OTLPTraceSampleRate controls ratio (from 0 to 1) of the queries, for which a span is exported to Benchmark.OTLPEndpoint.
+	// When 0 is configured, which is default value, only metrics are exported.
+	OTLPTraceSampleRate float64
 
 	// PprofAddr configures address for Go pprof HTTP endpoint. When set, an HTTP server exposing
 	// Go profiling information (heap, goroutine, CPU profiles, etc.) is started at the specified address.
 	// The profiles are available under /debug/pprof/ path.
 	PprofAddr string
 
+	// CompareServers configures additional servers, which will receive every question sent to Benchmark.Server.
+	// Answers of these servers are compared with the answer of Benchmark.Server and the found differences are collected
+	// in ResultStats.Consistency. Each server uses the same format as Benchmark.Server.
+	CompareServers []string
+	// CompareIgnoreOrder controls whether the order of records in the answer section is ignored when comparing answers.
+	CompareIgnoreOrder bool
+	// CompareTTL controls whether TTLs of the answer records are compared.
+	CompareTTL bool
+	// CompareTTLTolerance configures maximum difference of TTLs of matching answer records, which is not considered an answer difference.
+	// Applicable only when Benchmark.CompareTTL is enabled.
+	CompareTTLTolerance time.Duration
+
+	// TTLAnalysis controls whether answer TTLs are tracked for each question to analyze TTL distribution and caching behaviour
+	// of the benchmarked server. The analysis is stored in ResultStats.TTLs and ResultStats.TTLHist.
+	TTLAnalysis bool
+	// AuthoritativeTTL configures the authoritative TTL of all questions, which is used to detect TTL clamping, when the benchmarked server
+	// does not answer authoritatively, for example when benchmarking resolver using domains of a zone with known TTL. The authoritative TTL
+	// of the answers of the benchmarked server or of the servers configured by Benchmark.CompareServers takes precedence.
+	// Applicable only when Benchmark.TTLAnalysis is enabled.
+	AuthoritativeTTL time.Duration
+
+	// DomainStats controls whether latencies and outcomes are aggregated for each question (domain and query type).
+	// The aggregates are stored in ResultStats.Domains.
+	DomainStats bool
+	// DomainStatsTop controls number of domains printed in the slowest domains and the most failing domains reports.
+	// Default is 10.
+	DomainStatsTop int
+	// DomainStatsCsv path to file, where the per-domain aggregates are exported. Setting the path enables Benchmark.DomainStats.
+	DomainStatsCsv string
+
+	// WorkerStats controls whether the results of each worker and each connection are reported separately, highlighting the outlier workers
+	// and connections. The per-connection aggregates are stored in ResultStats.Connections.
+	WorkerStats bool
+
+	// IntervalReport controls how often the results of the last interval are reported during the benchmark run. When 0 is configured,
+	// which is default value, no interval reports are generated. The progress bar is not shown, when interval reporting is enabled.
+	IntervalReport time.Duration
+	// IntervalReportFormat controls format of the interval reports. Supported values are "text" and "ndjson". Default is "text".
+	IntervalReportFormat string
+	// IntervalReportPath path to file, where the interval reports are written. If empty, the interval reports are written to Benchmark.Writer.
+	IntervalReportPath string
+
+	// Controller allows to control the running benchmark (pause, resume, stop early and adjust the rate limit) and to subscribe to its live results.
+	// When nil, which is default value, the benchmark cannot be controlled while running.
+	Controller *Controller `json:"-"`
+
+	// Observers are notified about the lifecycle of the benchmark run and about each completed query, see Observer.
+	Observers []Observer `json:"-"`
+
+	// Transports registers custom transports by the scheme of the server address. When Benchmark.Server has format <scheme>://<address>
+	// and the scheme is registered, the queries are sent using the transport created by the registered factory instead of the built-in transports
+	// and the server address is used as is, without any normalization. The registered schemes take precedence over the built-in ones ("https", "http" and "quic").
+	Transports map[string]TransportFactory `json:"-"`
+
 	// internal variable so we do not have to parse the address with each request.
 	useDoH            bool
 	useQuic           bool
 	requestDelayStart time.Duration
 	requestDelayEnd   time.Duration
+	warmupDuration    time.Duration
+	warmupQueries     int64
+	compareBenchmarks []*Benchmark
+	transport         Transport
+
+	// printer prints colored output of the benchmark, so that the global color settings are not changed.
+	printer *printutils.Printer
+	// requestLog logs the requests of the benchmark run, when Benchmark.RequestLogEnabled is set.
+	requestLog *log.Logger
+	// runStart is the time when the workers of the benchmark run started, the time series of all workers start at this time.
+	runStart time.Time
 }
 
-type queryFunc func(context.Context, *dns.Msg) (*dns.Msg, error)
-
 // init validates and normalizes Benchmark settings.
 func (b *Benchmark) init() error {
 	if b.Writer == nil {
@@ -264,24 +398,10 @@ func (b *Benchmark) init() error {
 		b.DohUserAgent = defaultDoHUserAgent()
 	}
 
-	b.useDoH, _ = isHTTPUrl(b.Server)
-	b.useQuic = strings.HasPrefix(b.Server, "quic://")
-	if b.useQuic {
-		b.Server = strings.TrimPrefix(b.Server, "quic://")
-	}
-
-	if b.useDoH {
-		parsedURL, err := url.Parse(b.Server)
-		if err != nil {
-			return err
-		}
-		if len(parsedURL.Path) == 0 {
-			b.Server += "/dns-query"
-		}
+	if err := b.normalizeServer(); err != nil {
+		return err
 	}
 
-	b.addPortIfMissing()
-
 	if b.Count == 0 && b.Duration == 0 {
 		b.Count = DefaultCount
 	}
@@ -296,6 +416,104 @@ func (b *Benchmark) init() error {
 	if b.HistPre == 0 {
 		b.HistPre = DefaultHistPrecision
 	}
+	if b.RateBurst == 0 {
+		b.RateBurst = DefaultRateBurst
+	}
+	if b.RateBurst < 0 {
+		return errors.New("--rate-limit-burst must not be negative")
+	}
+	if b.RawSamples == 0 {
+		b.RawSamples = DefaultRawSamples
+	}
+
+	if b.TargetLatency < 0 {
+		return errors.New("--target-latency must not be negative")
+	}
+	if b.TargetLatency > 0 {
+		if b.Rate == 0 {
+			b.Rate = DefaultAdaptiveRate
+		}
+		if b.TargetPercentile == 0 {
+			b.TargetPercentile = DefaultTargetPercentile
+		}
+		if b.TargetPercentile < 0 || b.TargetPercentile > 100 {
+			return errors.New("--target-percentile must have value between 0 and 100")
+		}
+		if b.TargetErrorRate == 0 {
+			b.TargetErrorRate = DefaultTargetErrorRate
+		}
+		if b.TargetErrorRate < 0 || b.TargetErrorRate > 1 {
+			return errors.New("--target-error-rate must have value between 0 and 1")
+		}
+		if b.AdaptiveStep == 0 {
+			b.AdaptiveStep = max(1, b.Rate/10)
+		}
+		if b.AdaptiveStep < 0 {
+			return errors.New("--adaptive-step must not be negative")
+		}
+	}
+
+	if b.AuthoritativeTTL < 0 {
+		return errors.New("--authoritative-ttl must not be negative")
+	}
+
+	if b.DomainStatsCsv != "" {
+		b.DomainStats = true
+	}
+	if b.DomainStatsTop == 0 {
+		b.DomainStatsTop = DefaultDomainStatsTop
+	}
+
+	if b.pushEnabled() {
+		if len(b.PushJob) == 0 {
+			b.PushJob = DefaultPushJob
+		}
+		if len(b.PushInstance) == 0 {
+			b.PushInstance = defaultPushInstance()
+		}
+	}
+	if b.PushInterval < 0 {
+		return errors.New("--push-interval must not be negative")
+	}
+
+	if len(b.OTLPEndpoint) != 0 {
+		if len(b.OTLPProtocol) == 0 {
+			b.OTLPProtocol = DefaultOTLPProtocol
+		}
+		if b.OTLPProtocol != OTLPGRPCProtocol && b.OTLPProtocol != OTLPHTTPProtocol {
+			return fmt.Errorf("--otlp-protocol '%s' is not supported, supported values are '%s' and '%s'",
+				b.OTLPProtocol, OTLPGRPCProtocol, OTLPHTTPProtocol)
+		}
+		if u, err := url.Parse(b.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return errors.New("--otlp-endpoint must be URL with http or https scheme, for example http://localhost:4317")
+		}
+	}
+	if b.OTLPTraceSampleRate < 0 || b.OTLPTraceSampleRate > 1 {
+		return errors.New("--otlp-trace-sample-rate must have value between 0 and 1")
+	}
+
+	if b.IntervalReport < 0 {
+		return errors.New("--interval must not be negative")
+	}
+	if len(b.IntervalReportFormat) == 0 {
+		b.IntervalReportFormat = TextIntervalFormat
+	}
+	if b.IntervalReportFormat != TextIntervalFormat && b.IntervalReportFormat != NDJSONIntervalFormat {
+		return fmt.Errorf("--interval-format '%s' is not supported, supported values are '%s' and '%s'",
+			b.IntervalReportFormat, TextIntervalFormat, NDJSONIntervalFormat)
+	}
+
+	for _, p := range b.Percentiles {
+		if p <= 0 || p > 100 {
+			return errors.New("--percentile must have value greater than 0 and at most 100")
+		}
+	}
+
+	for _, c := range b.PlotCharts {
+		if !containsChart(PlotCharts, c) {
+			return fmt.Errorf("--plot-chart '%s' is not supported, supported values are '%s'", c, strings.Join(PlotCharts, "', '"))
+		}
+	}
 
 	if b.Edns0 != 0 && (b.Edns0 < 512 || b.Edns0 > 4096) {
 		return errors.New("--edns0 must have value between 512 and 4096")
@@ -330,22 +548,99 @@ func (b *Benchmark) init() error {
 		b.RequestLogPath = DefaultRequestLogPath
 	}
 
+	if b.Iterations == 0 {
+		b.Iterations = 1
+	}
+	if b.Iterations < 0 {
+		return errors.New("--iterations must not be negative")
+	}
+	if b.IterationPause < 0 {
+		return errors.New("--iteration-pause must not be negative")
+	}
+	if b.CVThreshold == 0 {
+		b.CVThreshold = DefaultCVThreshold
+	}
+	if b.CVThreshold < 0 {
+		return errors.New("--cv-threshold must not be negative")
+	}
+
+	if err := b.parseWarmup(); err != nil {
+		return err
+	}
+
 	if err := b.parseRequestDelay(); err != nil {
 		return err
 	}
 
-	b.writeUnsupportedOptionWarnings()
+	b.compareBenchmarks = nil
+	for _, s := range b.CompareServers {
+		cb, err := b.compareBenchmark(s)
+		if err != nil {
+			return fmt.Errorf("--compare-server '%s' is not valid: %w", s, err)
+		}
+		b.compareBenchmarks = append(b.compareBenchmarks, cb)
+	}
+
+	b.printer = printutils.NewPrinter(b.Color)
 
 	return nil
 }
 
+// normalizeServer detects the protocol from Benchmark.Server and normalizes the server address.
+// Normalizing already normalized server address does not change it.
+func (b *Benchmark) normalizeServer() error {
+	if b.transportFactory() != nil {
+		// the address is interpreted by the custom transport
+		b.useDoH, b.useQuic = false, false
+		return nil
+	}
+	b.useDoH, _ = isHTTPUrl(b.Server)
+	if strings.HasPrefix(b.Server, "quic://") {
+		b.useQuic = true
+		b.Server = strings.TrimPrefix(b.Server, "quic://")
+	}
+
+	if b.useDoH {
+		parsedURL, err := url.Parse(b.Server)
+		if err != nil {
+			return err
+		}
+		if len(parsedURL.Path) == 0 {
+			b.Server += "/dns-query"
+		}
+	}
+
+	b.addPortIfMissing()
+	return nil
+}
+
+// WithDefaults returns copy of the benchmark with default values filled in and with normalized server address, the benchmark itself is not modified.
+// The returned benchmark describes the configuration used by Benchmark.Run, so it can be used for reporting the results.
+// If the benchmark is not valid, the error is returned.
+func (b *Benchmark) WithDefaults() (*Benchmark, error) {
+	c := *b
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
 // Run executes benchmark, if benchmark is unable to start the error is returned, otherwise array of results from parallel benchmark goroutines is returned.
+// The benchmark is not modified by Run, each run uses its own copy of the configuration, output settings, request log and HTTP servers,
+// so the same benchmark can be run repeatedly and multiple benchmarks can run concurrently in the same process.
 func (b *Benchmark) Run(ctx context.Context) ([]*ResultStats, error) {
-	color.NoColor = !b.Color
-
-	if err := b.init(); err != nil {
+	run, err := b.WithDefaults()
+	if err != nil {
 		return nil, err
 	}
+	if (run.pushEnabled() || len(run.OTLPEndpoint) != 0) && len(run.RunID) == 0 {
+		run.RunID = NewRunID()
+	}
+	return run.run(ctx)
+}
+
+func (b *Benchmark) run(ctx context.Context) ([]*ResultStats, error) {
+	b.writeUnsupportedOptionWarnings()
 
 	if b.RequestLogEnabled {
 		file, err := os.OpenFile(b.RequestLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
@@ -353,32 +648,62 @@ func (b *Benchmark) Run(ctx context.Context) ([]*ResultStats, error) {
 			return nil, err
 		}
 		defer file.Close()
-		log.SetOutput(file)
+		b.requestLog = log.New(file, "", log.LstdFlags)
+	}
+
+	if b.transportFactory() != nil {
+		// the custom transport is created before the metrics, so that the metrics are labeled by the network of the transport
+		if err := b.initTransport(nil); err != nil {
+			return nil, err
+		}
+		if closer, ok := b.transport.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
+
+	var metrics *promMetrics
+	if b.metricsEnabled() {
+		var err error
+		if metrics, err = newPromMetrics(b); err != nil {
+			return nil, fmt.Errorf("failed to register Prometheus metrics: %w", err)
+		}
 	}
 
 	if len(b.PrometheusMetricsAddr) != 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.handler())
 		// nolint:gosec
 		server := http.Server{
 			Addr:    b.PrometheusMetricsAddr,
-			Handler: promhttp.Handler(),
+			Handler: mux,
 		}
 		defer server.Shutdown(ctx)
 		go func() {
 			if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-				printutils.ErrFprintf(b.ErrWriter, "Failed to start Prometheus metrics server at %s: %v\n", b.PrometheusMetricsAddr, err)
+				b.printer.ErrFprintf(b.ErrWriter, "Failed to start Prometheus metrics server at %s: %v\n", b.PrometheusMetricsAddr, err)
 			}
 		}()
 	}
 
+	var otelExp *otelExporter
+	if len(b.OTLPEndpoint) != 0 {
+		var err error
+		if otelExp, err = newOTelExporter(ctx, b); err != nil {
+			return nil, err
+		}
+		defer otelExp.shutdown()
+	}
+
 	if len(b.PprofAddr) != 0 {
 		// nolint:gosec
 		pprofServer := http.Server{
-			Addr: b.PprofAddr,
+			Addr:    b.PprofAddr,
+			Handler: pprofHandler(),
 		}
 		defer pprofServer.Shutdown(ctx)
 		go func() {
 			if err := pprofServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-				printutils.ErrFprintf(b.Writer, "Failed to start pprof server at %s: %v\n", b.PprofAddr, err)
+				b.printer.ErrFprintf(b.Writer, "Failed to start pprof server at %s: %v\n", b.PprofAddr, err)
 			}
 		}()
 	}
@@ -387,6 +712,11 @@ func (b *Benchmark) Run(ctx context.Context) ([]*ResultStats, error) {
 	if err != nil {
 		return nil, err
 	}
+	if b.Shuffle {
+		rand.Shuffle(len(questions), func(i, j int) {
+			questions[i], questions[j] = questions[j], questions[i]
+		})
+	}
 
 	if b.Duration != 0 {
 		timeoutCtx, cancel := context.WithTimeout(ctx, b.Duration)
@@ -395,7 +725,7 @@ func (b *Benchmark) Run(ctx context.Context) ([]*ResultStats, error) {
 	}
 
 	if !b.Silent && !b.JSON {
-		printutils.NeutralFprintf(b.Writer, "Using %s hostnames\n", printutils.HighlightSprint(len(questions)))
+		b.printer.NeutralFprintf(b.Writer, "Using %s hostnames\n", b.printer.HighlightSprint(len(questions)))
 	}
 
 	var qTypes []uint16
@@ -403,32 +733,74 @@ func (b *Benchmark) Run(ctx context.Context) ([]*ResultStats, error) {
 		qTypes = append(qTypes, dns.StringToType[v])
 	}
 
-	queryFactory := workerQueryFactory(b)
+	if b.transport == nil {
+		if err := b.initTransport(metrics); err != nil {
+			return nil, err
+		}
+	}
+	for _, cb := range b.compareBenchmarks {
+		if err := cb.initTransport(nil); err != nil {
+			return nil, err
+		}
+		if closer, ok := cb.transport.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
 
 	limits := ""
-	var limit ratelimit.Limiter
+	// the global rate limiter is created even when the rate is not limited, so that the rate can be adjusted by the controller
+	limit := NewRateLimiter(b.Rate, b.RateBurst)
 	if b.Rate > 0 {
-		limit = ratelimit.New(b.Rate)
 		if b.RateLimitWorker == 0 {
-			limits = fmt.Sprintf("(limited to %s QPS overall)", printutils.HighlightSprint(b.Rate))
+			limits = fmt.Sprintf("(limited to %s QPS overall)", b.printer.HighlightSprint(b.Rate))
 		} else {
 			limits = fmt.Sprintf("(limited to %s QPS overall and %s QPS per concurrent worker)",
-				printutils.HighlightSprint(b.Rate), printutils.HighlightSprint(b.RateLimitWorker))
+				b.printer.HighlightSprint(b.Rate), b.printer.HighlightSprint(b.RateLimitWorker))
 		}
 	}
 	if b.Rate == 0 && b.RateLimitWorker > 0 {
-		limits = fmt.Sprintf("(limited to %s QPS per concurrent worker)", printutils.HighlightSprint(b.RateLimitWorker))
+		limits = fmt.Sprintf("(limited to %s QPS per concurrent worker)", b.printer.HighlightSprint(b.RateLimitWorker))
+	}
+
+	if b.Controller != nil {
+		ctx = b.Controller.start(ctx, b, limit)
+		defer b.Controller.finish()
 	}
 
 	if !b.Silent && !b.JSON {
 		network := b.network()
-		printutils.NeutralFprintf(b.Writer, "Benchmarking %s via %s with %s concurrent requests %s\n",
-			printutils.HighlightSprint(b.Server), printutils.HighlightSprint(network), printutils.HighlightSprint(b.Concurrency), limits)
+		b.printer.NeutralFprintf(b.Writer, "Benchmarking %s via %s with %s concurrent requests %s\n",
+			b.printer.HighlightSprint(b.Server), b.printer.HighlightSprint(network), b.printer.HighlightSprint(b.Concurrency), limits)
+	}
+
+	var intervalOut io.Writer
+	if b.IntervalReport > 0 {
+		switch {
+		case len(b.IntervalReportPath) != 0:
+			file, err := os.Create(b.IntervalReportPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create file for interval reports: %w", err)
+			}
+			defer file.Close()
+			intervalOut = file
+		case !b.Silent:
+			intervalOut = b.Writer
+		}
 	}
+	var adaptive *adaptiveRate
+	if b.TargetLatency > 0 {
+		var out io.Writer
+		if !b.Silent && !b.JSON {
+			out = b.Writer
+		}
+		adaptive = newAdaptiveRate(b, limit, out)
+	}
+	// the progress bar would be interleaved with the interval reports and logged rate adjustments
+	showProgress := !b.Silent && b.ProgressBar && b.IntervalReport == 0 && adaptive == nil
 
 	var bar *progressbar.ProgressBar
 	var incrementBar bool
-	if repetitions := b.Count * int64(b.Concurrency) * int64(len(b.Types)) * int64(len(questions)); !b.Silent && b.ProgressBar && repetitions >= 100 {
+	if repetitions := b.Count * int64(b.Concurrency) * int64(len(b.Types)) * int64(len(questions)); showProgress && repetitions >= 100 {
 		fmt.Fprintln(b.ErrWriter)
 		if b.Probability < 1.0 {
 			// show spinner when Benchmark.Probability is less than 1.0, because the actual number of repetitions is not known
@@ -437,7 +809,7 @@ func (b *Benchmark) Run(ctx context.Context) ([]*ResultStats, error) {
 		bar = progressbar.Default(repetitions, "Progress:")
 		incrementBar = true
 	}
-	if !b.Silent && b.ProgressBar && b.Duration >= 10*time.Second {
+	if showProgress && b.Duration >= 10*time.Second {
 		fmt.Fprintln(b.ErrWriter)
 		bar = progressbar.Default(int64(b.Duration.Seconds()), "Progress:")
 		ticker := time.NewTicker(time.Second)
@@ -454,17 +826,57 @@ func (b *Benchmark) Run(ctx context.Context) ([]*ResultStats, error) {
 		}()
 	}
 
+	var intervalSinks []func(IntervalStats) error
+	if intervalOut != nil {
+		intervalSinks = append(intervalSinks, intervalWriter(intervalOut, b.IntervalReportFormat))
+	}
+	if b.Controller != nil && b.Controller.hasHandlers() {
+		intervalSinks = append(intervalSinks, b.Controller.notify)
+	}
+	if adaptive != nil {
+		intervalSinks = append(intervalSinks, adaptive.adjust)
+	}
+
 	stats := make([]*ResultStats, b.Concurrency)
+	var intervals []*intervalRecorder
+	if len(intervalSinks) > 0 {
+		intervals = make([]*intervalRecorder, b.Concurrency)
+	}
+
+	observed := len(b.Observers) > 0
+	// the query trace is needed to identify the connections used by the queries
+	traced := observed || b.WorkerStats
+	queryNetwork := b.network()
+	b.notify(func(o Observer) { o.RunStart(b) })
 
+	b.runStart = time.Now()
+	warm := newWarmup(b)
 	var wg sync.WaitGroup
 	var w uint32
 	for w = 0; w < b.Concurrency; w++ {
 		st := newResultStats(b)
 		stats[w] = st
+		if warm != nil {
+			st.Warmup = newWarmupStats(b)
+		}
+		var interval *intervalRecorder
+		if intervals != nil {
+			interval = newIntervalRecorder(b)
+			intervals[w] = interval
+		}
 
 		wg.Add(1)
 		go func(workerID uint32, st *ResultStats) {
+			var status *workerStatus
+			if b.Controller != nil {
+				status = b.Controller.worker(workerID)
+			}
+			b.notify(func(o Observer) { o.WorkerStart(workerID) })
 			defer func() {
+				if status != nil {
+					status.setState(WorkerDone)
+				}
+				b.notify(func(o Observer) { o.WorkerStop(workerID) })
 				wg.Done()
 			}()
 
@@ -472,12 +884,18 @@ func (b *Benchmark) Run(ctx context.Context) ([]*ResultStats, error) {
 			// nolint:gosec
 			rando := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-			var workerLimit ratelimit.Limiter
+			var workerLimit *RateLimiter
 			if b.RateLimitWorker > 0 {
-				workerLimit = ratelimit.New(b.RateLimitWorker)
+				workerLimit = NewRateLimiter(b.RateLimitWorker, b.RateBurst)
 			}
 
-			query := queryFactory()
+			query := b.transport.QueryFunc(workerID)
+			defer closeWorker(b.transport, workerID)
+			var cmp *comparer
+			if len(b.compareBenchmarks) > 0 {
+				cmp = b.newComparer(ctx, st, workerID)
+				defer cmp.finish(st)
+			}
 
 			// Generate client cookie once for this worker (RFC 7873)
 			cookie := make([]byte, 8)
@@ -494,27 +912,53 @@ func (b *Benchmark) Run(ctx context.Context) ([]*ResultStats, error) {
 						if ctx.Err() != nil {
 							return
 						}
+						if status != nil {
+							if err := b.Controller.wait(ctx, status); err != nil {
+								return
+							}
+						}
 						if rando.Float64() > b.Probability {
 							continue
 						}
-						if limit != nil {
-							if err := checkLimit(ctx, limit); err != nil {
+						switch {
+						case status != nil:
+							// global rate limit can be adjusted using the controller while the benchmark is running
+							if err := b.Controller.take(ctx, status); err != nil {
+								return
+							}
+						default:
+							if err := limit.Wait(ctx); err != nil {
 								return
 							}
 						}
 						if workerLimit != nil {
-							if err := checkLimit(ctx, workerLimit); err != nil {
+							if err := workerLimit.Wait(ctx); err != nil {
 								return
 							}
 						}
 
 						req := b.createReqMsg(q, qt, cookieHex, rando)
 
+						queryCtx := ctx
+						if otelExp != nil {
+							queryCtx = otelExp.startQuery(ctx, &req)
+						}
+						if traced && queryTraceFromContext(queryCtx) == nil {
+							// observers are notified about the connection used by each query
+							queryCtx = withQueryTrace(queryCtx, &queryTrace{})
+						}
+
 						start := time.Now()
 
-						reqTimeoutCtx, cancel := context.WithTimeout(ctx, b.RequestTimeout)
+						if metrics != nil {
+							metrics.inFlight.Inc()
+						}
+						reqTimeoutCtx, cancel := context.WithTimeout(queryCtx, b.RequestTimeout)
 						resp, err := query(reqTimeoutCtx, &req)
 						cancel()
+						if metrics != nil {
+							metrics.inFlight.Dec()
+						}
 						if deadline, deadlineSet := reqTimeoutCtx.Deadline(); err != nil && deadlineSet && start.After(deadline) {
 							// Benchmark was cancelled before sending request, do not count this query results and end the worker
 							return
@@ -530,10 +974,48 @@ func (b *Benchmark) Run(ctx context.Context) ([]*ResultStats, error) {
 						}
 
 						if b.RequestLogEnabled {
-							logRequest(workerID, req, resp, err, dur)
+							logRequest(b.requestLog, workerID, req, resp, err, dur)
+						}
+						sizes := newMessageSizes(&req, resp)
+						warmingUp := warm != nil && warm.active(start)
+						if warmingUp {
+							st.Warmup.record(&req, resp, err, dur)
+						} else {
+							if cmp != nil && st.TTLs != nil {
+								cmp.applyAuthoritative(st)
+							}
+							st.record(&req, resp, sizes, err, start, dur)
+							if st.Connections != nil {
+								st.recordConnection(queryTraceFromContext(queryCtx).connLocalAddr(), &req, resp, err, dur)
+							}
+						}
+						if interval != nil {
+							interval.record(&req, resp, err, dur)
+						}
+						if status != nil {
+							status.record(err, dur)
+						}
+						if metrics != nil {
+							metrics.measure(&req, resp, sizes, dur, err)
+						}
+						if otelExp != nil {
+							otelExp.endQuery(queryCtx, &req, resp, err, dur)
+						}
+						if observed {
+							result := QueryResult{
+								WorkerID:   workerID,
+								Request:    &req,
+								Response:   resp,
+								Err:        err,
+								Start:      start,
+								Duration:   dur,
+								Connection: queryTraceFromContext(queryCtx).connectionInfo(queryNetwork),
+							}
+							b.notify(func(o Observer) { o.QueryDone(result) })
+						}
+						if cmp != nil && !warmingUp {
+							cmp.enqueue(&req, resp, err)
 						}
-						st.record(&req, resp, err, start, dur)
-						b.measureProm(req, resp, dur, err)
 
 						if incrementBar {
 							bar.Add(1)
@@ -546,14 +1028,79 @@ func (b *Benchmark) Run(ctx context.Context) ([]*ResultStats, error) {
 		}(w, st)
 	}
 
+	var pushDone, pusherDone chan struct{}
+	if b.pushEnabled() {
+		pusher := newMetricsPusher(b, metrics.registry)
+		pushDone = make(chan struct{})
+		pusherDone = make(chan struct{})
+		go func() {
+			defer close(pusherDone)
+			pusher.run(pushDone)
+		}()
+	}
+
+	var intervalsDone, reporterDone chan struct{}
+	if intervals != nil {
+		period := b.IntervalReport
+		if period == 0 {
+			period = DefaultControllerInterval
+		}
+		reporter := newIntervalReporter(b, period, intervals, func(is IntervalStats) error {
+			var errs []error
+			for _, sink := range intervalSinks {
+				errs = append(errs, sink(is))
+			}
+			return errors.Join(errs...)
+		})
+		intervalsDone = make(chan struct{})
+		reporterDone = make(chan struct{})
+		go func() {
+			defer close(reporterDone)
+			reporter.run(intervalsDone)
+		}()
+	}
+
 	wg.Wait()
+	if warm != nil {
+		warmupDuration := warm.elapsed(time.Now())
+		for _, st := range stats {
+			st.Warmup.Duration = warmupDuration
+		}
+	}
+	if intervals != nil {
+		// report the last interval after all workers finished
+		close(intervalsDone)
+		<-reporterDone
+	}
+	if pushDone != nil {
+		// push the final metrics after all workers finished
+		close(pushDone)
+		<-pusherDone
+	}
 	if bar != nil {
 		_ = bar.Exit()
 	}
+	if adaptive != nil && len(stats) > 0 {
+		// the rate is adjusted globally, so the adjustments are recorded only once
+		stats[0].RateAdjustments = adaptive.adjustments
+	}
+	b.notify(func(o Observer) { o.RunEnd(stats) })
 
 	return stats, nil
 }
 
+// pprofHandler returns HTTP handler exposing pprof profiles on /debug/pprof/ path. Importing net/http/pprof also registers
+// its handlers on http.DefaultServeMux, the benchmark does not serve http.DefaultServeMux, it serves its own mux instead.
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
 func (b *Benchmark) createReqMsg(domain string, qtype uint16, cookie string, rando *rand.Rand) dns.Msg {
 	req := dns.Msg{}
 	req.RecursionDesired = b.Recurse
@@ -592,20 +1139,14 @@ func (b *Benchmark) createReqMsg(domain string, qtype uint16, cookie string, ran
 	return req
 }
 
-func (b *Benchmark) measureProm(req dns.Msg, resp *dns.Msg, time time.Duration, err error) {
-	if len(b.PrometheusMetricsAddr) == 0 {
-		return
-	}
-	if resp != nil {
-		rcode := dns.RcodeToString[resp.Rcode]
-		respType := dns.TypeToString[resp.Question[0].Qtype]
-		dnsResponseTotalMetrics.WithLabelValues(respType, rcode).Inc()
-	}
-	if err != nil {
-		errorsTotalMetrics.WithLabelValues().Inc()
-	}
-	reqType := dns.TypeToString[req.Question[0].Qtype]
-	dnsRequestsDurationMetrics.WithLabelValues(reqType).Observe(time.Seconds())
+// pushEnabled returns true, when the metrics are pushed to Pushgateway or remote-write endpoint.
+func (b *Benchmark) pushEnabled() bool {
+	return len(b.PushgatewayURL) != 0 || len(b.RemoteWriteURL) != 0
+}
+
+// metricsEnabled returns true, when the Prometheus metrics of the benchmark are collected.
+func (b *Benchmark) metricsEnabled() bool {
+	return len(b.PrometheusMetricsAddr) != 0 || b.PrometheusRegisterer != nil || b.pushEnabled()
 }
 
 func (b *Benchmark) delay(ctx context.Context, rando *rand.Rand) {
@@ -631,7 +1172,16 @@ func waitFor(ctx context.Context, dur time.Duration) {
 	}
 }
 
+// network returns name of the transport used by the benchmark.
 func (b *Benchmark) network() string {
+	if b.transport != nil {
+		return b.transport.Network()
+	}
+	return b.builtinNetwork()
+}
+
+// builtinNetwork returns name of the built-in transport selected by the benchmark configuration.
+func (b *Benchmark) builtinNetwork() string {
 	if b.useDoH {
 		_, network := isHTTPUrl(b.Server)
 		network += "/"
@@ -833,21 +1383,6 @@ func downloadURL(url string) ([]string, error) {
 	return lines, nil
 }
 
-func checkLimit(ctx context.Context, limiter ratelimit.Limiter) error {
-	done := make(chan struct{})
-	go func() {
-		limiter.Take()
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	}
-}
-
 func (b *Benchmark) unsupportedOptionWarnings() []string {
 	warnings := make([]string, 0)
 
@@ -889,7 +1424,7 @@ func (b *Benchmark) unsupportedOptionWarnings() []string {
 
 func (b *Benchmark) writeUnsupportedOptionWarnings() {
 	for _, warning := range b.unsupportedOptionWarnings() {
-		printutils.ErrFprintf(b.ErrWriter, "Warning: %s\n", warning)
+		b.printer.ErrFprintf(b.ErrWriter, "Warning: %s\n", warning)
 	}
 }
 
@@ -6,6 +6,7 @@ import (
 	"io"
 	"regexp"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -136,3 +137,55 @@ func assertRequestLogStructure(t *testing.T, reader io.Reader) {
 
 // A returns an A record from rr. It panics on errors.
 func A(rr string) *dns.A { r, _ := dns.NewRR(rr); return r.(*dns.A) }
+
+// recordingObserver records all events of the observed benchmark.
+type recordingObserver struct {
+	mu      sync.Mutex
+	events  []string
+	run     *dnsbench.Benchmark
+	results []dnsbench.QueryResult
+	stats   []*dnsbench.ResultStats
+}
+
+func (o *recordingObserver) RunStart(b *dnsbench.Benchmark) {
+	o.record("run start")
+	o.run = b
+}
+
+func (o *recordingObserver) WorkerStart(workerID uint32) {
+	o.record(fmt.Sprintf("worker %d start", workerID))
+}
+
+func (o *recordingObserver) QueryDone(result dnsbench.QueryResult) {
+	o.record(fmt.Sprintf("worker %d query", result.WorkerID))
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.results = append(o.results, result)
+}
+
+func (o *recordingObserver) WorkerStop(workerID uint32) {
+	o.record(fmt.Sprintf("worker %d stop", workerID))
+}
+
+func (o *recordingObserver) RunEnd(stats []*dnsbench.ResultStats) {
+	o.record("run end")
+	o.stats = stats
+}
+
+func (o *recordingObserver) record(event string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, event)
+}
+
+// workerEvents returns the recorded events of the worker.
+func (o *recordingObserver) workerEvents(workerID uint32) []string {
+	prefix := fmt.Sprintf("worker %d ", workerID)
+	var res []string
+	for _, e := range o.events {
+		if len(e) > len(prefix) && e[:len(prefix)] == prefix {
+			res = append(res, e[len(prefix):])
+		}
+	}
+	return res
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/miekg/dns"
 	"github.com/stretchr/testify/suite"
 	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
 type DoHTestSuite struct {
@@ -175,7 +176,7 @@ func (suite *DoHTestSuite) TestBenchmark_Run_default_user_agent() {
 	_, err := bench.Run(ctx)
 
 	suite.Require().NoError(err, "expected no error from benchmark run")
-	suite.Equal(bench.DohUserAgent, capturedUserAgent)
+	suite.Empty(bench.DohUserAgent, "expected benchmark not modified by the run")
 	suite.True(strings.HasPrefix(capturedUserAgent, "dnspyre/"), "expected dnspyre/{version} User-Agent")
 }
 
@@ -538,3 +539,124 @@ func (suite *DoHTestSuite) TestBenchmark_Run_separate_connections() {
 		})
 	}
 }
+
+func (suite *DoHTestSuite) TestBenchmark_Run_otlp_connection_phases() {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bd, err := io.ReadAll(r.Body)
+		if err != nil {
+			panic(err)
+		}
+		msg := dns.Msg{}
+		if err := msg.Unpack(bd); err != nil {
+			panic(err)
+		}
+		msg.Answer = append(msg.Answer, A("example.org. IN A 127.0.0.1"))
+		pack, err := msg.Pack()
+		if err != nil {
+			panic(err)
+		}
+		_, _ = w.Write(pack)
+	}))
+	defer ts.Close()
+
+	otlp := NewOTLPServer(dnsbench.OTLPHTTPProtocol)
+	defer otlp.Close()
+
+	buf := bytes.Buffer{}
+	bench := dnsbench.Benchmark{
+		Queries:             []string{"example.org"},
+		Types:               []string{"A"},
+		Server:              ts.URL,
+		Concurrency:         1,
+		Count:               2,
+		Rcodes:              true,
+		Recurse:             true,
+		Insecure:            true,
+		DohMethod:           dnsbench.PostHTTPMethod,
+		OTLPEndpoint:        otlp.Endpoint,
+		OTLPProtocol:        dnsbench.OTLPHTTPProtocol,
+		OTLPTraceSampleRate: 1,
+		Writer:              &buf,
+		ErrWriter:           &buf,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 1)
+
+	spans := make(map[string][]*tracepb.Span)
+	for _, span := range otlp.Spans() {
+		spans[span.GetName()] = append(spans[span.GetName()], span)
+	}
+	suite.Require().Len(spans["dns.query"], 2)
+	suite.Len(spans["connect"], 1, "single connection should be established")
+	suite.Len(spans["tls_handshake"], 1, "single TLS handshake should be done")
+
+	var reused []string
+	for _, span := range spans["dns.query"] {
+		attrs := Attributes(span.GetAttributes())
+		suite.Equal("https/1.1 (POST)", attrs["dnspyre.transport"])
+		reused = append(reused, attrs["dnspyre.connection.reused"])
+	}
+	suite.ElementsMatch([]string{"false", "true"}, reused)
+}
+
+func (suite *DoHTestSuite) TestBenchmark_Run_observer_connection_phases() {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bd, err := io.ReadAll(r.Body)
+		if err != nil {
+			panic(err)
+		}
+		msg := dns.Msg{}
+		if err := msg.Unpack(bd); err != nil {
+			panic(err)
+		}
+		msg.Answer = append(msg.Answer, A("example.org. IN A 127.0.0.1"))
+		pack, err := msg.Pack()
+		if err != nil {
+			panic(err)
+		}
+		_, _ = w.Write(pack)
+	}))
+	defer ts.Close()
+
+	observer := &recordingObserver{}
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A"},
+		Server:      ts.URL,
+		Concurrency: 1,
+		Count:       2,
+		Rcodes:      true,
+		Recurse:     true,
+		Insecure:    true,
+		DohMethod:   dnsbench.PostHTTPMethod,
+		Writer:      io.Discard,
+		Observers:   []dnsbench.Observer{observer},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(observer.results, 2)
+
+	first, second := observer.results[0].Connection, observer.results[1].Connection
+	suite.Equal("https/1.1 (POST)", first.Network)
+	suite.True(first.ReuseKnown)
+	suite.False(first.Reused)
+	var phases []string
+	for _, p := range first.Phases {
+		phases = append(phases, p.Name)
+		suite.NoError(p.Err)
+	}
+	suite.Equal([]string{dnsbench.ConnectPhase, dnsbench.TLSHandshakePhase}, phases)
+
+	suite.True(second.ReuseKnown)
+	suite.True(second.Reused)
+	suite.Empty(second.Phases)
+}
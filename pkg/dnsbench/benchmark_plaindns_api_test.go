@@ -4,17 +4,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"github.com/stretchr/testify/suite"
 	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
 type PlainDNSTestSuite struct {
@@ -823,6 +833,10 @@ func (suite *PlainDNSTestSuite) TestBenchmark_Run_error() {
 	suite.EqualValues(2, rs[0].Counters.IOError, "there should be errors")
 	suite.EqualValues(2, rs[1].Counters.Total, "there should be executions")
 	suite.EqualValues(2, rs[1].Counters.IOError, "there should be errors")
+	for _, st := range rs {
+		suite.Equal(map[dnsbench.ErrorClass]int64{{Type: dnsbench.TimeoutError, Detail: "read"}: 2}, st.ErrorClasses,
+			"errors should be classified as read timeouts")
+	}
 }
 
 func (suite *PlainDNSTestSuite) TestBenchmark_Run_truncated() {
@@ -904,6 +918,175 @@ func (suite *PlainDNSTestSuite) TestBenchmark_Run_requestlog() {
 	assertRequestLogStructure(suite.T(), requestLogFile)
 }
 
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_concurrent() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	dir := suite.T().TempDir()
+	outputs := []*bytes.Buffer{{}, {}, {}}
+	benchmarks := make([]*dnsbench.Benchmark, len(outputs))
+	for i, out := range outputs {
+		benchmarks[i] = &dnsbench.Benchmark{
+			Queries:           []string{"example.org"},
+			Server:            s.Addr,
+			Concurrency:       2,
+			Count:             int64(i + 1),
+			Color:             i%2 == 0,
+			Writer:            out,
+			ErrWriter:         out,
+			RequestLogEnabled: true,
+			RequestLogPath:    filepath.Join(dir, fmt.Sprintf("requests-%d.log", i)),
+		}
+	}
+	originals := make([]dnsbench.Benchmark, len(benchmarks))
+	for i, b := range benchmarks {
+		originals[i] = *b
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	results := make([][]*dnsbench.ResultStats, len(benchmarks))
+	for i, b := range benchmarks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rs, err := b.Run(ctx)
+			suite.NoError(err, "expected no error from benchmark run")
+			results[i] = rs
+		}()
+	}
+	wg.Wait()
+
+	for i, b := range benchmarks {
+		suite.Equal(originals[i], *b, "benchmark should not be modified by the run")
+		suite.Nil(b.Types, "defaults should not be filled in the benchmark")
+
+		total := int64(0)
+		for _, rs := range results[i] {
+			total += rs.Counters.Total
+		}
+		suite.Equal(2*b.Count, total, "each benchmark should send its own number of requests")
+
+		requestLog, err := os.ReadFile(b.RequestLogPath)
+		suite.Require().NoError(err)
+		suite.Equal(2*int(b.Count), strings.Count(string(requestLog), "\n"), "each benchmark should log only its own requests")
+
+		if b.Color {
+			suite.Contains(outputs[i].String(), "\x1b[", "output should be colored")
+		} else {
+			suite.NotContains(outputs[i].String(), "\x1b[", "output should not be colored")
+		}
+		suite.Contains(outputs[i].String(), s.Addr, "each benchmark should print to its own writer")
+	}
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_repeated() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A", "AAAA"},
+		Server:      s.Addr,
+		Concurrency: 2,
+		Count:       1,
+		Rcodes:      true,
+		Recurse:     true,
+		Writer:      io.Discard,
+	}
+	original := bench
+
+	for range 2 {
+		rs, err := bench.Run(context.Background())
+		suite.Require().NoError(err, "expected no error from benchmark run")
+		assertResult(suite.T(), rs)
+		suite.Equal(original, bench, "benchmark should not be modified by the run")
+	}
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_observer() {
+	s := NewServer(dnsbench.TCPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	observer := &recordingObserver{}
+	counter := &queryCounter{}
+
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A"},
+		Server:      s.Addr,
+		TCP:         true,
+		Concurrency: 2,
+		Count:       2,
+		Rcodes:      true,
+		Recurse:     true,
+		Writer:      io.Discard,
+		Observers:   []dnsbench.Observer{observer, counter},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(observer.events, 10)
+	suite.Equal("run start", observer.events[0])
+	suite.Equal("run end", observer.events[len(observer.events)-1])
+	for w := range uint32(2) {
+		suite.Equal([]string{"start", "query", "query", "stop"}, observer.workerEvents(w))
+	}
+	suite.Equal(s.Addr, observer.run.Server)
+	suite.Equal(rs, observer.stats)
+	suite.EqualValues(4, counter.count.Load())
+
+	suite.Require().Len(observer.results, 4)
+	reused := map[uint32][]bool{}
+	for _, r := range observer.results {
+		suite.Require().NoError(r.Err)
+		suite.Equal("example.org.", r.Request.Question[0].Name)
+		suite.Equal(r.Request.Id, r.Response.Id)
+		suite.NotZero(r.Start)
+		suite.Positive(r.Duration)
+		suite.Equal(dnsbench.TCPTransport, r.Connection.Network)
+		suite.True(r.Connection.ReuseKnown)
+		if r.Connection.Reused {
+			suite.Empty(r.Connection.Phases, "no connection should be established for reused connection")
+		} else if suite.Len(r.Connection.Phases, 1) {
+			suite.Equal(dnsbench.ConnectPhase, r.Connection.Phases[0].Name)
+			suite.NoError(r.Connection.Phases[0].Err)
+		}
+		reused[r.WorkerID] = append(reused[r.WorkerID], r.Connection.Reused)
+	}
+	suite.Equal(map[uint32][]bool{0: {false, true}, 1: {false, true}}, reused, "each worker should reuse its connection")
+}
+
+// queryCounter counts completed queries, other events are ignored using the embedded BaseObserver.
+type queryCounter struct {
+	dnsbench.BaseObserver
+	count atomic.Int64
+}
+
+func (c *queryCounter) QueryDone(dnsbench.QueryResult) {
+	c.count.Add(1)
+}
+
 func (suite *PlainDNSTestSuite) TestBenchmark_Run_constantRequestDelay() {
 	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
 		ret := new(dns.Msg)
@@ -1054,3 +1237,1085 @@ func (suite *PlainDNSTestSuite) TestBenchmark_Run_cancellation() {
 	suite.Require().NoError(err, "expected no error from benchmark run")
 	suite.Zero(rs[0].Counters.Total, "there should be no executions due to immediate cancellation")
 }
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_compare_servers() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	same := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer same.Close()
+
+	different := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Rcode = dns.RcodeServerFailure
+		w.WriteMsg(ret)
+	})
+	defer different.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:        []string{"example.org"},
+		Types:          []string{"A"},
+		Server:         s.Addr,
+		Concurrency:    2,
+		Count:          2,
+		Rcodes:         true,
+		Recurse:        true,
+		CompareServers: []string{same.Addr, different.Addr},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results from two workers")
+
+	for _, r := range rs {
+		suite.EqualValues(2, r.Counters.Total, "there should be executions")
+		suite.Require().NotNil(r.Consistency, "there should be consistency results")
+		suite.EqualValues(4, r.Consistency.Compared, "there should be compared answers")
+		suite.EqualValues(2, r.Consistency.Mismatched, "there should be mismatched answers")
+		suite.Zero(r.Consistency.Skipped, "there should be no skipped comparisons")
+		suite.Require().Len(r.Consistency.Servers, 2, "there should be results of compared servers")
+		suite.EqualValues(2, r.Consistency.Servers[same.Addr].Counters.Success, "there should be success responses from compared server")
+		suite.EqualValues(2, r.Consistency.Servers[different.Addr].Counters.Error, "there should be error responses from compared server")
+		suite.Equal(map[dnsbench.DifferenceKey]*dnsbench.Difference{
+			{Server: different.Addr, Domain: "example.org.", Qtype: "A", Kind: dnsbench.RcodeDifference}: {
+				Count: 2, Expected: "NOERROR", Actual: "SERVFAIL",
+			},
+			{Server: different.Addr, Domain: "example.org.", Qtype: "A", Kind: dnsbench.AnswerDifference}: {
+				Count: 2, Expected: "example.org. IN A 127.0.0.1", Actual: "",
+			},
+		}, r.Consistency.Differences)
+	}
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_compare_servers_concurrently() {
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	}
+	slowHandler := func(w dns.ResponseWriter, r *dns.Msg) {
+		time.Sleep(500 * time.Millisecond)
+		handler(w, r)
+	}
+
+	s := NewServer(dnsbench.UDPTransport, nil, handler)
+	defer s.Close()
+
+	slow1 := NewServer(dnsbench.UDPTransport, nil, slowHandler)
+	defer slow1.Close()
+
+	slow2 := NewServer(dnsbench.UDPTransport, nil, slowHandler)
+	defer slow2.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:        []string{"example.org"},
+		Types:          []string{"A"},
+		Server:         s.Addr,
+		Concurrency:    1,
+		Count:          1,
+		Recurse:        true,
+		CompareServers: []string{slow1.Addr, slow2.Addr},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	start := time.Now()
+	rs, err := bench.Run(ctx)
+	elapsed := time.Since(start)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 1, "expected results from one worker")
+	suite.EqualValues(2, rs[0].Consistency.Compared, "there should be compared answers")
+	suite.Zero(rs[0].Consistency.Mismatched, "there should be no mismatched answers")
+	suite.Less(elapsed, 900*time.Millisecond, "compared servers should be queried concurrently")
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_compare_servers_not_blocking_worker() {
+	var mu sync.Mutex
+	var received []time.Time
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		received = append(received, time.Now())
+		mu.Unlock()
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	slow := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		time.Sleep(300 * time.Millisecond)
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer slow.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:        []string{"example.org"},
+		Types:          []string{"A"},
+		Server:         s.Addr,
+		Concurrency:    1,
+		Count:          3,
+		Recurse:        true,
+		CompareServers: []string{slow.Addr},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 1, "expected results from one worker")
+	suite.EqualValues(3, rs[0].Consistency.Compared, "all queued answers should be compared")
+	mu.Lock()
+	defer mu.Unlock()
+	suite.Require().Len(received, 3, "benchmarked server should receive all questions")
+	suite.Less(received[2].Sub(received[0]), 300*time.Millisecond, "worker should not wait for the compared server")
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_ttl_analysis() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. 300 IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A"},
+		Server:      s.Addr,
+		Concurrency: 2,
+		Count:       3,
+		Rcodes:      true,
+		Recurse:     true,
+		TTLAnalysis: true,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results from two workers")
+
+	for _, r := range rs {
+		suite.Require().NotNil(r.TTLHist, "there should be TTL histogram")
+		suite.EqualValues(3, r.TTLHist.TotalCount(), "there should be recorded TTLs")
+		ttl := r.TTLs[dnsbench.QuestionKey{Name: "example.org.", Qtype: "A"}]
+		suite.Require().NotNil(ttl, "there should be TTL stats for question")
+		suite.EqualValues(3, ttl.Count, "there should be answers")
+		suite.EqualValues(300, ttl.Min, "there should be min TTL")
+		suite.EqualValues(300, ttl.Max, "there should be max TTL")
+		suite.EqualValues(2, ttl.Hits, "answers with static TTL should be considered cache hits")
+	}
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_ttl_analysis_authoritative_ttl() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. 300 IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+	authoritative := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Authoritative = true
+		ret.Answer = append(ret.Answer, A("example.org. 7200 IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer authoritative.Close()
+
+	tests := []struct {
+		name             string
+		authoritativeTTL time.Duration
+		compareServers   []string
+		want             uint32
+	}{
+		{
+			name:             "configured authoritative TTL",
+			authoritativeTTL: time.Hour,
+			want:             3600,
+		},
+		{
+			name:             "authoritative compared server",
+			authoritativeTTL: time.Hour,
+			compareServers:   []string{authoritative.Addr},
+			want:             7200,
+		},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			bench := dnsbench.Benchmark{
+				Queries:          []string{"example.org"},
+				Types:            []string{"A"},
+				Server:           s.Addr,
+				Concurrency:      1,
+				Count:            2,
+				Rcodes:           true,
+				Recurse:          true,
+				TTLAnalysis:      true,
+				AuthoritativeTTL: tt.authoritativeTTL,
+				CompareServers:   tt.compareServers,
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			rs, err := bench.Run(ctx)
+
+			suite.Require().NoError(err, "expected no error from benchmark run")
+			suite.Require().Len(rs, 1, "expected results from single worker")
+			ttl := rs[0].TTLs[dnsbench.QuestionKey{Name: "example.org.", Qtype: "A"}]
+			suite.Require().NotNil(ttl, "there should be TTL stats for question")
+			suite.True(ttl.Authoritative, "authoritative TTL should be known")
+			suite.Equal(tt.want, ttl.AuthoritativeTTL, "unexpected authoritative TTL")
+		})
+	}
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_sizes() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A", "AAAA"},
+		Server:      s.Addr,
+		Concurrency: 2,
+		Rcodes:      true,
+		Recurse:     true,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results from two workers")
+
+	for _, r := range rs {
+		suite.Require().NotNil(r.Sizes, "there should be size stats")
+		suite.EqualValues(2, r.Sizes.RequestHist.TotalCount(), "there should be recorded request sizes")
+		suite.EqualValues(2, r.Sizes.ResponseHist.TotalCount(), "there should be recorded response sizes")
+		suite.Positive(r.Sizes.BytesSent, "there should be sent bytes")
+		suite.Greater(r.Sizes.BytesReceived, r.Sizes.BytesSent, "responses should be larger than requests")
+		suite.Len(r.Sizes.Qtypes, 2, "there should be sizes for both question types")
+		suite.Len(r.Sizes.Largest, 2, "there should be largest responses for both questions")
+		suite.Zero(r.Sizes.NearBufferSize, "there should be no responses close to EDNS0 buffer size")
+	}
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_domain_stats() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		if r.Question[0].Name == "failing.org." {
+			ret.Rcode = dns.RcodeServerFailure
+		} else {
+			ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		}
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org", "failing.org"},
+		Types:       []string{"A"},
+		Server:      s.Addr,
+		Concurrency: 2,
+		Rcodes:      true,
+		Recurse:     true,
+		DomainStats: true,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results from two workers")
+
+	for _, r := range rs {
+		suite.Require().Len(r.Domains, 2, "there should be stats for both domains")
+
+		ok := r.Domains[dnsbench.QuestionKey{Name: "example.org.", Qtype: "A"}]
+		suite.Require().NotNil(ok)
+		suite.EqualValues(1, ok.Count)
+		suite.Equal(map[int]int64{dns.RcodeSuccess: 1}, ok.Codes)
+		suite.Zero(ok.Errors())
+		suite.EqualValues(1, ok.Hist.TotalCount())
+		suite.EqualValues(1, ok.Hist.SignificantFigures(), "domain histograms should use low precision")
+
+		failing := r.Domains[dnsbench.QuestionKey{Name: "failing.org.", Qtype: "A"}]
+		suite.Require().NotNil(failing)
+		suite.EqualValues(1, failing.Count)
+		suite.Equal(map[int]int64{dns.RcodeServerFailure: 1}, failing.Codes)
+		suite.EqualValues(1, failing.Errors())
+	}
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_interval_report() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	file := filepath.Join(suite.T().TempDir(), "intervals.ndjson")
+	bench := dnsbench.Benchmark{
+		Queries:              []string{"example.org"},
+		Types:                []string{"A"},
+		Server:               s.Addr,
+		Concurrency:          2,
+		Duration:             time.Second,
+		Rate:                 100,
+		Rcodes:               true,
+		Recurse:              true,
+		IntervalReport:       300 * time.Millisecond,
+		IntervalReportFormat: dnsbench.NDJSONIntervalFormat,
+		IntervalReportPath:   file,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results from two workers")
+
+	content, err := os.ReadFile(file)
+	suite.Require().NoError(err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	suite.GreaterOrEqual(len(lines), 3, "there should be interval reports")
+
+	var total int64
+	for _, l := range lines {
+		var interval struct {
+			TotalRequests    int64 `json:"totalRequests"`
+			SuccessResponses int64 `json:"successResponses"`
+		}
+		suite.Require().NoError(json.Unmarshal([]byte(l), &interval), "interval report should be JSON object")
+		suite.Equal(interval.TotalRequests, interval.SuccessResponses)
+		total += interval.TotalRequests
+	}
+	suite.Equal(rs[0].Counters.Total+rs[1].Counters.Total, total, "intervals should cover all requests")
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_adaptive_rate() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+
+		// wait some time to actually have some observable duration
+		time.Sleep(5 * time.Millisecond)
+
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	buf := bytes.Buffer{}
+	bench := dnsbench.Benchmark{
+		Queries:        []string{"example.org"},
+		Types:          []string{"A"},
+		Server:         s.Addr,
+		Concurrency:    2,
+		Duration:       time.Second,
+		Rate:           100,
+		Rcodes:         true,
+		Recurse:        true,
+		TargetLatency:  time.Millisecond,
+		IntervalReport: 200 * time.Millisecond,
+		Writer:         &buf,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results from two workers")
+	suite.Require().NotEmpty(rs[0].RateAdjustments, "rate should be adjusted")
+	suite.Empty(rs[1].RateAdjustments, "adjustments should be recorded only once")
+
+	previous := 100
+	for _, a := range rs[0].RateAdjustments {
+		suite.Equal(previous, a.PreviousRate)
+		suite.Less(a.Rate, a.PreviousRate, "rate should be decreased, when the target latency is exceeded")
+		suite.Greater(a.Latency, bench.TargetLatency)
+		previous = a.Rate
+	}
+	suite.Contains(buf.String(), "rate adjusted from 100 to 70 QPS")
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_warmup_queries() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A", "AAAA"},
+		Server:      s.Addr,
+		Concurrency: 2,
+		Count:       10,
+		Rcodes:      true,
+		Recurse:     true,
+		Warmup:      "15",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results from two workers")
+
+	var measured, warmup, hist int64
+	for _, st := range rs {
+		suite.Require().NotNil(st.Warmup)
+		measured += st.Counters.Total
+		warmup += st.Warmup.Counters.Total
+		hist += st.Warmup.Hist.TotalCount()
+		suite.Positive(st.Warmup.Duration)
+	}
+	suite.EqualValues(15, warmup, "warm-up queries should be recorded separately")
+	suite.EqualValues(15, hist)
+	suite.EqualValues(40-15, measured, "warm-up queries should not be part of the results")
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_worker_stats() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A", "AAAA"},
+		Server:      s.Addr,
+		Concurrency: 2,
+		Count:       10,
+		QperConn:    5,
+		Recurse:     true,
+		WorkerStats: true,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results from two workers")
+
+	addrs := make(map[string]struct{})
+	for _, st := range rs {
+		suite.Len(st.Connections, 4, "each worker should open new connection after 5 queries")
+		for addr, cs := range st.Connections {
+			addrs[addr] = struct{}{}
+			suite.EqualValues(5, cs.Counters.Total)
+			suite.EqualValues(5, cs.Hist.TotalCount())
+		}
+	}
+	suite.Len(addrs, 8, "connections should be identified by their local address")
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_warmup_duration() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A"},
+		Server:      s.Addr,
+		Concurrency: 2,
+		Duration:    time.Second,
+		Rate:        100,
+		Rcodes:      true,
+		Recurse:     true,
+		Warmup:      "300ms",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	start := time.Now()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results from two workers")
+
+	var measured, warmup int64
+	for _, st := range rs {
+		suite.Require().NotNil(st.Warmup)
+		suite.Equal(300*time.Millisecond, st.Warmup.Duration)
+		measured += st.Counters.Total
+		warmup += st.Warmup.Counters.Total
+		for _, d := range st.Timings {
+			suite.False(d.Start.Before(start.Add(300*time.Millisecond)), "warm-up responses should not be part of the results")
+		}
+	}
+	suite.InDelta(30, warmup, 10)
+	suite.InDelta(70, measured, 10)
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_RunIterations() {
+	var mu sync.Mutex
+	var questions []string
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		mu.Lock()
+		questions = append(questions, r.Question[0].Name)
+		mu.Unlock()
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A(r.Question[0].Name+" IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	buf := bytes.Buffer{}
+	bench := dnsbench.Benchmark{
+		Queries:        []string{"example.org", "example.com", "example.net"},
+		Types:          []string{"A"},
+		Server:         s.Addr,
+		Concurrency:    2,
+		Count:          2,
+		Rcodes:         true,
+		Recurse:        true,
+		Iterations:     3,
+		IterationPause: 50 * time.Millisecond,
+		Shuffle:        true,
+		Writer:         &buf,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	res, err := bench.RunIterations(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(res, 3, "expected results of three iterations")
+	for i, it := range res {
+		suite.Require().Len(it.Stats, 2, "expected results from two workers")
+		suite.EqualValues(6, it.Stats[0].Counters.Total, "each worker should send all queries twice")
+		suite.EqualValues(6, it.Stats[1].Counters.Total)
+		suite.Positive(it.Duration)
+		if i > 0 {
+			suite.GreaterOrEqual(it.Start.Sub(res[i-1].Start.Add(res[i-1].Duration)), 50*time.Millisecond, "iterations should be paused")
+		}
+	}
+	mu.Lock()
+	suite.Len(questions, 36)
+	mu.Unlock()
+	suite.Contains(buf.String(), "Iteration 3/3")
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_RunIterations_stopped() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	controller := dnsbench.NewController()
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A"},
+		Server:      s.Addr,
+		Concurrency: 1,
+		Duration:    10 * time.Second,
+		Rate:        100,
+		Recurse:     true,
+		Iterations:  3,
+		Controller:  controller,
+	}
+	time.AfterFunc(200*time.Millisecond, controller.Stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	res, err := bench.RunIterations(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Len(res, 1, "stopping the benchmark should stop all iterations")
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_RunIterations_error() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	var downloads atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if downloads.Add(1) > 1 {
+			// the queries are available only for the first iteration
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("example.org"))
+	}))
+	defer ts.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:     []string{ts.URL},
+		Types:       []string{"A"},
+		Server:      s.Addr,
+		Concurrency: 1,
+		Count:       1,
+		Recurse:     true,
+		Iterations:  3,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	res, err := bench.RunIterations(ctx)
+
+	suite.Require().Error(err, "expected error from second iteration")
+	suite.Require().Len(res, 1, "results of the first iteration should be returned")
+	suite.EqualValues(1, res[0].Stats[0].Counters.Total)
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_controller() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	controller := dnsbench.NewController()
+	controller.Pause()
+	var intervals []dnsbench.IntervalStats
+	controller.OnInterval(func(is dnsbench.IntervalStats) {
+		intervals = append(intervals, is)
+		if is.Counters.Total > 0 {
+			controller.Stop()
+		}
+	})
+
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A"},
+		Server:      s.Addr,
+		Concurrency: 2,
+		Duration:    time.Minute,
+		Rate:        100,
+		Rcodes:      true,
+		Recurse:     true,
+		Controller:  controller,
+	}
+
+	paused := make(chan []dnsbench.WorkerStatus, 1)
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		paused <- controller.Workers()
+		controller.SetRate(200)
+		controller.Resume()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	start := time.Now()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results from two workers")
+	suite.Less(time.Since(start), 10*time.Second, "benchmark should be stopped early")
+
+	for _, w := range <-paused {
+		suite.Equal(dnsbench.WorkerPaused, w.State, "workers should wait while the benchmark is paused")
+		suite.Zero(w.Requests, "no requests should be sent while the benchmark is paused")
+	}
+	suite.Equal(200, controller.Rate())
+
+	total := rs[0].Counters.Total + rs[1].Counters.Total
+	suite.Positive(total, "requests should be sent after the benchmark is resumed")
+
+	var reported int64
+	for _, is := range intervals {
+		reported += is.Counters.Total
+		for code, count := range is.Codes {
+			suite.Equal(dns.RcodeSuccess, code)
+			suite.Equal(is.Counters.Total, count)
+		}
+	}
+	suite.Equal(total, reported, "intervals should cover all requests")
+
+	workers := controller.Workers()
+	suite.Require().Len(workers, 2)
+	for i, w := range workers {
+		suite.Equal(dnsbench.WorkerDone, w.State)
+		suite.Equal(rs[i].Counters.Total, w.Requests)
+	}
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_push() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	var mu sync.Mutex
+	var pushPaths []string
+	var pushed []*dto.MetricFamily
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		suite.Equal(http.MethodPut, r.Method)
+		pushPaths = append(pushPaths, r.URL.Path)
+		decoder := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+		pushed = nil
+		for {
+			mf := &dto.MetricFamily{}
+			if err := decoder.Decode(mf); err != nil {
+				suite.ErrorIs(err, io.EOF)
+				break
+			}
+			pushed = append(pushed, mf)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer pushgateway.Close()
+
+	var remoteWrites [][]byte
+	remoteWrite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		suite.Equal(http.MethodPost, r.Method)
+		suite.Equal("snappy", r.Header.Get("Content-Encoding"))
+		suite.Equal("application/x-protobuf", r.Header.Get("Content-Type"))
+		suite.Equal("0.1.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+		body, err := io.ReadAll(r.Body)
+		suite.NoError(err)
+		remoteWrites = append(remoteWrites, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer remoteWrite.Close()
+
+	buf := bytes.Buffer{}
+	bench := dnsbench.Benchmark{
+		Queries:        []string{"example.org"},
+		Types:          []string{"A"},
+		Server:         s.Addr,
+		Concurrency:    2,
+		Count:          5,
+		Rcodes:         true,
+		Recurse:        true,
+		PushgatewayURL: pushgateway.URL,
+		RemoteWriteURL: remoteWrite.URL + "/api/v1/write",
+		PushInstance:   "test",
+		RunID:          "run1",
+		Writer:         &buf,
+		ErrWriter:      &buf,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results from two workers")
+	suite.NotContains(buf.String(), "Failed to push metrics")
+
+	mu.Lock()
+	defer mu.Unlock()
+	suite.Require().Len(pushPaths, 1, "final metrics should be pushed to Pushgateway once")
+	segments := strings.Split(strings.TrimPrefix(pushPaths[0], "/metrics/"), "/")
+	suite.Require().Len(segments, 6, "expected job and two grouping labels in the path %s", pushPaths[0])
+	grouping := make(map[string]string)
+	for i := 0; i < len(segments); i += 2 {
+		grouping[segments[i]] = segments[i+1]
+	}
+	suite.Equal(map[string]string{
+		"job":      "dnspyre",
+		"instance": "test",
+		"run_id":   "run1",
+	}, grouping)
+	var names []string
+	for _, mf := range pushed {
+		names = append(names, mf.GetName())
+	}
+	suite.Contains(names, "dnspyre_dns_requests_duration_seconds")
+	suite.Contains(names, "dnspyre_dns_response_total")
+
+	suite.Require().Len(remoteWrites, 1, "final metrics should be pushed to remote-write endpoint once")
+	for _, expected := range []string{"__name__", "dnspyre_dns_requests_duration_seconds_count", "dnspyre_dns_response_total",
+		"job", "dnspyre", "instance", "test", "run_id", "run1", "server", s.Addr, "transport", "udp"} {
+		suite.Contains(string(remoteWrites[0]), expected, "the remote-write request should contain %s", expected)
+	}
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_RunIterations_push_run_id() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	var mu sync.Mutex
+	var pushPaths []string
+	pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		pushPaths = append(pushPaths, r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer pushgateway.Close()
+
+	bench := dnsbench.Benchmark{
+		Queries:        []string{"example.org"},
+		Types:          []string{"A"},
+		Server:         s.Addr,
+		Concurrency:    1,
+		Count:          1,
+		Iterations:     2,
+		PushgatewayURL: pushgateway.URL,
+		PushInstance:   "test",
+		Writer:         io.Discard,
+		ErrWriter:      io.Discard,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.RunIterations(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2, "expected results of two iterations")
+	suite.Empty(bench.RunID, "generated run identifier should not be stored to the benchmark")
+	effective, err := bench.WithDefaults()
+	suite.Require().NoError(err)
+	suite.Empty(effective.RunID, "run identifier should not be generated by WithDefaults")
+
+	mu.Lock()
+	defer mu.Unlock()
+	suite.Require().Len(pushPaths, 2, "final metrics of each iteration should be pushed")
+	runIDs := make([]string, 0, len(pushPaths))
+	for _, p := range pushPaths {
+		_, runID, ok := strings.Cut(p, "/run_id/")
+		suite.Require().True(ok, "pushed metrics should be grouped by run identifier")
+		runID, _, _ = strings.Cut(runID, "/")
+		runIDs = append(runIDs, runID)
+	}
+	suite.NotEmpty(runIDs[0], "run identifier should be generated")
+	suite.Equal(runIDs[0], runIDs[1], "all iterations should share the run identifier")
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_push_error() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	remoteWrite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer remoteWrite.Close()
+
+	buf := bytes.Buffer{}
+	bench := dnsbench.Benchmark{
+		Queries:        []string{"example.org"},
+		Types:          []string{"A"},
+		Server:         s.Addr,
+		Concurrency:    1,
+		Count:          1,
+		RemoteWriteURL: remoteWrite.URL,
+		Writer:         &buf,
+		ErrWriter:      &buf,
+	}
+
+	rs, err := bench.Run(context.Background())
+
+	suite.Require().NoError(err, "failed push should not fail the benchmark")
+	suite.Require().Len(rs, 1)
+	suite.Contains(buf.String(), "Failed to push metrics to remote-write endpoint at "+remoteWrite.URL+": unexpected HTTP status 400 Bad Request: out of order sample")
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_otlp() {
+	for _, protocol := range []string{dnsbench.OTLPGRPCProtocol, dnsbench.OTLPHTTPProtocol} {
+		suite.Run(protocol, func() {
+			s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+				ret := new(dns.Msg)
+				ret.SetReply(r)
+				ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+				w.WriteMsg(ret)
+			})
+			defer s.Close()
+
+			otlp := NewOTLPServer(protocol)
+			defer otlp.Close()
+
+			buf := bytes.Buffer{}
+			bench := dnsbench.Benchmark{
+				Queries:             []string{"example.org"},
+				Types:               []string{"A"},
+				Server:              s.Addr,
+				Concurrency:         1,
+				Count:               3,
+				Rcodes:              true,
+				Recurse:             true,
+				OTLPEndpoint:        otlp.Endpoint,
+				OTLPProtocol:        protocol,
+				OTLPTraceSampleRate: 1,
+				RunID:               "run1",
+				Writer:              &buf,
+				ErrWriter:           &buf,
+			}
+
+			rs, err := bench.Run(context.Background())
+
+			suite.Require().NoError(err, "expected no error from benchmark run")
+			suite.Require().Len(rs, 1, "expected results from single worker")
+			suite.NotContains(buf.String(), "Failed to export")
+
+			metrics := otlp.Metrics()
+			suite.Require().Contains(metrics, "dnspyre.dns.request.duration")
+			suite.Require().Contains(metrics, "dnspyre.dns.responses")
+			points := metrics["dnspyre.dns.request.duration"].GetHistogram().GetDataPoints()
+			suite.Require().Len(points, 1)
+			suite.Equal(uint64(3), points[0].GetCount())
+			suite.Equal(map[string]string{"dns.question.type": "A"}, Attributes(points[0].GetAttributes()))
+			suite.Equal(map[string]string{
+				"service.name":     "dnspyre",
+				"dnspyre.server":   s.Addr,
+				"dnspyre.protocol": "udp",
+				"dnspyre.run_id":   "run1",
+			}, otlp.ResourceAttributes())
+
+			var queries, connects []*tracepb.Span
+			for _, span := range otlp.Spans() {
+				switch span.GetName() {
+				case "dns.query":
+					queries = append(queries, span)
+				case "connect":
+					connects = append(connects, span)
+				}
+			}
+			suite.Require().Len(queries, 3, "every query should be sampled")
+			suite.Require().Len(connects, 1, "single connection should be established")
+
+			var reused []string
+			for _, span := range queries {
+				suite.Equal(tracepb.Span_SPAN_KIND_CLIENT, span.GetKind())
+				attrs := Attributes(span.GetAttributes())
+				suite.Equal("example.org.", attrs["dns.question.name"])
+				suite.Equal("A", attrs["dns.question.type"])
+				suite.Equal("NOERROR", attrs["dns.response.code"])
+				suite.Equal("udp", attrs["dnspyre.transport"])
+				reused = append(reused, attrs["dnspyre.connection.reused"])
+				if attrs["dnspyre.connection.reused"] == "false" {
+					suite.Equal(span.GetSpanId(), connects[0].GetParentSpanId(), "connect span should be child of the query, which established the connection")
+				}
+			}
+			suite.ElementsMatch([]string{"false", "true", "true"}, reused)
+		})
+	}
+}
+
+func (suite *PlainDNSTestSuite) TestBenchmark_Run_prometheus_registerer() {
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	}
+	s := NewServer(dnsbench.TCPTransport, nil, handler)
+	defer s.Close()
+
+	counts := []int64{3, 5}
+	registries := make([]*prometheus.Registry, len(counts))
+	var wg sync.WaitGroup
+	for i, count := range counts {
+		registries[i] = prometheus.NewRegistry()
+		bench := dnsbench.Benchmark{
+			Queries:              []string{"example.org"},
+			Types:                []string{"A"},
+			Server:               s.Addr,
+			TCP:                  true,
+			Concurrency:          2,
+			Count:                count,
+			Rcodes:               true,
+			Recurse:              true,
+			PrometheusRegisterer: registries[i],
+			Writer:               io.Discard,
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := bench.Run(context.Background())
+			suite.NoError(err, "expected no error from benchmark run")
+		}()
+	}
+	wg.Wait()
+
+	for i, count := range counts {
+		mfs, err := registries[i].Gather()
+		suite.Require().NoError(err)
+		values := make(map[string]float64)
+		for _, mf := range mfs {
+			for _, m := range mf.GetMetric() {
+				labels := make(map[string]string)
+				for _, l := range m.GetLabel() {
+					labels[l.GetName()] = l.GetValue()
+				}
+				suite.Equal(s.Addr, labels["server"], "metric %s should be labeled by server", mf.GetName())
+				suite.Equal("tcp", labels["transport"], "metric %s should be labeled by transport", mf.GetName())
+				values[mf.GetName()] += m.GetCounter().GetValue() + m.GetGauge().GetValue()
+			}
+		}
+		suite.InDelta(float64(2*count), values["dnspyre_dns_response_total"], 0, "benchmarks should not share metrics")
+		suite.Positive(values["dnspyre_request_bytes_total"])
+		suite.Positive(values["dnspyre_response_bytes_total"])
+		suite.InDelta(0, values["dnspyre_in_flight_requests"], 0)
+		suite.InDelta(0, values["dnspyre_open_connections"], 0, "connections of the workers should be closed after the run")
+	}
+}
@@ -2,6 +2,8 @@ package dnsbench
 
 import (
 	"bytes"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -159,6 +161,96 @@ func TestBenchmark_init(t *testing.T) {
 			benchmark: Benchmark{Server: "8.8.8.8", RequestDelay: "invalid"},
 			wantErr:   true,
 		},
+		{
+			name:      "invalid OTLP endpoint",
+			benchmark: Benchmark{Server: "8.8.8.8", OTLPEndpoint: "localhost:4317"},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid OTLP protocol",
+			benchmark: Benchmark{Server: "8.8.8.8", OTLPEndpoint: "http://localhost:4317", OTLPProtocol: "udp"},
+			wantErr:   true,
+		},
+		{
+			name:      "negative rate burst",
+			benchmark: Benchmark{Server: "8.8.8.8", RateBurst: -1},
+			wantErr:   true,
+		},
+		{
+			name:      "negative authoritative TTL",
+			benchmark: Benchmark{Server: "8.8.8.8", AuthoritativeTTL: -time.Second},
+			wantErr:   true,
+		},
+		{
+			name:      "negative iterations",
+			benchmark: Benchmark{Server: "8.8.8.8", Iterations: -1},
+			wantErr:   true,
+		},
+		{
+			name:      "negative iteration pause",
+			benchmark: Benchmark{Server: "8.8.8.8", IterationPause: -time.Second},
+			wantErr:   true,
+		},
+		{
+			name:      "negative cv threshold",
+			benchmark: Benchmark{Server: "8.8.8.8", CVThreshold: -0.1},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid warmup",
+			benchmark: Benchmark{Server: "8.8.8.8", Warmup: "1x"},
+			wantErr:   true,
+		},
+		{
+			name:      "negative target latency",
+			benchmark: Benchmark{Server: "8.8.8.8", TargetLatency: -time.Millisecond},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid target percentile",
+			benchmark: Benchmark{Server: "8.8.8.8", TargetLatency: time.Millisecond, TargetPercentile: 101},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid target error rate",
+			benchmark: Benchmark{Server: "8.8.8.8", TargetLatency: time.Millisecond, TargetErrorRate: 1.5},
+			wantErr:   true,
+		},
+		{
+			name:      "negative adaptive step",
+			benchmark: Benchmark{Server: "8.8.8.8", TargetLatency: time.Millisecond, AdaptiveStep: -1},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid OTLP trace sample rate",
+			benchmark: Benchmark{Server: "8.8.8.8", OTLPEndpoint: "http://localhost:4317", OTLPTraceSampleRate: 1.5},
+			wantErr:   true,
+		},
+		{
+			name:         "supported plot charts",
+			benchmark:    Benchmark{Server: "8.8.8.8", PlotCharts: []string{LatencyCDFChart, QtypeBoxPlotChart}},
+			assertServer: assertServerEqual("8.8.8.8:53"),
+		},
+		{
+			name:      "unsupported plot chart",
+			benchmark: Benchmark{Server: "8.8.8.8", PlotCharts: []string{"pie-chart"}},
+			wantErr:   true,
+		},
+		{
+			name:         "valid percentiles",
+			benchmark:    Benchmark{Server: "8.8.8.8", Percentiles: []float64{99.9, 100}},
+			assertServer: assertServerEqual("8.8.8.8:53"),
+		},
+		{
+			name:      "zero percentile",
+			benchmark: Benchmark{Server: "8.8.8.8", Percentiles: []float64{0}},
+			wantErr:   true,
+		},
+		{
+			name:      "percentile above 100",
+			benchmark: Benchmark{Server: "8.8.8.8", Percentiles: []float64{99, 100.5}},
+			wantErr:   true,
+		},
 		{
 			name:         "DoH with plain DNS transport flags",
 			benchmark:    Benchmark{Server: "https://1.1.1.1/dns-query", TCP: true, DOT: true, QperConn: 10},
@@ -200,6 +292,7 @@ func TestBenchmark_init(t *testing.T) {
 
 			require.Equal(t, tt.wantErr, err != nil)
 			if !tt.wantErr {
+				tt.benchmark.writeUnsupportedOptionWarnings()
 				tt.assertServer(t, tt.benchmark.Server)
 				assert.Equal(t, tt.wantRequestLogPath, tt.benchmark.RequestLogPath)
 				assert.Equal(t, tt.wantRequestDelayStart, tt.benchmark.requestDelayStart)
@@ -222,3 +315,36 @@ func assertServerEqual(server string) assert.ValueAssertionFunc {
 		return assert.Equal(t, server, val, i2)
 	}
 }
+
+func TestBenchmark_WithDefaults(t *testing.T) {
+	b := Benchmark{Server: "quic://127.0.0.1", Queries: []string{"example.org"}}
+
+	got, err := b.WithDefaults()
+
+	require.NoError(t, err)
+	assert.Equal(t, Benchmark{Server: "quic://127.0.0.1", Queries: []string{"example.org"}}, b, "benchmark should not be modified")
+	assert.Equal(t, "127.0.0.1:853", got.Server)
+	assert.True(t, got.useQuic)
+	assert.Equal(t, []string{DefaultQueryType}, got.Types)
+
+	again, err := got.WithDefaults()
+
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:853", again.Server, "normalized server should not change")
+	assert.True(t, again.useQuic)
+}
+
+func Test_pprofHandler(t *testing.T) {
+	server := httptest.NewServer(pprofHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/debug/pprof/cmdline")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/metrics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
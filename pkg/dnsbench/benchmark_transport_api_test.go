@@ -0,0 +1,188 @@
+package dnsbench_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/suite"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+type TransportTestSuite struct {
+	suite.Suite
+}
+
+func TestTransportTestSuite(t *testing.T) {
+	suite.Run(t, new(TransportTestSuite))
+}
+
+// memoryTransport answers the queries in memory without any network communication.
+type memoryTransport struct {
+	server string
+
+	mu            sync.Mutex
+	workers       []uint32
+	closedWorkers []uint32
+	closed        bool
+}
+
+func (t *memoryTransport) Network() string {
+	return "memory"
+}
+
+func (t *memoryTransport) QueryFunc(workerID uint32) dnsbench.QueryFunc {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.workers = append(t.workers, workerID)
+	return func(_ context.Context, req *dns.Msg) (*dns.Msg, error) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Answer = append(resp.Answer, A("example.org. IN A 127.0.0.1"))
+		// wait some time to actually have some observable duration
+		time.Sleep(time.Millisecond)
+		return resp, nil
+	}
+}
+
+func (t *memoryTransport) CloseWorker(workerID uint32) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closedWorkers = append(t.closedWorkers, workerID)
+	return nil
+}
+
+func (t *memoryTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	return nil
+}
+
+func (suite *TransportTestSuite) TestBenchmark_Run_custom_transport() {
+	transport := &memoryTransport{}
+	registry := prometheus.NewRegistry()
+	buf := bytes.Buffer{}
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A", "AAAA"},
+		Server:      "mem://resolver",
+		Concurrency: 2,
+		Rcodes:      true,
+		Recurse:     true,
+		Writer:      &buf,
+		Transports: map[string]dnsbench.TransportFactory{
+			"mem": func(b *dnsbench.Benchmark) (dnsbench.Transport, error) {
+				transport.server = b.Server
+				return transport, nil
+			},
+		},
+		PrometheusRegisterer: registry,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	rs, err := bench.Run(ctx)
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	assertResult(suite.T(), rs)
+	suite.Equal("mem://resolver", transport.server, "server address should be passed to the transport as is")
+	suite.ElementsMatch([]uint32{0, 1}, transport.workers, "each worker should get its own query function")
+	suite.ElementsMatch([]uint32{0, 1}, transport.closedWorkers, "query function of each worker should be closed")
+	suite.True(transport.closed, "transport should be closed after the benchmark")
+	suite.Contains(buf.String(), "Benchmarking mem://resolver via memory with 2 concurrent requests")
+
+	mfs, err := registry.Gather()
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(mfs)
+	labels := make(map[string]string)
+	for _, l := range mfs[0].GetMetric()[0].GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+	suite.Equal("memory", labels["transport"], "metrics should be labeled by network of the transport")
+}
+
+func (suite *TransportTestSuite) TestBenchmark_Run_custom_transport_overrides_builtin() {
+	transport := &memoryTransport{}
+	bench := dnsbench.Benchmark{
+		Queries:     []string{"example.org"},
+		Types:       []string{"A", "AAAA"},
+		Server:      "https://resolver.invalid",
+		Concurrency: 2,
+		Rcodes:      true,
+		Recurse:     true,
+		Writer:      io.Discard,
+		Transports: map[string]dnsbench.TransportFactory{
+			"https": func(*dnsbench.Benchmark) (dnsbench.Transport, error) {
+				return transport, nil
+			},
+		},
+	}
+
+	rs, err := bench.Run(context.Background())
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	assertResult(suite.T(), rs)
+}
+
+func (suite *TransportTestSuite) TestBenchmark_Run_custom_transport_compare_server() {
+	s := NewServer(dnsbench.UDPTransport, nil, func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.Answer = append(ret.Answer, A("example.org. IN A 127.0.0.1"))
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	transport := &memoryTransport{}
+	bench := dnsbench.Benchmark{
+		Queries:        []string{"example.org"},
+		Types:          []string{"A", "AAAA"},
+		Server:         s.Addr,
+		CompareServers: []string{"mem://resolver"},
+		Concurrency:    2,
+		Rcodes:         true,
+		Recurse:        true,
+		Writer:         io.Discard,
+		Transports: map[string]dnsbench.TransportFactory{
+			"mem": func(*dnsbench.Benchmark) (dnsbench.Transport, error) {
+				return transport, nil
+			},
+		},
+	}
+
+	rs, err := bench.Run(context.Background())
+
+	suite.Require().NoError(err, "expected no error from benchmark run")
+	suite.Require().Len(rs, 2)
+	suite.ElementsMatch([]uint32{0, 1}, transport.closedWorkers, "query function of each worker should be closed")
+	for _, st := range rs {
+		suite.Require().NotNil(st.Consistency)
+		suite.EqualValues(2, st.Consistency.Compared)
+		suite.Zero(st.Consistency.Mismatched, "answers of the custom transport should match")
+	}
+}
+
+func (suite *TransportTestSuite) TestBenchmark_Run_custom_transport_error() {
+	bench := dnsbench.Benchmark{
+		Queries: []string{"example.org"},
+		Server:  "mem://resolver",
+		Writer:  io.Discard,
+		Transports: map[string]dnsbench.TransportFactory{
+			"mem": func(*dnsbench.Benchmark) (dnsbench.Transport, error) {
+				return nil, errors.New("test error")
+			},
+		},
+	}
+
+	_, err := bench.Run(context.Background())
+
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "test error")
+}
@@ -0,0 +1,46 @@
+package dnsbench
+
+import (
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+)
+
+// maxTrackedConnections is the maximum number of connections tracked by single worker, queries sent over further connections
+// are not part of ResultStats.Connections, this bounds the memory used, when the connections are reopened frequently (see Benchmark.QperConn).
+const maxTrackedConnections = 100
+
+// ConnectionStats represents aggregated latencies and outcomes of requests sent over single connection.
+type ConnectionStats struct {
+	// Counters holds counters of the requests sent over the connection.
+	Counters Counters
+	// Hist is a histogram of latencies in nanoseconds.
+	Hist *hdrhistogram.Histogram
+}
+
+func newConnectionStats(hist *hdrhistogram.Histogram) *ConnectionStats {
+	return &ConnectionStats{
+		Hist: hdrhistogram.New(hist.LowestTrackableValue(), hist.HighestTrackableValue(), int(hist.SignificantFigures())),
+	}
+}
+
+// recordConnection records outcome of the request sent over the connection identified by its local address, the requests are not recorded,
+// when the connection is not known.
+func (rs *ResultStats) recordConnection(localAddr string, req *dns.Msg, resp *dns.Msg, err error, duration time.Duration) {
+	if len(localAddr) == 0 {
+		return
+	}
+	cs, ok := rs.Connections[localAddr]
+	if !ok {
+		if len(rs.Connections) >= maxTrackedConnections {
+			return
+		}
+		cs = newConnectionStats(rs.Hist)
+		rs.Connections[localAddr] = cs
+	}
+	cs.Counters.count(req, resp, err)
+	if err == nil {
+		cs.Hist.RecordValue(duration.Nanoseconds())
+	}
+}
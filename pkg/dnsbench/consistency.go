@@ -0,0 +1,401 @@
+package dnsbench
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DifferenceKind represents the part of DNS response, in which the compared answers differed.
+type DifferenceKind string
+
+const (
+	// RcodeDifference represents difference in response codes.
+	RcodeDifference DifferenceKind = "rcode"
+	// AnswerDifference represents difference in answer RRsets.
+	AnswerDifference DifferenceKind = "answer"
+	// TTLDifference represents difference in TTLs of otherwise matching answer RRsets.
+	TTLDifference DifferenceKind = "ttl"
+	// ADDifference represents difference in Authenticated Data (AD) flags.
+	ADDifference DifferenceKind = "ad"
+)
+
+// DifferenceKey identifies answer difference found for single domain and query type on the compared server.
+type DifferenceKey struct {
+	Server string
+	Domain string
+	Qtype  string
+	Kind   DifferenceKind
+}
+
+// Difference represents answer difference found during cross-server answer consistency checking.
+type Difference struct {
+	// Count is counter of all occurrences of the difference.
+	Count int64
+	// Expected is the first observed value from the answer of Benchmark.Server.
+	Expected string
+	// Actual is the first observed value from the answer of the compared server.
+	Actual string
+}
+
+// ConsistencyStats represents results of cross-server answer consistency checking of single concurrent thread.
+type ConsistencyStats struct {
+	// Servers holds results of the compared servers keyed by the server address.
+	Servers map[string]*ResultStats
+	// Compared is counter of all answers of the compared servers, which were compared with the answer of Benchmark.Server.
+	Compared int64
+	// Mismatched is counter of all compared answers, which differed from the answer of Benchmark.Server.
+	Mismatched int64
+	// Skipped is counter of all comparisons skipped, because either of the servers did not provide an answer or because
+	// the comparison queue of the worker was full.
+	Skipped int64
+	// Differences holds the found differences.
+	Differences map[DifferenceKey]*Difference
+}
+
+func newConsistencyStats(b *Benchmark) *ConsistencyStats {
+	cs := &ConsistencyStats{
+		Servers:     make(map[string]*ResultStats),
+		Differences: make(map[DifferenceKey]*Difference),
+	}
+	for _, cb := range b.compareBenchmarks {
+		cs.Servers[cb.Server] = newResultStats(cb)
+	}
+	return cs
+}
+
+// compareBenchmark creates copy of the Benchmark targeting the provided server, which is used for sending
+// the same questions to the compared server.
+func (b *Benchmark) compareBenchmark(server string) (*Benchmark, error) {
+	cb := *b
+	cb.Server = server
+	cb.CompareServers = nil
+	cb.compareBenchmarks = nil
+	cb.useQuic = false
+	if err := cb.normalizeServer(); err != nil {
+		return nil, err
+	}
+	return &cb, nil
+}
+
+// comparisonQueueSize is the number of the benchmarked queries of single worker waiting for the comparison with the compared servers,
+// the comparisons of the queries, which do not fit into the queue, are skipped.
+const comparisonQueueSize = 100
+
+// comparer compares answers of the compared servers with the answers of Benchmark.Server of single worker. The compared servers
+// are queried by the comparer goroutine, so the worker, its rate and latencies are not affected by the compared servers.
+type comparer struct {
+	b        *Benchmark
+	workerID uint32
+	stats    *ConsistencyStats
+	queries  []QueryFunc
+	ttls     bool
+	queue    chan comparedQuery
+	// authoritative passes authoritative TTLs of the compared servers to the worker, which owns the TTL analysis.
+	authoritative chan authoritativeAnswer
+	// dropped is counter of the queries, which did not fit into the queue, it is accessed only by the worker.
+	dropped int64
+	done    chan struct{}
+}
+
+type comparedQuery struct {
+	req  *dns.Msg
+	resp *dns.Msg
+	err  error
+}
+
+type authoritativeAnswer struct {
+	req *dns.Msg
+	ttl uint32
+}
+
+// newComparer starts comparer of the answers of the worker, the comparer records the results to st.Consistency, which must not be accessed
+// by the worker until the comparer is finished.
+func (b *Benchmark) newComparer(ctx context.Context, st *ResultStats, workerID uint32) *comparer {
+	c := &comparer{
+		b:             b,
+		workerID:      workerID,
+		stats:         st.Consistency,
+		ttls:          st.TTLs != nil,
+		queue:         make(chan comparedQuery, comparisonQueueSize),
+		authoritative: make(chan authoritativeAnswer, comparisonQueueSize),
+		done:          make(chan struct{}),
+	}
+	for _, cb := range b.compareBenchmarks {
+		c.queries = append(c.queries, cb.transport.QueryFunc(workerID))
+	}
+	go c.run(ctx)
+	return c
+}
+
+func (c *comparer) run(ctx context.Context) {
+	defer func() {
+		for _, cb := range c.b.compareBenchmarks {
+			closeWorker(cb.transport, c.workerID)
+		}
+		close(c.done)
+	}()
+	for q := range c.queue {
+		if ctx.Err() != nil {
+			// Benchmark was cancelled, do not count results of the remaining comparisons
+			continue
+		}
+		c.compare(ctx, q)
+	}
+}
+
+// enqueue queues the benchmarked query for the comparison, the comparison is skipped, when the queue is full.
+func (c *comparer) enqueue(req *dns.Msg, resp *dns.Msg, err error) {
+	select {
+	case c.queue <- comparedQuery{req: req, resp: resp, err: err}:
+	default:
+		c.dropped++
+	}
+}
+
+// applyAuthoritative records authoritative TTLs of the compared servers received so far to the TTL analysis of the worker.
+func (c *comparer) applyAuthoritative(st *ResultStats) {
+	for {
+		select {
+		case a := <-c.authoritative:
+			st.ttlStats(a.req).recordAuthoritative(a.ttl)
+		default:
+			return
+		}
+	}
+}
+
+// finish waits for the queued comparisons and records the results of the comparer to the worker results.
+func (c *comparer) finish(st *ResultStats) {
+	close(c.queue)
+	<-c.done
+	if st.TTLs != nil {
+		c.applyAuthoritative(st)
+	}
+	st.Consistency.Skipped += c.dropped * int64(len(c.queries))
+}
+
+// compare sends the request to all compared servers and compares their answers with the answer of Benchmark.Server.
+// The compared servers are queried concurrently, so the comparison waits only for the slowest of them.
+func (c *comparer) compare(ctx context.Context, q comparedQuery) {
+	type comparedAnswer struct {
+		req      *dns.Msg
+		resp     *dns.Msg
+		err      error
+		start    time.Time
+		duration time.Duration
+	}
+
+	answers := make([]comparedAnswer, len(c.queries))
+	var wg sync.WaitGroup
+	for i, query := range c.queries {
+		cmpReq := q.req.Copy()
+		if c.b.compareBenchmarks[i].useQuic {
+			cmpReq.Id = 0
+		}
+
+		wg.Add(1)
+		go func(i int, query QueryFunc, cmpReq *dns.Msg) {
+			defer wg.Done()
+			start := time.Now()
+			reqTimeoutCtx, cancel := context.WithTimeout(ctx, c.b.RequestTimeout)
+			cmpResp, err := query(reqTimeoutCtx, cmpReq)
+			cancel()
+			answers[i] = comparedAnswer{req: cmpReq, resp: cmpResp, err: err, start: start, duration: time.Since(start)}
+		}(i, query, cmpReq)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		// Benchmark was cancelled, do not count results of this comparison
+		return
+	}
+
+	for i, a := range answers {
+		cb := c.b.compareBenchmarks[i]
+		cmpReq, cmpResp, err := a.req, a.resp, a.err
+		c.stats.Servers[cb.Server].record(cmpReq, cmpResp, newMessageSizes(cmpReq, cmpResp), err, a.start, a.duration)
+
+		if err == nil && c.ttls && cmpResp.Authoritative {
+			// the compared server is authoritative for the question, its TTL is used to detect TTL clamping by the benchmarked server
+			if ttl, ok := answerTTL(cmpResp); ok {
+				select {
+				case c.authoritative <- authoritativeAnswer{req: q.req, ttl: ttl}:
+				default:
+					// the worker did not apply the previous TTLs yet, the TTL is sent again with the next authoritative answer
+				}
+			}
+		}
+
+		if q.err != nil || err != nil {
+			c.stats.Skipped++
+			continue
+		}
+
+		c.stats.Compared++
+		diffs := compareAnswers(q.resp, cmpResp, c.b.CompareIgnoreOrder, c.b.CompareTTL, c.b.CompareTTLTolerance)
+		if len(diffs) > 0 {
+			c.stats.Mismatched++
+		}
+		for _, d := range diffs {
+			key := DifferenceKey{
+				Server: cb.Server,
+				Domain: q.req.Question[0].Name,
+				Qtype:  dns.TypeToString[q.req.Question[0].Qtype],
+				Kind:   d.kind,
+			}
+			if v, ok := c.stats.Differences[key]; ok {
+				v.Count++
+				continue
+			}
+			c.stats.Differences[key] = &Difference{Count: 1, Expected: d.expected, Actual: d.actual}
+		}
+	}
+}
+
+type answerDifference struct {
+	kind     DifferenceKind
+	expected string
+	actual   string
+}
+
+// compareAnswers compares rcode, AD flag and answer RRset of the expected and actual DNS responses.
+func compareAnswers(expected, actual *dns.Msg, ignoreOrder, compareTTL bool, ttlTolerance time.Duration) []answerDifference {
+	var diffs []answerDifference
+
+	if expected.Rcode != actual.Rcode {
+		diffs = append(diffs, answerDifference{
+			kind:     RcodeDifference,
+			expected: dns.RcodeToString[expected.Rcode],
+			actual:   dns.RcodeToString[actual.Rcode],
+		})
+	}
+
+	if expected.AuthenticatedData != actual.AuthenticatedData {
+		diffs = append(diffs, answerDifference{
+			kind:     ADDifference,
+			expected: strconv.FormatBool(expected.AuthenticatedData),
+			actual:   strconv.FormatBool(actual.AuthenticatedData),
+		})
+	}
+
+	expectedRecords := normalizeAnswer(expected.Answer, ignoreOrder)
+	actualRecords := normalizeAnswer(actual.Answer, ignoreOrder)
+
+	if !equalRecords(expectedRecords, actualRecords) {
+		diffs = append(diffs, answerDifference{
+			kind:     AnswerDifference,
+			expected: formatRecords(expectedRecords),
+			actual:   formatRecords(actualRecords),
+		})
+		return diffs
+	}
+
+	if compareTTL {
+		tolerance := uint32(ttlTolerance.Seconds())
+		for i := range expectedRecords {
+			e, a := expectedRecords[i].ttl, actualRecords[i].ttl
+			if (e > a && e-a > tolerance) || (a > e && a-e > tolerance) {
+				diffs = append(diffs, answerDifference{
+					kind:     TTLDifference,
+					expected: formatTTLs(expectedRecords),
+					actual:   formatTTLs(actualRecords),
+				})
+				break
+			}
+		}
+	}
+
+	return diffs
+}
+
+type normalizedRecord struct {
+	// record is textual representation of the resource record without TTL.
+	record string
+	ttl    uint32
+}
+
+func normalizeAnswer(answer []dns.RR, ignoreOrder bool) []normalizedRecord {
+	records := make([]normalizedRecord, 0, len(answer))
+	for _, rr := range answer {
+		rrCopy := dns.Copy(rr)
+		rrCopy.Header().Name = strings.ToLower(rrCopy.Header().Name)
+		canonicalizeRdata(rrCopy)
+		fields := strings.Fields(rrCopy.String())
+		if len(fields) > 1 {
+			// drop TTL, which is compared separately
+			fields = append(fields[:1], fields[2:]...)
+		}
+		records = append(records, normalizedRecord{
+			record: strings.Join(fields, " "),
+			ttl:    rr.Header().Ttl,
+		})
+	}
+	if ignoreOrder {
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].record < records[j].record
+		})
+	}
+	return records
+}
+
+// canonicalizeRdata lowercases domain names in the rdata of the record, so the answers of the resolvers randomizing case
+// of the names (DNS 0x20 encoding) are equal.
+func canonicalizeRdata(rr dns.RR) {
+	switch r := rr.(type) {
+	case *dns.CNAME:
+		r.Target = dns.CanonicalName(r.Target)
+	case *dns.DNAME:
+		r.Target = dns.CanonicalName(r.Target)
+	case *dns.NS:
+		r.Ns = dns.CanonicalName(r.Ns)
+	case *dns.PTR:
+		r.Ptr = dns.CanonicalName(r.Ptr)
+	case *dns.MX:
+		r.Mx = dns.CanonicalName(r.Mx)
+	case *dns.SRV:
+		r.Target = dns.CanonicalName(r.Target)
+	case *dns.SOA:
+		r.Ns = dns.CanonicalName(r.Ns)
+		r.Mbox = dns.CanonicalName(r.Mbox)
+	case *dns.NAPTR:
+		r.Replacement = dns.CanonicalName(r.Replacement)
+	case *dns.SVCB:
+		r.Target = dns.CanonicalName(r.Target)
+	case *dns.HTTPS:
+		r.Target = dns.CanonicalName(r.Target)
+	}
+}
+
+func equalRecords(a, b []normalizedRecord) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].record != b[i].record {
+			return false
+		}
+	}
+	return true
+}
+
+func formatRecords(records []normalizedRecord) string {
+	res := make([]string, 0, len(records))
+	for _, r := range records {
+		res = append(res, r.record)
+	}
+	return strings.Join(res, " | ")
+}
+
+func formatTTLs(records []normalizedRecord) string {
+	res := make([]string, 0, len(records))
+	for _, r := range records {
+		res = append(res, strconv.FormatUint(uint64(r.ttl), 10))
+	}
+	return strings.Join(res, " | ")
+}
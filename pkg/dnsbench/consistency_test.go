@@ -0,0 +1,119 @@
+package dnsbench
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_compareAnswers(t *testing.T) {
+	rr := func(s string) dns.RR {
+		r, err := dns.NewRR(s)
+		if err != nil {
+			panic(err)
+		}
+		return r
+	}
+	msg := func(rcode int, ad bool, answer ...dns.RR) *dns.Msg {
+		return &dns.Msg{MsgHdr: dns.MsgHdr{Rcode: rcode, AuthenticatedData: ad}, Answer: answer}
+	}
+
+	type args struct {
+		expected     *dns.Msg
+		actual       *dns.Msg
+		ignoreOrder  bool
+		compareTTL   bool
+		ttlTolerance time.Duration
+	}
+	tests := []struct {
+		name string
+		args args
+		want []answerDifference
+	}{
+		{
+			name: "same answers",
+			args: args{
+				expected: msg(dns.RcodeSuccess, false, rr("example.org. 300 IN A 127.0.0.1")),
+				actual:   msg(dns.RcodeSuccess, false, rr("EXAMPLE.org. 200 IN A 127.0.0.1")),
+			},
+		},
+		{
+			name: "mixed case names in rdata",
+			args: args{
+				expected: msg(dns.RcodeSuccess, false, rr("www.example.org. 300 IN CNAME example.org."), rr("example.org. 300 IN MX 10 mail.example.org.")),
+				actual:   msg(dns.RcodeSuccess, false, rr("wWw.example.org. 300 IN CNAME ExAmPlE.oRg."), rr("exAMPle.org. 300 IN MX 10 MAIL.example.ORG.")),
+			},
+		},
+		{
+			name: "different rcode",
+			args: args{
+				expected: msg(dns.RcodeSuccess, false),
+				actual:   msg(dns.RcodeNameError, false),
+			},
+			want: []answerDifference{{kind: RcodeDifference, expected: "NOERROR", actual: "NXDOMAIN"}},
+		},
+		{
+			name: "different AD flag",
+			args: args{
+				expected: msg(dns.RcodeSuccess, true),
+				actual:   msg(dns.RcodeSuccess, false),
+			},
+			want: []answerDifference{{kind: ADDifference, expected: "true", actual: "false"}},
+		},
+		{
+			name: "different answer",
+			args: args{
+				expected: msg(dns.RcodeSuccess, false, rr("example.org. 300 IN A 127.0.0.1")),
+				actual:   msg(dns.RcodeSuccess, false, rr("example.org. 300 IN A 127.0.0.2")),
+			},
+			want: []answerDifference{{kind: AnswerDifference, expected: "example.org. IN A 127.0.0.1", actual: "example.org. IN A 127.0.0.2"}},
+		},
+		{
+			name: "different order",
+			args: args{
+				expected: msg(dns.RcodeSuccess, false, rr("example.org. 300 IN A 127.0.0.1"), rr("example.org. 300 IN A 127.0.0.2")),
+				actual:   msg(dns.RcodeSuccess, false, rr("example.org. 300 IN A 127.0.0.2"), rr("example.org. 300 IN A 127.0.0.1")),
+			},
+			want: []answerDifference{{
+				kind:     AnswerDifference,
+				expected: "example.org. IN A 127.0.0.1 | example.org. IN A 127.0.0.2",
+				actual:   "example.org. IN A 127.0.0.2 | example.org. IN A 127.0.0.1",
+			}},
+		},
+		{
+			name: "different order ignored",
+			args: args{
+				expected:    msg(dns.RcodeSuccess, false, rr("example.org. 300 IN A 127.0.0.1"), rr("example.org. 300 IN A 127.0.0.2")),
+				actual:      msg(dns.RcodeSuccess, false, rr("example.org. 300 IN A 127.0.0.2"), rr("example.org. 300 IN A 127.0.0.1")),
+				ignoreOrder: true,
+			},
+		},
+		{
+			name: "different TTL",
+			args: args{
+				expected:   msg(dns.RcodeSuccess, false, rr("example.org. 300 IN A 127.0.0.1")),
+				actual:     msg(dns.RcodeSuccess, false, rr("example.org. 200 IN A 127.0.0.1")),
+				compareTTL: true,
+			},
+			want: []answerDifference{{kind: TTLDifference, expected: "300", actual: "200"}},
+		},
+		{
+			name: "different TTL within tolerance",
+			args: args{
+				expected:     msg(dns.RcodeSuccess, false, rr("example.org. 300 IN A 127.0.0.1")),
+				actual:       msg(dns.RcodeSuccess, false, rr("example.org. 200 IN A 127.0.0.1")),
+				compareTTL:   true,
+				ttlTolerance: 100 * time.Second,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareAnswers(tt.args.expected, tt.args.actual, tt.args.ignoreOrder, tt.args.compareTTL, tt.args.ttlTolerance)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
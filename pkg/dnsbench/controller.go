@@ -0,0 +1,292 @@
+package dnsbench
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultControllerInterval is the interval of live results reported to Controller subscribers,
+// when Benchmark.IntervalReport is not configured.
+const DefaultControllerInterval = time.Second
+
+// WorkerState represents state of single benchmark worker.
+type WorkerState int32
+
+const (
+	// WorkerStarting represents worker, which did not send any query yet.
+	WorkerStarting WorkerState = iota
+	// WorkerRunning represents worker sending queries.
+	WorkerRunning
+	// WorkerPaused represents worker waiting for the paused benchmark to be resumed.
+	WorkerPaused
+	// WorkerThrottled represents worker waiting for the global rate limit.
+	WorkerThrottled
+	// WorkerDone represents worker, which finished sending queries.
+	WorkerDone
+)
+
+func (s WorkerState) String() string {
+	switch s {
+	case WorkerStarting:
+		return "starting"
+	case WorkerRunning:
+		return "running"
+	case WorkerPaused:
+		return "paused"
+	case WorkerThrottled:
+		return "throttled"
+	case WorkerDone:
+		return "done"
+	default:
+		return "unknown"
+	}
+}
+
+// WorkerStatus is a snapshot of the status of single benchmark worker.
+type WorkerStatus struct {
+	// ID is the identifier of the worker.
+	ID uint32
+	// State is the current state of the worker.
+	State WorkerState
+	// Requests is the number of requests finished by the worker.
+	Requests int64
+	// IOErrors is the number of requests of the worker, which ended with IO error.
+	IOErrors int64
+	// LastLatency is the latency of the last request finished by the worker.
+	LastLatency time.Duration
+}
+
+// workerStatus is updated by the worker and read concurrently by the Controller users.
+type workerStatus struct {
+	state       atomic.Int32
+	requests    atomic.Int64
+	ioErrors    atomic.Int64
+	lastLatency atomic.Int64
+}
+
+func (s *workerStatus) setState(state WorkerState) {
+	s.state.Store(int32(state))
+}
+
+func (s *workerStatus) record(err error, duration time.Duration) {
+	s.requests.Add(1)
+	if err != nil {
+		s.ioErrors.Add(1)
+	}
+	s.lastLatency.Store(duration.Nanoseconds())
+}
+
+// Controller controls the running Benchmark and provides its live results. Controller allows to pause and resume the benchmark,
+// to stop the benchmark early and to adjust the global rate limit while the benchmark is running.
+// Controller is assigned to the benchmark using Benchmark.Controller and it must be created using NewController.
+type Controller struct {
+	mu       sync.Mutex
+	paused   bool
+	resumed  chan struct{}
+	stopped  bool
+	cancel   context.CancelFunc
+	rate     int
+	workers  []*workerStatus
+	handlers []func(IntervalStats)
+
+	// limiter is the global rate limiter of the running benchmark.
+	limiter *RateLimiter
+	// lastStopped is whether the last finished run was stopped using Controller.Stop.
+	lastStopped bool
+}
+
+// NewController creates new Controller.
+func NewController() *Controller {
+	resumed := make(chan struct{})
+	close(resumed)
+	return &Controller{resumed: resumed}
+}
+
+// OnInterval registers handler called with the results of each reporting interval. The interval is Benchmark.IntervalReport,
+// or DefaultControllerInterval when interval reporting is not configured. Handlers must be registered before the benchmark is started.
+func (c *Controller) OnInterval(handler func(IntervalStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers = append(c.handlers, handler)
+}
+
+// Pause pauses the benchmark, workers finish their in-flight requests and do not send new requests until the benchmark is resumed.
+// Time spent paused counts towards Benchmark.Duration.
+func (c *Controller) Pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paused {
+		return
+	}
+	c.paused = true
+	c.resumed = make(chan struct{})
+}
+
+// Resume resumes the paused benchmark.
+func (c *Controller) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.paused {
+		return
+	}
+	c.paused = false
+	close(c.resumed)
+}
+
+// Paused returns whether the benchmark is paused.
+func (c *Controller) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// Stop stops the benchmark early, Benchmark.Run returns the results collected so far.
+func (c *Controller) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+// SetRate changes the global rate limit of queries per second, 0 removes the global rate limit.
+// The rate limit per worker configured by Benchmark.RateLimitWorker is not affected.
+func (c *Controller) SetRate(rate int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rate = max(rate, 0)
+	if c.limiter != nil {
+		c.limiter.SetRate(c.rate)
+	}
+}
+
+// Rate returns the current global rate limit of queries per second, 0 means the rate is not limited.
+func (c *Controller) Rate() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.limiter != nil {
+		// the rate can be also adjusted by the adaptive rate control
+		return c.limiter.Rate()
+	}
+	return c.rate
+}
+
+// Workers returns the current status of all benchmark workers.
+func (c *Controller) Workers() []WorkerStatus {
+	c.mu.Lock()
+	workers := c.workers
+	c.mu.Unlock()
+
+	res := make([]WorkerStatus, 0, len(workers))
+	for i, w := range workers {
+		res = append(res, WorkerStatus{
+			// nolint:gosec
+			ID:          uint32(i),
+			State:       WorkerState(w.state.Load()),
+			Requests:    w.requests.Load(),
+			IOErrors:    w.ioErrors.Load(),
+			LastLatency: time.Duration(w.lastLatency.Load()),
+		})
+	}
+	return res
+}
+
+// start prepares the Controller for the benchmark run and returns context cancelled by Controller.Stop. The global rate limit
+// of the benchmark is adjusted using the limiter, the limiter is created when not provided.
+func (c *Controller) start(ctx context.Context, b *Benchmark, limiter *RateLimiter) context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	if c.stopped {
+		c.cancel()
+	}
+	if limiter == nil {
+		limiter = NewRateLimiter(b.Rate, b.RateBurst)
+	}
+	c.limiter = limiter
+	c.rate = limiter.Rate()
+	c.workers = make([]*workerStatus, b.Concurrency)
+	for i := range c.workers {
+		c.workers[i] = &workerStatus{}
+	}
+	return ctx
+}
+
+// finish releases resources of the finished benchmark run.
+func (c *Controller) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastStopped = c.stopped
+	c.stopped = false
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+}
+
+// wasStopped returns whether the last finished run was stopped using Controller.Stop.
+func (c *Controller) wasStopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastStopped
+}
+
+func (c *Controller) worker(id uint32) *workerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.workers[id]
+}
+
+// wait blocks the worker while the benchmark is paused.
+func (c *Controller) wait(ctx context.Context, w *workerStatus) error {
+	c.mu.Lock()
+	resumed := c.resumed
+	c.mu.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+	default:
+	}
+
+	w.setState(WorkerPaused)
+	defer w.setState(WorkerRunning)
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// take waits for the current global rate limit.
+func (c *Controller) take(ctx context.Context, w *workerStatus) error {
+	if !c.limiter.Limited() {
+		w.setState(WorkerRunning)
+		return nil
+	}
+	w.setState(WorkerThrottled)
+	defer w.setState(WorkerRunning)
+	return c.limiter.Wait(ctx)
+}
+
+func (c *Controller) hasHandlers() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.handlers) > 0
+}
+
+func (c *Controller) notify(is IntervalStats) error {
+	c.mu.Lock()
+	handlers := c.handlers
+	c.mu.Unlock()
+
+	for _, h := range handlers {
+		h(is)
+	}
+	return nil
+}
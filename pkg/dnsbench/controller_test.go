@@ -0,0 +1,66 @@
+package dnsbench
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestController_wait(t *testing.T) {
+	c := NewController()
+	w := &workerStatus{}
+
+	require.NoError(t, c.wait(context.Background(), w), "worker should not wait, when the benchmark is not paused")
+
+	c.Pause()
+	assert.True(t, c.Paused())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, c.wait(ctx, w), context.DeadlineExceeded, "worker should wait until the context is done")
+
+	done := make(chan error)
+	go func() {
+		done <- c.wait(context.Background(), w)
+	}()
+	assert.Eventually(t, func() bool { return WorkerState(w.state.Load()) == WorkerPaused }, time.Second, time.Millisecond)
+	c.Resume()
+	require.NoError(t, <-done)
+	assert.False(t, c.Paused())
+	assert.Equal(t, WorkerRunning, WorkerState(w.state.Load()))
+}
+
+func TestController_SetRate(t *testing.T) {
+	c := NewController()
+	c.start(context.Background(), &Benchmark{Rate: 10, Concurrency: 1}, nil)
+	defer c.finish()
+	assert.Equal(t, 10, c.Rate())
+	assert.True(t, c.limiter.Limited())
+
+	c.SetRate(20)
+	assert.Equal(t, 20, c.Rate())
+	assert.Equal(t, 20, c.limiter.Rate(), "the rate of the running benchmark should be adjusted")
+
+	c.SetRate(0)
+	assert.Equal(t, 0, c.Rate())
+	assert.False(t, c.limiter.Limited(), "the rate should not be limited")
+
+	c.SetRate(-1)
+	assert.Equal(t, 0, c.Rate())
+}
+
+func TestController_Stop(t *testing.T) {
+	c := NewController()
+	c.Stop()
+	ctx := c.start(context.Background(), &Benchmark{Concurrency: 1}, nil)
+	require.Error(t, ctx.Err(), "benchmark stopped before start should not run")
+	c.finish()
+
+	ctx = c.start(context.Background(), &Benchmark{Concurrency: 1}, nil)
+	require.NoError(t, ctx.Err(), "controller should be reusable for the next benchmark run")
+	c.Stop()
+	require.Error(t, ctx.Err())
+	c.finish()
+}
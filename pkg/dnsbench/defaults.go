@@ -41,8 +41,38 @@ const (
 
 	// DefaultHistPrecision is a default precision for histogram.
 	DefaultHistPrecision = 1
+
+	// DefaultRateBurst is a default maximum number of queries sent at once by the rate limits.
+	DefaultRateBurst = 1
+
+	// DefaultRawSamples is a default maximum number of raw datapoints of responses and IO errors kept by each worker.
+	DefaultRawSamples = 10000
+
+	// DefaultAdaptiveRate is a default starting rate of the adaptive rate control, when the rate is not limited.
+	DefaultAdaptiveRate = 100
+
+	// DefaultTargetPercentile is a default latency percentile kept at the target latency by the adaptive rate control.
+	DefaultTargetPercentile = 99.0
+
+	// DefaultTargetErrorRate is a default maximum error rate tolerated by the adaptive rate control.
+	DefaultTargetErrorRate = 0.01
+
+	// DefaultCVThreshold is a default maximum coefficient of variation of the results of repeated iterations, above which the iterations are unstable.
+	DefaultCVThreshold = 0.1
+
+	// DefaultDomainStatsTop is a default number of domains printed in per-domain reports.
+	DefaultDomainStatsTop = 10
+
+	// DefaultPushJob is a default job label of the metrics pushed to Pushgateway and remote-write endpoint.
+	DefaultPushJob = "dnspyre"
+
+	// DefaultOTLPProtocol is a default protocol for exporting metrics and traces using OTLP.
+	DefaultOTLPProtocol = OTLPGRPCProtocol
 )
 
+// DefaultPercentiles are the latency percentiles reported by default.
+var DefaultPercentiles = []float64{99, 95, 90, 75, 50}
+
 func defaultDoHUserAgent() string {
 	version := "unknown"
 	if info, ok := debug.ReadBuildInfo(); ok && len(info.Main.Version) > 0 {
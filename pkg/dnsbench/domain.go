@@ -0,0 +1,50 @@
+package dnsbench
+
+import (
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+)
+
+// domainStatsPrecision is a precision of the latency histograms of DomainStats, single significant figure is enough for ordering
+// and reporting the slowest domains and keeps the histograms small, since there is a histogram for each question of each worker.
+const domainStatsPrecision = 1
+
+// DomainStats represents aggregated latencies and outcomes of requests for single question (domain and query type).
+type DomainStats struct {
+	// Count is counter of all requests.
+	Count int64
+	// Codes counts response codes of the received responses.
+	Codes map[int]int64
+	// IOError is counter of all requests for which there was no answer.
+	IOError int64
+	// Hist is a low precision histogram of latencies in nanoseconds.
+	Hist *hdrhistogram.Histogram
+}
+
+// Errors returns number of requests, which either ended with IO error or with response code other than NOERROR or NXDOMAIN.
+func (ds *DomainStats) Errors() int64 {
+	errs := ds.IOError
+	for k, v := range ds.Codes {
+		if k != dns.RcodeSuccess && k != dns.RcodeNameError {
+			errs += v
+		}
+	}
+	return errs
+}
+
+func newDomainStats(hist *hdrhistogram.Histogram) *DomainStats {
+	return &DomainStats{
+		Codes: make(map[int]int64),
+		Hist:  hdrhistogram.New(hist.LowestTrackableValue(), hist.HighestTrackableValue(), domainStatsPrecision),
+	}
+}
+
+func (rs *ResultStats) domainStats(req *dns.Msg) *DomainStats {
+	key := QuestionKey{Name: req.Question[0].Name, Qtype: dns.TypeToString[req.Question[0].Qtype]}
+	ds, ok := rs.Domains[key]
+	if !ok {
+		ds = newDomainStats(rs.Hist)
+		rs.Domains[key] = ds
+	}
+	return ds
+}
@@ -0,0 +1,168 @@
+package dnsbench
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+	"github.com/tantalor93/doh-go/doh"
+)
+
+// ErrorType is a stable type of the query error, see ClassifyError.
+type ErrorType string
+
+const (
+	// TimeoutError is a type of errors of queries, which timed out, the phase of the query, which timed out, is in ErrorClass.Detail.
+	TimeoutError ErrorType = "timeout"
+	// RefusedError is a type of errors of queries, for which the connection was refused by the server.
+	RefusedError ErrorType = "connection_refused"
+	// ResetError is a type of errors of queries, for which the connection was reset by the server.
+	ResetError ErrorType = "connection_reset"
+	// EOFError is a type of errors of queries, for which the connection was closed before the response was received.
+	EOFError ErrorType = "eof"
+	// UnreachableError is a type of errors of queries, for which the server host or network was unreachable.
+	UnreachableError ErrorType = "unreachable"
+	// ResolveError is a type of errors of queries, for which the hostname of the server could not be resolved.
+	ResolveError ErrorType = "resolve"
+	// TLSAlertError is a type of errors of queries, for which the TLS handshake failed with an alert, the alert is in ErrorClass.Detail.
+	TLSAlertError ErrorType = "tls_alert"
+	// CertificateError is a type of errors of queries, for which the server certificate was not verified, the reason is in ErrorClass.Detail.
+	CertificateError ErrorType = "certificate"
+	// TLSError is a type of other TLS errors of queries, for example when the server does not speak TLS.
+	TLSError ErrorType = "tls"
+	// HTTPStatusError is a type of errors of DoH queries, for which the server responded with unexpected HTTP status,
+	// the status code is in ErrorClass.Detail.
+	HTTPStatusError ErrorType = "http_status"
+	// IDMismatchError is a type of errors of queries, for which the ID of the response did not match the ID of the request.
+	IDMismatchError ErrorType = "id_mismatch"
+	// MalformedResponseError is a type of errors of queries, for which the response could not be parsed.
+	MalformedResponseError ErrorType = "malformed_response"
+	// OtherError is a type of all other errors of queries.
+	OtherError ErrorType = "other"
+)
+
+// ErrorTypes are all types of the query errors.
+var ErrorTypes = []ErrorType{
+	TimeoutError, RefusedError, ResetError, EOFError, UnreachableError, ResolveError, TLSAlertError, CertificateError, TLSError,
+	HTTPStatusError, IDMismatchError, MalformedResponseError, OtherError,
+}
+
+// ErrorClass is a class of the query error within the stable taxonomy of the errors.
+type ErrorClass struct {
+	// Type is the type of the error.
+	Type ErrorType
+	// Detail refines the type of the error, for example the phase of the timeout, TLS alert or HTTP status code. It is empty,
+	// when the error type is not refined or the detail is not known.
+	Detail string
+}
+
+// String returns the error class formatted as "<type>" or "<type> (<detail>)".
+func (c ErrorClass) String() string {
+	if len(c.Detail) == 0 {
+		return string(c.Type)
+	}
+	return string(c.Type) + " (" + c.Detail + ")"
+}
+
+// ClassifyError classifies the query error into the stable taxonomy of the errors.
+func ClassifyError(err error) ErrorClass {
+	var opErr *net.OpError
+	var netErr net.Error
+	var resolveErr *net.DNSError
+	var statusErr doh.UnexpectedServerHTTPStatusError
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	var alertErr tls.AlertError
+	var idleErr *quic.IdleTimeoutError
+	var handshakeErr *quic.HandshakeTimeoutError
+	var dnsErr *dns.Error
+
+	switch {
+	case errors.As(err, &idleErr):
+		return ErrorClass{Type: TimeoutError, Detail: "quic idle"}
+	case errors.As(err, &handshakeErr):
+		return ErrorClass{Type: TimeoutError, Detail: "quic handshake"}
+	case errors.As(err, &resolveErr):
+		if resolveErr.IsTimeout {
+			return ErrorClass{Type: TimeoutError, Detail: "resolve"}
+		}
+		return ErrorClass{Type: ResolveError}
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClass{Type: TimeoutError, Detail: timeoutPhase(err)}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorClass{Type: RefusedError}
+	case errors.Is(err, syscall.ECONNRESET):
+		return ErrorClass{Type: ResetError}
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return ErrorClass{Type: UnreachableError}
+	case errors.As(err, &statusErr):
+		return ErrorClass{Type: HTTPStatusError, Detail: strconv.Itoa(statusErr.HTTPStatus())}
+	case errors.As(err, &certErr):
+		return ErrorClass{Type: CertificateError, Detail: certificateReason(certErr.Err)}
+	case errors.As(err, &alertErr):
+		return ErrorClass{Type: TLSAlertError, Detail: strings.TrimPrefix(alertErr.Error(), "tls: ")}
+	case errors.As(err, &opErr) && opErr.Op == "remote error":
+		// TLS alerts received over TCP are not exported by crypto/tls, they are reported as remote errors
+		return ErrorClass{Type: TLSAlertError, Detail: strings.TrimPrefix(opErr.Err.Error(), "tls: ")}
+	case errors.As(err, &recordErr):
+		return ErrorClass{Type: TLSError}
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorClass{Type: EOFError}
+	case errors.Is(err, dns.ErrId):
+		return ErrorClass{Type: IDMismatchError}
+	case errors.As(err, &dnsErr):
+		return ErrorClass{Type: MalformedResponseError}
+	default:
+		return ErrorClass{Type: OtherError}
+	}
+}
+
+// timeoutPhase returns the phase of the query, which timed out, or empty string, when the phase is not known.
+func timeoutPhase(err error) string {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return ""
+	}
+	switch opErr.Op {
+	case "dial":
+		return "connect"
+	case "read", "write":
+		return opErr.Op
+	default:
+		return ""
+	}
+}
+
+// certificateReason returns the reason, why the server certificate was not verified.
+func certificateReason(err error) string {
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &authorityErr):
+		return "unknown authority"
+	case errors.As(err, &hostnameErr):
+		return "hostname mismatch"
+	case errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired:
+		return "expired"
+	case errors.As(err, &invalidErr):
+		return "invalid"
+	default:
+		return ""
+	}
+}
+
+// errorClass returns low cardinality class of the query error.
+func errorClass(err error) string {
+	return string(ClassifyError(err).Type)
+}
@@ -0,0 +1,144 @@
+package dnsbench
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/tantalor93/doh-go/doh"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{
+			name: "read deadline exceeded",
+			err:  &net.OpError{Op: "read", Net: "udp", Err: os.ErrDeadlineExceeded},
+			want: ErrorClass{Type: TimeoutError, Detail: "read"},
+		},
+		{
+			name: "dial timeout",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded},
+			want: ErrorClass{Type: TimeoutError, Detail: "connect"},
+		},
+		{
+			name: "context deadline exceeded",
+			err:  fmt.Errorf("exchange: %w", context.DeadlineExceeded),
+			want: ErrorClass{Type: TimeoutError},
+		},
+		{
+			name: "QUIC idle timeout",
+			err:  &url.Error{Op: "Post", URL: "https://127.0.0.1", Err: &quic.IdleTimeoutError{}},
+			want: ErrorClass{Type: TimeoutError, Detail: "quic idle"},
+		},
+		{
+			name: "resolve timeout",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "i/o timeout", Name: "dns.google", IsTimeout: true}},
+			want: ErrorClass{Type: TimeoutError, Detail: "resolve"},
+		},
+		{
+			name: "resolve error",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "dns.google", IsNotFound: true}},
+			want: ErrorClass{Type: ResolveError},
+		},
+		{
+			name: "connection refused",
+			err:  &net.OpError{Op: "read", Net: "udp", Err: os.NewSyscallError("recvfrom", syscall.ECONNREFUSED)},
+			want: ErrorClass{Type: RefusedError},
+		},
+		{
+			name: "connection reset",
+			err:  &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)},
+			want: ErrorClass{Type: ResetError},
+		},
+		{
+			name: "host unreachable",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)},
+			want: ErrorClass{Type: UnreachableError},
+		},
+		{
+			name: "EOF",
+			err:  io.EOF,
+			want: ErrorClass{Type: EOFError},
+		},
+		{
+			name: "DoH status",
+			err:  doh.UnexpectedServerHTTPStatusError{},
+			want: ErrorClass{Type: HTTPStatusError, Detail: "0"},
+		},
+		{
+			name: "TLS alert over TCP",
+			err:  &net.OpError{Op: "remote error", Net: "tcp", Err: errors.New("tls: handshake failure")},
+			want: ErrorClass{Type: TLSAlertError, Detail: "handshake failure"},
+		},
+		{
+			name: "TLS alert over QUIC",
+			err:  fmt.Errorf("handshake: %w", tls.AlertError(40)),
+			want: ErrorClass{Type: TLSAlertError, Detail: "handshake failure"},
+		},
+		{
+			name: "unknown certificate authority",
+			err:  &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}},
+			want: ErrorClass{Type: CertificateError, Detail: "unknown authority"},
+		},
+		{
+			name: "expired certificate",
+			err:  &tls.CertificateVerificationError{Err: x509.CertificateInvalidError{Reason: x509.Expired}},
+			want: ErrorClass{Type: CertificateError, Detail: "expired"},
+		},
+		{
+			name: "hostname mismatch",
+			err:  &tls.CertificateVerificationError{Err: x509.HostnameError{Host: "dns.google"}},
+			want: ErrorClass{Type: CertificateError, Detail: "hostname mismatch"},
+		},
+		{
+			name: "TLS record header",
+			err:  tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"},
+			want: ErrorClass{Type: TLSError},
+		},
+		{
+			name: "ID mismatch",
+			err:  dns.ErrId,
+			want: ErrorClass{Type: IDMismatchError},
+		},
+		{
+			name: "malformed response",
+			err:  dns.ErrShortRead,
+			want: ErrorClass{Type: MalformedResponseError},
+		},
+		{
+			name: "other",
+			err:  errors.New("test"),
+			want: ErrorClass{Type: OtherError},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyError(tt.err))
+		})
+	}
+}
+
+func TestErrorClass_String(t *testing.T) {
+	assert.Equal(t, "timeout (read)", ErrorClass{Type: TimeoutError, Detail: "read"}.String())
+	assert.Equal(t, "connection_refused", ErrorClass{Type: RefusedError}.String())
+}
+
+func Test_errorClass(t *testing.T) {
+	assert.Equal(t, "timeout", errorClass(&net.OpError{Op: "read", Net: "udp", Err: os.ErrDeadlineExceeded}))
+	assert.Equal(t, "tls_alert", errorClass(&net.OpError{Op: "remote error", Net: "tcp", Err: errors.New("tls: handshake failure")}))
+}
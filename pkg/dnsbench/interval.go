@@ -0,0 +1,238 @@
+package dnsbench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+)
+
+const (
+	// TextIntervalFormat represents interval reports printed as human-readable lines.
+	TextIntervalFormat = "text"
+	// NDJSONIntervalFormat represents interval reports written as newline delimited JSON objects.
+	NDJSONIntervalFormat = "ndjson"
+)
+
+// IntervalStats represents results of all workers within single reporting interval.
+type IntervalStats struct {
+	// Start is the time, when the interval started.
+	Start time.Time
+	// Duration is the duration of the interval, the last interval can be shorter than Benchmark.IntervalReport.
+	Duration time.Duration
+	// Elapsed is the time elapsed since the start of the benchmark until the end of the interval.
+	Elapsed time.Duration
+	// Counters holds counters of requests finished within the interval.
+	Counters Counters
+	// Hist is a histogram of latencies of responses received within the interval.
+	Hist *hdrhistogram.Histogram
+	// Codes counts response codes of responses received within the interval.
+	Codes map[int]int64
+	// EDECodes counts Extended DNS Error (RFC 8914) info codes of responses received within the interval.
+	EDECodes map[uint16]int64
+	// Errors counts IO errors within the interval, similar errors are grouped using ErrorGroup.
+	Errors map[string]int64
+}
+
+// QPS returns number of requests per second finished within the interval.
+func (is *IntervalStats) QPS() float64 {
+	if is.Duration <= 0 {
+		return 0
+	}
+	return float64(is.Counters.Total) / is.Duration.Seconds()
+}
+
+// intervalRecorder collects results of single worker for the current interval. The worker is the only writer,
+// so the lock is contended only when the interval is collected.
+type intervalRecorder struct {
+	mu       sync.Mutex
+	counters Counters
+	hist     *hdrhistogram.Histogram
+	spare    *hdrhistogram.Histogram
+	codes    map[int]int64
+	ede      map[uint16]int64
+	errors   map[string]int64
+}
+
+func newIntervalRecorder(b *Benchmark) *intervalRecorder {
+	return &intervalRecorder{
+		hist:  hdrhistogram.New(b.HistMin.Nanoseconds(), b.HistMax.Nanoseconds(), b.HistPre),
+		spare: hdrhistogram.New(b.HistMin.Nanoseconds(), b.HistMax.Nanoseconds(), b.HistPre),
+	}
+}
+
+func (r *intervalRecorder) record(req *dns.Msg, resp *dns.Msg, err error, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters.count(req, resp, err)
+	if err != nil {
+		if r.errors == nil {
+			r.errors = make(map[string]int64)
+		}
+		r.errors[ErrorGroup(err)]++
+		return
+	}
+	if resp.Rcode == dns.RcodeSuccess && resp.Id != req.Id {
+		return
+	}
+	r.hist.RecordValue(duration.Nanoseconds())
+	if r.codes == nil {
+		r.codes = make(map[int]int64)
+	}
+	r.codes[resp.Rcode]++
+	if opt := resp.IsEdns0(); opt != nil {
+		for _, option := range opt.Option {
+			if ede, ok := option.(*dns.EDNS0_EDE); ok {
+				if r.ede == nil {
+					r.ede = make(map[uint16]int64)
+				}
+				r.ede[ede.InfoCode]++
+			}
+		}
+	}
+}
+
+// collect adds results of the current interval to the interval stats and resets the recorder.
+func (r *intervalRecorder) collect(is *IntervalStats) {
+	r.mu.Lock()
+	counters := r.counters
+	r.counters = Counters{}
+	hist := r.hist
+	r.hist, r.spare = r.spare, r.hist
+	codes, ede, errs := r.codes, r.ede, r.errors
+	r.codes, r.ede, r.errors = nil, nil, nil
+	r.mu.Unlock()
+
+	is.Counters.Total += counters.Total
+	is.Counters.IOError += counters.IOError
+	is.Counters.Success += counters.Success
+	is.Counters.Negative += counters.Negative
+	is.Counters.Error += counters.Error
+	is.Counters.IDmismatch += counters.IDmismatch
+	is.Counters.Truncated += counters.Truncated
+	is.Hist.Merge(hist)
+	// the histogram is not used by the worker until the next collection
+	hist.Reset()
+	for k, v := range codes {
+		is.Codes[k] += v
+	}
+	for k, v := range ede {
+		is.EDECodes[k] += v
+	}
+	for k, v := range errs {
+		is.Errors[k] += v
+	}
+}
+
+// intervalReporter periodically collects results of all workers and reports them.
+type intervalReporter struct {
+	b         *Benchmark
+	period    time.Duration
+	recorders []*intervalRecorder
+	report    func(IntervalStats) error
+	start     time.Time
+	last      time.Time
+}
+
+func newIntervalReporter(b *Benchmark, period time.Duration, recorders []*intervalRecorder, report func(IntervalStats) error) *intervalReporter {
+	now := time.Now()
+	return &intervalReporter{b: b, period: period, recorders: recorders, report: report, start: now, last: now}
+}
+
+// run reports intervals until done is closed, then reports the last (possibly shorter) interval.
+func (r *intervalReporter) run(done <-chan struct{}) {
+	ticker := time.NewTicker(r.period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.flush()
+		case <-done:
+			r.flush()
+			return
+		}
+	}
+}
+
+func (r *intervalReporter) flush() {
+	now := time.Now()
+	is := IntervalStats{
+		Start:    r.last,
+		Duration: now.Sub(r.last),
+		Elapsed:  now.Sub(r.start),
+		Hist:     hdrhistogram.New(r.b.HistMin.Nanoseconds(), r.b.HistMax.Nanoseconds(), r.b.HistPre),
+		Codes:    make(map[int]int64),
+		EDECodes: make(map[uint16]int64),
+		Errors:   make(map[string]int64),
+	}
+	r.last = now
+	for _, rec := range r.recorders {
+		rec.collect(&is)
+	}
+	if is.Counters.Total == 0 && is.Duration < r.period/2 {
+		// do not report the last interval, if it is very short and empty
+		return
+	}
+	if err := r.report(is); err != nil {
+		fmt.Fprintf(r.b.ErrWriter, "Failed to write interval report: %v\n", err)
+	}
+}
+
+type jsonInterval struct {
+	Timestamp         time.Time `json:"timestamp"`
+	ElapsedSeconds    float64   `json:"elapsedSeconds"`
+	IntervalSeconds   float64   `json:"intervalSeconds"`
+	QueriesPerSecond  float64   `json:"queriesPerSecond"`
+	TotalRequests     int64     `json:"totalRequests"`
+	SuccessResponses  int64     `json:"successResponses"`
+	NegativeResponses int64     `json:"negativeResponses"`
+	ErrorResponses    int64     `json:"errorResponses"`
+	IOErrors          int64     `json:"ioErrors"`
+	P50Ms             float64   `json:"p50Ms"`
+	P95Ms             float64   `json:"p95Ms"`
+	P99Ms             float64   `json:"p99Ms"`
+}
+
+// intervalWriter returns function writing interval reports in the configured format.
+func intervalWriter(w io.Writer, format string) func(IntervalStats) error {
+	if format == NDJSONIntervalFormat {
+		enc := json.NewEncoder(w)
+		return func(is IntervalStats) error {
+			return enc.Encode(jsonInterval{
+				Timestamp:         is.Start.Add(is.Duration).UTC(),
+				ElapsedSeconds:    math.Round(is.Elapsed.Seconds()*1000) / 1000,
+				IntervalSeconds:   math.Round(is.Duration.Seconds()*1000) / 1000,
+				QueriesPerSecond:  math.Round(is.QPS()*100) / 100,
+				TotalRequests:     is.Counters.Total,
+				SuccessResponses:  is.Counters.Success,
+				NegativeResponses: is.Counters.Negative,
+				ErrorResponses:    is.Counters.Error,
+				IOErrors:          is.Counters.IOError,
+				P50Ms:             durationMs(is.Hist.ValueAtQuantile(50)),
+				P95Ms:             durationMs(is.Hist.ValueAtQuantile(95)),
+				P99Ms:             durationMs(is.Hist.ValueAtQuantile(99)),
+			})
+		}
+	}
+	return func(is IntervalStats) error {
+		_, err := fmt.Fprintf(w, "[%s] %.1f QPS, total: %d, success: %d, negative: %d, error: %d, IO error: %d, p50: %s, p95: %s, p99: %s\n",
+			is.Elapsed.Round(time.Second), is.QPS(), is.Counters.Total, is.Counters.Success, is.Counters.Negative, is.Counters.Error,
+			is.Counters.IOError, roundLatency(is.Hist.ValueAtQuantile(50)), roundLatency(is.Hist.ValueAtQuantile(95)),
+			roundLatency(is.Hist.ValueAtQuantile(99)))
+		return err
+	}
+}
+
+func durationMs(ns int64) float64 {
+	return math.Round(float64(ns)/1e4) / 100
+}
+
+func roundLatency(ns int64) time.Duration {
+	return time.Duration(ns).Round(10 * time.Microsecond)
+}
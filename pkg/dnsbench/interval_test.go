@@ -0,0 +1,104 @@
+package dnsbench
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntervalRecorder_collect(t *testing.T) {
+	b := Benchmark{HistMax: time.Second, HistPre: 1}
+	req := &dns.Msg{
+		MsgHdr:   dns.MsgHdr{Id: 1},
+		Question: []dns.Question{{Name: "example.org.", Qclass: dns.ClassINET, Qtype: dns.TypeA}},
+	}
+	success := &dns.Msg{
+		MsgHdr: dns.MsgHdr{Id: 1, Rcode: dns.RcodeSuccess, Response: true},
+		Answer: []dns.RR{&dns.A{A: net.ParseIP("127.0.0.1")}},
+	}
+	servfail := &dns.Msg{MsgHdr: dns.MsgHdr{Id: 1, Rcode: dns.RcodeServerFailure, Response: true}}
+	servfail.SetEdns0(DefaultEdns0BufferSize, false)
+	servfail.IsEdns0().Option = append(servfail.IsEdns0().Option, &dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeNetworkError})
+
+	first := newIntervalRecorder(&b)
+	second := newIntervalRecorder(&b)
+	first.record(req, success, nil, 10*time.Millisecond)
+	first.record(req, nil, errors.New("test error"), 100*time.Millisecond)
+	second.record(req, servfail, nil, 20*time.Millisecond)
+
+	is := newTestIntervalStats()
+	first.collect(&is)
+	second.collect(&is)
+
+	assert.Equal(t, Counters{Total: 3, Success: 1, Error: 1, IOError: 1}, is.Counters)
+	assert.EqualValues(t, 2, is.Hist.TotalCount(), "IO errors should not be recorded in the latency histogram")
+	assert.InDelta(t, 3.0, is.QPS(), 0.001)
+	assert.Equal(t, map[int]int64{dns.RcodeSuccess: 1, dns.RcodeServerFailure: 1}, is.Codes)
+	assert.Equal(t, map[uint16]int64{dns.ExtendedErrorCodeNetworkError: 1}, is.EDECodes)
+	assert.Equal(t, map[string]int64{"test error": 1}, is.Errors)
+
+	next := newTestIntervalStats()
+	first.record(req, success, nil, 10*time.Millisecond)
+	first.collect(&next)
+	second.collect(&next)
+
+	assert.Equal(t, Counters{Total: 1, Success: 1}, next.Counters, "the recorders should be reset after collection")
+	assert.EqualValues(t, 1, next.Hist.TotalCount(), "the histograms should be reset after collection")
+	assert.Equal(t, map[int]int64{dns.RcodeSuccess: 1}, next.Codes)
+	assert.Empty(t, next.Errors)
+}
+
+func newTestIntervalStats() IntervalStats {
+	return IntervalStats{
+		Duration: time.Second,
+		Hist:     hdrhistogram.New(0, time.Second.Nanoseconds(), 1),
+		Codes:    make(map[int]int64),
+		EDECodes: make(map[uint16]int64),
+		Errors:   make(map[string]int64),
+	}
+}
+
+func TestIntervalWriter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	hist := hdrhistogram.New(0, time.Second.Nanoseconds(), 1)
+	hist.RecordValue((10 * time.Millisecond).Nanoseconds())
+	is := IntervalStats{
+		Start:    start,
+		Duration: 2 * time.Second,
+		Elapsed:  10 * time.Second,
+		Counters: Counters{Total: 5, Success: 2, Negative: 1, Error: 1, IOError: 1},
+		Hist:     hist,
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "text",
+			format: TextIntervalFormat,
+			want:   "[10s] 2.5 QPS, total: 5, success: 2, negative: 1, error: 1, IO error: 1, p50: 10.49ms, p95: 10.49ms, p99: 10.49ms\n",
+		},
+		{
+			name:   "ndjson",
+			format: NDJSONIntervalFormat,
+			want: `{"timestamp":"2024-01-01T10:00:02Z","elapsedSeconds":10,"intervalSeconds":2,"queriesPerSecond":2.5,"totalRequests":5,` +
+				`"successResponses":2,"negativeResponses":1,"errorResponses":1,"ioErrors":1,"p50Ms":10.49,"p95Ms":10.49,"p99Ms":10.49}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			require.NoError(t, intervalWriter(&buf, tt.format)(is))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
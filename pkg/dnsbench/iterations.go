@@ -0,0 +1,58 @@
+package dnsbench
+
+import (
+	"context"
+	"time"
+)
+
+// IterationResult represents results of single iteration of the benchmark repeated using Benchmark.Iterations.
+type IterationResult struct {
+	// Start is the time, when the iteration started.
+	Start time.Time
+	// Duration is the duration of the iteration.
+	Duration time.Duration
+	// Stats holds results of the parallel benchmark goroutines of the iteration, same as returned by Benchmark.Run.
+	Stats []*ResultStats
+}
+
+// RunIterations executes the benchmark Benchmark.Iterations times, pausing for Benchmark.IterationPause between the iterations. If an iteration
+// is unable to start, the error is returned together with the results of the iterations executed before it, otherwise results of all executed
+// iterations are returned. When the context is cancelled or the benchmark is stopped using Benchmark.Controller, the results of the iterations
+// executed so far are returned.
+func (b *Benchmark) RunIterations(ctx context.Context) ([]IterationResult, error) {
+	effective, err := b.WithDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	// all iterations share the same run identifier
+	iteration := *b
+	if len(iteration.RunID) == 0 {
+		iteration.RunID = NewRunID()
+	}
+
+	results := make([]IterationResult, 0, effective.Iterations)
+	for i := range effective.Iterations {
+		if i > 0 {
+			waitFor(ctx, effective.IterationPause)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if !b.Silent && !b.JSON && effective.Iterations > 1 {
+			effective.printer.NeutralFprintf(effective.Writer, "\nIteration %s/%d\n", effective.printer.HighlightSprint(i+1), effective.Iterations)
+		}
+
+		start := time.Now()
+		stats, err := iteration.Run(ctx)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, IterationResult{Start: start, Duration: time.Since(start), Stats: stats})
+
+		if b.Controller != nil && b.Controller.wasStopped() {
+			break
+		}
+	}
+	return results, nil
+}
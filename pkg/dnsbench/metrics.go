@@ -1,26 +1,234 @@
 package dnsbench
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tantalor93/doh-go/doh"
 )
 
-var (
-	dnsRequestsDurationMetrics = promauto.NewHistogramVec(prometheus.HistogramOpts{
-		Namespace: "dnspyre",
-		Name:      "dns_requests_duration_seconds",
-		Help:      "DNS request duration in seconds",
-	}, []string{"type"})
-
-	dnsResponseTotalMetrics = promauto.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "dnspyre",
-		Name:      "dns_response_total",
-		Help:      "The total number DNS responses",
-	}, []string{"type", "rcode"})
-
-	errorsTotalMetrics = promauto.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "dnspyre",
-		Name:      "errors_total",
-		Help:      "The total number errors",
-	}, []string{})
-)
+// promMetrics holds Prometheus metrics of the benchmark. The metrics are registered on the registry owned by the benchmark,
+// so that multiple benchmarks running in the same process do not share them. All metrics are labeled by server and transport of the benchmark.
+type promMetrics struct {
+	registry *prometheus.Registry
+	doh      bool
+
+	requestDuration *prometheus.HistogramVec
+	responses       *prometheus.CounterVec
+	errors          *prometheus.CounterVec
+	dohResponses    *prometheus.CounterVec
+	truncated       *prometheus.CounterVec
+	ede             *prometheus.CounterVec
+	requestBytes    prometheus.Counter
+	responseBytes   prometheus.Counter
+	inFlight        prometheus.Gauge
+	openConnections prometheus.Gauge
+}
+
+func newPromMetrics(b *Benchmark) (*promMetrics, error) {
+	m := &promMetrics{
+		registry: prometheus.NewRegistry(),
+		doh:      b.useDoH,
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "dnspyre",
+			Name:      "dns_requests_duration_seconds",
+			Help:      "DNS request duration in seconds",
+		}, []string{"type"}),
+		responses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "dnspyre",
+			Name:      "dns_response_total",
+			Help:      "The total number DNS responses",
+		}, []string{"type", "rcode"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "dnspyre",
+			Name:      "errors_total",
+			Help:      "The total number errors",
+		}, []string{"error_class"}),
+		dohResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "dnspyre",
+			Name:      "doh_responses_total",
+			Help:      "The total number of DoH responses by HTTP status",
+		}, []string{"status"}),
+		truncated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "dnspyre",
+			Name:      "truncated_responses_total",
+			Help:      "The total number of truncated DNS responses",
+		}, []string{"type"}),
+		ede: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "dnspyre",
+			Name:      "ede_total",
+			Help:      "The total number of Extended DNS Errors received in DNS responses by info code",
+		}, []string{"code"}),
+		requestBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "dnspyre",
+			Name:      "request_bytes_total",
+			Help:      "The estimated total size of sent DNS requests in bytes",
+		}),
+		responseBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "dnspyre",
+			Name:      "response_bytes_total",
+			Help:      "The estimated total size of received DNS responses in bytes",
+		}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "dnspyre",
+			Name:      "in_flight_requests",
+			Help:      "The number of DNS requests waiting for response",
+		}),
+		openConnections: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "dnspyre",
+			Name:      "open_connections",
+			Help:      "The number of open connections to the benchmarked server",
+		}),
+	}
+
+	labels := prometheus.Labels{"server": b.Server, "transport": b.network()}
+	registry := prometheus.WrapRegistererWith(labels, m.registry)
+	var external prometheus.Registerer
+	if b.PrometheusRegisterer != nil {
+		external = prometheus.WrapRegistererWith(labels, b.PrometheusRegisterer)
+	}
+	var err error
+	if m.requestDuration, err = register(registry, external, m.requestDuration); err != nil {
+		return nil, err
+	}
+	if m.responses, err = register(registry, external, m.responses); err != nil {
+		return nil, err
+	}
+	if m.errors, err = register(registry, external, m.errors); err != nil {
+		return nil, err
+	}
+	if m.dohResponses, err = register(registry, external, m.dohResponses); err != nil {
+		return nil, err
+	}
+	if m.truncated, err = register(registry, external, m.truncated); err != nil {
+		return nil, err
+	}
+	if m.ede, err = register(registry, external, m.ede); err != nil {
+		return nil, err
+	}
+	if m.requestBytes, err = register(registry, external, m.requestBytes); err != nil {
+		return nil, err
+	}
+	if m.responseBytes, err = register(registry, external, m.responseBytes); err != nil {
+		return nil, err
+	}
+	if m.inFlight, err = register(registry, external, m.inFlight); err != nil {
+		return nil, err
+	}
+	if m.openConnections, err = register(registry, external, m.openConnections); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// register registers the collector on the registry owned by the benchmark and on the external registerer, if not nil.
+// When the same collector is already registered on the external registerer (e.g. by the previous run of the benchmark), the already registered
+// collector is used, so that the metrics continue across the runs.
+func register[T prometheus.Collector](registry, external prometheus.Registerer, c T) (T, error) {
+	if external != nil {
+		if err := external.Register(c); err != nil {
+			var are prometheus.AlreadyRegisteredError
+			if !errors.As(err, &are) {
+				return c, err
+			}
+			existing, ok := are.ExistingCollector.(T)
+			if !ok {
+				return c, err
+			}
+			c = existing
+		}
+	}
+	return c, registry.Register(c)
+}
+
+// handler returns HTTP handler exposing the metrics of the benchmark together with Go runtime and process metrics.
+func (m *promMetrics) handler() http.Handler {
+	runtime := prometheus.NewRegistry()
+	runtime.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return promhttp.HandlerFor(prometheus.Gatherers{m.registry, runtime}, promhttp.HandlerOpts{})
+}
+
+func (m *promMetrics) measure(req *dns.Msg, resp *dns.Msg, sizes messageSizes, duration time.Duration, err error) {
+	reqType := dns.TypeToString[req.Question[0].Qtype]
+	m.requestDuration.WithLabelValues(reqType).Observe(duration.Seconds())
+	m.requestBytes.Add(float64(sizes.request))
+
+	if resp != nil {
+		m.responses.WithLabelValues(dns.TypeToString[resp.Question[0].Qtype], dns.RcodeToString[resp.Rcode]).Inc()
+		m.responseBytes.Add(float64(sizes.response))
+		if resp.Truncated {
+			m.truncated.WithLabelValues(reqType).Inc()
+		}
+		if opt := resp.IsEdns0(); opt != nil {
+			for _, option := range opt.Option {
+				if ede, ok := option.(*dns.EDNS0_EDE); ok {
+					m.ede.WithLabelValues(strconv.Itoa(int(ede.InfoCode))).Inc()
+				}
+			}
+		}
+	}
+	if err != nil {
+		m.errors.WithLabelValues(errorClass(err)).Inc()
+	}
+
+	if m.doh {
+		statusError := doh.UnexpectedServerHTTPStatusError{}
+		switch {
+		case err == nil:
+			m.dohResponses.WithLabelValues(strconv.Itoa(http.StatusOK)).Inc()
+		case errors.As(err, &statusError):
+			m.dohResponses.WithLabelValues(strconv.Itoa(statusError.HTTPStatus())).Inc()
+		}
+	}
+}
+
+// dialContext wraps the dial function, so that the connections are counted in the open connections gauge.
+func (m *promMetrics) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		m.openConnections.Inc()
+		return &countedConn{Conn: conn, gauge: m.openConnections}, nil
+	}
+}
+
+// dialTLSContext returns dial function establishing TLS connections, the underlying connections are counted in the open connections gauge.
+func (m *promMetrics) dialTLSContext() func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+	dial := m.dialContext((&net.Dialer{}).DialContext)
+	return func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+// countedConn decrements the open connections gauge, when the connection is closed.
+type countedConn struct {
+	net.Conn
+	gauge prometheus.Gauge
+	once  sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(c.gauge.Dec)
+	return c.Conn.Close()
+}
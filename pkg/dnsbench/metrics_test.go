@@ -0,0 +1,94 @@
+package dnsbench
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tantalor93/doh-go/doh"
+)
+
+func TestPromMetrics_measure(t *testing.T) {
+	b := Benchmark{Server: "127.0.0.1:53"}
+	m, err := newPromMetrics(&b)
+	require.NoError(t, err)
+
+	req := &dns.Msg{Question: []dns.Question{{Name: "example.org.", Qtype: dns.TypeA, Qclass: dns.ClassINET}}}
+	resp := &dns.Msg{MsgHdr: dns.MsgHdr{Response: true, Truncated: true, Rcode: dns.RcodeServerFailure}, Question: req.Question}
+	resp.SetEdns0(DefaultEdns0BufferSize, false)
+	resp.IsEdns0().Option = append(resp.IsEdns0().Option, &dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeNetworkError})
+
+	m.measure(req, resp, newMessageSizes(req, resp), 10*time.Millisecond, nil)
+	m.measure(req, nil, newMessageSizes(req, nil), time.Second, &net.OpError{Op: "read", Net: "udp", Err: os.ErrDeadlineExceeded})
+
+	expected := `
+# HELP dnspyre_dns_response_total The total number DNS responses
+# TYPE dnspyre_dns_response_total counter
+dnspyre_dns_response_total{rcode="SERVFAIL",server="127.0.0.1:53",transport="udp",type="A"} 1
+# HELP dnspyre_ede_total The total number of Extended DNS Errors received in DNS responses by info code
+# TYPE dnspyre_ede_total counter
+dnspyre_ede_total{code="23",server="127.0.0.1:53",transport="udp"} 1
+# HELP dnspyre_errors_total The total number errors
+# TYPE dnspyre_errors_total counter
+dnspyre_errors_total{error_class="timeout",server="127.0.0.1:53",transport="udp"} 1
+# HELP dnspyre_request_bytes_total The estimated total size of sent DNS requests in bytes
+# TYPE dnspyre_request_bytes_total counter
+dnspyre_request_bytes_total{server="127.0.0.1:53",transport="udp"} 58
+# HELP dnspyre_truncated_responses_total The total number of truncated DNS responses
+# TYPE dnspyre_truncated_responses_total counter
+dnspyre_truncated_responses_total{server="127.0.0.1:53",transport="udp",type="A"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.registry, strings.NewReader(expected),
+		"dnspyre_dns_response_total", "dnspyre_ede_total", "dnspyre_errors_total", "dnspyre_request_bytes_total", "dnspyre_truncated_responses_total"))
+	duration := &dto.Metric{}
+	require.NoError(t, m.requestDuration.WithLabelValues("A").(prometheus.Histogram).Write(duration))
+	assert.Equal(t, uint64(2), duration.GetHistogram().GetSampleCount())
+	assert.Equal(t, float64(responseSize(resp)), testutil.ToFloat64(m.responseBytes))
+}
+
+func TestPromMetrics_measure_doh(t *testing.T) {
+	b := Benchmark{Server: "https://127.0.0.1/dns-query", useDoH: true}
+	m, err := newPromMetrics(&b)
+	require.NoError(t, err)
+
+	req := &dns.Msg{Question: []dns.Question{{Name: "example.org.", Qtype: dns.TypeA, Qclass: dns.ClassINET}}}
+	resp := &dns.Msg{MsgHdr: dns.MsgHdr{Response: true}, Question: req.Question}
+
+	m.measure(req, resp, newMessageSizes(req, resp), time.Millisecond, nil)
+	m.measure(req, nil, newMessageSizes(req, nil), time.Millisecond, doh.UnexpectedServerHTTPStatusError{})
+	m.measure(req, nil, newMessageSizes(req, nil), time.Millisecond, errors.New("test"))
+
+	assert.InDelta(t, 1, testutil.ToFloat64(m.dohResponses.WithLabelValues("200")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(m.dohResponses.WithLabelValues("0")), 0)
+	assert.Equal(t, 2, testutil.CollectAndCount(m.dohResponses))
+}
+
+func Test_newPromMetrics_registerer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	first, err := newPromMetrics(&Benchmark{Server: "127.0.0.1:53", PrometheusRegisterer: registry})
+	require.NoError(t, err)
+	first.inFlight.Inc()
+
+	second, err := newPromMetrics(&Benchmark{Server: "127.0.0.1:53", PrometheusRegisterer: registry})
+	require.NoError(t, err, "metrics registered by the previous run should be reused")
+	assert.Same(t, first.inFlight, second.inFlight)
+
+	other, err := newPromMetrics(&Benchmark{Server: "127.0.0.2:53", PrometheusRegisterer: registry})
+	require.NoError(t, err)
+	assert.NotSame(t, first.inFlight, other.inFlight, "benchmarks of different servers should not share metrics")
+	other.inFlight.Add(2)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(registry, "dnspyre_in_flight_requests"))
+	assert.InDelta(t, 1, testutil.ToFloat64(first.inFlight), 0)
+	assert.InDelta(t, 2, testutil.ToFloat64(other.inFlight), 0)
+}
@@ -0,0 +1,113 @@
+package dnsbench
+
+import (
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Observer observes the running benchmark, it is notified about the lifecycle of the benchmark run and about each completed query.
+// The methods are called synchronously by the benchmark workers, so the implementations must be safe for concurrent use and should return quickly,
+// otherwise they slow down the benchmark. Embed BaseObserver to implement only some of the methods.
+type Observer interface {
+	// RunStart is called once before the workers start sending queries, b is the configuration used by the run with the defaults filled in.
+	RunStart(b *Benchmark)
+	// WorkerStart is called by each worker before it sends its first query.
+	WorkerStart(workerID uint32)
+	// QueryDone is called by the worker for each completed query. The result must not be modified and must not be retained after the call.
+	QueryDone(result QueryResult)
+	// WorkerStop is called by each worker after it sent its last query.
+	WorkerStop(workerID uint32)
+	// RunEnd is called once after all workers finished, stats are the results of the workers returned by Benchmark.Run.
+	RunEnd(stats []*ResultStats)
+}
+
+// BaseObserver implements Observer with no-op methods, it can be embedded by observers, which are interested only in some of the events.
+type BaseObserver struct{}
+
+// RunStart implements Observer.
+func (BaseObserver) RunStart(*Benchmark) {}
+
+// WorkerStart implements Observer.
+func (BaseObserver) WorkerStart(uint32) {}
+
+// QueryDone implements Observer.
+func (BaseObserver) QueryDone(QueryResult) {}
+
+// WorkerStop implements Observer.
+func (BaseObserver) WorkerStop(uint32) {}
+
+// RunEnd implements Observer.
+func (BaseObserver) RunEnd([]*ResultStats) {}
+
+// QueryResult is the result of single completed query.
+type QueryResult struct {
+	// WorkerID is the identifier of the worker, which sent the query.
+	WorkerID uint32
+	// Request is the sent DNS request.
+	Request *dns.Msg
+	// Response is the received DNS response, it is nil if the query ended with error.
+	Response *dns.Msg
+	// Err is the error of the query, it is nil if the response was received.
+	Err error
+	// Start is the time when the query was sent.
+	Start time.Time
+	// Duration is the duration of the query.
+	Duration time.Duration
+	// Connection holds details about the connection used by the query.
+	Connection ConnectionInfo
+}
+
+// ConnectionInfo holds details about the connection used by single query.
+type ConnectionInfo struct {
+	// Network is the transport used by the query, for example "udp", "tcp-tls", "quic" or "https/2 (POST)".
+	Network string
+	// ReuseKnown is true, when it is known whether the connection was reused. This is reported for plain DNS, DoT and DoH over HTTP/1.1 and HTTP/2.
+	ReuseKnown bool
+	// Reused is true, when the query was sent over already established connection.
+	Reused bool
+	// LocalAddr is the local address of the connection, which identifies the connection. This is reported for plain DNS, DoT
+	// and DoH over HTTP/1.1 and HTTP/2, otherwise it is empty.
+	LocalAddr string
+	// Phases are the phases of establishing a new connection for the query (see ConnectPhase, ResolvePhase and TLSHandshakePhase),
+	// the phases are empty when the connection was reused or when the phases are not reported for the protocol.
+	Phases []ConnectionPhase
+}
+
+// ConnectionPhase is a time span of single phase of establishing a connection.
+type ConnectionPhase struct {
+	// Name is the name of the phase, see ConnectPhase, ResolvePhase and TLSHandshakePhase.
+	Name string
+	// Start is the time when the phase started.
+	Start time.Time
+	// Duration is the duration of the phase.
+	Duration time.Duration
+	// Err is the error of the phase, if it failed.
+	Err error
+}
+
+// connectionInfo returns details about the connection collected by the query trace, the trace can be nil.
+func (t *queryTrace) connectionInfo(network string) ConnectionInfo {
+	info := ConnectionInfo{Network: network}
+	if t == nil {
+		return info
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info.ReuseKnown = t.reuseKnown
+	info.Reused = t.reused
+	info.LocalAddr = t.localAddr
+	for _, p := range t.phases {
+		info.Phases = append(info.Phases, ConnectionPhase{Name: p.name, Start: p.start, Duration: p.end.Sub(p.start), Err: p.err})
+	}
+	return info
+}
+
+// notify calls the function for each configured observer.
+func (b *Benchmark) notify(f func(o Observer)) {
+	for _, o := range b.Observers {
+		if o != nil {
+			f(o)
+		}
+	}
+}
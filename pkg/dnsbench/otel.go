@@ -0,0 +1,216 @@
+package dnsbench
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// OTLPGRPCProtocol represents OTLP over gRPC.
+	OTLPGRPCProtocol = "grpc"
+	// OTLPHTTPProtocol represents OTLP over HTTP using protobuf payloads.
+	OTLPHTTPProtocol = "http"
+
+	// otelScope is the name of the instrumentation scope of the exported metrics and traces.
+	otelScope = "github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+	// otelShutdownTimeout is the timeout of flushing the metrics and traces after the benchmark finishes.
+	otelShutdownTimeout = 10 * time.Second
+)
+
+// Attributes of the exported metrics and spans.
+const (
+	qnameAttr      = attribute.Key("dns.question.name")
+	qtypeAttr      = attribute.Key("dns.question.type")
+	rcodeAttr      = attribute.Key("dns.response.code")
+	transportAttr  = attribute.Key("dnspyre.transport")
+	connReusedAttr = attribute.Key("dnspyre.connection.reused")
+	errorTypeAttr  = attribute.Key("error.type")
+)
+
+// otelExporter exports metrics of the benchmark and sampled spans of the queries using OTLP.
+type otelExporter struct {
+	b              *Benchmark
+	meterProvider  *sdkmetric.MeterProvider
+	tracerProvider *sdktrace.TracerProvider
+	tracer         trace.Tracer
+
+	duration  metric.Float64Histogram
+	responses metric.Int64Counter
+	errors    metric.Int64Counter
+}
+
+func newOTelExporter(ctx context.Context, b *Benchmark) (*otelExporter, error) {
+	endpoint, err := url.Parse(b.OTLPEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("--otlp-endpoint is not valid URL: %w", err)
+	}
+	res := resource.NewSchemaless(
+		attribute.String("service.name", "dnspyre"),
+		attribute.String("dnspyre.server", b.Server),
+		attribute.String("dnspyre.protocol", b.network()),
+		attribute.String("dnspyre.run_id", b.RunID),
+	)
+
+	metricExporter, err := newOTLPMetricExporter(ctx, b.OTLPProtocol, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+	}
+	e := &otelExporter{
+		b: b,
+		meterProvider: sdkmetric.NewMeterProvider(
+			sdkmetric.WithResource(res),
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		),
+	}
+
+	if b.OTLPTraceSampleRate > 0 {
+		traceExporter, err := newOTLPTraceExporter(ctx, b.OTLPProtocol, endpoint)
+		if err != nil {
+			_ = e.meterProvider.Shutdown(ctx)
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		e.tracerProvider = sdktrace.NewTracerProvider(
+			sdktrace.WithResource(res),
+			sdktrace.WithBatcher(traceExporter),
+			sdktrace.WithSampler(sdktrace.TraceIDRatioBased(b.OTLPTraceSampleRate)),
+		)
+		e.tracer = e.tracerProvider.Tracer(otelScope)
+	}
+
+	meter := e.meterProvider.Meter(otelScope)
+	if e.duration, err = meter.Float64Histogram("dnspyre.dns.request.duration",
+		metric.WithDescription("DNS request duration in seconds"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(prometheus.DefBuckets...)); err != nil {
+		return nil, err
+	}
+	if e.responses, err = meter.Int64Counter("dnspyre.dns.responses",
+		metric.WithDescription("The total number of DNS responses")); err != nil {
+		return nil, err
+	}
+	if e.errors, err = meter.Int64Counter("dnspyre.errors",
+		metric.WithDescription("The total number of (I/O) errors")); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func newOTLPMetricExporter(ctx context.Context, protocol string, endpoint *url.URL) (sdkmetric.Exporter, error) {
+	if protocol == OTLPHTTPProtocol {
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(endpoint.Host), otlpmetrichttp.WithURLPath(path.Join("/", endpoint.Path, "v1/metrics"))}
+		if endpoint.Scheme == "http" {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	}
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(endpoint.Host)}
+	if endpoint.Scheme == "http" {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+	return otlpmetricgrpc.New(ctx, opts...)
+}
+
+func newOTLPTraceExporter(ctx context.Context, protocol string, endpoint *url.URL) (sdktrace.SpanExporter, error) {
+	if protocol == OTLPHTTPProtocol {
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint.Host), otlptracehttp.WithURLPath(path.Join("/", endpoint.Path, "v1/traces"))}
+		if endpoint.Scheme == "http" {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint.Host)}
+	if endpoint.Scheme == "http" {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return otlptracegrpc.New(ctx, opts...)
+}
+
+// startQuery starts span of the query, if the query is sampled. The returned context carries the span and the query trace
+// collecting connection details, when the query is not sampled, the context is returned unchanged.
+func (e *otelExporter) startQuery(ctx context.Context, req *dns.Msg) context.Context {
+	if e.tracer == nil {
+		return ctx
+	}
+	ctx, span := e.tracer.Start(ctx, "dns.query", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			qnameAttr.String(req.Question[0].Name),
+			qtypeAttr.String(dns.TypeToString[req.Question[0].Qtype]),
+			transportAttr.String(e.b.network()),
+		))
+	if !span.IsRecording() {
+		return ctx
+	}
+	return withQueryTrace(ctx, &queryTrace{})
+}
+
+// endQuery records metrics of the finished query and ends its span started by startQuery.
+func (e *otelExporter) endQuery(ctx context.Context, req *dns.Msg, resp *dns.Msg, err error, duration time.Duration) {
+	qtype := dns.TypeToString[req.Question[0].Qtype]
+	e.duration.Record(ctx, duration.Seconds(), metric.WithAttributes(qtypeAttr.String(qtype)))
+	if resp != nil {
+		e.responses.Add(ctx, 1, metric.WithAttributes(qtypeAttr.String(qtype), rcodeAttr.String(dns.RcodeToString[resp.Rcode])))
+	}
+	if err != nil {
+		e.errors.Add(ctx, 1, metric.WithAttributes(errorTypeAttr.String(errorClass(err))))
+	}
+
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	if resp != nil {
+		span.SetAttributes(rcodeAttr.String(dns.RcodeToString[resp.Rcode]))
+	}
+	if err != nil {
+		span.SetAttributes(errorTypeAttr.String(errorClass(err)))
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if qt := queryTraceFromContext(ctx); qt != nil {
+		qt.mu.Lock()
+		if qt.reuseKnown {
+			span.SetAttributes(connReusedAttr.Bool(qt.reused))
+		}
+		for _, p := range qt.phases {
+			_, child := e.tracer.Start(ctx, p.name, trace.WithTimestamp(p.start))
+			if p.err != nil {
+				child.SetAttributes(errorTypeAttr.String(errorClass(p.err)))
+				child.SetStatus(codes.Error, p.err.Error())
+			}
+			child.End(trace.WithTimestamp(p.end))
+		}
+		qt.mu.Unlock()
+	}
+	span.End()
+}
+
+// shutdown flushes all pending metrics and spans and stops the exporters.
+func (e *otelExporter) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), otelShutdownTimeout)
+	defer cancel()
+
+	if err := e.meterProvider.Shutdown(ctx); err != nil {
+		e.b.printer.ErrFprintf(e.b.ErrWriter, "Failed to export metrics to OTLP endpoint at %s: %v\n", e.b.OTLPEndpoint, err)
+	}
+	if e.tracerProvider != nil {
+		if err := e.tracerProvider.Shutdown(ctx); err != nil {
+			e.b.printer.ErrFprintf(e.b.ErrWriter, "Failed to export traces to OTLP endpoint at %s: %v\n", e.b.OTLPEndpoint, err)
+		}
+	}
+}
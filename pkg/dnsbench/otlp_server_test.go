@@ -0,0 +1,187 @@
+package dnsbench_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// OTLPServer represents simple OTLP receiver collecting exported metrics and spans.
+type OTLPServer struct {
+	// Endpoint is URL of the receiver.
+	Endpoint string
+	close    func()
+
+	mu      sync.Mutex
+	metrics []*metricspb.ResourceMetrics
+	spans   []*tracepb.ResourceSpans
+}
+
+// Close shuts down running OTLP receiver.
+func (s *OTLPServer) Close() {
+	s.close()
+}
+
+// Metrics returns all received metrics by their name.
+func (s *OTLPServer) Metrics() map[string]*metricspb.Metric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make(map[string]*metricspb.Metric)
+	for _, rm := range s.metrics {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				res[m.GetName()] = m
+			}
+		}
+	}
+	return res
+}
+
+// Spans returns all received spans.
+func (s *OTLPServer) Spans() []*tracepb.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []*tracepb.Span
+	for _, rs := range s.spans {
+		for _, ss := range rs.GetScopeSpans() {
+			res = append(res, ss.GetSpans()...)
+		}
+	}
+	return res
+}
+
+// ResourceAttributes returns attributes of the resource of the received metrics.
+func (s *OTLPServer) ResourceAttributes() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.metrics) == 0 {
+		return nil
+	}
+	return Attributes(s.metrics[0].GetResource().GetAttributes())
+}
+
+func (s *OTLPServer) exportMetrics(req *collectormetrics.ExportMetricsServiceRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = append(s.metrics, req.GetResourceMetrics()...)
+}
+
+func (s *OTLPServer) exportSpans(req *collectortrace.ExportTraceServiceRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spans = append(s.spans, req.GetResourceSpans()...)
+}
+
+// NewOTLPServer creates and starts new OTLP receiver using the protocol.
+func NewOTLPServer(protocol string) *OTLPServer {
+	s := &OTLPServer{}
+	if protocol == dnsbench.OTLPHTTPProtocol {
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /v1/metrics", func(w http.ResponseWriter, r *http.Request) {
+			req := &collectormetrics.ExportMetricsServiceRequest{}
+			if !unmarshalRequest(w, r, req) {
+				return
+			}
+			s.exportMetrics(req)
+			writeResponse(w, &collectormetrics.ExportMetricsServiceResponse{})
+		})
+		mux.HandleFunc("POST /v1/traces", func(w http.ResponseWriter, r *http.Request) {
+			req := &collectortrace.ExportTraceServiceRequest{}
+			if !unmarshalRequest(w, r, req) {
+				return
+			}
+			s.exportSpans(req)
+			writeResponse(w, &collectortrace.ExportTraceServiceResponse{})
+		})
+		server := httptest.NewServer(mux)
+		s.Endpoint = server.URL
+		s.close = server.Close
+		return s
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	server := grpc.NewServer()
+	collectormetrics.RegisterMetricsServiceServer(server, &grpcMetricsService{s: s})
+	collectortrace.RegisterTraceServiceServer(server, &grpcTraceService{s: s})
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	s.Endpoint = "http://" + lis.Addr().String()
+	s.close = server.Stop
+	return s
+}
+
+func unmarshalRequest(w http.ResponseWriter, r *http.Request, msg proto.Message) bool {
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = proto.Unmarshal(body, msg)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeResponse(w http.ResponseWriter, msg proto.Message) {
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(body)
+}
+
+type grpcMetricsService struct {
+	collectormetrics.UnimplementedMetricsServiceServer
+	s *OTLPServer
+}
+
+func (g *grpcMetricsService) Export(_ context.Context, req *collectormetrics.ExportMetricsServiceRequest) (*collectormetrics.ExportMetricsServiceResponse, error) {
+	g.s.exportMetrics(req)
+	return &collectormetrics.ExportMetricsServiceResponse{}, nil
+}
+
+type grpcTraceService struct {
+	collectortrace.UnimplementedTraceServiceServer
+	s *OTLPServer
+}
+
+func (g *grpcTraceService) Export(_ context.Context, req *collectortrace.ExportTraceServiceRequest) (*collectortrace.ExportTraceServiceResponse, error) {
+	g.s.exportSpans(req)
+	return &collectortrace.ExportTraceServiceResponse{}, nil
+}
+
+// Attributes converts OTLP attributes to map of their string representations.
+func Attributes(attrs []*commonpb.KeyValue) map[string]string {
+	res := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		switch v := kv.GetValue().GetValue().(type) {
+		case *commonpb.AnyValue_StringValue:
+			res[kv.GetKey()] = v.StringValue
+		case *commonpb.AnyValue_BoolValue:
+			if v.BoolValue {
+				res[kv.GetKey()] = "true"
+			} else {
+				res[kv.GetKey()] = "false"
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,46 @@
+package dnsbench
+
+const (
+	// LatencyHistogramChart is a histogram of latencies of the responses.
+	LatencyHistogramChart = "latency-histogram"
+	// LatencyBoxPlotChart is a box plot of latencies of the responses.
+	LatencyBoxPlotChart = "latency-boxplot"
+	// ResponsesBarChart is a bar chart of response codes of the responses.
+	ResponsesBarChart = "responses-barchart"
+	// ThroughputLinePlotChart is a line plot of throughput over time.
+	ThroughputLinePlotChart = "throughput-lineplot"
+	// LatencyLinePlotChart is a line plot of latency percentiles over time.
+	LatencyLinePlotChart = "latency-lineplot"
+	// ErrorRateLinePlotChart is a line plot of error rate over time.
+	ErrorRateLinePlotChart = "errorrate-lineplot"
+	// RateLinePlotChart is a line plot of the rate limit over time, it is plotted only with the adaptive rate control.
+	RateLinePlotChart = "rate-lineplot"
+	// LatencyCDFChart is a plot of latency percentiles with logarithmic percentile axis, which highlights tail latencies.
+	LatencyCDFChart = "latency-cdf"
+	// LatencyHeatmapChart is a heatmap of latencies of the responses over time.
+	LatencyHeatmapChart = "latency-heatmap"
+	// RcodesAreaPlotChart is a stacked area plot of response codes over time.
+	RcodesAreaPlotChart = "rcodes-areaplot"
+	// QtypeBoxPlotChart is a box plot of latencies of the responses grouped by query type.
+	QtypeBoxPlotChart = "qtype-boxplot"
+)
+
+// PlotCharts are all charts, which can be plotted, see Benchmark.PlotCharts.
+var PlotCharts = []string{
+	LatencyHistogramChart, LatencyBoxPlotChart, ResponsesBarChart, ThroughputLinePlotChart, LatencyLinePlotChart, ErrorRateLinePlotChart,
+	RateLinePlotChart, LatencyCDFChart, LatencyHeatmapChart, RcodesAreaPlotChart, QtypeBoxPlotChart,
+}
+
+// PlotChart returns true, if the chart should be plotted.
+func (b *Benchmark) PlotChart(chart string) bool {
+	return len(b.PlotCharts) == 0 || containsChart(b.PlotCharts, chart)
+}
+
+func containsChart(charts []string, chart string) bool {
+	for _, c := range charts {
+		if c == chart {
+			return true
+		}
+	}
+	return false
+}
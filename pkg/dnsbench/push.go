@@ -0,0 +1,146 @@
+package dnsbench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const (
+	// pushTimeout is the timeout of single push of metrics.
+	pushTimeout = 10 * time.Second
+
+	// maxErrorBodySize limits size of the response body included in the error of failed remote-write request.
+	maxErrorBodySize = 512
+)
+
+// metricsPusher pushes Prometheus metrics of the benchmark to Pushgateway and Prometheus remote-write endpoint.
+type metricsPusher struct {
+	b        *Benchmark
+	gatherer prometheus.Gatherer
+	// labels are added to all pushed metrics, for Pushgateway they are used as grouping key. The metrics themselves are labeled by server and transport.
+	labels map[string]string
+	pusher *push.Pusher
+	client *http.Client
+}
+
+func newMetricsPusher(b *Benchmark, gatherer prometheus.Gatherer) *metricsPusher {
+	p := &metricsPusher{
+		b:        b,
+		gatherer: gatherer,
+		labels: map[string]string{
+			"instance": b.PushInstance,
+			"run_id":   b.RunID,
+		},
+		client: &http.Client{Timeout: pushTimeout},
+	}
+	if len(b.PushgatewayURL) != 0 {
+		p.pusher = push.New(b.PushgatewayURL, b.PushJob).Gatherer(gatherer).Client(p.client)
+		for name, value := range p.labels {
+			p.pusher.Grouping(name, value)
+		}
+	}
+	return p
+}
+
+// run pushes metrics every Benchmark.PushInterval until done is closed, then pushes the final metrics.
+func (p *metricsPusher) run(done <-chan struct{}) {
+	var tick <-chan time.Time
+	if p.b.PushInterval > 0 {
+		ticker := time.NewTicker(p.b.PushInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-tick:
+			p.push()
+		case <-done:
+			p.push()
+			return
+		}
+	}
+}
+
+func (p *metricsPusher) push() {
+	ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+	defer cancel()
+
+	if p.pusher != nil {
+		if err := p.pusher.PushContext(ctx); err != nil {
+			p.b.printer.ErrFprintf(p.b.ErrWriter, "Failed to push metrics to Pushgateway at %s: %v\n", p.b.PushgatewayURL, err)
+		}
+	}
+	if len(p.b.RemoteWriteURL) != 0 {
+		if err := p.remoteWrite(ctx); err != nil {
+			p.b.printer.ErrFprintf(p.b.ErrWriter, "Failed to push metrics to remote-write endpoint at %s: %v\n", p.b.RemoteWriteURL, err)
+		}
+	}
+}
+
+// remoteWrite sends the current metrics using Prometheus remote-write protocol (version 1.0).
+func (p *metricsPusher) remoteWrite(ctx context.Context) error {
+	mfs, err := p.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{"job": p.b.PushJob}
+	for k, v := range p.labels {
+		labels[k] = v
+	}
+	series := newTimeSeries(mfs, labels)
+	body := snappyEncode(encodeWriteRequest(series, time.Now().UnixMilli()))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.b.RemoteWriteURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return fmt.Errorf("unexpected HTTP status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// defaultPushInstance returns the default value of instance label of pushed metrics.
+func defaultPushInstance() string {
+	hostname, err := os.Hostname()
+	if err != nil || len(hostname) == 0 {
+		return "unknown"
+	}
+	return hostname
+}
+
+// NewRunID generates identifier of the benchmark run used as run_id label of the pushed metrics and run_id resource attribute
+// of the OTLP metrics and traces, see Benchmark.RunID.
+func NewRunID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// sortedLabelNames returns names of the labels in sorted order, remote-write requires labels of the series to be sorted.
+func sortedLabelNames(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,128 @@
+package dnsbench
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const (
+	// ConnectPhase is the phase of establishing a new connection, for DoT it includes the TLS handshake.
+	ConnectPhase = "connect"
+	// ResolvePhase is the phase of resolving hostname of the DoH server.
+	ResolvePhase = "resolve"
+	// TLSHandshakePhase is the phase of TLS handshake of a new DoH connection.
+	TLSHandshakePhase = "tls_handshake"
+)
+
+// queryPhase is a time span of single phase of the query, for example establishing a new connection.
+type queryPhase struct {
+	name  string
+	start time.Time
+	end   time.Time
+	err   error
+}
+
+// queryTrace collects details about the connection used by a single query. The trace is passed to the query functions using the context,
+// the query functions fill in the details, which are available for the protocol.
+type queryTrace struct {
+	mu sync.Mutex
+	// reused is true, when the query was sent over already established connection.
+	reused bool
+	// reuseKnown is true, when the query function reported whether the connection was reused.
+	reuseKnown bool
+	// localAddr is the local address of the connection used by the query, it identifies the connection.
+	localAddr string
+	phases    []queryPhase
+	// pending holds start times of the phases, which did not finish yet.
+	pending map[string]time.Time
+}
+
+type queryTraceKey struct{}
+
+func withQueryTrace(ctx context.Context, trace *queryTrace) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, trace)
+}
+
+// queryTraceFromContext returns the query trace stored in the context or nil, when the query is not traced.
+func queryTraceFromContext(ctx context.Context) *queryTrace {
+	trace, _ := ctx.Value(queryTraceKey{}).(*queryTrace)
+	return trace
+}
+
+func (t *queryTrace) connReused(reused bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reused = reused
+	t.reuseKnown = true
+}
+
+func (t *queryTrace) connAddr(addr net.Addr) {
+	if addr == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.localAddr = addr.String()
+}
+
+// connLocalAddr returns the local address of the connection used by the query, the trace can be nil.
+func (t *queryTrace) connLocalAddr() string {
+	if t == nil {
+		return ""
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.localAddr
+}
+
+func (t *queryTrace) phaseStart(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending == nil {
+		t.pending = make(map[string]time.Time)
+	}
+	t.pending[name] = time.Now()
+}
+
+func (t *queryTrace) phaseEnd(name string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	start, ok := t.pending[name]
+	if !ok {
+		return
+	}
+	delete(t.pending, name)
+	t.phases = append(t.phases, queryPhase{name: name, start: start, end: time.Now(), err: err})
+}
+
+// httpTrace returns client trace collecting connection phases and connection reuse of the DoH query.
+func (t *queryTrace) httpTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.connReused(info.Reused)
+			t.connAddr(info.Conn.LocalAddr())
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.phaseStart(ResolvePhase)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.phaseEnd(ResolvePhase, info.Err)
+		},
+		ConnectStart: func(string, string) {
+			t.phaseStart(ConnectPhase)
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.phaseEnd(ConnectPhase, err)
+		},
+		TLSHandshakeStart: func() {
+			t.phaseStart(TLSHandshakePhase)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.phaseEnd(TLSHandshakePhase, err)
+		},
+	}
+}
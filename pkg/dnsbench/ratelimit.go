@@ -0,0 +1,148 @@
+package dnsbench
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimiter is a token bucket rate limiter used to limit the rate of queries sent by the benchmark. The bucket holds at most burst tokens
+// and it is refilled at the configured rate, each query takes single token. With burst of 1 the queries are evenly spaced, larger burst
+// allows to send more queries at once after the queries were not sent for a while, for example because the benchmarked server was slow to respond.
+// RateLimiter is safe for concurrent use, waiting for a token does not start any goroutines and does not allocate.
+type RateLimiter struct {
+	mu sync.Mutex
+	// rate is the number of tokens added to the bucket per second.
+	rate float64
+	// burst is the maximum number of tokens in the bucket.
+	burst float64
+	// tokens is the number of tokens in the bucket at the time last, it is negative when the tokens were reserved by the waiting callers.
+	tokens float64
+	last   time.Time
+
+	// limited allows checking, whether the rate is limited, without taking the lock.
+	limited atomic.Bool
+}
+
+// rateLimitResolution is the shortest time the caller waits for a token. Sleeping for shorter periods is not precise, so the tokens
+// are taken ahead of time by at most this duration instead. At high rates the bucket holds at least the tokens added during this duration,
+// so the tokens added while the waiting caller oversleeps are not lost and the queries are sent in small batches.
+const rateLimitResolution = time.Millisecond
+
+// timers are reused by the waiting callers, so that waiting does not allocate.
+var timers = sync.Pool{
+	New: func() any {
+		t := time.NewTimer(time.Hour)
+		t.Stop()
+		return t
+	},
+}
+
+// NewRateLimiter creates new rate limiter with the rate of tokens per second and the maximum burst of tokens. Rate 0 means that the rate is not limited,
+// burst lower than 1 is treated as 1. The bucket is full when the limiter is created.
+func NewRateLimiter(rate, burst int) *RateLimiter {
+	l := &RateLimiter{burst: float64(max(burst, 1))}
+	l.tokens = l.burst
+	l.SetRate(rate)
+	return l
+}
+
+// Wait waits until a token is available or until the context is done. When the context is done before the token is available,
+// the context error is returned and the reserved token is returned back to the bucket. When the token would be available in less than
+// a millisecond, the caller does not wait at all.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !l.limited.Load() {
+		return nil
+	}
+
+	l.mu.Lock()
+	if l.rate == 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	l.advance(time.Now())
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait < rateLimitResolution {
+		return nil
+	}
+
+	t := timers.Get().(*time.Timer)
+	defer timers.Put(t)
+	t.Reset(wait)
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		t.Stop()
+		l.mu.Lock()
+		l.tokens = min(l.tokens+1, l.capacity())
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// SetRate changes the rate of tokens per second, 0 removes the rate limit. The tokens already reserved by the waiting callers are not affected.
+func (l *RateLimiter) SetRate(rate int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.advance(now)
+	l.rate = float64(max(rate, 0))
+	l.last = now
+	l.limited.Store(l.rate > 0)
+}
+
+// SetBurst changes the maximum number of tokens in the bucket, burst lower than 1 is treated as 1.
+func (l *RateLimiter) SetBurst(burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(time.Now())
+	l.burst = float64(max(burst, 1))
+	l.tokens = min(l.tokens, l.capacity())
+}
+
+// Rate returns the current rate of tokens per second, 0 means that the rate is not limited.
+func (l *RateLimiter) Rate() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.rate)
+}
+
+// Burst returns the current maximum number of tokens in the bucket.
+func (l *RateLimiter) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.burst)
+}
+
+// Limited returns whether the rate is limited.
+func (l *RateLimiter) Limited() bool {
+	return l.limited.Load()
+}
+
+// advance refills the bucket with the tokens added since the last update. The caller must hold the lock.
+func (l *RateLimiter) advance(now time.Time) {
+	if l.rate == 0 {
+		// the bucket is full, when the rate is not limited
+		l.tokens = l.burst
+	} else if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = min(l.tokens+elapsed.Seconds()*l.rate, l.capacity())
+	}
+	l.last = now
+}
+
+// capacity returns the maximum number of tokens in the bucket, which is the burst or the number of tokens added during rateLimitResolution,
+// whichever is larger. The caller must hold the lock.
+func (l *RateLimiter) capacity() float64 {
+	return max(l.burst, l.rate*rateLimitResolution.Seconds())
+}
@@ -0,0 +1,128 @@
+package dnsbench
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	l := NewRateLimiter(100, 1)
+
+	start := time.Now()
+	for range 11 {
+		require.NoError(t, l.Wait(context.Background()))
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond, "queries should be evenly spaced")
+}
+
+func TestRateLimiter_Wait_burst(t *testing.T) {
+	l := NewRateLimiter(10, 5)
+
+	start := time.Now()
+	for range 5 {
+		require.NoError(t, l.Wait(context.Background()))
+	}
+
+	assert.Less(t, time.Since(start), 50*time.Millisecond, "burst of queries should not wait")
+	assert.Equal(t, 5, l.Burst())
+}
+
+func TestRateLimiter_Wait_unlimited(t *testing.T) {
+	l := NewRateLimiter(0, 0)
+
+	start := time.Now()
+	for range 1000 {
+		require.NoError(t, l.Wait(context.Background()))
+	}
+
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	assert.False(t, l.Limited())
+	assert.Equal(t, 1, l.Burst(), "burst should be at least 1")
+}
+
+func TestRateLimiter_Wait_cancelled(t *testing.T) {
+	l := NewRateLimiter(1, 1)
+	require.NoError(t, l.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, l.Wait(cancelled), context.Canceled)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	assert.Greater(t, l.tokens, -0.5, "token of the cancelled wait should be returned")
+}
+
+func TestRateLimiter_SetRate(t *testing.T) {
+	l := NewRateLimiter(1, 1)
+	require.NoError(t, l.Wait(context.Background()))
+
+	l.SetRate(1000)
+	start := time.Now()
+	require.NoError(t, l.Wait(context.Background()))
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "new rate should be used by the next wait")
+	assert.Equal(t, 1000, l.Rate())
+
+	l.SetRate(0)
+	assert.False(t, l.Limited())
+	assert.Equal(t, 0, l.Rate())
+	l.SetRate(-1)
+	assert.Equal(t, 0, l.Rate())
+
+	l.SetBurst(10)
+	assert.Equal(t, 10, l.Burst())
+}
+
+func TestRateLimiter_Wait_allocations(t *testing.T) {
+	l := NewRateLimiter(1_000_000, 1)
+	ctx := context.Background()
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		_ = l.Wait(ctx)
+	})
+
+	// the pooled timers can be very rarely collected by GC and allocated again
+	assert.Less(t, allocs, 1.0)
+}
+
+func BenchmarkRateLimiter_Wait(b *testing.B) {
+	for _, rate := range []int{0, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("rate=%d", rate), func(b *testing.B) {
+			l := NewRateLimiter(rate, 1)
+			ctx := context.Background()
+			b.ReportAllocs()
+			start := time.Now()
+			for b.Loop() {
+				_ = l.Wait(ctx)
+			}
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "qps")
+		})
+	}
+}
+
+func BenchmarkRateLimiter_Wait_parallel(b *testing.B) {
+	for _, rate := range []int{0, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("rate=%d", rate), func(b *testing.B) {
+			l := NewRateLimiter(rate, 1)
+			ctx := context.Background()
+			b.ReportAllocs()
+			start := time.Now()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = l.Wait(ctx)
+				}
+			})
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "qps")
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package dnsbench
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// timeSeries represents single sample of Prometheus remote-write time series, labels are sorted by name.
+type timeSeries struct {
+	labels []label
+	value  float64
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+// newTimeSeries converts gathered metric families into remote-write time series, the extra labels are added to each series.
+// Histograms and summaries are converted the same way as they are exposed in the text format (_bucket, _sum and _count series).
+func newTimeSeries(mfs []*dto.MetricFamily, extra map[string]string) []timeSeries {
+	var res []timeSeries
+	for _, mf := range mfs {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string, len(extra)+len(m.GetLabel()))
+			for k, v := range extra {
+				labels[k] = v
+			}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				res = append(res, newSeries(name, labels, m.GetCounter().GetValue()))
+			case dto.MetricType_GAUGE:
+				res = append(res, newSeries(name, labels, m.GetGauge().GetValue()))
+			case dto.MetricType_UNTYPED:
+				res = append(res, newSeries(name, labels, m.GetUntyped().GetValue()))
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					res = append(res, newSeries(name+"_bucket", withLabel(labels, "le", formatFloat(b.GetUpperBound())), float64(b.GetCumulativeCount())))
+				}
+				res = append(res, newSeries(name+"_bucket", withLabel(labels, "le", "+Inf"), float64(h.GetSampleCount())))
+				res = append(res, newSeries(name+"_sum", labels, h.GetSampleSum()))
+				res = append(res, newSeries(name+"_count", labels, float64(h.GetSampleCount())))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					res = append(res, newSeries(name, withLabel(labels, "quantile", formatFloat(q.GetQuantile())), q.GetValue()))
+				}
+				res = append(res, newSeries(name+"_sum", labels, s.GetSampleSum()))
+				res = append(res, newSeries(name+"_count", labels, float64(s.GetSampleCount())))
+			}
+		}
+	}
+	return res
+}
+
+func newSeries(name string, labels map[string]string, value float64) timeSeries {
+	ts := timeSeries{labels: make([]label, 0, len(labels)+1), value: value}
+	ts.labels = append(ts.labels, label{name: "__name__", value: name})
+	for _, k := range sortedLabelNames(labels) {
+		ts.labels = append(ts.labels, label{name: k, value: labels[k]})
+	}
+	sort.Slice(ts.labels, func(i, j int) bool { return ts.labels[i].name < ts.labels[j].name })
+	return ts
+}
+
+func withLabel(labels map[string]string, name, value string) map[string]string {
+	res := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		res[k] = v
+	}
+	res[name] = value
+	return res
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// encodeWriteRequest encodes the time series as prometheus.WriteRequest protobuf message, all samples share the same timestamp in milliseconds.
+func encodeWriteRequest(series []timeSeries, timestamp int64) []byte {
+	var req []byte
+	for _, ts := range series {
+		var msg []byte
+		for _, l := range ts.labels {
+			var lb []byte
+			lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.name)
+			lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+			lb = protowire.AppendString(lb, l.value)
+			msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+			msg = protowire.AppendBytes(msg, lb)
+		}
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(ts.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(timestamp))
+		msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+		msg = protowire.AppendBytes(msg, sample)
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, msg)
+	}
+	return req
+}
+
+// maxSnappyLiteral is the maximal length of single literal element written by snappyEncode.
+const maxSnappyLiteral = 1 << 16
+
+// snappyEncode encodes data using Snappy block format required by remote-write. The data are written as literal elements only,
+// which is valid Snappy stream readable by any Snappy decoder. The metrics payload is small, so the compression is not worth the complexity.
+func snappyEncode(src []byte) []byte {
+	dst := protowire.AppendVarint(make([]byte, 0, len(src)+len(src)/maxSnappyLiteral*5+10), uint64(len(src)))
+	for len(src) > 0 {
+		n := min(len(src), maxSnappyLiteral)
+		switch l := n - 1; {
+		case l < 60:
+			dst = append(dst, byte(l)<<2)
+		case l < 1<<8:
+			dst = append(dst, 60<<2, byte(l))
+		default:
+			dst = append(dst, 61<<2, byte(l), byte(l>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
@@ -0,0 +1,168 @@
+package dnsbench
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func Test_snappyEncode(t *testing.T) {
+	long := bytes.Repeat([]byte{'x'}, 100)
+	huge := bytes.Repeat([]byte{'y'}, maxSnappyLiteral+1)
+
+	tests := []struct {
+		name string
+		src  []byte
+		want []byte
+	}{
+		{
+			name: "empty",
+			src:  nil,
+			want: []byte{0},
+		},
+		{
+			name: "short literal",
+			src:  []byte("abc"),
+			want: []byte{3, 2 << 2, 'a', 'b', 'c'},
+		},
+		{
+			name: "literal with 1 byte length",
+			src:  long,
+			want: append([]byte{100, 60 << 2, 99}, long...),
+		},
+		{
+			name: "multiple literals",
+			src:  huge,
+			want: append(append(append([]byte{0x81, 0x80, 0x04, 61 << 2, 0xff, 0xff}, huge[:maxSnappyLiteral]...), 0), 'y'),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := snappyEncode(tt.src)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.src, snappyDecode(t, got))
+		})
+	}
+}
+
+func Test_newTimeSeries(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_total", Help: "test"}, []string{"rcode"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_seconds", Help: "test", Buckets: []float64{0.1, 1}})
+	registry.MustRegister(counter, histogram)
+	counter.WithLabelValues("NOERROR").Add(3)
+	histogram.Observe(0.05)
+	histogram.Observe(0.5)
+	histogram.Observe(2)
+
+	mfs, err := registry.Gather()
+	require.NoError(t, err)
+
+	got := newTimeSeries(mfs, map[string]string{"job": "dnspyre"})
+
+	assert.Equal(t, []timeSeries{
+		{labels: []label{{"__name__", "test_seconds_bucket"}, {"job", "dnspyre"}, {"le", "0.1"}}, value: 1},
+		{labels: []label{{"__name__", "test_seconds_bucket"}, {"job", "dnspyre"}, {"le", "1"}}, value: 2},
+		{labels: []label{{"__name__", "test_seconds_bucket"}, {"job", "dnspyre"}, {"le", "+Inf"}}, value: 3},
+		{labels: []label{{"__name__", "test_seconds_sum"}, {"job", "dnspyre"}}, value: 2.55},
+		{labels: []label{{"__name__", "test_seconds_count"}, {"job", "dnspyre"}}, value: 3},
+		{labels: []label{{"__name__", "test_total"}, {"job", "dnspyre"}, {"rcode", "NOERROR"}}, value: 3},
+	}, got)
+}
+
+func Test_encodeWriteRequest(t *testing.T) {
+	series := []timeSeries{
+		{labels: []label{{"__name__", "test_total"}, {"job", "dnspyre"}}, value: 3},
+		{labels: []label{{"__name__", "test_seconds_count"}, {"job", "dnspyre"}}, value: 1.5},
+	}
+
+	got := decodeWriteRequest(t, encodeWriteRequest(series, 1234))
+
+	assert.Equal(t, series, got.series)
+	assert.Equal(t, []int64{1234, 1234}, got.timestamps)
+}
+
+// snappyDecode decodes Snappy block consisting of literal elements only.
+func snappyDecode(t *testing.T, src []byte) []byte {
+	t.Helper()
+	size, n := protowire.ConsumeVarint(src)
+	require.GreaterOrEqual(t, n, 0)
+	src = src[n:]
+	var dst []byte
+	for len(src) > 0 {
+		tag := src[0]
+		require.Zero(t, tag&0x03, "only literal elements are expected")
+		l := int(tag >> 2)
+		src = src[1:]
+		switch l {
+		case 60:
+			l = int(src[0])
+			src = src[1:]
+		case 61:
+			l = int(src[0]) | int(src[1])<<8
+			src = src[2:]
+		}
+		l++
+		dst = append(dst, src[:l]...)
+		src = src[l:]
+	}
+	require.Equal(t, size, uint64(len(dst)))
+	return dst
+}
+
+type writeRequest struct {
+	series     []timeSeries
+	timestamps []int64
+}
+
+func decodeWriteRequest(t *testing.T, b []byte) writeRequest {
+	t.Helper()
+	var res writeRequest
+	forEachField(t, b, func(num protowire.Number, v []byte) {
+		require.Equal(t, protowire.Number(1), num, "unexpected field of WriteRequest")
+		var ts timeSeries
+		forEachField(t, v, func(num protowire.Number, v []byte) {
+			switch num {
+			case 1:
+				var l label
+				forEachField(t, v, func(num protowire.Number, v []byte) {
+					if num == 1 {
+						l.name = string(v)
+					} else {
+						l.value = string(v)
+					}
+				})
+				ts.labels = append(ts.labels, l)
+			case 2:
+				value, n := protowire.ConsumeFixed64(v[1:])
+				require.GreaterOrEqual(t, n, 0)
+				ts.value = math.Float64frombits(value)
+				timestamp, n := protowire.ConsumeVarint(v[1+n+1:])
+				require.GreaterOrEqual(t, n, 0)
+				res.timestamps = append(res.timestamps, int64(timestamp))
+			}
+		})
+		res.series = append(res.series, ts)
+	})
+	return res
+}
+
+// forEachField calls f for each length-delimited field of the protobuf message.
+func forEachField(t *testing.T, b []byte, f func(protowire.Number, []byte)) {
+	t.Helper()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		require.Equal(t, protowire.BytesType, typ)
+		b = b[n:]
+		v, n := protowire.ConsumeBytes(b)
+		require.GreaterOrEqual(t, n, 0)
+		f(num, v)
+		b = b[n:]
+	}
+}
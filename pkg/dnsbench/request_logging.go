@@ -8,7 +8,7 @@ import (
 	"github.com/miekg/dns"
 )
 
-func logRequest(workerID uint32, req dns.Msg, resp *dns.Msg, err error, dur time.Duration) {
+func logRequest(logger *log.Logger, workerID uint32, req dns.Msg, resp *dns.Msg, err error, dur time.Duration) {
 	rcode := "<nil>"
 	respid := "<nil>"
 	respflags := "<nil>"
@@ -17,7 +17,7 @@ func logRequest(workerID uint32, req dns.Msg, resp *dns.Msg, err error, dur time
 		respid = fmt.Sprint(resp.Id)
 		respflags = getFlags(resp)
 	}
-	log.Printf("worker:[%v] reqid:[%d] qname:[%s] qtype:[%s] respid:[%s] rcode:[%s] respflags:[%s] err:[%v] duration:[%v]",
+	logger.Printf("worker:[%v] reqid:[%d] qname:[%s] qtype:[%s] respid:[%s] rcode:[%s] respflags:[%s] err:[%v] duration:[%v]",
 		workerID, req.Id, req.Question[0].Name, dns.TypeToString[req.Question[0].Qtype], respid, rcode, respflags, err, dur)
 }
 
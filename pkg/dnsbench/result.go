@@ -2,6 +2,7 @@ package dnsbench
 
 import (
 	"errors"
+	"net"
 	"time"
 
 	"github.com/HdrHistogram/hdrhistogram-go"
@@ -27,6 +28,34 @@ type Counters struct {
 	Truncated int64
 }
 
+// count updates counters with the outcome of single request.
+func (c *Counters) count(req *dns.Msg, resp *dns.Msg, err error) {
+	c.Total++
+	if err != nil {
+		c.IOError++
+		return
+	}
+
+	if resp.Truncated {
+		c.Truncated++
+	}
+
+	switch {
+	case resp.Rcode == dns.RcodeSuccess && resp.Id != req.Id:
+		c.IDmismatch++
+	case resp.Rcode == dns.RcodeSuccess && len(resp.Answer) == 0:
+		// NODATA negative response
+		c.Negative++
+	case resp.Rcode == dns.RcodeSuccess:
+		c.Success++
+	case resp.Rcode == dns.RcodeNameError:
+		c.Negative++
+	default:
+		// assume every rcode not NOERROR or NXDOMAIN is error
+		c.Error++
+	}
+}
+
 // Datapoint one datapoint of benchmark (single DNS request).
 type Datapoint struct {
 	Duration time.Duration
@@ -39,18 +68,82 @@ type ErrorDatapoint struct {
 	Err   error
 }
 
+// ErrorGroup returns description of the error used for grouping similar IO errors, for example errors of requests
+// sent over the same network operation to the same address are grouped together.
+func ErrorGroup(err error) string {
+	var netOpErr *net.OpError
+	var resolveErr *net.DNSError
+
+	switch {
+	case errors.As(err, &resolveErr):
+		return resolveErr.Err + " " + resolveErr.Name
+	case errors.As(err, &netOpErr):
+		errorString := netOpErr.Op + " " + netOpErr.Net
+		if netOpErr.Addr != nil {
+			errorString += " " + netOpErr.Addr.String()
+		}
+		return errorString
+	default:
+		return err.Error()
+	}
+}
+
+// NoDataOutcome is a key of ResultStats.RcodeHists for NOERROR responses without any answer records (NODATA).
+const NoDataOutcome = "NODATA"
+
 // ResultStats is a representation of benchmark results of single concurrent thread.
 type ResultStats struct {
-	Codes                map[int]int64
-	Qtypes               map[string]int64
-	Hist                 *hdrhistogram.Histogram
-	Timings              []Datapoint
-	Counters             *Counters
-	Errors               []ErrorDatapoint
+	Codes  map[int]int64
+	Qtypes map[string]int64
+	Hist   *hdrhistogram.Histogram
+	// Timings holds raw datapoints of the responses, at most Benchmark.RawSamples datapoints uniformly sampled from all responses are kept.
+	Timings  []Datapoint
+	Counters *Counters
+	// Errors holds raw datapoints of the IO errors, at most Benchmark.RawSamples datapoints uniformly sampled from all IO errors are kept.
+	Errors []ErrorDatapoint
+	// ErrorGroups counts all IO errors grouped using ErrorGroup.
+	ErrorGroups map[string]int64
+	// ErrorClasses counts all IO errors classified using ClassifyError.
+	ErrorClasses map[ErrorClass]int64
+	// TimeSeries holds counts of responses and IO errors and latency histograms aggregated over time.
+	TimeSeries           *TimeSeries
 	AuthenticatedDomains map[string]struct{}
 	DoHStatusCodes       map[int]int64
 	// EDECodes counts Extended DNS Error (RFC 8914) info codes received in responses.
 	EDECodes map[uint16]int64
+	// QtypeHists holds latency histograms of responses keyed by query type.
+	QtypeHists map[string]*hdrhistogram.Histogram
+	// RcodeHists holds latency histograms of responses keyed by response code, NOERROR responses without answers are keyed by NoDataOutcome.
+	RcodeHists map[string]*hdrhistogram.Histogram
+	// ErrorHist is a histogram of time to error of requests, which ended with IO error.
+	ErrorHist *hdrhistogram.Histogram
+	// Sizes holds statistics of wire sizes of requests and responses.
+	Sizes *SizeStats
+	// Domains holds aggregated latencies and outcomes for each question, it is filled only when Benchmark.DomainStats is enabled.
+	Domains map[QuestionKey]*DomainStats
+	// TTLs holds analysis of answer TTLs for each question, it is filled only when Benchmark.TTLAnalysis is enabled.
+	TTLs map[QuestionKey]*TTLStats
+	// TTLHist is a histogram of answer TTLs in seconds, it is filled only when Benchmark.TTLAnalysis is enabled.
+	TTLHist *hdrhistogram.Histogram
+	// Consistency holds results of cross-server answer consistency checking, it is filled only when Benchmark.CompareServers is configured.
+	Consistency *ConsistencyStats
+	// RateAdjustments holds adjustments of the global rate limit done by the adaptive rate control, they are recorded only to the results
+	// of the first worker, because the rate is shared by all workers. It is filled only when Benchmark.TargetLatency is configured.
+	RateAdjustments []RateAdjustment
+	// Warmup holds results of the queries sent during the warm-up phase, which are not part of the other results.
+	// It is filled only when Benchmark.Warmup is configured.
+	Warmup *WarmupStats
+	// Connections holds aggregated latencies and outcomes of requests keyed by the local address of the connection used by the requests,
+	// the connections are tracked for plain DNS, DoT and DoH over HTTP/1.1 and HTTP/2. It is filled only when Benchmark.WorkerStats is enabled.
+	Connections map[string]*ConnectionStats
+
+	// rawSamples is the maximum number of kept Timings and Errors datapoints.
+	rawSamples int
+	// seenTimings and seenErrors count all datapoints offered to Timings and Errors.
+	seenTimings int64
+	seenErrors  int64
+	// authoritativeTTL is the authoritative TTL of all questions configured by Benchmark.AuthoritativeTTL, zero when not configured.
+	authoritativeTTL uint32
 }
 
 func newResultStats(b *Benchmark) *ResultStats {
@@ -59,16 +152,42 @@ func newResultStats(b *Benchmark) *ResultStats {
 		st.Codes = make(map[int]int64)
 	}
 	st.Qtypes = make(map[string]int64)
+	st.QtypeHists = make(map[string]*hdrhistogram.Histogram)
+	st.RcodeHists = make(map[string]*hdrhistogram.Histogram)
+	st.ErrorHist = hdrhistogram.New(b.HistMin.Nanoseconds(), b.HistMax.Nanoseconds(), b.HistPre)
 	if b.useDoH {
 		st.DoHStatusCodes = make(map[int]int64)
 	}
 	st.EDECodes = make(map[uint16]int64)
 	st.Counters = &Counters{}
+	st.ErrorGroups = make(map[string]int64)
+	st.ErrorClasses = make(map[ErrorClass]int64)
+	st.TimeSeries = NewTimeSeries(b.runStart, b.HistMin, b.HistMax)
+	st.rawSamples = b.RawSamples
+	if st.rawSamples == 0 {
+		st.rawSamples = DefaultRawSamples
+	}
+	st.rawSamples = max(0, st.rawSamples)
+	st.Sizes = newSizeStats()
+	if b.DomainStats {
+		st.Domains = make(map[QuestionKey]*DomainStats)
+	}
+	if b.TTLAnalysis {
+		st.TTLs = make(map[QuestionKey]*TTLStats)
+		st.TTLHist = newTTLHistogram()
+		st.authoritativeTTL = uint32(b.AuthoritativeTTL.Seconds())
+	}
+	if b.WorkerStats {
+		st.Connections = make(map[string]*ConnectionStats)
+	}
+	if len(b.compareBenchmarks) > 0 {
+		st.Consistency = newConsistencyStats(b)
+	}
 	return st
 }
 
-func (rs *ResultStats) record(req *dns.Msg, resp *dns.Msg, err error, time time.Time, duration time.Duration) {
-	rs.Counters.Total++
+func (rs *ResultStats) record(req *dns.Msg, resp *dns.Msg, sizes messageSizes, err error, time time.Time, duration time.Duration) {
+	rs.Counters.count(req, resp, err)
 
 	if rs.DoHStatusCodes != nil {
 		statusError := doh.UnexpectedServerHTTPStatusError{}
@@ -84,34 +203,43 @@ func (rs *ResultStats) record(req *dns.Msg, resp *dns.Msg, err error, time time.
 		rs.Qtypes[dns.TypeToString[req.Question[0].Qtype]]++
 	}
 
-	if err != nil {
-		rs.Counters.IOError++
-		rs.Errors = append(rs.Errors, ErrorDatapoint{Start: time, Err: err})
-		return
+	if rs.Sizes != nil {
+		rs.Sizes.recordRequest(req, sizes.request)
 	}
 
-	if resp.Truncated {
-		rs.Counters.Truncated++
+	var ds *DomainStats
+	if rs.Domains != nil {
+		ds = rs.domainStats(req)
+		ds.Count++
 	}
 
-	if resp.Rcode == dns.RcodeSuccess {
-		if resp.Id != req.Id {
-			rs.Counters.IDmismatch++
-			return
+	if err != nil {
+		if ds != nil {
+			ds.IOError++
+		}
+		if rs.ErrorHist != nil {
+			rs.ErrorHist.RecordValue(duration.Nanoseconds())
+		}
+		if rs.ErrorGroups != nil {
+			rs.ErrorGroups[ErrorGroup(err)]++
+		}
+		if rs.ErrorClasses != nil {
+			rs.ErrorClasses[ClassifyError(err)]++
 		}
-		if len(resp.Answer) == 0 {
-			// NODATA negative response
-			rs.Counters.Negative++
-		} else {
-			rs.Counters.Success++
+		if rs.TimeSeries != nil {
+			rs.TimeSeries.RecordError(time)
 		}
+		rs.seenErrors++
+		rs.Errors = sample(rs.Errors, rs.rawSamples, rs.seenErrors, ErrorDatapoint{Start: time, Err: err})
+		return
 	}
-	if resp.Rcode == dns.RcodeNameError {
-		rs.Counters.Negative++
+
+	if rs.Sizes != nil {
+		rs.Sizes.recordResponse(req, sizes.response)
 	}
-	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
-		// assume every rcode not NOERROR or NXDOMAIN is error
-		rs.Counters.Error++
+
+	if resp.Rcode == dns.RcodeSuccess && resp.Id != req.Id {
+		return
 	}
 
 	if rs.Codes != nil {
@@ -122,6 +250,9 @@ func (rs *ResultStats) record(req *dns.Msg, resp *dns.Msg, err error, time time.
 		c++
 		rs.Codes[resp.Rcode] = c
 	}
+	if ds != nil {
+		ds.Codes[resp.Rcode]++
+	}
 	if resp.AuthenticatedData {
 		if rs.AuthenticatedDomains == nil {
 			rs.AuthenticatedDomains = make(map[string]struct{})
@@ -140,6 +271,55 @@ func (rs *ResultStats) record(req *dns.Msg, resp *dns.Msg, err error, time time.
 		}
 	}
 
+	if rs.TTLs != nil {
+		rs.ttlStats(req).record(resp, time)
+		if ttl, ok := answerTTL(resp); ok {
+			rs.TTLHist.RecordValue(int64(ttl))
+		}
+	}
+
 	rs.Hist.RecordValue(duration.Nanoseconds())
-	rs.Timings = append(rs.Timings, Datapoint{Duration: duration, Start: time})
+	if rs.QtypeHists != nil {
+		rs.recordBreakdown(rs.QtypeHists, dns.TypeToString[req.Question[0].Qtype], duration)
+	}
+	if rs.RcodeHists != nil {
+		outcome := dns.RcodeToString[resp.Rcode]
+		if resp.Rcode == dns.RcodeSuccess && len(resp.Answer) == 0 {
+			outcome = NoDataOutcome
+		}
+		rs.recordBreakdown(rs.RcodeHists, outcome, duration)
+	}
+	if ds != nil {
+		ds.Hist.RecordValue(duration.Nanoseconds())
+	}
+	if rs.TimeSeries != nil {
+		rs.TimeSeries.RecordResponse(time, duration)
+		rs.TimeSeries.RecordRcode(time, resp.Rcode)
+	}
+	rs.seenTimings++
+	rs.Timings = sample(rs.Timings, rs.rawSamples, rs.seenTimings, Datapoint{Duration: duration, Start: time})
+}
+
+// recordBreakdown records latency to the histogram of the given dimension value, the histogram is created with the same parameters as ResultStats.Hist.
+func (rs *ResultStats) recordBreakdown(hists map[string]*hdrhistogram.Histogram, key string, duration time.Duration) {
+	h, ok := hists[key]
+	if !ok {
+		h = hdrhistogram.New(rs.Hist.LowestTrackableValue(), rs.Hist.HighestTrackableValue(), int(rs.Hist.SignificantFigures()))
+		hists[key] = h
+	}
+	h.RecordValue(duration.Nanoseconds())
+}
+
+// ttlStats returns TTL analysis of the question of the request, the analysis is created, when the question was not seen yet.
+func (rs *ResultStats) ttlStats(req *dns.Msg) *TTLStats {
+	key := QuestionKey{Name: req.Question[0].Name, Qtype: dns.TypeToString[req.Question[0].Qtype]}
+	ts, ok := rs.TTLs[key]
+	if !ok {
+		ts = &TTLStats{}
+		if rs.authoritativeTTL > 0 {
+			ts.recordAuthoritative(rs.authoritativeTTL)
+		}
+		rs.TTLs[key] = ts
+	}
+	return ts
 }
@@ -63,7 +63,9 @@ func TestResultStats_record(t *testing.T) {
 					Total:   1,
 					Success: 1,
 				},
-				EDECodes: map[uint16]int64{},
+				ErrorGroups:  map[string]int64{},
+				ErrorClasses: map[ErrorClass]int64{},
+				EDECodes:     map[uint16]int64{},
 			},
 		},
 		{
@@ -102,7 +104,9 @@ func TestResultStats_record(t *testing.T) {
 					Total:    1,
 					Negative: 1,
 				},
-				EDECodes: map[uint16]int64{},
+				ErrorGroups:  map[string]int64{},
+				ErrorClasses: map[ErrorClass]int64{},
+				EDECodes:     map[uint16]int64{},
 			},
 		},
 		{
@@ -141,7 +145,9 @@ func TestResultStats_record(t *testing.T) {
 					Total:    1,
 					Negative: 1,
 				},
-				EDECodes: map[uint16]int64{},
+				ErrorGroups:  map[string]int64{},
+				ErrorClasses: map[ErrorClass]int64{},
+				EDECodes:     map[uint16]int64{},
 			},
 		},
 		{
@@ -180,7 +186,9 @@ func TestResultStats_record(t *testing.T) {
 					Total: 1,
 					Error: 1,
 				},
-				EDECodes: map[uint16]int64{},
+				ErrorGroups:  map[string]int64{},
+				ErrorClasses: map[ErrorClass]int64{},
+				EDECodes:     map[uint16]int64{},
 			},
 		},
 		{
@@ -215,6 +223,12 @@ func TestResultStats_record(t *testing.T) {
 					Total:   1,
 					IOError: 1,
 				},
+				ErrorGroups: map[string]int64{
+					"test error": 1,
+				},
+				ErrorClasses: map[ErrorClass]int64{
+					{Type: OtherError}: 1,
+				},
 				EDECodes: map[uint16]int64{},
 			},
 		},
@@ -256,7 +270,9 @@ func TestResultStats_record(t *testing.T) {
 					Truncated: 1,
 					Success:   1,
 				},
-				EDECodes: map[uint16]int64{},
+				ErrorGroups:  map[string]int64{},
+				ErrorClasses: map[ErrorClass]int64{},
+				EDECodes:     map[uint16]int64{},
 			},
 		},
 		{
@@ -288,7 +304,9 @@ func TestResultStats_record(t *testing.T) {
 					Total:      1,
 					IDmismatch: 1,
 				},
-				EDECodes: map[uint16]int64{},
+				ErrorGroups:  map[string]int64{},
+				ErrorClasses: map[ErrorClass]int64{},
+				EDECodes:     map[uint16]int64{},
 			},
 		},
 		{
@@ -332,7 +350,9 @@ func TestResultStats_record(t *testing.T) {
 				DoHStatusCodes: map[int]int64{
 					200: 1,
 				},
-				EDECodes: map[uint16]int64{},
+				ErrorGroups:  map[string]int64{},
+				ErrorClasses: map[ErrorClass]int64{},
+				EDECodes:     map[uint16]int64{},
 			},
 		},
 		{
@@ -375,7 +395,9 @@ func TestResultStats_record(t *testing.T) {
 				AuthenticatedDomains: map[string]struct{}{
 					"example.org.": {},
 				},
-				EDECodes: map[uint16]int64{},
+				ErrorGroups:  map[string]int64{},
+				ErrorClasses: map[ErrorClass]int64{},
+				EDECodes:     map[uint16]int64{},
 			},
 		},
 		{
@@ -429,6 +451,8 @@ func TestResultStats_record(t *testing.T) {
 					Total:   1,
 					Success: 1,
 				},
+				ErrorGroups:  map[string]int64{},
+				ErrorClasses: map[ErrorClass]int64{},
 				EDECodes: map[uint16]int64{
 					17: 1,
 				},
@@ -443,12 +467,54 @@ func TestResultStats_record(t *testing.T) {
 			}
 			rs := newResultStats(&b)
 
-			rs.record(tt.args.req, tt.args.resp, tt.args.err, now, tt.args.duration)
+			rs.record(tt.args.req, tt.args.resp, newMessageSizes(tt.args.req, tt.args.resp), tt.args.err, now, tt.args.duration)
 
-			// null the Histogram for simple assertion excluding the histogram
+			// null the Histograms, sizes and time series for simple assertion excluding the histograms
 			rs.Hist = nil
+			rs.QtypeHists = nil
+			rs.RcodeHists = nil
+			rs.ErrorHist = nil
+			rs.Sizes = nil
+			rs.TimeSeries = nil
+			rs.rawSamples, rs.seenTimings, rs.seenErrors = 0, 0, 0
 
 			assert.Equal(t, tt.want, rs)
 		})
 	}
 }
+
+func TestResultStats_record_latency_breakdown(t *testing.T) {
+	question := func(qtype uint16) *dns.Msg {
+		return &dns.Msg{
+			MsgHdr:   dns.MsgHdr{Id: 1},
+			Question: []dns.Question{{Name: "example.org.", Qclass: dns.ClassINET, Qtype: qtype}},
+		}
+	}
+	answer := &dns.Msg{
+		MsgHdr: dns.MsgHdr{Id: 1, Rcode: dns.RcodeSuccess, Response: true},
+		Answer: []dns.RR{&dns.A{A: net.ParseIP("127.0.0.1")}},
+	}
+	nodata := &dns.Msg{MsgHdr: dns.MsgHdr{Id: 1, Rcode: dns.RcodeSuccess, Response: true}}
+	servfail := &dns.Msg{MsgHdr: dns.MsgHdr{Id: 1, Rcode: dns.RcodeServerFailure, Response: true}}
+
+	b := Benchmark{Rcodes: true, HistMax: 10 * time.Second, HistPre: 1}
+	rs := newResultStats(&b)
+
+	rs.record(question(dns.TypeA), answer, newMessageSizes(question(dns.TypeA), answer), nil, now, time.Millisecond)
+	rs.record(question(dns.TypeAAAA), nodata, newMessageSizes(question(dns.TypeAAAA), nodata), nil, now, 2*time.Millisecond)
+	rs.record(question(dns.TypeA), servfail, newMessageSizes(question(dns.TypeA), servfail), nil, now, 5*time.Second)
+	rs.record(question(dns.TypeA), nil, newMessageSizes(question(dns.TypeA), nil), errors.New("test error"), now, 3*time.Second)
+
+	assert.Len(t, rs.QtypeHists, 2)
+	assert.EqualValues(t, 2, rs.QtypeHists["A"].TotalCount())
+	assert.EqualValues(t, 1, rs.QtypeHists["AAAA"].TotalCount())
+
+	assert.Len(t, rs.RcodeHists, 3)
+	assert.EqualValues(t, 1, rs.RcodeHists["NOERROR"].TotalCount())
+	assert.EqualValues(t, 1, rs.RcodeHists[NoDataOutcome].TotalCount())
+	assert.EqualValues(t, 1, rs.RcodeHists["SERVFAIL"].TotalCount())
+	assert.InDelta(t, (5 * time.Second).Nanoseconds(), rs.RcodeHists["SERVFAIL"].Max(), float64(time.Second.Nanoseconds()))
+
+	assert.EqualValues(t, 1, rs.ErrorHist.TotalCount())
+	assert.InDelta(t, (3 * time.Second).Nanoseconds(), rs.ErrorHist.Max(), float64(time.Second.Nanoseconds()))
+}
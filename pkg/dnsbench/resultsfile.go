@@ -0,0 +1,487 @@
+package dnsbench
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// ResultsFileVersion is the version of the results file format written by WriteResults. ReadResults reads only the results files
+// of this version.
+const ResultsFileVersion = 1
+
+// Results represents results of the benchmark run, which can be saved to the results file using WriteResults
+// and reported again later without re-running the benchmark.
+type Results struct {
+	// Benchmark is the configuration of the benchmark, which produced the results.
+	Benchmark *Benchmark
+	// Start is the time, when the benchmark started.
+	Start time.Time
+	// Duration is the duration of the benchmark.
+	Duration time.Duration
+	// Stats holds results of the parallel benchmark goroutines, same as returned by Benchmark.Run. It is empty,
+	// when the benchmark was repeated using Benchmark.Iterations.
+	Stats []*ResultStats
+	// Iterations holds results of the iterations of the benchmark repeated using Benchmark.Iterations, same as returned by Benchmark.RunIterations.
+	Iterations []IterationResult
+}
+
+// resultsFile is the serialized form of Results. The histograms are stored in the compressed HDR histogram encoding
+// and the maps keyed by structs are stored as lists sorted by the keys, so that the file is deterministic.
+type resultsFile struct {
+	Version    int
+	Benchmark  *Benchmark
+	Start      time.Time
+	Duration   time.Duration
+	Stats      []*resultStatsFile
+	Iterations []iterationFile
+}
+
+type iterationFile struct {
+	Start    time.Time
+	Duration time.Duration
+	Stats    []*resultStatsFile
+}
+
+type resultStatsFile struct {
+	Codes                map[int]int64
+	Qtypes               map[string]int64
+	Hist                 []byte
+	Timings              []Datapoint
+	Counters             *Counters
+	Errors               []errorDatapointFile
+	ErrorGroups          map[string]int64
+	ErrorClasses         []errorClassFile
+	TimeSeries           *timeSeriesFile
+	AuthenticatedDomains []string
+	DoHStatusCodes       map[int]int64
+	EDECodes             map[uint16]int64
+	QtypeHists           map[string][]byte
+	RcodeHists           map[string][]byte
+	ErrorHist            []byte
+	Sizes                *sizeStatsFile
+	Domains              []domainStatsFile
+	TTLs                 []ttlStatsFile
+	TTLHist              []byte
+	Consistency          *consistencyStatsFile
+	RateAdjustments      []RateAdjustment
+	Warmup               *warmupStatsFile
+	Connections          map[string]*connectionStatsFile
+}
+
+type errorDatapointFile struct {
+	Start time.Time
+	Err   string
+}
+
+type errorClassFile struct {
+	ErrorClass
+	Count int64
+}
+
+type timeSeriesFile struct {
+	Start   time.Time
+	Width   time.Duration
+	HistMin int64
+	HistMax int64
+	Buckets []timeBucketFile
+}
+
+type timeBucketFile struct {
+	Responses int64
+	Errors    int64
+	Hist      []byte
+	Rcodes    map[int]int64
+}
+
+type sizeStatsFile struct {
+	RequestHist    []byte
+	ResponseHist   []byte
+	BytesSent      int64
+	BytesReceived  int64
+	Qtypes         map[string]*QtypeSizes
+	Largest        []ResponseSize
+	NearBufferSize int64
+}
+
+type domainStatsFile struct {
+	QuestionKey
+	Count   int64
+	Codes   map[int]int64
+	IOError int64
+	Hist    []byte
+}
+
+type ttlStatsFile struct {
+	QuestionKey
+	TTLStats
+}
+
+type consistencyStatsFile struct {
+	Servers     map[string]*resultStatsFile
+	Compared    int64
+	Mismatched  int64
+	Skipped     int64
+	Differences []differenceFile
+}
+
+type differenceFile struct {
+	DifferenceKey
+	Difference
+}
+
+type warmupStatsFile struct {
+	Counters Counters
+	Hist     []byte
+	Duration time.Duration
+}
+
+type connectionStatsFile struct {
+	Counters Counters
+	Hist     []byte
+}
+
+// WriteResults writes the results to the results file in JSON format, the results can be read back using ReadResults.
+func WriteResults(w io.Writer, r Results) error {
+	var c resultsCodec
+	f := resultsFile{
+		Version:   ResultsFileVersion,
+		Benchmark: r.Benchmark,
+		Start:     r.Start,
+		Duration:  r.Duration,
+		Stats:     c.encodeStats(r.Stats),
+	}
+	for _, it := range r.Iterations {
+		f.Iterations = append(f.Iterations, iterationFile{Start: it.Start, Duration: it.Duration, Stats: c.encodeStats(it.Stats)})
+	}
+	if c.err != nil {
+		return fmt.Errorf("failed to encode histogram: %w", c.err)
+	}
+	return json.NewEncoder(w).Encode(f)
+}
+
+// ReadResults reads the results from the results file written by WriteResults.
+func ReadResults(r io.Reader) (Results, error) {
+	var f resultsFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return Results{}, fmt.Errorf("failed to read results file: %w", err)
+	}
+	if f.Version != ResultsFileVersion {
+		return Results{}, fmt.Errorf("unsupported results file version %d, supported version is %d", f.Version, ResultsFileVersion)
+	}
+	if f.Benchmark == nil {
+		return Results{}, errors.New("results file does not contain benchmark configuration")
+	}
+
+	var c resultsCodec
+	res := Results{
+		Benchmark: f.Benchmark,
+		Start:     f.Start,
+		Duration:  f.Duration,
+		Stats:     c.decodeStats(f.Stats),
+	}
+	for _, it := range f.Iterations {
+		res.Iterations = append(res.Iterations, IterationResult{Start: it.Start, Duration: it.Duration, Stats: c.decodeStats(it.Stats)})
+	}
+	if c.err != nil {
+		return Results{}, fmt.Errorf("failed to decode histogram: %w", c.err)
+	}
+	return res, nil
+}
+
+// resultsCodec converts results to and from their serialized form, it keeps the first error of encoding or decoding of the histograms.
+type resultsCodec struct {
+	err error
+}
+
+func (c *resultsCodec) encode(h *hdrhistogram.Histogram) []byte {
+	if h == nil || c.err != nil {
+		return nil
+	}
+	encoded, err := h.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+	if err != nil {
+		c.err = err
+	}
+	return encoded
+}
+
+func (c *resultsCodec) decode(encoded []byte) *hdrhistogram.Histogram {
+	if encoded == nil || c.err != nil {
+		return nil
+	}
+	h, err := hdrhistogram.Decode(encoded)
+	if err != nil {
+		c.err = err
+	}
+	return h
+}
+
+func (c *resultsCodec) encodeHists(hists map[string]*hdrhistogram.Histogram) map[string][]byte {
+	if hists == nil {
+		return nil
+	}
+	res := make(map[string][]byte, len(hists))
+	for k, v := range hists {
+		res[k] = c.encode(v)
+	}
+	return res
+}
+
+func (c *resultsCodec) decodeHists(hists map[string][]byte) map[string]*hdrhistogram.Histogram {
+	if hists == nil {
+		return nil
+	}
+	res := make(map[string]*hdrhistogram.Histogram, len(hists))
+	for k, v := range hists {
+		res[k] = c.decode(v)
+	}
+	return res
+}
+
+func (c *resultsCodec) encodeStats(stats []*ResultStats) []*resultStatsFile {
+	var res []*resultStatsFile
+	for _, s := range stats {
+		res = append(res, c.encodeResultStats(s))
+	}
+	return res
+}
+
+func (c *resultsCodec) decodeStats(stats []*resultStatsFile) []*ResultStats {
+	var res []*ResultStats
+	for _, s := range stats {
+		res = append(res, c.decodeResultStats(s))
+	}
+	return res
+}
+
+func (c *resultsCodec) encodeResultStats(rs *ResultStats) *resultStatsFile {
+	if rs == nil {
+		return nil
+	}
+	f := &resultStatsFile{
+		Codes:           rs.Codes,
+		Qtypes:          rs.Qtypes,
+		Hist:            c.encode(rs.Hist),
+		Timings:         rs.Timings,
+		Counters:        rs.Counters,
+		ErrorGroups:     rs.ErrorGroups,
+		DoHStatusCodes:  rs.DoHStatusCodes,
+		EDECodes:        rs.EDECodes,
+		QtypeHists:      c.encodeHists(rs.QtypeHists),
+		RcodeHists:      c.encodeHists(rs.RcodeHists),
+		ErrorHist:       c.encode(rs.ErrorHist),
+		TTLHist:         c.encode(rs.TTLHist),
+		RateAdjustments: rs.RateAdjustments,
+	}
+	for _, e := range rs.Errors {
+		f.Errors = append(f.Errors, errorDatapointFile{Start: e.Start, Err: e.Err.Error()})
+	}
+	if rs.ErrorClasses != nil {
+		f.ErrorClasses = []errorClassFile{}
+		for k, v := range rs.ErrorClasses {
+			f.ErrorClasses = append(f.ErrorClasses, errorClassFile{ErrorClass: k, Count: v})
+		}
+		sort.Slice(f.ErrorClasses, func(i, j int) bool { return f.ErrorClasses[i].String() < f.ErrorClasses[j].String() })
+	}
+	if rs.TimeSeries != nil {
+		f.TimeSeries = &timeSeriesFile{
+			Start:   rs.TimeSeries.Start,
+			Width:   rs.TimeSeries.Width,
+			HistMin: rs.TimeSeries.histMin,
+			HistMax: rs.TimeSeries.histMax,
+		}
+		for _, b := range rs.TimeSeries.Buckets {
+			f.TimeSeries.Buckets = append(f.TimeSeries.Buckets, timeBucketFile{
+				Responses: b.Responses,
+				Errors:    b.Errors,
+				Hist:      c.encode(b.Hist),
+				Rcodes:    b.Rcodes,
+			})
+		}
+	}
+	if rs.AuthenticatedDomains != nil {
+		f.AuthenticatedDomains = []string{}
+		for k := range rs.AuthenticatedDomains {
+			f.AuthenticatedDomains = append(f.AuthenticatedDomains, k)
+		}
+		sort.Strings(f.AuthenticatedDomains)
+	}
+	if rs.Sizes != nil {
+		f.Sizes = &sizeStatsFile{
+			RequestHist:    c.encode(rs.Sizes.RequestHist),
+			ResponseHist:   c.encode(rs.Sizes.ResponseHist),
+			BytesSent:      rs.Sizes.BytesSent,
+			BytesReceived:  rs.Sizes.BytesReceived,
+			Qtypes:         rs.Sizes.Qtypes,
+			Largest:        rs.Sizes.Largest,
+			NearBufferSize: rs.Sizes.NearBufferSize,
+		}
+	}
+	if rs.Domains != nil {
+		f.Domains = []domainStatsFile{}
+		for k, v := range rs.Domains {
+			f.Domains = append(f.Domains, domainStatsFile{QuestionKey: k, Count: v.Count, Codes: v.Codes, IOError: v.IOError, Hist: c.encode(v.Hist)})
+		}
+		sort.Slice(f.Domains, func(i, j int) bool { return lessQuestionKey(f.Domains[i].QuestionKey, f.Domains[j].QuestionKey) })
+	}
+	if rs.TTLs != nil {
+		f.TTLs = []ttlStatsFile{}
+		for k, v := range rs.TTLs {
+			f.TTLs = append(f.TTLs, ttlStatsFile{QuestionKey: k, TTLStats: *v})
+		}
+		sort.Slice(f.TTLs, func(i, j int) bool { return lessQuestionKey(f.TTLs[i].QuestionKey, f.TTLs[j].QuestionKey) })
+	}
+	if rs.Consistency != nil {
+		f.Consistency = &consistencyStatsFile{
+			Servers:     make(map[string]*resultStatsFile, len(rs.Consistency.Servers)),
+			Compared:    rs.Consistency.Compared,
+			Mismatched:  rs.Consistency.Mismatched,
+			Skipped:     rs.Consistency.Skipped,
+			Differences: []differenceFile{},
+		}
+		for k, v := range rs.Consistency.Servers {
+			f.Consistency.Servers[k] = c.encodeResultStats(v)
+		}
+		for k, v := range rs.Consistency.Differences {
+			f.Consistency.Differences = append(f.Consistency.Differences, differenceFile{DifferenceKey: k, Difference: *v})
+		}
+		sort.Slice(f.Consistency.Differences, func(i, j int) bool {
+			a, b := f.Consistency.Differences[i].DifferenceKey, f.Consistency.Differences[j].DifferenceKey
+			if a.Server != b.Server {
+				return a.Server < b.Server
+			}
+			if a.Domain != b.Domain {
+				return a.Domain < b.Domain
+			}
+			if a.Qtype != b.Qtype {
+				return a.Qtype < b.Qtype
+			}
+			return a.Kind < b.Kind
+		})
+	}
+	if rs.Warmup != nil {
+		f.Warmup = &warmupStatsFile{Counters: rs.Warmup.Counters, Hist: c.encode(rs.Warmup.Hist), Duration: rs.Warmup.Duration}
+	}
+	if rs.Connections != nil {
+		f.Connections = make(map[string]*connectionStatsFile, len(rs.Connections))
+		for k, v := range rs.Connections {
+			f.Connections[k] = &connectionStatsFile{Counters: v.Counters, Hist: c.encode(v.Hist)}
+		}
+	}
+	return f
+}
+
+func (c *resultsCodec) decodeResultStats(f *resultStatsFile) *ResultStats {
+	if f == nil {
+		return nil
+	}
+	rs := &ResultStats{
+		Codes:           f.Codes,
+		Qtypes:          f.Qtypes,
+		Hist:            c.decode(f.Hist),
+		Timings:         f.Timings,
+		Counters:        f.Counters,
+		ErrorGroups:     f.ErrorGroups,
+		DoHStatusCodes:  f.DoHStatusCodes,
+		EDECodes:        f.EDECodes,
+		QtypeHists:      c.decodeHists(f.QtypeHists),
+		RcodeHists:      c.decodeHists(f.RcodeHists),
+		ErrorHist:       c.decode(f.ErrorHist),
+		TTLHist:         c.decode(f.TTLHist),
+		RateAdjustments: f.RateAdjustments,
+	}
+	for _, e := range f.Errors {
+		// the original errors are not preserved, only their messages
+		rs.Errors = append(rs.Errors, ErrorDatapoint{Start: e.Start, Err: errors.New(e.Err)})
+	}
+	if f.ErrorClasses != nil {
+		rs.ErrorClasses = make(map[ErrorClass]int64, len(f.ErrorClasses))
+		for _, e := range f.ErrorClasses {
+			rs.ErrorClasses[e.ErrorClass] = e.Count
+		}
+	}
+	if f.TimeSeries != nil {
+		rs.TimeSeries = &TimeSeries{
+			Start:   f.TimeSeries.Start,
+			Width:   f.TimeSeries.Width,
+			histMin: f.TimeSeries.HistMin,
+			histMax: f.TimeSeries.HistMax,
+		}
+		for _, b := range f.TimeSeries.Buckets {
+			rs.TimeSeries.Buckets = append(rs.TimeSeries.Buckets, TimeBucket{
+				Responses: b.Responses,
+				Errors:    b.Errors,
+				Hist:      c.decode(b.Hist),
+				Rcodes:    b.Rcodes,
+			})
+		}
+	}
+	if f.AuthenticatedDomains != nil {
+		rs.AuthenticatedDomains = make(map[string]struct{}, len(f.AuthenticatedDomains))
+		for _, d := range f.AuthenticatedDomains {
+			rs.AuthenticatedDomains[d] = struct{}{}
+		}
+	}
+	if f.Sizes != nil {
+		rs.Sizes = &SizeStats{
+			RequestHist:    c.decode(f.Sizes.RequestHist),
+			ResponseHist:   c.decode(f.Sizes.ResponseHist),
+			BytesSent:      f.Sizes.BytesSent,
+			BytesReceived:  f.Sizes.BytesReceived,
+			Qtypes:         f.Sizes.Qtypes,
+			Largest:        f.Sizes.Largest,
+			NearBufferSize: f.Sizes.NearBufferSize,
+		}
+	}
+	if f.Domains != nil {
+		rs.Domains = make(map[QuestionKey]*DomainStats, len(f.Domains))
+		for _, d := range f.Domains {
+			rs.Domains[d.QuestionKey] = &DomainStats{Count: d.Count, Codes: d.Codes, IOError: d.IOError, Hist: c.decode(d.Hist)}
+		}
+	}
+	if f.TTLs != nil {
+		rs.TTLs = make(map[QuestionKey]*TTLStats, len(f.TTLs))
+		for _, t := range f.TTLs {
+			ttl := t.TTLStats
+			rs.TTLs[t.QuestionKey] = &ttl
+		}
+	}
+	if f.Consistency != nil {
+		rs.Consistency = &ConsistencyStats{
+			Servers:     make(map[string]*ResultStats, len(f.Consistency.Servers)),
+			Compared:    f.Consistency.Compared,
+			Mismatched:  f.Consistency.Mismatched,
+			Skipped:     f.Consistency.Skipped,
+			Differences: make(map[DifferenceKey]*Difference, len(f.Consistency.Differences)),
+		}
+		for k, v := range f.Consistency.Servers {
+			rs.Consistency.Servers[k] = c.decodeResultStats(v)
+		}
+		for _, d := range f.Consistency.Differences {
+			diff := d.Difference
+			rs.Consistency.Differences[d.DifferenceKey] = &diff
+		}
+	}
+	if f.Warmup != nil {
+		rs.Warmup = &WarmupStats{Counters: f.Warmup.Counters, Hist: c.decode(f.Warmup.Hist), Duration: f.Warmup.Duration}
+	}
+	if f.Connections != nil {
+		rs.Connections = make(map[string]*ConnectionStats, len(f.Connections))
+		for k, v := range f.Connections {
+			rs.Connections[k] = &ConnectionStats{Counters: v.Counters, Hist: c.decode(v.Hist)}
+		}
+	}
+	return rs
+}
+
+func lessQuestionKey(a, b QuestionKey) bool {
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.Qtype < b.Qtype
+}
@@ -0,0 +1,144 @@
+package dnsbench
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResults_write_read(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := &Benchmark{
+		Server:      "8.8.8.8:53",
+		Types:       []string{"A", "AAAA"},
+		Concurrency: 2,
+		Rcodes:      true,
+		HistMax:     time.Second,
+		HistPre:     2,
+		DomainStats: true,
+		TTLAnalysis: true,
+		WorkerStats: true,
+		Percentiles: []float64{99.9, 50},
+		Queries:     []string{"example.org"},
+		runStart:    start,
+	}
+
+	req := &dns.Msg{
+		MsgHdr:   dns.MsgHdr{Id: 1},
+		Question: []dns.Question{{Name: "example.org.", Qtype: dns.TypeA, Qclass: dns.ClassINET}},
+	}
+	resp := &dns.Msg{
+		MsgHdr: dns.MsgHdr{Id: 1, Rcode: dns.RcodeSuccess, Response: true, AuthenticatedData: true},
+		Answer: []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300}, A: net.ParseIP("127.0.0.1")}},
+	}
+	rs := newResultStats(b)
+	rs.record(req, resp, newMessageSizes(req, resp), nil, start, 5*time.Millisecond)
+	rs.record(req, resp, newMessageSizes(req, resp), nil, start.Add(1500*time.Millisecond), 10*time.Millisecond)
+	rs.record(req, nil, newMessageSizes(req, nil), errors.New("i/o timeout"), start.Add(2*time.Second), time.Second)
+	rs.recordConnection("127.0.0.1:5353", req, resp, nil, 5*time.Millisecond)
+	rs.AuthenticatedDomains = map[string]struct{}{"example.org.": {}}
+	rs.Warmup = newWarmupStats(b)
+	rs.Warmup.record(req, resp, nil, time.Millisecond)
+	rs.Warmup.Duration = time.Second
+	rs.Consistency = &ConsistencyStats{
+		Servers:     map[string]*ResultStats{"1.1.1.1:53": newResultStats(b)},
+		Compared:    2,
+		Mismatched:  1,
+		Differences: map[DifferenceKey]*Difference{{Server: "1.1.1.1:53", Domain: "example.org.", Qtype: "A", Kind: RcodeDifference}: {Count: 1, Expected: "NOERROR", Actual: "SERVFAIL"}},
+	}
+	rs.RateAdjustments = []RateAdjustment{{Time: start, Elapsed: time.Second, PreviousRate: 100, Rate: 110, Latency: time.Millisecond, QPS: 99}}
+
+	results := Results{
+		Benchmark: b,
+		Start:     start,
+		Duration:  3 * time.Second,
+		Stats:     []*ResultStats{rs, newResultStats(b)},
+	}
+
+	buf := bytes.Buffer{}
+	require.NoError(t, WriteResults(&buf, results))
+
+	read, err := ReadResults(&buf)
+	require.NoError(t, err)
+
+	// unexported state of the benchmark and of the results is not written
+	b.runStart = time.Time{}
+	for _, s := range append(results.Stats, rs.Consistency.Servers["1.1.1.1:53"]) {
+		s.rawSamples, s.seenTimings, s.seenErrors = 0, 0, 0
+		for _, ts := range s.TTLs {
+			ts.cachedTTL, ts.cachedAt = 0, time.Time{}
+			ts.lastTTL, ts.lastTTLAt, ts.lastTTLHits = 0, time.Time{}, 0
+		}
+	}
+	assert.Equal(t, results, read)
+}
+
+func TestResults_write_read_iterations(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := &Benchmark{Server: "8.8.8.8:53", HistMax: time.Second, HistPre: 1, Iterations: 2}
+
+	results := Results{
+		Benchmark: b,
+		Start:     start,
+		Duration:  3 * time.Second,
+		Iterations: []IterationResult{
+			{Start: start, Duration: time.Second, Stats: []*ResultStats{newResultStats(b)}},
+			{Start: start.Add(2 * time.Second), Duration: time.Second, Stats: []*ResultStats{newResultStats(b)}},
+		},
+	}
+
+	buf := bytes.Buffer{}
+	require.NoError(t, WriteResults(&buf, results))
+
+	read, err := ReadResults(&buf)
+	require.NoError(t, err)
+	for _, it := range results.Iterations {
+		for _, s := range it.Stats {
+			s.rawSamples = 0
+		}
+	}
+	assert.Equal(t, results, read)
+}
+
+func TestReadResults_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{
+			name:    "not JSON",
+			file:    "results",
+			wantErr: "failed to read results file",
+		},
+		{
+			name:    "unsupported version",
+			file:    `{"Version": 2, "Benchmark": {}}`,
+			wantErr: "unsupported results file version 2, supported version is 1",
+		},
+		{
+			name:    "missing benchmark",
+			file:    `{"Version": 1}`,
+			wantErr: "results file does not contain benchmark configuration",
+		},
+		{
+			name:    "invalid histogram",
+			file:    `{"Version": 1, "Benchmark": {}, "Stats": [{"Hist": "aGlzdG9ncmFt"}]}`,
+			wantErr: "failed to decode histogram",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadResults(strings.NewReader(tt.file))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package dnsbench
+
+import (
+	"sort"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+)
+
+const (
+	// maxMessageSize is the maximal size of DNS message in wire format.
+	maxMessageSize = 65535
+
+	// maxLargestResponses limits number of the largest responses tracked.
+	maxLargestResponses = 10
+
+	// nearBufferSizeRatio is the ratio of EDNS0 buffer size, above which the response is considered close to the buffer size.
+	nearBufferSizeRatio = 0.9
+)
+
+// newSizeHistogram creates histogram suitable for recording DNS message sizes in bytes.
+func newSizeHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(0, maxMessageSize, 3)
+}
+
+// SizeStats represents statistics of wire sizes of DNS requests and responses. The sizes are estimated from the DNS messages
+// packed to the wire format, they do not include the framing of the transport (TCP length prefix, HTTP, TLS or QUIC overhead).
+// Response sizes assume DNS name compression, since the information about compression is lost when the response is unpacked.
+type SizeStats struct {
+	// RequestHist is a histogram of estimated request sizes in bytes.
+	RequestHist *hdrhistogram.Histogram
+	// ResponseHist is a histogram of estimated response sizes in bytes.
+	ResponseHist *hdrhistogram.Histogram
+	// BytesSent is the estimated total size of all sent requests in bytes.
+	BytesSent int64
+	// BytesReceived is the estimated total size of all received responses in bytes.
+	BytesReceived int64
+	// Qtypes holds sizes of requests and responses per query type.
+	Qtypes map[string]*QtypeSizes
+	// Largest holds the largest responses ordered by size descending, each question is present at most once.
+	Largest []ResponseSize
+	// NearBufferSize is counter of responses, which size was close to the EDNS0 buffer size advertised in the request.
+	NearBufferSize int64
+}
+
+// QtypeSizes represents sizes of requests and responses of single query type.
+type QtypeSizes struct {
+	Requests      int64
+	RequestBytes  int64
+	Responses     int64
+	ResponseBytes int64
+}
+
+// Amplification returns ratio between mean response size and mean request size.
+func (q *QtypeSizes) Amplification() float64 {
+	if q.Requests == 0 || q.Responses == 0 || q.RequestBytes == 0 {
+		return 0
+	}
+	return (float64(q.ResponseBytes) / float64(q.Responses)) / (float64(q.RequestBytes) / float64(q.Requests))
+}
+
+// ResponseSize represents estimated size of the response for single question.
+type ResponseSize struct {
+	QuestionKey
+	Size int
+}
+
+func newSizeStats() *SizeStats {
+	return &SizeStats{
+		RequestHist:  newSizeHistogram(),
+		ResponseHist: newSizeHistogram(),
+		Qtypes:       make(map[string]*QtypeSizes),
+	}
+}
+
+// messageSizes holds estimated wire sizes of the request and of the response of single query, they are computed once
+// for each query and shared by the results and the metrics.
+type messageSizes struct {
+	request  int
+	response int
+}
+
+// newMessageSizes computes wire sizes of the request and of the response, response size is zero when there is no response.
+func newMessageSizes(req, resp *dns.Msg) messageSizes {
+	sizes := messageSizes{request: req.Len()}
+	if resp != nil {
+		sizes.response = responseSize(resp)
+	}
+	return sizes
+}
+
+func (s *SizeStats) recordRequest(req *dns.Msg, size int) {
+	s.RequestHist.RecordValue(int64(size))
+	s.BytesSent += int64(size)
+	q := s.qtype(req)
+	q.Requests++
+	q.RequestBytes += int64(size)
+}
+
+func (s *SizeStats) recordResponse(req *dns.Msg, size int) {
+	s.ResponseHist.RecordValue(int64(size))
+	s.BytesReceived += int64(size)
+	q := s.qtype(req)
+	q.Responses++
+	q.ResponseBytes += int64(size)
+
+	if opt := req.IsEdns0(); opt != nil && float64(size) >= nearBufferSizeRatio*float64(opt.UDPSize()) {
+		s.NearBufferSize++
+	}
+
+	s.recordLargest(ResponseSize{
+		QuestionKey: QuestionKey{Name: req.Question[0].Name, Qtype: dns.TypeToString[req.Question[0].Qtype]},
+		Size:        size,
+	})
+}
+
+func (s *SizeStats) qtype(req *dns.Msg) *QtypeSizes {
+	qtype := dns.TypeToString[req.Question[0].Qtype]
+	q, ok := s.Qtypes[qtype]
+	if !ok {
+		q = &QtypeSizes{}
+		s.Qtypes[qtype] = q
+	}
+	return q
+}
+
+func (s *SizeStats) recordLargest(rs ResponseSize) {
+	if len(s.Largest) == maxLargestResponses && rs.Size <= s.Largest[len(s.Largest)-1].Size {
+		return
+	}
+	s.Largest = MergeLargest(s.Largest, []ResponseSize{rs})
+}
+
+// MergeLargest merges responses into the list of the largest responses, keeping only the largest response for each question.
+// The list is ordered by size descending and limited to 10 responses.
+func MergeLargest(largest []ResponseSize, responses []ResponseSize) []ResponseSize {
+	largest = append([]ResponseSize(nil), largest...)
+	for _, rs := range responses {
+		found := false
+		for i := range largest {
+			if largest[i].QuestionKey == rs.QuestionKey {
+				largest[i].Size = max(largest[i].Size, rs.Size)
+				found = true
+				break
+			}
+		}
+		if !found {
+			largest = append(largest, rs)
+		}
+	}
+	sort.SliceStable(largest, func(i, j int) bool {
+		if largest[i].Size != largest[j].Size {
+			return largest[i].Size > largest[j].Size
+		}
+		if largest[i].Name != largest[j].Name {
+			return largest[i].Name < largest[j].Name
+		}
+		return largest[i].Qtype < largest[j].Qtype
+	})
+	if len(largest) > maxLargestResponses {
+		largest = largest[:maxLargestResponses]
+	}
+	return largest
+}
+
+// responseSize returns estimated wire size of the response. Responses are unpacked without the information about compression,
+// since servers usually compress the responses, compression is assumed. The response itself is not modified,
+// since it may be shared with the observers.
+func responseSize(resp *dns.Msg) int {
+	compressed := *resp
+	compressed.Compress = true
+	return compressed.Len()
+}
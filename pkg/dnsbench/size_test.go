@@ -0,0 +1,86 @@
+package dnsbench
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSizeStats_record(t *testing.T) {
+	req := &dns.Msg{Question: []dns.Question{{Name: "example.org.", Qtype: dns.TypeTXT, Qclass: dns.ClassINET}}}
+	req.SetEdns0(512, false)
+
+	small := new(dns.Msg)
+	small.SetReply(req)
+	small.Answer = append(small.Answer, &dns.TXT{Hdr: dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeTXT, Class: dns.ClassINET}, Txt: []string{"small"}})
+
+	large := new(dns.Msg)
+	large.SetReply(req)
+	for range 4 {
+		large.Answer = append(large.Answer, &dns.TXT{Hdr: dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+			Txt: []string{string(make([]byte, 100))}})
+	}
+
+	s := newSizeStats()
+	smallSizes := newMessageSizes(req, small)
+	s.recordRequest(req, smallSizes.request)
+	s.recordResponse(req, smallSizes.response)
+	largeSizes := newMessageSizes(req, large)
+	s.recordRequest(req, largeSizes.request)
+	s.recordResponse(req, largeSizes.response)
+
+	assert.EqualValues(t, 2*req.Len(), s.BytesSent)
+	assert.EqualValues(t, responseSize(small)+responseSize(large), s.BytesReceived)
+	assert.EqualValues(t, 2, s.RequestHist.TotalCount())
+	assert.EqualValues(t, 2, s.ResponseHist.TotalCount())
+	assert.EqualValues(t, 1, s.NearBufferSize, "only large response should be close to the EDNS0 buffer size")
+	assert.Equal(t, []ResponseSize{{QuestionKey: QuestionKey{Name: "example.org.", Qtype: "TXT"}, Size: responseSize(large)}}, s.Largest)
+	assert.Equal(t, &QtypeSizes{
+		Requests:      2,
+		RequestBytes:  int64(2 * req.Len()),
+		Responses:     2,
+		ResponseBytes: int64(responseSize(small) + responseSize(large)),
+	}, s.Qtypes["TXT"])
+	assert.False(t, large.Compress, "response compression flag should not be changed")
+}
+
+func Test_newMessageSizes(t *testing.T) {
+	req := &dns.Msg{Question: []dns.Question{{Name: "example.org.", Qtype: dns.TypeA, Qclass: dns.ClassINET}}}
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	for range 4 {
+		resp.Answer = append(resp.Answer, &dns.A{Hdr: dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeA, Class: dns.ClassINET}})
+	}
+	uncompressed := resp.Len()
+
+	sizes := newMessageSizes(req, resp)
+
+	assert.Equal(t, req.Len(), sizes.request)
+	assert.Less(t, sizes.response, uncompressed, "response size should assume name compression")
+	assert.False(t, resp.Compress, "response compression flag should not be changed")
+	assert.Equal(t, uncompressed, resp.Len())
+	assert.Equal(t, messageSizes{request: req.Len()}, newMessageSizes(req, nil), "response size should be zero without response")
+}
+
+func TestMergeLargest(t *testing.T) {
+	key := func(name string) QuestionKey {
+		return QuestionKey{Name: name, Qtype: "A"}
+	}
+	var largest []ResponseSize
+	for i := range 15 {
+		largest = MergeLargest(largest, []ResponseSize{{QuestionKey: key(string(rune('a' + i))), Size: 100 + i}})
+	}
+	largest = MergeLargest(largest, []ResponseSize{{QuestionKey: key("a"), Size: 1000}, {QuestionKey: key("o"), Size: 50}})
+
+	want := []ResponseSize{{QuestionKey: key("a"), Size: 1000}}
+	for i := 14; i > 5; i-- {
+		want = append(want, ResponseSize{QuestionKey: key(string(rune('a' + i))), Size: 100 + i})
+	}
+	assert.Equal(t, want, largest)
+}
+
+func TestQtypeSizes_Amplification(t *testing.T) {
+	assert.InDelta(t, 4.0, (&QtypeSizes{Requests: 2, RequestBytes: 60, Responses: 1, ResponseBytes: 120}).Amplification(), 0.001)
+	assert.Zero(t, (&QtypeSizes{Requests: 2, RequestBytes: 60}).Amplification())
+}
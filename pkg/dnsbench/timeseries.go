@@ -0,0 +1,174 @@
+package dnsbench
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const (
+	// MaxTimeBuckets is the maximum number of buckets kept by TimeSeries. When a longer time span needs to be recorded, the width
+	// of the buckets is doubled and the neighbouring buckets are merged, so the memory used by TimeSeries does not depend on the duration of the benchmark.
+	MaxTimeBuckets = 256
+
+	// timeSeriesPrecision is a precision of the latency histograms of TimeSeries buckets, single significant figure is enough for percentiles
+	// plotted over time and keeps the buckets small.
+	timeSeriesPrecision = 1
+)
+
+// TimeSeries holds results of the benchmark aggregated into consecutive time buckets of the same width. The buckets start at 1 second width,
+// which is doubled whenever more than MaxTimeBuckets buckets would be needed.
+type TimeSeries struct {
+	// Start is the start of the first bucket.
+	Start time.Time
+	// Width is the time span covered by each bucket.
+	Width time.Duration
+	// Buckets are the consecutive buckets, bucket i covers time span starting at Start + i*Width.
+	Buckets []TimeBucket
+
+	histMin int64
+	histMax int64
+}
+
+// TimeBucket holds results of the benchmark within single time bucket.
+type TimeBucket struct {
+	// Responses counts responses received within the bucket.
+	Responses int64
+	// Errors counts IO errors within the bucket.
+	Errors int64
+	// Hist is a histogram of latencies of the responses received within the bucket, it is nil when there were no responses.
+	Hist *hdrhistogram.Histogram
+	// Rcodes counts response codes of the responses received within the bucket, it is nil when no response codes were recorded.
+	Rcodes map[int]int64
+}
+
+// NewTimeSeries creates empty time series starting at start, when start is zero, the time series starts at the second of the first recorded value.
+// The latency histograms of the buckets track values between histMin and histMax.
+func NewTimeSeries(start time.Time, histMin, histMax time.Duration) *TimeSeries {
+	return &TimeSeries{Start: start, Width: time.Second, histMin: histMin.Nanoseconds(), histMax: histMax.Nanoseconds()}
+}
+
+// BucketStart returns start of the i-th bucket.
+func (ts *TimeSeries) BucketStart(i int) time.Time {
+	return ts.Start.Add(time.Duration(i) * ts.Width)
+}
+
+// RecordResponse records response received at t with latency duration.
+func (ts *TimeSeries) RecordResponse(t time.Time, duration time.Duration) {
+	bucket := ts.bucket(t)
+	bucket.Responses++
+	if bucket.Hist == nil {
+		bucket.Hist = hdrhistogram.New(ts.histMin, ts.histMax, timeSeriesPrecision)
+	}
+	bucket.Hist.RecordValue(duration.Nanoseconds())
+}
+
+// RecordRcode records response code of the response received at t.
+func (ts *TimeSeries) RecordRcode(t time.Time, rcode int) {
+	bucket := ts.bucket(t)
+	if bucket.Rcodes == nil {
+		bucket.Rcodes = make(map[int]int64)
+	}
+	bucket.Rcodes[rcode]++
+}
+
+// RecordError records IO error of the request sent at t.
+func (ts *TimeSeries) RecordError(t time.Time) {
+	ts.bucket(t).Errors++
+}
+
+// bucket returns the bucket covering t, the series is coarsened and extended as needed.
+func (ts *TimeSeries) bucket(t time.Time) *TimeBucket {
+	if ts.Start.IsZero() {
+		ts.Start = t.Truncate(time.Second)
+	}
+	i := ts.index(t)
+	for i >= MaxTimeBuckets {
+		ts.coarsen()
+		i = ts.index(t)
+	}
+	for len(ts.Buckets) <= i {
+		ts.Buckets = append(ts.Buckets, TimeBucket{})
+	}
+	return &ts.Buckets[i]
+}
+
+// index returns index of the bucket covering t, the values before the start of the series are recorded to the first bucket.
+func (ts *TimeSeries) index(t time.Time) int {
+	return max(0, int(t.Sub(ts.Start)/ts.Width))
+}
+
+// coarsen doubles the width of the buckets by merging each two neighbouring buckets.
+func (ts *TimeSeries) coarsen() {
+	buckets := make([]TimeBucket, (len(ts.Buckets)+1)/2)
+	for i := range ts.Buckets {
+		buckets[i/2].merge(&ts.Buckets[i])
+	}
+	ts.Buckets = buckets
+	ts.Width *= 2
+}
+
+// merge adds the results of other bucket to this bucket.
+func (b *TimeBucket) merge(other *TimeBucket) {
+	b.Responses += other.Responses
+	b.Errors += other.Errors
+	if other.Rcodes != nil {
+		if b.Rcodes == nil {
+			b.Rcodes = make(map[int]int64)
+		}
+		for k, v := range other.Rcodes {
+			b.Rcodes[k] += v
+		}
+	}
+	if other.Hist == nil {
+		return
+	}
+	if b.Hist == nil {
+		b.Hist = hdrhistogram.Import(other.Hist.Export())
+		return
+	}
+	b.Hist.Merge(other.Hist)
+}
+
+// MergeTimeSeries merges the time series into a new time series starting at the earliest start and using the widest buckets
+// of the merged time series. It returns nil, when there is no time series to merge.
+func MergeTimeSeries(series ...*TimeSeries) *TimeSeries {
+	var merged *TimeSeries
+	for _, s := range series {
+		if s == nil || s.Start.IsZero() {
+			continue
+		}
+		if merged == nil {
+			merged = &TimeSeries{Start: s.Start, Width: s.Width, histMin: s.histMin, histMax: s.histMax}
+		}
+		if s.Start.Before(merged.Start) {
+			merged.Start = s.Start
+		}
+		merged.Width = max(merged.Width, s.Width)
+	}
+	if merged == nil {
+		return nil
+	}
+	for _, s := range series {
+		if s == nil || s.Start.IsZero() {
+			continue
+		}
+		for i := range s.Buckets {
+			merged.bucket(s.BucketStart(i)).merge(&s.Buckets[i])
+		}
+	}
+	return merged
+}
+
+// sample adds the value to the reservoir holding at most size values, seen is the number of all values offered to the reservoir
+// including this one. Each offered value is kept in the reservoir with the same probability (reservoir sampling).
+func sample[T any](reservoir []T, size int, seen int64, v T) []T {
+	if len(reservoir) < size {
+		return append(reservoir, v)
+	}
+	if j := rand.Int64N(seen); j < int64(size) {
+		reservoir[j] = v
+	}
+	return reservoir
+}
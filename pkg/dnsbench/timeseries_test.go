@@ -0,0 +1,152 @@
+package dnsbench
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeSeries_record(t *testing.T) {
+	start := time.Unix(1000, 0)
+	ts := NewTimeSeries(start, 0, time.Second)
+
+	ts.RecordResponse(start.Add(100*time.Millisecond), 10*time.Millisecond)
+	ts.RecordResponse(start.Add(900*time.Millisecond), 20*time.Millisecond)
+	ts.RecordError(start.Add(2500 * time.Millisecond))
+	ts.RecordResponse(start.Add(-time.Second), 30*time.Millisecond)
+
+	assert.Equal(t, time.Second, ts.Width)
+	require.Len(t, ts.Buckets, 3)
+	assert.EqualValues(t, 3, ts.Buckets[0].Responses, "responses before the start should be recorded to the first bucket")
+	assert.EqualValues(t, 3, ts.Buckets[0].Hist.TotalCount())
+	assert.Zero(t, ts.Buckets[1].Responses)
+	assert.Nil(t, ts.Buckets[1].Hist, "histogram should not be allocated for empty bucket")
+	assert.EqualValues(t, 1, ts.Buckets[2].Errors)
+	assert.Nil(t, ts.Buckets[2].Hist)
+	assert.Equal(t, start.Add(2*time.Second), ts.BucketStart(2))
+}
+
+func TestTimeSeries_record_zero_start(t *testing.T) {
+	ts := NewTimeSeries(time.Time{}, 0, time.Second)
+
+	ts.RecordResponse(time.Unix(1000, 600), time.Millisecond)
+
+	assert.Equal(t, time.Unix(1000, 0), ts.Start)
+	require.Len(t, ts.Buckets, 1)
+	assert.EqualValues(t, 1, ts.Buckets[0].Responses)
+}
+
+func TestTimeSeries_record_bounded(t *testing.T) {
+	start := time.Unix(1000, 0)
+	ts := NewTimeSeries(start, 0, time.Second)
+
+	// simulate 24 hours long benchmark
+	day := 24 * time.Hour
+	for offset := time.Duration(0); offset < day; offset += 10 * time.Second {
+		ts.RecordResponse(start.Add(offset), time.Millisecond)
+		ts.RecordError(start.Add(offset))
+	}
+
+	assert.LessOrEqual(t, len(ts.Buckets), MaxTimeBuckets)
+	assert.Equal(t, 512*time.Second, ts.Width)
+	assert.Equal(t, start, ts.Start)
+	var responses, errs, hist int64
+	for _, b := range ts.Buckets {
+		responses += b.Responses
+		errs += b.Errors
+		hist += b.Hist.TotalCount()
+	}
+	assert.EqualValues(t, day/(10*time.Second), responses)
+	assert.EqualValues(t, day/(10*time.Second), errs)
+	assert.EqualValues(t, day/(10*time.Second), hist)
+}
+
+func TestTimeSeries_record_rcodes(t *testing.T) {
+	start := time.Unix(1000, 0)
+	ts := NewTimeSeries(start, 0, time.Second)
+
+	ts.RecordRcode(start, dns.RcodeSuccess)
+	ts.RecordRcode(start.Add(100*time.Millisecond), dns.RcodeNameError)
+	ts.RecordRcode(start.Add(200*time.Millisecond), dns.RcodeSuccess)
+	ts.RecordError(start.Add(time.Second))
+
+	require.Len(t, ts.Buckets, 2)
+	assert.Equal(t, map[int]int64{dns.RcodeSuccess: 2, dns.RcodeNameError: 1}, ts.Buckets[0].Rcodes)
+	assert.Nil(t, ts.Buckets[1].Rcodes, "response codes should not be allocated for bucket without responses")
+
+	other := NewTimeSeries(start, 0, time.Second)
+	other.RecordRcode(start, dns.RcodeNameError)
+	other.RecordRcode(start.Add(time.Second), dns.RcodeServerFailure)
+
+	merged := MergeTimeSeries(ts, other)
+
+	require.Len(t, merged.Buckets, 2)
+	assert.Equal(t, map[int]int64{dns.RcodeSuccess: 2, dns.RcodeNameError: 2}, merged.Buckets[0].Rcodes)
+	assert.Equal(t, map[int]int64{dns.RcodeServerFailure: 1}, merged.Buckets[1].Rcodes)
+	assert.Equal(t, map[int]int64{dns.RcodeSuccess: 2, dns.RcodeNameError: 1}, ts.Buckets[0].Rcodes, "merged time series should not be modified")
+}
+
+func TestMergeTimeSeries(t *testing.T) {
+	start := time.Unix(1000, 0)
+	first := NewTimeSeries(start, 0, time.Second)
+	first.RecordResponse(start, time.Millisecond)
+	first.RecordResponse(start.Add(3*time.Second), time.Millisecond)
+	second := NewTimeSeries(start.Add(-time.Second), 0, time.Second)
+	second.RecordError(start.Add(-time.Second))
+	second.RecordResponse(start.Add(5*time.Second), time.Millisecond)
+	second.coarsen()
+
+	merged := MergeTimeSeries(first, nil, second, NewTimeSeries(time.Time{}, 0, time.Second))
+
+	require.NotNil(t, merged)
+	assert.Equal(t, start.Add(-time.Second), merged.Start)
+	assert.Equal(t, 2*time.Second, merged.Width)
+	require.Len(t, merged.Buckets, 4)
+	assert.Equal(t, TimeBucket{Errors: 1, Responses: 1, Hist: merged.Buckets[0].Hist}, merged.Buckets[0])
+	assert.EqualValues(t, 1, merged.Buckets[0].Hist.TotalCount())
+	assert.EqualValues(t, 1, merged.Buckets[2].Responses)
+	assert.EqualValues(t, 1, merged.Buckets[3].Responses)
+	assert.EqualValues(t, 1, first.Buckets[0].Hist.TotalCount(), "merged time series should not be modified")
+
+	assert.Nil(t, MergeTimeSeries(nil, NewTimeSeries(time.Time{}, 0, time.Second)))
+}
+
+func TestResultStats_record_raw_samples(t *testing.T) {
+	req := &dns.Msg{MsgHdr: dns.MsgHdr{Id: 1}, Question: []dns.Question{{Name: "example.org.", Qclass: dns.ClassINET, Qtype: dns.TypeA}}}
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+
+	tests := []struct {
+		name       string
+		rawSamples int
+		want       int
+	}{
+		{name: "default", rawSamples: 0, want: 100},
+		{name: "limited", rawSamples: 10, want: 10},
+		{name: "disabled", rawSamples: -1, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := newResultStats(&Benchmark{RawSamples: tt.rawSamples, HistMax: time.Second, HistPre: 1})
+
+			for i := range 100 {
+				rs.record(req, resp, newMessageSizes(req, resp), nil, now.Add(time.Duration(i)*time.Second), time.Millisecond)
+				rs.record(req, nil, newMessageSizes(req, nil), errors.New("test error"), now, time.Millisecond)
+			}
+
+			assert.Len(t, rs.Timings, tt.want)
+			assert.Len(t, rs.Errors, tt.want)
+			assert.Equal(t, map[string]int64{"test error": 100}, rs.ErrorGroups, "all errors should be counted")
+			assert.EqualValues(t, 100, rs.Hist.TotalCount(), "all responses should be counted")
+			var responses int64
+			for _, b := range rs.TimeSeries.Buckets {
+				responses += b.Responses
+			}
+			assert.EqualValues(t, 100, responses, "all responses should be counted in time series")
+		})
+	}
+}
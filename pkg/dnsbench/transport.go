@@ -0,0 +1,78 @@
+package dnsbench
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// QueryFunc sends the DNS request to the benchmarked server and returns the response.
+type QueryFunc func(ctx context.Context, req *dns.Msg) (*dns.Msg, error)
+
+// Transport sends the queries of the benchmark to the benchmarked server. The built-in plain DNS, DoT, DoH and DoQ transports
+// are implementations of Transport, custom implementations can be registered using Benchmark.Transports.
+type Transport interface {
+	// Network returns name of the transport used in the output, reports and metrics of the benchmark, for example "udp" or "https/2 (POST)".
+	Network() string
+	// QueryFunc returns query function used by the benchmark worker with the workerID. It is called once by each worker before the worker
+	// sends its first query and the returned function is used only by that worker, so it does not need to be safe for concurrent use.
+	// The functions returned for different workers can share connections, if the transport is safe for concurrent use.
+	// When the transport implements WorkerCloser, CloseWorker is called after the worker sent its last query.
+	QueryFunc(workerID uint32) QueryFunc
+}
+
+// WorkerCloser is implemented by the transports holding resources of single worker, for example the connection of the worker.
+type WorkerCloser interface {
+	// CloseWorker releases the resources of the query function returned for the worker with the workerID. It is called by the worker,
+	// when it exits, so it is never called concurrently with the query function of the same worker.
+	CloseWorker(workerID uint32) error
+}
+
+// TransportFactory creates the transport of the benchmark run, b is the configuration used by the run with the defaults filled in.
+// When the created transport implements io.Closer, it is closed after all workers finished.
+type TransportFactory func(b *Benchmark) (Transport, error)
+
+// transportFactory returns the registered transport factory for the scheme of Benchmark.Server or nil, when the built-in transport is used.
+func (b *Benchmark) transportFactory() TransportFactory {
+	scheme, _, ok := strings.Cut(b.Server, "://")
+	if !ok {
+		return nil
+	}
+	return b.Transports[scheme]
+}
+
+// closeWorker calls Transport.CloseWorker, when the transport implements WorkerCloser.
+func closeWorker(t Transport, workerID uint32) {
+	if closer, ok := t.(WorkerCloser); ok {
+		_ = closer.CloseWorker(workerID)
+	}
+}
+
+// initTransport creates the transport of the benchmark run.
+func (b *Benchmark) initTransport(metrics *promMetrics) error {
+	transport, err := b.newTransport(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to create transport for server '%s': %w", b.Server, err)
+	}
+	b.transport = transport
+	return nil
+}
+
+// newTransport creates the transport of the benchmark, either the registered custom transport or the built-in transport
+// selected by Benchmark.Server. When metrics is not nil, the connections established by the built-in transports are counted
+// in the open connections gauge, where available.
+func (b *Benchmark) newTransport(metrics *promMetrics) (Transport, error) {
+	if factory := b.transportFactory(); factory != nil {
+		return factory(b)
+	}
+	switch {
+	case b.useDoH:
+		return newDoHTransport(b, metrics), nil
+	case b.useQuic:
+		return newDoQTransport(b), nil
+	default:
+		return &dnsTransport{b: b, metrics: metrics}, nil
+	}
+}
@@ -0,0 +1,247 @@
+package dnsbench
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/tantalor93/doh-go/doh"
+	"github.com/tantalor93/doq-go/doq"
+	"golang.org/x/net/http2"
+)
+
+// dnsTransport sends queries using plain DNS over UDP or TCP or using DoT, each worker uses its own connection.
+type dnsTransport struct {
+	b       *Benchmark
+	metrics *promMetrics
+	workers workerClosers
+}
+
+func (t *dnsTransport) Network() string {
+	return t.b.builtinNetwork()
+}
+
+func (t *dnsTransport) QueryFunc(workerID uint32) QueryFunc {
+	b, metrics := t.b, t.metrics
+	dnsClient := getDNSClient(b)
+	var co *dns.Conn
+	var i int64
+	closeConn := func() {
+		co.Close()
+		co = nil
+		if metrics != nil {
+			metrics.openConnections.Dec()
+		}
+	}
+	t.workers.add(workerID, func() {
+		if co != nil {
+			closeConn()
+		}
+	})
+	// this allows DoT and plain DNS protocols to support counting queries per connection
+	// and granular control of the connection
+	return func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+		if co != nil && b.QperConn > 0 && i%b.QperConn == 0 {
+			closeConn()
+		}
+		i++
+		trace := queryTraceFromContext(ctx)
+		if trace != nil {
+			trace.connReused(co != nil)
+		}
+		if co == nil {
+			var err error
+			if trace != nil {
+				trace.phaseStart(ConnectPhase)
+			}
+			co, err = dnsClient.DialContext(ctx, b.Server)
+			if trace != nil {
+				trace.phaseEnd(ConnectPhase, err)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if metrics != nil {
+				metrics.openConnections.Inc()
+			}
+		}
+		if trace != nil {
+			trace.connAddr(co.LocalAddr())
+		}
+		r, _, err := dnsClient.ExchangeWithConnContext(ctx, msg, co)
+		if err != nil {
+			closeConn()
+			return nil, err
+		}
+		return r, nil
+	}
+}
+
+func (t *dnsTransport) CloseWorker(workerID uint32) error {
+	t.workers.close(workerID)
+	return nil
+}
+
+// doqTransport sends queries using DoQ, the workers share single client unless Benchmark.SeparateWorkerConnections is set.
+type doqTransport struct {
+	b      *Benchmark
+	shared QueryFunc
+}
+
+func newDoQTransport(b *Benchmark) *doqTransport {
+	t := &doqTransport{b: b}
+	if !b.SeparateWorkerConnections {
+		t.shared = getDoQClient(b).Send
+	}
+	return t
+}
+
+func (t *doqTransport) Network() string {
+	return t.b.builtinNetwork()
+}
+
+func (t *doqTransport) QueryFunc(uint32) QueryFunc {
+	if t.shared != nil {
+		return t.shared
+	}
+	return getDoQClient(t.b).Send
+}
+
+// dohTransport sends queries using DoH, the workers share single HTTP client unless Benchmark.SeparateWorkerConnections is set.
+type dohTransport struct {
+	b       *Benchmark
+	metrics *promMetrics
+	shared  QueryFunc
+	workers workerClosers
+}
+
+func newDoHTransport(b *Benchmark, metrics *promMetrics) *dohTransport {
+	t := &dohTransport{b: b, metrics: metrics}
+	if !b.SeparateWorkerConnections {
+		t.shared, _ = dohQuery(b, metrics)
+	}
+	return t
+}
+
+func (t *dohTransport) Network() string {
+	return t.b.builtinNetwork()
+}
+
+func (t *dohTransport) QueryFunc(workerID uint32) QueryFunc {
+	if t.shared != nil {
+		return t.shared
+	}
+	query, closeConns := dohQuery(t.b, t.metrics)
+	t.workers.add(workerID, closeConns)
+	return query
+}
+
+func (t *dohTransport) CloseWorker(workerID uint32) error {
+	t.workers.close(workerID)
+	return nil
+}
+
+// dohQuery returns query function using its own HTTP client and function closing the connections of the client.
+func dohQuery(b *Benchmark, metrics *promMetrics) (QueryFunc, func()) {
+	var tr http.RoundTripper
+	switch b.DohProtocol {
+	case HTTP3Proto:
+		// nolint:gosec
+		tr = &http3.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: b.Insecure}}
+	case HTTP2Proto:
+		// nolint:gosec
+		h2 := &http2.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: b.Insecure}}
+		if metrics != nil {
+			h2.DialTLSContext = metrics.dialTLSContext()
+		}
+		tr = h2
+	case HTTP1Proto:
+		fallthrough
+	default:
+		// nolint:gosec
+		h1 := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: b.Insecure}}
+		if metrics != nil {
+			h1.DialContext = metrics.dialContext((&net.Dialer{}).DialContext)
+		}
+		tr = h1
+	}
+	c := http.Client{Transport: tr, Timeout: b.ReadTimeout}
+	dohClient := doh.NewClient(b.Server, doh.WithHTTPClient(&c), doh.WithUserAgent(b.DohUserAgent))
+
+	var send QueryFunc
+	switch b.DohMethod {
+	case PostHTTPMethod:
+		send = dohClient.SendViaPost
+	case GetHTTPMethod:
+		send = dohClient.SendViaGet
+	default:
+		send = dohClient.SendViaPost
+	}
+	return func(ctx context.Context, msg *dns.Msg) (*dns.Msg, error) {
+		if trace := queryTraceFromContext(ctx); trace != nil {
+			ctx = httptrace.WithClientTrace(ctx, trace.httpTrace())
+		}
+		return send(ctx, msg)
+	}, c.CloseIdleConnections
+}
+
+// workerClosers holds functions releasing the resources of the query functions of the workers.
+type workerClosers struct {
+	mu      sync.Mutex
+	closers map[uint32]func()
+}
+
+func (w *workerClosers) add(workerID uint32, closer func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closers == nil {
+		w.closers = make(map[uint32]func())
+	}
+	w.closers[workerID] = closer
+}
+
+func (w *workerClosers) close(workerID uint32) {
+	w.mu.Lock()
+	closer, ok := w.closers[workerID]
+	delete(w.closers, workerID)
+	w.mu.Unlock()
+	if ok {
+		closer()
+	}
+}
+
+func getDoQClient(b *Benchmark) *doq.Client {
+	h, _, _ := net.SplitHostPort(b.Server)
+	return doq.NewClient(b.Server,
+		// nolint:gosec
+		doq.WithTLSConfig(&tls.Config{ServerName: h, InsecureSkipVerify: b.Insecure}),
+		doq.WithReadTimeout(b.ReadTimeout),
+		doq.WithWriteTimeout(b.WriteTimeout),
+		doq.WithConnectTimeout(b.ConnectTimeout),
+	)
+}
+
+func getDNSClient(b *Benchmark) *dns.Client {
+	network := UDPTransport
+	if b.TCP {
+		network = TCPTransport
+	}
+	if b.DOT {
+		network = TLSTransport
+	}
+
+	return &dns.Client{
+		Net:          network,
+		DialTimeout:  b.ConnectTimeout,
+		WriteTimeout: b.WriteTimeout,
+		ReadTimeout:  b.ReadTimeout,
+		Timeout:      b.RequestTimeout,
+		// nolint:gosec
+		TLSConfig: &tls.Config{InsecureSkipVerify: b.Insecure},
+	}
+}
@@ -0,0 +1,154 @@
+package dnsbench
+
+import (
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+)
+
+// maxTTL is the maximal TTL value according to RFC 2181.
+const maxTTL = 1<<31 - 1
+
+// newTTLHistogram creates histogram suitable for recording TTLs in seconds.
+func newTTLHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(0, maxTTL, 3)
+}
+
+// QuestionKey identifies DNS question by the domain name and query type.
+type QuestionKey struct {
+	Name  string
+	Qtype string
+}
+
+// TTLStats represents analysis of answer TTLs returned for single question.
+//
+// Cache behaviour is inferred from TTL decrements. After the first answer, the next answers are expected to have TTL decremented
+// by the time elapsed since the answer was cached. Answer with such TTL is considered a cache hit, answer with higher TTL means
+// that the record was refreshed by the server, either after it expired (cache miss) or before it expired (prefetch). Answers repeating
+// the same TTL for more than a second, although the record did not expire, are not served from cache, because the cached TTL
+// would be decremented.
+type TTLStats struct {
+	// Count is counter of all answers with at least one answer record.
+	Count int64
+	// Min is the minimal answer TTL seen.
+	Min uint32
+	// Max is the maximal answer TTL seen.
+	Max uint32
+	// Hits is counter of answers inferred as served from cache.
+	Hits int64
+	// Misses is counter of answers inferred as fetched again after the cached record expired.
+	Misses int64
+	// Prefetches is counter of answers inferred as refreshed before the cached record expired.
+	Prefetches int64
+	// NotCached is counter of answers with TTL not decremented, the same TTL was returned more than a second earlier
+	// and the record did not expire since then.
+	NotCached int64
+	// Stale is counter of answers with TTL 0 or with Extended DNS Error "Stale Answer" or "Stale NXDOMAIN Answer",
+	// stale answers are not counted as cache hits.
+	Stale int64
+	// Authoritative controls whether AuthoritativeTTL is known.
+	Authoritative bool
+	// AuthoritativeTTL is the authoritative TTL of the answer, it is either the answer TTL of the last authoritative (AA flag set) answer
+	// of the benchmarked or compared server or the TTL configured by Benchmark.AuthoritativeTTL.
+	AuthoritativeTTL uint32
+
+	// cachedTTL and cachedAt represent the last observed refresh of the record.
+	cachedTTL uint32
+	cachedAt  time.Time
+	// lastTTL is the TTL of the last answer and lastTTLAt is the time, when the TTL was seen first.
+	lastTTL   uint32
+	lastTTLAt time.Time
+	// lastTTLHits is counter of cache hits with lastTTL, which are not served from cache, if the TTL is not decremented.
+	lastTTLHits int64
+}
+
+func (ts *TTLStats) record(resp *dns.Msg, start time.Time) {
+	stale := isStale(resp)
+	if stale {
+		ts.Stale++
+	}
+
+	ttl, ok := answerTTL(resp)
+	if !ok {
+		return
+	}
+
+	if resp.Authoritative {
+		ts.recordAuthoritative(ttl)
+	}
+
+	if ts.Count == 0 {
+		ts.Min, ts.Max = ttl, ttl
+		ts.cachedTTL, ts.cachedAt = ttl, start
+		ts.lastTTL, ts.lastTTLAt = ttl, start
+		ts.Count++
+		return
+	}
+	ts.Count++
+	ts.Min = min(ts.Min, ttl)
+	ts.Max = max(ts.Max, ttl)
+	if stale {
+		return
+	}
+
+	if ttl != ts.lastTTL {
+		ts.lastTTL, ts.lastTTLAt, ts.lastTTLHits = ttl, start, 0
+	}
+	sinceLastTTL := start.Sub(ts.lastTTLAt)
+	expected := float64(ts.cachedTTL) - start.Sub(ts.cachedAt).Seconds()
+
+	switch {
+	case sinceLastTTL > time.Second && sinceLastTTL.Seconds() < float64(ttl):
+		// cached TTL would be decremented at least once, the answers with this TTL are not served from cache
+		ts.NotCached += ts.lastTTLHits + 1
+		ts.Hits -= ts.lastTTLHits
+		ts.lastTTLHits = 0
+		ts.cachedTTL, ts.cachedAt = ttl, start
+	case float64(ttl) <= expected+1:
+		// TTL decrements as expected (with 1 second tolerance for rounding), the answer is served from cache
+		ts.Hits++
+		ts.lastTTLHits++
+	case expected > 1:
+		ts.Prefetches++
+		ts.cachedTTL, ts.cachedAt = ttl, start
+	default:
+		ts.Misses++
+		ts.cachedTTL, ts.cachedAt = ttl, start
+	}
+}
+
+// recordAuthoritative records authoritative TTL of the answer.
+func (ts *TTLStats) recordAuthoritative(ttl uint32) {
+	ts.Authoritative = true
+	ts.AuthoritativeTTL = ttl
+}
+
+// answerTTL returns the minimal TTL of the answer records, which is the TTL of the whole answer.
+func answerTTL(resp *dns.Msg) (uint32, bool) {
+	if len(resp.Answer) == 0 {
+		return 0, false
+	}
+	ttl := uint32(maxTTL)
+	for _, rr := range resp.Answer {
+		ttl = min(ttl, rr.Header().Ttl)
+	}
+	return ttl, true
+}
+
+func isStale(resp *dns.Msg) bool {
+	for _, rr := range resp.Answer {
+		if rr.Header().Ttl == 0 {
+			return true
+		}
+	}
+	if opt := resp.IsEdns0(); opt != nil {
+		for _, option := range opt.Option {
+			if ede, ok := option.(*dns.EDNS0_EDE); ok &&
+				(ede.InfoCode == dns.ExtendedErrorCodeStaleAnswer || ede.InfoCode == dns.ExtendedErrorCodeStaleNXDOMAINAnswer) {
+				return true
+			}
+		}
+	}
+	return false
+}
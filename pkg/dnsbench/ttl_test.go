@@ -0,0 +1,127 @@
+package dnsbench
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLStats_record(t *testing.T) {
+	answer := func(ttl uint32) *dns.Msg {
+		return &dns.Msg{Answer: []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeA, Ttl: ttl}}}}
+	}
+	authoritative := func(ttl uint32) *dns.Msg {
+		m := answer(ttl)
+		m.Authoritative = true
+		return m
+	}
+	staleEDE := func(ttl uint32) *dns.Msg {
+		m := answer(ttl)
+		m.SetEdns0(DefaultEdns0BufferSize, false)
+		opt := m.IsEdns0()
+		opt.Option = append(opt.Option, &dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeStaleAnswer})
+		return m
+	}
+
+	type response struct {
+		msg     *dns.Msg
+		elapsed time.Duration
+	}
+	tests := []struct {
+		name      string
+		responses []response
+		want      TTLStats
+	}{
+		{
+			name:      "single answer",
+			responses: []response{{msg: answer(300)}},
+			want:      TTLStats{Count: 1, Min: 300, Max: 300},
+		},
+		{
+			name: "TTL decrements",
+			responses: []response{
+				{msg: answer(300)},
+				{msg: answer(300), elapsed: 500 * time.Millisecond},
+				{msg: answer(290), elapsed: 10 * time.Second},
+				{msg: answer(280), elapsed: 20 * time.Second},
+			},
+			want: TTLStats{Count: 4, Min: 280, Max: 300, Hits: 3},
+		},
+		{
+			name: "TTL refreshed after expiration",
+			responses: []response{
+				{msg: answer(10)},
+				{msg: answer(300), elapsed: 20 * time.Second},
+				{msg: answer(290), elapsed: 30 * time.Second},
+			},
+			want: TTLStats{Count: 3, Min: 10, Max: 300, Hits: 1, Misses: 1},
+		},
+		{
+			name: "TTL refreshed before expiration",
+			responses: []response{
+				{msg: answer(300)},
+				{msg: answer(250), elapsed: 50 * time.Second},
+				{msg: answer(300), elapsed: 100 * time.Second},
+			},
+			want: TTLStats{Count: 3, Min: 250, Max: 300, Hits: 1, Prefetches: 1},
+		},
+		{
+			name: "constant TTL",
+			responses: []response{
+				{msg: answer(300)},
+				{msg: answer(300), elapsed: 200 * time.Millisecond},
+				{msg: answer(300), elapsed: 700 * time.Millisecond},
+				{msg: answer(300), elapsed: 1500 * time.Millisecond},
+				{msg: answer(300), elapsed: 2500 * time.Millisecond},
+				{msg: answer(300), elapsed: 3500 * time.Millisecond},
+			},
+			want: TTLStats{Count: 6, Min: 300, Max: 300, NotCached: 5},
+		},
+		{
+			name: "same TTL after expiration",
+			responses: []response{
+				{msg: answer(10)},
+				{msg: answer(10), elapsed: 20 * time.Second},
+			},
+			want: TTLStats{Count: 2, Min: 10, Max: 10, Misses: 1},
+		},
+		{
+			name: "stale answers",
+			responses: []response{
+				{msg: answer(10)},
+				{msg: answer(0), elapsed: 20 * time.Second},
+				{msg: staleEDE(30), elapsed: 30 * time.Second},
+			},
+			want: TTLStats{Count: 3, Min: 0, Max: 30, Stale: 2},
+		},
+		{
+			name: "authoritative answer",
+			responses: []response{
+				{msg: authoritative(3600)},
+				{msg: answer(60), elapsed: time.Second},
+			},
+			want: TTLStats{Count: 2, Min: 60, Max: 3600, Hits: 1, Authoritative: true, AuthoritativeTTL: 3600},
+		},
+		{
+			name:      "no answer",
+			responses: []response{{msg: &dns.Msg{}}},
+			want:      TTLStats{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := TTLStats{}
+			for _, r := range tt.responses {
+				ts.record(r.msg, now.Add(r.elapsed))
+			}
+
+			// null the internal state for simple assertion
+			ts.cachedTTL, ts.cachedAt = 0, time.Time{}
+			ts.lastTTL, ts.lastTTLAt, ts.lastTTLHits = 0, time.Time{}, 0
+
+			assert.Equal(t, tt.want, ts)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package dnsbench
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+)
+
+// WarmupStats represents results of the queries sent during the warm-up phase (see Benchmark.Warmup), these queries are not part
+// of the other results of the benchmark.
+type WarmupStats struct {
+	// Counters holds counters of the warm-up queries.
+	Counters Counters
+	// Hist is a histogram of latencies of the responses received during the warm-up.
+	Hist *hdrhistogram.Histogram
+	// Duration is the time elapsed since the start of the benchmark run until the end of the warm-up phase.
+	Duration time.Duration
+}
+
+func newWarmupStats(b *Benchmark) *WarmupStats {
+	return &WarmupStats{Hist: hdrhistogram.New(b.HistMin.Nanoseconds(), b.HistMax.Nanoseconds(), b.HistPre)}
+}
+
+func (ws *WarmupStats) record(req *dns.Msg, resp *dns.Msg, err error, duration time.Duration) {
+	ws.Counters.count(req, resp, err)
+	if err == nil {
+		ws.Hist.RecordValue(duration.Nanoseconds())
+	}
+}
+
+// warmup decides, which queries belong to the warm-up phase shared by all workers. The warm-up ends either after the configured duration
+// since the start of the run, or after the configured number of queries finished.
+type warmup struct {
+	duration time.Duration
+	queries  int64
+	start    time.Time
+
+	// finished counts the finished queries, when the warm-up is given as a number of queries.
+	finished atomic.Int64
+	// end is the time in Unix nanoseconds, when the last warm-up query finished, it is 0 until then.
+	end atomic.Int64
+}
+
+func newWarmup(b *Benchmark) *warmup {
+	if b.warmupDuration == 0 && b.warmupQueries == 0 {
+		return nil
+	}
+	return &warmup{duration: b.warmupDuration, queries: b.warmupQueries, start: b.runStart}
+}
+
+// active returns whether the query started at start and finished now belongs to the warm-up phase.
+func (w *warmup) active(start time.Time) bool {
+	if w.duration > 0 {
+		return start.Before(w.start.Add(w.duration))
+	}
+	if w.end.Load() != 0 {
+		return false
+	}
+	n := w.finished.Add(1)
+	if n == w.queries {
+		w.end.Store(time.Now().UnixNano())
+	}
+	return n <= w.queries
+}
+
+// elapsed returns the duration of the warm-up phase of the run, which ended at runEnd.
+func (w *warmup) elapsed(runEnd time.Time) time.Duration {
+	end := runEnd
+	switch {
+	case w.duration > 0:
+		end = minTime(w.start.Add(w.duration), runEnd)
+	case w.end.Load() != 0:
+		end = time.Unix(0, w.end.Load())
+	}
+	return max(0, end.Sub(w.start))
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// parseWarmup parses Benchmark.Warmup given either as a duration or as a number of queries.
+func (b *Benchmark) parseWarmup() error {
+	b.warmupDuration, b.warmupQueries = 0, 0
+	if len(b.Warmup) == 0 {
+		return nil
+	}
+	if queries, err := strconv.ParseInt(b.Warmup, 10, 64); err == nil {
+		if queries < 0 {
+			return errors.New("--warmup must not be negative")
+		}
+		b.warmupQueries = queries
+		return nil
+	}
+	duration, err := time.ParseDuration(b.Warmup)
+	if err != nil {
+		return fmt.Errorf("'%s' has unexpected format, either <GO duration> or <number of queries> is expected", b.Warmup)
+	}
+	if duration < 0 {
+		return errors.New("--warmup must not be negative")
+	}
+	if b.Duration > 0 && duration >= b.Duration {
+		return errors.New("--warmup must be shorter than --duration")
+	}
+	b.warmupDuration = duration
+	return nil
+}
@@ -0,0 +1,77 @@
+package dnsbench
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmark_parseWarmup(t *testing.T) {
+	tests := []struct {
+		name         string
+		benchmark    Benchmark
+		wantDuration time.Duration
+		wantQueries  int64
+		wantErr      bool
+	}{
+		{name: "disabled", benchmark: Benchmark{}},
+		{name: "duration", benchmark: Benchmark{Warmup: "10s"}, wantDuration: 10 * time.Second},
+		{name: "queries", benchmark: Benchmark{Warmup: "1000"}, wantQueries: 1000},
+		{name: "duration shorter than benchmark", benchmark: Benchmark{Warmup: "1s", Duration: 2 * time.Second}, wantDuration: time.Second},
+		{name: "duration longer than benchmark", benchmark: Benchmark{Warmup: "2s", Duration: 2 * time.Second}, wantErr: true},
+		{name: "negative duration", benchmark: Benchmark{Warmup: "-1s"}, wantErr: true},
+		{name: "negative queries", benchmark: Benchmark{Warmup: "-1"}, wantErr: true},
+		{name: "invalid", benchmark: Benchmark{Warmup: "abc"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.benchmark.parseWarmup()
+
+			require.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantDuration, tt.benchmark.warmupDuration)
+			assert.Equal(t, tt.wantQueries, tt.benchmark.warmupQueries)
+		})
+	}
+}
+
+func Test_warmup_duration(t *testing.T) {
+	w := newWarmup(&Benchmark{warmupDuration: time.Second, runStart: now})
+
+	assert.True(t, w.active(now.Add(500*time.Millisecond)))
+	assert.False(t, w.active(now.Add(time.Second)))
+	assert.Equal(t, time.Second, w.elapsed(now.Add(2*time.Second)))
+	assert.Equal(t, 500*time.Millisecond, w.elapsed(now.Add(500*time.Millisecond)), "warm-up should end with the run")
+}
+
+func Test_warmup_queries(t *testing.T) {
+	w := newWarmup(&Benchmark{warmupQueries: 100, runStart: now})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	warmingUp := 0
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if w.active(now) {
+					mu.Lock()
+					warmingUp++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 100, warmingUp, "exactly the configured number of queries should be warm-up")
+	assert.Positive(t, w.elapsed(now.Add(time.Hour)))
+	assert.Less(t, w.elapsed(now.Add(time.Hour)), time.Hour, "warm-up should end with the last warm-up query")
+}
+
+func Test_newWarmup_disabled(t *testing.T) {
+	assert.Nil(t, newWarmup(&Benchmark{}))
+}
@@ -2,6 +2,8 @@
 package printutils
 
 import (
+	"io"
+
 	"github.com/fatih/color"
 )
 
@@ -19,3 +21,55 @@ var (
 	// HighlightSprint is a wrapper for highlighting strings with color.
 	HighlightSprint = highlightColor.SprintFunc()
 )
+
+// Printer prints colored output. Unlike the package level functions, which are controlled by the global color.NoColor setting,
+// the coloring is configured for each Printer separately, so that multiple benchmarks with different settings can print at once.
+type Printer struct {
+	err       *color.Color
+	success   *color.Color
+	neutral   *color.Color
+	highlight *color.Color
+}
+
+// NewPrinter creates new Printer, the output is colored only if colored is true.
+func NewPrinter(colored bool) *Printer {
+	p := &Printer{
+		err:       color.New(color.FgRed),
+		success:   color.New(color.FgGreen),
+		neutral:   color.New(),
+		highlight: color.New(color.FgYellow),
+	}
+	for _, c := range []*color.Color{p.err, p.success, p.neutral, p.highlight} {
+		if colored {
+			c.EnableColor()
+		} else {
+			c.DisableColor()
+		}
+	}
+	return p
+}
+
+// ErrFprintf prints colored error.
+func (p *Printer) ErrFprintf(w io.Writer, format string, a ...any) {
+	p.err.Fprintf(w, format, a...)
+}
+
+// SuccessFprintf prints colored success.
+func (p *Printer) SuccessFprintf(w io.Writer, format string, a ...any) {
+	p.success.Fprintf(w, format, a...)
+}
+
+// NeutralFprintf prints neutral information.
+func (p *Printer) NeutralFprintf(w io.Writer, format string, a ...any) {
+	p.neutral.Fprintf(w, format, a...)
+}
+
+// HighlightSprintf highlights formatted string with color.
+func (p *Printer) HighlightSprintf(format string, a ...any) string {
+	return p.highlight.Sprintf(format, a...)
+}
+
+// HighlightSprint highlights string with color.
+func (p *Printer) HighlightSprint(a ...any) string {
+	return p.highlight.Sprint(a...)
+}
@@ -0,0 +1,28 @@
+package reporter
+
+import "github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+
+type adaptiveRateSummary struct {
+	startRate int
+	finalRate int
+	minRate   int
+	maxRate   int
+	increases int
+	decreases int
+}
+
+// summarizeRateAdjustments summarizes the adjustments of the adaptive rate control, which started at startRate.
+func summarizeRateAdjustments(startRate int, adjustments []dnsbench.RateAdjustment) adaptiveRateSummary {
+	summary := adaptiveRateSummary{startRate: startRate, finalRate: startRate, minRate: startRate, maxRate: startRate}
+	for _, a := range adjustments {
+		if a.Rate > a.PreviousRate {
+			summary.increases++
+		} else {
+			summary.decreases++
+		}
+		summary.finalRate = a.Rate
+		summary.minRate = min(summary.minRate, a.Rate)
+		summary.maxRate = max(summary.maxRate, a.Rate)
+	}
+	return summary
+}
@@ -0,0 +1,59 @@
+package reporter
+
+import (
+	"sort"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+// ioErrorOutcome labels time to error of requests ending with IO error in the per response code breakdown.
+const ioErrorOutcome = "IO error"
+
+type latencyBreakdown struct {
+	key  string
+	hist *hdrhistogram.Histogram
+}
+
+// qtypeBreakdown returns latency histograms per query type ordered by the query type.
+func qtypeBreakdown(hists map[string]*hdrhistogram.Histogram) []latencyBreakdown {
+	res := make([]latencyBreakdown, 0, len(hists))
+	for _, k := range sortedKeys(hists) {
+		res = append(res, latencyBreakdown{key: k, hist: hists[k]})
+	}
+	return res
+}
+
+// rcodeBreakdown returns latency histograms per response code ordered by the response code value, NODATA follows NOERROR.
+// Time to error of IO errors is the last, if there were any.
+func rcodeBreakdown(hists map[string]*hdrhistogram.Histogram, errorHist *hdrhistogram.Histogram) []latencyBreakdown {
+	res := make([]latencyBreakdown, 0, len(hists)+1)
+	for k, v := range hists {
+		res = append(res, latencyBreakdown{key: k, hist: v})
+	}
+	order := func(key string) int {
+		if key == dnsbench.NoDataOutcome {
+			return 0
+		}
+		if rcode, ok := dns.StringToRcode[key]; ok {
+			return rcode
+		}
+		return dns.RcodeBadCookie + 1
+	}
+	sort.Slice(res, func(i, j int) bool {
+		oi, oj := order(res[i].key), order(res[j].key)
+		if oi != oj {
+			return oi < oj
+		}
+		// NOERROR precedes NODATA
+		if res[i].key == dns.RcodeToString[dns.RcodeSuccess] || res[j].key == dns.RcodeToString[dns.RcodeSuccess] {
+			return res[i].key == dns.RcodeToString[dns.RcodeSuccess]
+		}
+		return res[i].key < res[j].key
+	})
+	if errorHist != nil && errorHist.TotalCount() > 0 {
+		res = append(res, latencyBreakdown{key: ioErrorOutcome, hist: errorHist})
+	}
+	return res
+}
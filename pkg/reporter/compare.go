@@ -0,0 +1,675 @@
+package reporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+	"github.com/tantalor93/dnspyre/v3/pkg/printutils"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+const (
+	// DefaultSignificance is a default significance level of the tests of the differences between the compared benchmark runs.
+	DefaultSignificance = 0.05
+	// DefaultMinEffect is a default minimum relative change of the median latency considered as better or worse latency.
+	DefaultMinEffect = 0.05
+)
+
+// CompareOptions configures the comparison of the benchmark runs.
+type CompareOptions struct {
+	// Significance is the significance level of the tests, the differences with p-value lower than the significance level are significant.
+	Significance float64
+	// MinEffect is the minimum relative change of the median latency, the significant differences of latencies with smaller change
+	// are not considered as better or worse latency. The latency samples of a single run are not independent and the runs differ
+	// more than the test of the samples assumes, so even the runs with the same configuration often differ significantly.
+	MinEffect float64
+}
+
+// verdict is the outcome of the comparison of the candidate benchmark run with the baseline run.
+type verdict string
+
+const (
+	betterVerdict    verdict = "better"
+	worseVerdict     verdict = "worse"
+	unchangedVerdict verdict = "unchanged"
+	// changedVerdict means, that the results differ significantly, but the difference is neither better nor worse.
+	changedVerdict verdict = "changed"
+)
+
+// String returns human-readable description of the verdict.
+func (v verdict) String() string {
+	if v == unchangedVerdict {
+		return "no significant change"
+	}
+	return string(v)
+}
+
+type metricKind int
+
+const (
+	qpsMetric metricKind = iota
+	latencyMetric
+	rateMetric
+)
+
+// comparedMetric holds value of single metric of the compared runs, latencies are in nanoseconds and rates are ratios.
+type comparedMetric struct {
+	name      string
+	kind      metricKind
+	baseline  float64
+	candidate float64
+}
+
+// relativeDelta returns the relative change of the metric, false is returned, when the change cannot be computed.
+func (m comparedMetric) relativeDelta() (float64, bool) {
+	if m.baseline == 0 {
+		return 0, false
+	}
+	return (m.candidate - m.baseline) / m.baseline, true
+}
+
+type comparedRcode struct {
+	name      string
+	baseline  int64
+	candidate int64
+}
+
+// significanceTest holds result of single test of the difference between the compared runs.
+type significanceTest struct {
+	name   string
+	method string
+	// performed is false, when there is not enough data for the test.
+	performed bool
+	statistic float64
+	pValue    float64
+	// effect is the relative change of the median latency, it is set only for the latency test.
+	effect *float64
+	// belowMinEffect is true, when the latencies differ significantly, but the effect is smaller than the minimum effect.
+	belowMinEffect bool
+	verdict        verdict
+}
+
+// result returns human-readable result of the test.
+func (t significanceTest) result() string {
+	switch {
+	case !t.performed:
+		return "not enough data"
+	case t.belowMinEffect:
+		return "below minimum effect"
+	default:
+		return t.verdict.String()
+	}
+}
+
+type comparison struct {
+	significance float64
+	minEffect    float64
+	metrics      []comparedMetric
+	rcodes       []comparedRcode
+	// baselineResponses and candidateResponses are totals of the responses with response codes.
+	baselineResponses  int64
+	candidateResponses int64
+	tests              []significanceTest
+	verdict            verdict
+}
+
+// PrintComparison prints comparison of the candidate benchmark run with the baseline run to b.Writer, as JSON when b.JSON is set.
+// QPS, latency percentiles (see dnsbench.Benchmark.Percentiles), error rates and response codes of the runs are compared. The differences
+// of latencies, IO error rates and response code distributions (chi-square tests) are tested at the significance level. The latencies
+// are compared using Welch's t-test of the per-iteration median latencies, when both runs were repeated using dnsbench.Benchmark.RunIterations,
+// otherwise using Mann-Whitney U test of the raw latency samples, the latency is better or worse only when the median latency changed
+// at least by CompareOptions.MinEffect. The candidate is worse, when any of the significant differences is worse, the results changed,
+// when the response codes differ without change of the error response codes, the candidate is better, when any of the significant
+// differences is better, otherwise there is no significant change.
+func PrintComparison(b *dnsbench.Benchmark, baseline, candidate dnsbench.Results, opts CompareOptions) error {
+	if opts.Significance <= 0 || opts.Significance >= 1 {
+		return errors.New("significance level must have value between 0 and 1")
+	}
+	if opts.MinEffect < 0 {
+		return errors.New("minimum effect must not be negative")
+	}
+	c := compare(b, baseline, candidate, opts)
+	if b.JSON {
+		return json.NewEncoder(b.Writer).Encode(newJSONComparison(c))
+	}
+	return printComparison(b.Writer, printutils.NewPrinter(b.Color), c)
+}
+
+// mergeResults merges the results of the benchmark run, the returned duration excludes the warm-up.
+func mergeResults(r dnsbench.Results) (BenchmarkResultStats, time.Duration) {
+	var totals BenchmarkResultStats
+	duration := r.Duration
+	if len(r.Iterations) > 0 {
+		totals, duration = mergeIterations(r.Benchmark, r.Iterations)
+	} else {
+		totals = Merge(r.Benchmark, r.Stats)
+	}
+	if totals.Warmup != nil && duration > totals.Warmup.Duration {
+		duration -= totals.Warmup.Duration
+	}
+	return totals, duration
+}
+
+func compare(b *dnsbench.Benchmark, baseline, candidate dnsbench.Results, opts CompareOptions) comparison {
+	base, baseDuration := mergeResults(baseline)
+	cand, candDuration := mergeResults(candidate)
+
+	c := comparison{significance: opts.Significance, minEffect: opts.MinEffect}
+	c.metrics = append(c.metrics, comparedMetric{
+		name:      "QPS",
+		kind:      qpsMetric,
+		baseline:  queriesPerSecond(base.Counters.Total, baseDuration),
+		candidate: queriesPerSecond(cand.Counters.Total, candDuration),
+	})
+	for _, p := range percentiles(b) {
+		c.metrics = append(c.metrics, comparedMetric{
+			name:      percentileName(p),
+			kind:      latencyMetric,
+			baseline:  float64(base.Hist.ValueAtQuantile(p)),
+			candidate: float64(cand.Hist.ValueAtQuantile(p)),
+		})
+	}
+	c.metrics = append(c.metrics,
+		comparedMetric{name: "IO error rate", kind: rateMetric, baseline: ratio(base.Counters.IOError, base.Counters.Total),
+			candidate: ratio(cand.Counters.IOError, cand.Counters.Total)},
+		comparedMetric{name: "Error response rate", kind: rateMetric, baseline: ratio(base.Counters.Error, base.Counters.Total),
+			candidate: ratio(cand.Counters.Error, cand.Counters.Total)},
+	)
+
+	codes := make(map[int]struct{})
+	for k := range base.Codes {
+		codes[k] = struct{}{}
+	}
+	for k := range cand.Codes {
+		codes[k] = struct{}{}
+	}
+	sortedCodes := make([]int, 0, len(codes))
+	for k := range codes {
+		sortedCodes = append(sortedCodes, k)
+	}
+	sort.Ints(sortedCodes)
+	for _, k := range sortedCodes {
+		c.rcodes = append(c.rcodes, comparedRcode{name: dns.RcodeToString[k], baseline: base.Codes[k], candidate: cand.Codes[k]})
+		c.baselineResponses += base.Codes[k]
+		c.candidateResponses += cand.Codes[k]
+	}
+
+	var latency significanceTest
+	if len(baseline.Iterations) > 1 && len(candidate.Iterations) > 1 {
+		latency = iterationsLatencyTest(iterationMedians(baseline), iterationMedians(candidate), opts)
+	} else {
+		effect := relativeChange(float64(base.Hist.ValueAtQuantile(50)), float64(cand.Hist.ValueAtQuantile(50)))
+		latency = latencyTest(base.Timings, cand.Timings, effect, opts)
+	}
+	c.tests = []significanceTest{
+		latency,
+		ioErrorsTest(base.Counters, cand.Counters, opts.Significance),
+		rcodesTest(sortedCodes, base.Codes, cand.Codes, opts.Significance),
+	}
+	c.verdict = overallVerdict(c.tests)
+	return c
+}
+
+// overallVerdict returns worse, when any test is worse, changed, when any test is changed, better, when any test is better,
+// otherwise unchanged.
+func overallVerdict(tests []significanceTest) verdict {
+	res := unchangedVerdict
+	for _, t := range tests {
+		switch {
+		case t.verdict == worseVerdict:
+			return worseVerdict
+		case t.verdict == changedVerdict:
+			res = changedVerdict
+		case t.verdict == betterVerdict && res == unchangedVerdict:
+			res = betterVerdict
+		}
+	}
+	return res
+}
+
+// relativeChange returns the change of the value relative to the baseline, zero is returned, when the baseline is zero.
+func relativeChange(baseline, candidate float64) float64 {
+	if baseline == 0 {
+		return 0
+	}
+	return (candidate - baseline) / baseline
+}
+
+// iterationMedians returns the median latency of each iteration of the benchmark run.
+func iterationMedians(r dnsbench.Results) []float64 {
+	res := make([]float64, 0, len(r.Iterations))
+	for _, it := range r.Iterations {
+		res = append(res, float64(Merge(r.Benchmark, it.Stats).Hist.ValueAtQuantile(50)))
+	}
+	return res
+}
+
+// queriesPerSecond returns the rate of the queries, zero is returned, when the duration is not positive.
+func queriesPerSecond(total int64, duration time.Duration) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return float64(total) / duration.Seconds()
+}
+
+func ratio(count, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total)
+}
+
+// latencyTest tests the difference of the latencies using Mann-Whitney U test of the raw latency samples, lower latencies are better.
+// The effect is the relative change of the median latency.
+func latencyTest(baseline, candidate []dnsbench.Datapoint, effect float64, opts CompareOptions) significanceTest {
+	t := significanceTest{name: "Latency", method: "Mann-Whitney U", effect: &effect, verdict: unchangedVerdict}
+	if len(baseline) == 0 || len(candidate) == 0 {
+		return t
+	}
+	durations := func(datapoints []dnsbench.Datapoint) []float64 {
+		res := make([]float64, 0, len(datapoints))
+		for _, d := range datapoints {
+			res = append(res, float64(d.Duration))
+		}
+		return res
+	}
+	u, p := mannWhitneyU(durations(baseline), durations(candidate))
+	t.performed, t.statistic, t.pValue = true, u, p
+	t.belowMinEffect = p < opts.Significance && math.Abs(effect) < opts.MinEffect
+	if p < opts.Significance && !t.belowMinEffect {
+		// U greater than half of the pairs means, that the baseline latencies tend to be greater
+		if u > float64(len(baseline))*float64(len(candidate))/2 {
+			t.verdict = betterVerdict
+		} else {
+			t.verdict = worseVerdict
+		}
+	}
+	return t
+}
+
+// iterationsLatencyTest tests the difference of the median latencies of the iterations using Welch's t-test, lower latencies are better.
+// The effect is the relative change of the mean of the median latencies.
+func iterationsLatencyTest(baseline, candidate []float64, opts CompareOptions) significanceTest {
+	effect := relativeChange(stat.Mean(baseline, nil), stat.Mean(candidate, nil))
+	t := significanceTest{name: "Latency", method: "Welch's t-test of iteration medians", effect: &effect, verdict: unchangedVerdict}
+	statistic, p := welchTTest(baseline, candidate)
+	t.performed, t.statistic, t.pValue = true, statistic, p
+	t.belowMinEffect = p < opts.Significance && math.Abs(effect) < opts.MinEffect
+	if p < opts.Significance && !t.belowMinEffect {
+		// positive statistic means, that the baseline latencies are greater
+		if statistic > 0 {
+			t.verdict = betterVerdict
+		} else {
+			t.verdict = worseVerdict
+		}
+	}
+	return t
+}
+
+// ioErrorsTest tests the difference of IO error rates using chi-square test, lower IO error rate is better.
+func ioErrorsTest(baseline, candidate dnsbench.Counters, significance float64) significanceTest {
+	t := significanceTest{name: "IO errors", method: "chi-square", verdict: unchangedVerdict}
+	table := [2][]float64{
+		{float64(baseline.IOError), float64(baseline.Total - baseline.IOError)},
+		{float64(candidate.IOError), float64(candidate.Total - candidate.IOError)},
+	}
+	statistic, df, p := chiSquareTest(table)
+	if df == 0 {
+		return t
+	}
+	t.performed, t.statistic, t.pValue = true, statistic, p
+	if p < significance {
+		t.verdict = directionVerdict(ratio(baseline.IOError, baseline.Total), ratio(candidate.IOError, candidate.Total))
+	}
+	return t
+}
+
+// rcodesTest tests the difference of response code distributions using chi-square test. When the distributions differ, the ratios
+// of error response codes (other than NOERROR and NXDOMAIN) are tested, lower ratio of error response codes is better, when the ratios
+// do not differ, the response codes changed without being better or worse, for example when the ratio of NXDOMAIN changed.
+func rcodesTest(codes []int, baseline, candidate map[int]int64, significance float64) significanceTest {
+	t := significanceTest{name: "Response codes", method: "chi-square", verdict: unchangedVerdict}
+	var table [2][]float64
+	var baselineErrors, baselineTotal, candidateErrors, candidateTotal int64
+	for _, k := range codes {
+		table[0] = append(table[0], float64(baseline[k]))
+		table[1] = append(table[1], float64(candidate[k]))
+		baselineTotal += baseline[k]
+		candidateTotal += candidate[k]
+		if k != dns.RcodeSuccess && k != dns.RcodeNameError {
+			baselineErrors += baseline[k]
+			candidateErrors += candidate[k]
+		}
+	}
+	statistic, df, p := chiSquareTest(table)
+	if df == 0 {
+		return t
+	}
+	t.performed, t.statistic, t.pValue = true, statistic, p
+	if p < significance {
+		t.verdict = changedVerdict
+		_, errorsDf, errorsP := chiSquareTest([2][]float64{
+			{float64(baselineErrors), float64(baselineTotal - baselineErrors)},
+			{float64(candidateErrors), float64(candidateTotal - candidateErrors)},
+		})
+		if errorsDf > 0 && errorsP < significance {
+			t.verdict = directionVerdict(ratio(baselineErrors, baselineTotal), ratio(candidateErrors, candidateTotal))
+		}
+	}
+	return t
+}
+
+// directionVerdict returns verdict of significant change of the error rate.
+func directionVerdict(baselineRate, candidateRate float64) verdict {
+	switch {
+	case candidateRate < baselineRate:
+		return betterVerdict
+	case candidateRate > baselineRate:
+		return worseVerdict
+	default:
+		return changedVerdict
+	}
+}
+
+// mannWhitneyU performs two-sided Mann-Whitney U test of the values of a and b using normal approximation corrected for ties.
+// It returns U statistic of a and p-value, U greater than len(a)*len(b)/2 means, that the values of a tend to be greater than the values of b.
+func mannWhitneyU(a, b []float64) (float64, float64) {
+	type value struct {
+		v     float64
+		fromA bool
+	}
+	values := make([]value, 0, len(a)+len(b))
+	for _, v := range a {
+		values = append(values, value{v: v, fromA: true})
+	}
+	for _, v := range b {
+		values = append(values, value{v: v})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].v < values[j].v })
+
+	// tied values get the average of their ranks
+	var rankSumA, ties float64
+	for i := 0; i < len(values); {
+		j := i
+		for j < len(values) && values[j].v == values[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if values[k].fromA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	na, nb, n := float64(len(a)), float64(len(b)), float64(len(values))
+	u := rankSumA - na*(na+1)/2
+	sigma := math.Sqrt(na * nb / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 || math.IsNaN(sigma) {
+		// all values are the same
+		return u, 1
+	}
+	z := (u - na*nb/2) / sigma
+	return u, 2 * distuv.UnitNormal.Survival(math.Abs(z))
+}
+
+// welchTTest performs two-sided Welch's t-test of the means of a and b, it returns t statistic and p-value,
+// positive t statistic means, that the mean of a is greater than the mean of b.
+func welchTTest(a, b []float64) (float64, float64) {
+	meanA, varA := stat.MeanVariance(a, nil)
+	meanB, varB := stat.MeanVariance(b, nil)
+	na, nb := float64(len(a)), float64(len(b))
+	se2 := varA/na + varB/nb
+	if se2 == 0 {
+		// the values of both samples do not vary
+		if meanA == meanB {
+			return 0, 1
+		}
+		return math.Copysign(math.Inf(1), meanA-meanB), 0
+	}
+	t := (meanA - meanB) / math.Sqrt(se2)
+	// Welch-Satterthwaite approximation of the degrees of freedom
+	df := se2 * se2 / ((varA/na)*(varA/na)/(na-1) + (varB/nb)*(varB/nb)/(nb-1))
+	return t, 2 * distuv.StudentsT{Mu: 0, Sigma: 1, Nu: df}.Survival(math.Abs(t))
+}
+
+// chiSquareTest performs Pearson's chi-square test of independence of the rows of the contingency table, the columns without any
+// observations are ignored. It returns the chi-square statistic, degrees of freedom and p-value, the degrees of freedom are zero,
+// when the test cannot be performed.
+func chiSquareTest(table [2][]float64) (float64, int, float64) {
+	rowTotals := [2]float64{}
+	var colTotals []float64
+	var total float64
+	for j := range table[0] {
+		col := table[0][j] + table[1][j]
+		colTotals = append(colTotals, col)
+		rowTotals[0] += table[0][j]
+		rowTotals[1] += table[1][j]
+		total += col
+	}
+	var cols int
+	for _, c := range colTotals {
+		if c > 0 {
+			cols++
+		}
+	}
+	if cols < 2 || rowTotals[0] == 0 || rowTotals[1] == 0 {
+		return 0, 0, 1
+	}
+
+	var statistic float64
+	for i := range table {
+		for j, observed := range table[i] {
+			if colTotals[j] == 0 {
+				continue
+			}
+			expected := rowTotals[i] * colTotals[j] / total
+			statistic += (observed - expected) * (observed - expected) / expected
+		}
+	}
+	df := cols - 1
+	return statistic, df, distuv.ChiSquared{K: float64(df)}.Survival(statistic)
+}
+
+func printComparison(w io.Writer, colors *printutils.Printer, c comparison) error {
+	colors.NeutralFprintf(w, "Comparison of the candidate with the baseline:\n")
+	lines := make([][]string, 0, len(c.metrics))
+	for _, m := range c.metrics {
+		lines = append(lines, []string{m.name, formatMetric(m.kind, m.baseline), formatMetric(m.kind, m.candidate), formatDelta(m)})
+	}
+	if err := printTable(w, []string{"Metric", "Baseline", "Candidate", "Delta"}, lines); err != nil {
+		return err
+	}
+
+	if len(c.rcodes) > 0 {
+		colors.NeutralFprintf(w, "\nResponse codes:\n")
+		lines = make([][]string, 0, len(c.rcodes))
+		for _, r := range c.rcodes {
+			baselineShare, candidateShare := ratio(r.baseline, c.baselineResponses), ratio(r.candidate, c.candidateResponses)
+			lines = append(lines, []string{
+				r.name,
+				fmt.Sprintf("%d (%.2f%%)", r.baseline, baselineShare*100),
+				fmt.Sprintf("%d (%.2f%%)", r.candidate, candidateShare*100),
+				fmt.Sprintf("%+.2fpp", (candidateShare-baselineShare)*100),
+			})
+		}
+		if err := printTable(w, []string{"Response code", "Baseline", "Candidate", "Delta"}, lines); err != nil {
+			return err
+		}
+	}
+
+	colors.NeutralFprintf(w, "\nSignificance tests, significance level %s, minimum latency effect %s:\n",
+		colors.HighlightSprint(strconv.FormatFloat(c.significance, 'f', -1, 64)), colors.HighlightSprintf("%.2f%%", c.minEffect*100))
+	lines = make([][]string, 0, len(c.tests))
+	for _, t := range c.tests {
+		effect := "-"
+		if t.effect != nil {
+			effect = fmt.Sprintf("%+.2f%%", *t.effect*100)
+		}
+		if !t.performed {
+			lines = append(lines, []string{t.name, t.method, "-", "-", effect, t.result()})
+			continue
+		}
+		lines = append(lines, []string{t.name, t.method, fmt.Sprintf("%.2f", t.statistic), fmt.Sprintf("%.4f", t.pValue), effect, t.result()})
+	}
+	if err := printTable(w, []string{"Test", "Method", "Statistic", "p-value", "Effect", "Result"}, lines); err != nil {
+		return err
+	}
+
+	switch c.verdict {
+	case betterVerdict:
+		colors.SuccessFprintf(w, "\nVerdict: %s\n", c.verdict)
+	case worseVerdict:
+		colors.ErrFprintf(w, "\nVerdict: %s\n", c.verdict)
+	default:
+		colors.NeutralFprintf(w, "\nVerdict: %s\n", colors.HighlightSprint(c.verdict))
+	}
+	return nil
+}
+
+func formatMetric(kind metricKind, v float64) string {
+	switch kind {
+	case latencyMetric:
+		return roundDuration(time.Duration(v)).String()
+	case rateMetric:
+		return fmt.Sprintf("%.2f%%", v*100)
+	default:
+		return fmt.Sprintf("%.1f", v)
+	}
+}
+
+// formatDelta formats change of the metric, the change of rates is in percentage points, the change of other metrics is relative.
+func formatDelta(m comparedMetric) string {
+	if m.kind == rateMetric {
+		return fmt.Sprintf("%+.2fpp", (m.candidate-m.baseline)*100)
+	}
+	delta, ok := m.relativeDelta()
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%+.2f%%", delta*100)
+}
+
+type jsonComparison struct {
+	Significance      float64                       `json:"significance"`
+	MinEffect         float64                       `json:"minEffect"`
+	Verdict           verdict                       `json:"verdict"`
+	QueriesPerSecond  jsonComparedMetric            `json:"queriesPerSecond"`
+	LatencyMs         map[string]jsonComparedMetric `json:"latencyMs"`
+	IOErrorRate       jsonComparedMetric            `json:"ioErrorRate"`
+	ErrorResponseRate jsonComparedMetric            `json:"errorResponseRate"`
+	ResponseRcodes    map[string]jsonComparedRcode  `json:"responseRcodes,omitempty"`
+	Tests             []jsonSignificanceTest        `json:"tests"`
+}
+
+type jsonComparedMetric struct {
+	Baseline  float64 `json:"baseline"`
+	Candidate float64 `json:"candidate"`
+	Delta     float64 `json:"delta"`
+	// RelativeDelta is the change relative to the baseline, it is omitted when the baseline is zero.
+	RelativeDelta *float64 `json:"relativeDelta,omitempty"`
+}
+
+type jsonComparedRcode struct {
+	Baseline  int64   `json:"baseline"`
+	Candidate int64   `json:"candidate"`
+	Delta     float64 `json:"delta"`
+}
+
+type jsonSignificanceTest struct {
+	Name      string `json:"name"`
+	Method    string `json:"method"`
+	Performed bool   `json:"performed"`
+	// Statistic is omitted, when it is not finite, e.g. when the compared samples do not vary.
+	Statistic *float64 `json:"statistic,omitempty"`
+	PValue    float64  `json:"pValue"`
+	// Effect is the relative change of the median latency, it is set only for the latency test.
+	Effect         *float64 `json:"effect,omitempty"`
+	BelowMinEffect bool     `json:"belowMinEffect,omitempty"`
+	Verdict        verdict  `json:"verdict"`
+}
+
+func newJSONComparison(c comparison) jsonComparison {
+	round := func(v float64) float64 {
+		return math.Round(v*10000) / 10000
+	}
+	metric := func(m comparedMetric) jsonComparedMetric {
+		scale := round
+		if m.kind == latencyMetric {
+			scale = func(v float64) float64 {
+				return round(v / float64(time.Millisecond))
+			}
+		}
+		res := jsonComparedMetric{Baseline: scale(m.baseline), Candidate: scale(m.candidate), Delta: scale(m.candidate - m.baseline)}
+		if delta, ok := m.relativeDelta(); ok {
+			delta = round(delta)
+			res.RelativeDelta = &delta
+		}
+		return res
+	}
+
+	res := jsonComparison{
+		Significance: c.significance,
+		MinEffect:    c.minEffect,
+		Verdict:      c.verdict,
+		LatencyMs:    make(map[string]jsonComparedMetric),
+		Tests:        make([]jsonSignificanceTest, 0, len(c.tests)),
+	}
+	for _, m := range c.metrics {
+		switch {
+		case m.kind == qpsMetric:
+			res.QueriesPerSecond = metric(m)
+		case m.kind == latencyMetric:
+			res.LatencyMs[m.name] = metric(m)
+		case m.name == "IO error rate":
+			res.IOErrorRate = metric(m)
+		default:
+			res.ErrorResponseRate = metric(m)
+		}
+	}
+	if len(c.rcodes) > 0 {
+		res.ResponseRcodes = make(map[string]jsonComparedRcode, len(c.rcodes))
+		for _, r := range c.rcodes {
+			res.ResponseRcodes[r.name] = jsonComparedRcode{
+				Baseline:  r.baseline,
+				Candidate: r.candidate,
+				Delta:     round(ratio(r.candidate, c.candidateResponses) - ratio(r.baseline, c.baselineResponses)),
+			}
+		}
+	}
+	for _, t := range c.tests {
+		var effect *float64
+		if t.effect != nil {
+			e := round(*t.effect)
+			effect = &e
+		}
+		var statistic *float64
+		if !math.IsInf(t.statistic, 0) && !math.IsNaN(t.statistic) {
+			s := round(t.statistic)
+			statistic = &s
+		}
+		res.Tests = append(res.Tests, jsonSignificanceTest{
+			Name:           t.name,
+			Method:         t.method,
+			Performed:      t.performed,
+			Statistic:      statistic,
+			PValue:         round(t.pValue),
+			Effect:         effect,
+			BelowMinEffect: t.belowMinEffect,
+			Verdict:        t.verdict,
+		})
+	}
+	return res
+}
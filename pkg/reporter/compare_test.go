@@ -0,0 +1,203 @@
+package reporter
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     []float64
+		b     []float64
+		wantU float64
+		wantP float64
+	}{
+		{
+			name:  "a lower than b",
+			a:     []float64{1, 2, 3},
+			b:     []float64{4, 5, 6},
+			wantU: 0,
+			wantP: 0.0495,
+		},
+		{
+			name:  "a greater than b",
+			a:     []float64{4, 5, 6},
+			b:     []float64{1, 2, 3},
+			wantU: 9,
+			wantP: 0.0495,
+		},
+		{
+			name:  "interleaved",
+			a:     []float64{1, 3, 5},
+			b:     []float64{2, 4, 6},
+			wantU: 3,
+			wantP: 0.5127,
+		},
+		{
+			name:  "ties",
+			a:     []float64{1, 2, 2, 3},
+			b:     []float64{2, 3, 3, 4},
+			wantU: 3,
+			wantP: 0.1292,
+		},
+		{
+			name:  "all values equal",
+			a:     []float64{1, 1, 1},
+			b:     []float64{1, 1, 1},
+			wantU: 4.5,
+			wantP: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, p := mannWhitneyU(tt.a, tt.b)
+			assert.InDelta(t, tt.wantU, u, 1e-9)
+			assert.InDelta(t, tt.wantP, p, 1e-4)
+		})
+	}
+}
+
+func Test_chiSquareTest(t *testing.T) {
+	tests := []struct {
+		name          string
+		table         [2][]float64
+		wantStatistic float64
+		wantDf        int
+		wantP         float64
+	}{
+		{
+			name:          "different distributions",
+			table:         [2][]float64{{10, 20}, {20, 10}},
+			wantStatistic: 6.6667,
+			wantDf:        1,
+			wantP:         0.0098,
+		},
+		{
+			name:          "same distributions",
+			table:         [2][]float64{{10, 20, 30}, {20, 40, 60}},
+			wantStatistic: 0,
+			wantDf:        2,
+			wantP:         1,
+		},
+		{
+			name:          "empty columns are ignored",
+			table:         [2][]float64{{10, 0, 20}, {20, 0, 10}},
+			wantStatistic: 6.6667,
+			wantDf:        1,
+			wantP:         0.0098,
+		},
+		{
+			name:          "single column",
+			table:         [2][]float64{{10, 0}, {20, 0}},
+			wantStatistic: 0,
+			wantDf:        0,
+			wantP:         1,
+		},
+		{
+			name:          "empty row",
+			table:         [2][]float64{{0, 0}, {20, 10}},
+			wantStatistic: 0,
+			wantDf:        0,
+			wantP:         1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statistic, df, p := chiSquareTest(tt.table)
+			assert.InDelta(t, tt.wantStatistic, statistic, 1e-4)
+			assert.Equal(t, tt.wantDf, df)
+			assert.InDelta(t, tt.wantP, p, 1e-4)
+		})
+	}
+}
+
+func Test_welchTTest(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     []float64
+		b     []float64
+		wantT float64
+		wantP float64
+	}{
+		{
+			name:  "a greater than b",
+			a:     []float64{20, 21, 22},
+			b:     []float64{10, 11, 12},
+			wantT: 12.2474,
+			wantP: 0.0003,
+		},
+		{
+			name:  "different variances",
+			a:     []float64{1, 2, 3, 4},
+			b:     []float64{2, 4, 6, 8, 10},
+			wantT: -2.2514,
+			wantP: 0.0691,
+		},
+		{
+			name:  "same values",
+			a:     []float64{1, 2, 3},
+			b:     []float64{1, 2, 3},
+			wantT: 0,
+			wantP: 1,
+		},
+		{
+			name:  "constant values",
+			a:     []float64{1, 1, 1},
+			b:     []float64{1, 1, 1},
+			wantT: 0,
+			wantP: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statistic, p := welchTTest(tt.a, tt.b)
+			assert.InDelta(t, tt.wantT, statistic, 1e-4)
+			assert.InDelta(t, tt.wantP, p, 1e-4)
+		})
+	}
+}
+
+func Test_rcodesTest(t *testing.T) {
+	codes := []int{dns.RcodeSuccess, dns.RcodeServerFailure, dns.RcodeNameError}
+	tests := []struct {
+		name        string
+		baseline    map[int]int64
+		candidate   map[int]int64
+		wantVerdict verdict
+	}{
+		{
+			name:        "same distributions",
+			baseline:    map[int]int64{dns.RcodeSuccess: 90, dns.RcodeNameError: 10},
+			candidate:   map[int]int64{dns.RcodeSuccess: 91, dns.RcodeNameError: 9},
+			wantVerdict: unchangedVerdict,
+		},
+		{
+			name:        "more error response codes",
+			baseline:    map[int]int64{dns.RcodeSuccess: 90, dns.RcodeServerFailure: 10},
+			candidate:   map[int]int64{dns.RcodeSuccess: 50, dns.RcodeServerFailure: 50},
+			wantVerdict: worseVerdict,
+		},
+		{
+			name:        "less error response codes",
+			baseline:    map[int]int64{dns.RcodeSuccess: 50, dns.RcodeServerFailure: 50},
+			candidate:   map[int]int64{dns.RcodeSuccess: 90, dns.RcodeServerFailure: 10},
+			wantVerdict: betterVerdict,
+		},
+		{
+			name:        "NXDOMAIN changed",
+			baseline:    map[int]int64{dns.RcodeSuccess: 90, dns.RcodeNameError: 10},
+			candidate:   map[int]int64{dns.RcodeSuccess: 50, dns.RcodeNameError: 50},
+			wantVerdict: changedVerdict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := rcodesTest(codes, tt.baseline, tt.candidate, DefaultSignificance)
+			assert.True(t, res.performed)
+			assert.Equal(t, tt.wantVerdict, res.verdict)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package reporter
+
+import (
+	"sort"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+type serverStats struct {
+	server   string
+	counters dnsbench.Counters
+	hist     *hdrhistogram.Histogram
+}
+
+type keyedDifference struct {
+	key dnsbench.DifferenceKey
+	*dnsbench.Difference
+}
+
+// comparedServers returns stats of the benchmarked server followed by stats of the compared servers sorted by the server address.
+func comparedServers(params reportParameters) []serverStats {
+	servers := make([]string, 0, len(params.consistency.Servers))
+	for k := range params.consistency.Servers {
+		servers = append(servers, k)
+	}
+	sort.Strings(servers)
+
+	res := []serverStats{{server: params.benchmark.Server, counters: params.totalCounters, hist: params.hist}}
+	for _, s := range servers {
+		st := params.consistency.Servers[s]
+		res = append(res, serverStats{server: s, counters: st.Counters, hist: st.Hist})
+	}
+	return res
+}
+
+// sortedDifferences returns answer differences sorted from the most frequent ones.
+func sortedDifferences(diffs map[dnsbench.DifferenceKey]*dnsbench.Difference) []keyedDifference {
+	res := make([]keyedDifference, 0, len(diffs))
+	for k, v := range diffs {
+		res = append(res, keyedDifference{key: k, Difference: v})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		if res[i].key.Server != res[j].key.Server {
+			return res[i].key.Server < res[j].key.Server
+		}
+		if res[i].key.Domain != res[j].key.Domain {
+			return res[i].key.Domain < res[j].key.Domain
+		}
+		if res[i].key.Qtype != res[j].key.Qtype {
+			return res[i].key.Qtype < res[j].key.Qtype
+		}
+		return res[i].key.Kind < res[j].key.Kind
+	})
+	return res
+}
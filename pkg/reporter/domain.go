@@ -0,0 +1,130 @@
+package reporter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+type domainSummary struct {
+	name string
+	*dnsbench.DomainStats
+}
+
+// summarizeDomains aggregates per-question statistics by the domain name, the result is ordered by the domain name.
+func summarizeDomains(domains map[dnsbench.QuestionKey]*dnsbench.DomainStats) []domainSummary {
+	byName := make(map[string]*dnsbench.DomainStats)
+	for k, v := range domains {
+		d, ok := byName[k.Name]
+		if !ok {
+			d = &dnsbench.DomainStats{
+				Codes: make(map[int]int64),
+				Hist:  hdrhistogram.New(v.Hist.LowestTrackableValue(), v.Hist.HighestTrackableValue(), int(v.Hist.SignificantFigures())),
+			}
+			byName[k.Name] = d
+		}
+		d.Count += v.Count
+		d.IOError += v.IOError
+		for code, c := range v.Codes {
+			d.Codes[code] += c
+		}
+		d.Hist.Merge(v.Hist)
+	}
+
+	res := make([]domainSummary, 0, len(byName))
+	for _, name := range sortedKeys(byName) {
+		res = append(res, domainSummary{name: name, DomainStats: byName[name]})
+	}
+	return res
+}
+
+// slowestDomains returns at most n domains with the highest p95 latency.
+func slowestDomains(summaries []domainSummary, n int) []domainSummary {
+	var res []domainSummary
+	for _, s := range summaries {
+		if s.Hist.TotalCount() > 0 {
+			res = append(res, s)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Hist.ValueAtQuantile(95) > res[j].Hist.ValueAtQuantile(95)
+	})
+	return res[:min(n, len(res))]
+}
+
+// failingDomains returns at most n domains with the highest number of errors.
+func failingDomains(summaries []domainSummary, n int) []domainSummary {
+	var res []domainSummary
+	for _, s := range summaries {
+		if s.Errors() > 0 {
+			res = append(res, s)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Errors() > res[j].Errors()
+	})
+	return res[:min(n, len(res))]
+}
+
+// formatCodes formats response codes ordered by the code, for example "NOERROR=2 SERVFAIL=1".
+func formatCodes(codes map[int]int64) string {
+	keys := make([]int, 0, len(codes))
+	for k := range codes {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	formatted := make([]string, 0, len(keys))
+	for _, k := range keys {
+		formatted = append(formatted, fmt.Sprintf("%s=%d", dns.RcodeToString[k], codes[k]))
+	}
+	return strings.Join(formatted, " ")
+}
+
+// writeDomainsCsv exports per-question statistics to CSV ordered by the domain name and query type.
+func writeDomainsCsv(w io.Writer, domains map[dnsbench.QuestionKey]*dnsbench.DomainStats) error {
+	keys := make([]dnsbench.QuestionKey, 0, len(domains))
+	for k := range domains {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Name != keys[j].Name {
+			return keys[i].Name < keys[j].Name
+		}
+		return keys[i].Qtype < keys[j].Qtype
+	})
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"Domain", "Type", "Count", "IO errors", "Errors", "Response codes",
+		"Min (ns)", "Mean (ns)", "P50 (ns)", "P95 (ns)", "P99 (ns)", "Max (ns)",
+	}); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		d := domains[k]
+		if err := cw.Write([]string{
+			k.Name, k.Qtype,
+			strconv.FormatInt(d.Count, 10),
+			strconv.FormatInt(d.IOError, 10),
+			strconv.FormatInt(d.Errors(), 10),
+			formatCodes(d.Codes),
+			strconv.FormatInt(d.Hist.Min(), 10),
+			strconv.FormatInt(int64(d.Hist.Mean()), 10),
+			strconv.FormatInt(d.Hist.ValueAtQuantile(50), 10),
+			strconv.FormatInt(d.Hist.ValueAtQuantile(95), 10),
+			strconv.FormatInt(d.Hist.ValueAtQuantile(99), 10),
+			strconv.FormatInt(d.Hist.Max(), 10),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -3,6 +3,9 @@ package reporter
 import "time"
 
 func roundDuration(dur time.Duration) time.Duration {
+	if dur < 0 {
+		return -roundDuration(-dur)
+	}
 	if dur > time.Minute {
 		return dur.Round(10 * time.Second)
 	}
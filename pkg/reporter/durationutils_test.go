@@ -41,6 +41,11 @@ func Test_roundDuration(t *testing.T) {
 			args: args{dur: 500 * time.Nanosecond},
 			want: 500 * time.Nanosecond,
 		},
+		{
+			name: "negative",
+			args: args{dur: -(2*time.Millisecond + 123*time.Microsecond)},
+			want: -(2*time.Millisecond + 120*time.Microsecond),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
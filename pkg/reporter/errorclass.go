@@ -0,0 +1,34 @@
+package reporter
+
+import (
+	"sort"
+
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+// sortedErrorClasses returns the error classes ordered by the number of errors from the most frequent, classes with the same number
+// of errors are ordered by their name.
+func sortedErrorClasses(classes map[dnsbench.ErrorClass]int64) []dnsbench.ErrorClass {
+	res := make([]dnsbench.ErrorClass, 0, len(classes))
+	for k := range classes {
+		res = append(res, k)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if classes[res[i]] != classes[res[j]] {
+			return classes[res[i]] > classes[res[j]]
+		}
+		return res[i].String() < res[j].String()
+	})
+	return res
+}
+
+// ErrorsOfType returns the number of IO errors of the error type.
+func (s *BenchmarkResultStats) ErrorsOfType(errorType dnsbench.ErrorType) int64 {
+	var n int64
+	for k, v := range s.ErrorClasses {
+		if k.Type == errorType {
+			n += v
+		}
+	}
+	return n
+}
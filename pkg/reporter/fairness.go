@@ -0,0 +1,196 @@
+package reporter
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+const (
+	// minOutlierEntries is the minimal number of workers or connections, among which the outliers are detected.
+	minOutlierEntries = 3
+	// outlierScore is the threshold of the modified z-score (deviation from the median in units of the median absolute deviation),
+	// above which the value is considered an outlier.
+	outlierScore = 3.5
+	// outlierRelativeDeviation is the minimal deviation from the median relative to the median, which is considered an outlier,
+	// so that negligible differences between otherwise uniform values are not reported.
+	outlierRelativeDeviation = 0.5
+	// outlierErrorRateDeviation is the minimal deviation of the error rate from the median, which is considered an outlier.
+	outlierErrorRateDeviation = 0.01
+	// maxReportedConnections is the maximum number of connections printed in the standard report, when there are more connections,
+	// only the outlier connections are printed.
+	maxReportedConnections = 20
+)
+
+// WorkerResultStats represents results of single worker of the benchmark.
+type WorkerResultStats struct {
+	// Counters holds counters of the requests sent by the worker.
+	Counters dnsbench.Counters
+	// Hist is a histogram of latencies in nanoseconds.
+	Hist *hdrhistogram.Histogram
+}
+
+// fairnessEntry represents results of single worker or connection compared with the other workers or connections.
+type fairnessEntry struct {
+	name     string
+	counters dnsbench.Counters
+	hist     *hdrhistogram.Histogram
+	// outliers are names of the metrics, in which the entry deviates from the others.
+	outliers []string
+}
+
+// errors returns number of requests, which ended with IO error or error response.
+func (e *fairnessEntry) errors() int64 {
+	return e.counters.IOError + e.counters.Error
+}
+
+type fairnessSummary struct {
+	workers     []fairnessEntry
+	connections []fairnessEntry
+}
+
+// outlierMetric is a metric, in which the workers and connections are compared.
+type outlierMetric struct {
+	name string
+	// value returns value of the metric for the entry, false is returned, when the metric is not available for the entry.
+	value func(e *fairnessEntry) (float64, bool)
+	// low controls whether values lower than the median are considered outliers as well.
+	low bool
+	// minDeviation is the minimal absolute deviation from the median, which is considered an outlier.
+	minDeviation float64
+}
+
+var outlierMetrics = []outlierMetric{
+	{
+		name: "requests",
+		value: func(e *fairnessEntry) (float64, bool) {
+			return float64(e.counters.Total), true
+		},
+		low: true,
+	},
+	{
+		name: "errors",
+		value: func(e *fairnessEntry) (float64, bool) {
+			if e.counters.Total == 0 {
+				return 0, false
+			}
+			return float64(e.errors()) / float64(e.counters.Total), true
+		},
+		minDeviation: outlierErrorRateDeviation,
+	},
+	{
+		name: "p99",
+		value: func(e *fairnessEntry) (float64, bool) {
+			if e.hist.TotalCount() == 0 {
+				return 0, false
+			}
+			return float64(e.hist.ValueAtQuantile(99)), true
+		},
+	},
+}
+
+// summarizeFairness compares results of the workers and the connections and marks their outliers, workers are ordered by the worker ID
+// and connections by the local address.
+func summarizeFairness(workers []WorkerResultStats, connections map[string]*dnsbench.ConnectionStats) fairnessSummary {
+	var summary fairnessSummary
+	for i, w := range workers {
+		summary.workers = append(summary.workers, fairnessEntry{name: strconv.Itoa(i), counters: w.Counters, hist: w.Hist})
+	}
+	for _, addr := range sortedKeys(connections) {
+		c := connections[addr]
+		summary.connections = append(summary.connections, fairnessEntry{name: addr, counters: c.Counters, hist: c.Hist})
+	}
+	markOutliers(summary.workers)
+	markOutliers(summary.connections)
+	return summary
+}
+
+// outlierConnections returns the connections, which are outliers in any metric.
+func (s *fairnessSummary) outlierConnections() []fairnessEntry {
+	var res []fairnessEntry
+	for _, c := range s.connections {
+		if len(c.outliers) > 0 {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// outlierWorkers returns number of workers, which are outliers in any metric.
+func (s *fairnessSummary) outlierWorkers() int {
+	var n int
+	for _, w := range s.workers {
+		if len(w.outliers) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// markOutliers marks entries, which deviate from the median of the entries in any metric. The deviation is measured using
+// the modified z-score, which is robust to the outliers themselves, so that a single bad worker or connection stands out.
+func markOutliers(entries []fairnessEntry) {
+	if len(entries) < minOutlierEntries {
+		return
+	}
+	for _, m := range outlierMetrics {
+		indexes := make([]int, 0, len(entries))
+		values := make([]float64, 0, len(entries))
+		for i := range entries {
+			if v, ok := m.value(&entries[i]); ok {
+				indexes = append(indexes, i)
+				values = append(values, v)
+			}
+		}
+		if len(values) < minOutlierEntries {
+			continue
+		}
+		for k, outlier := range outliers(values, m.low, m.minDeviation) {
+			if outlier {
+				entries[indexes[k]].outliers = append(entries[indexes[k]].outliers, m.name)
+			}
+		}
+	}
+}
+
+// outliers returns for each value whether it is an outlier. Values higher than the median are outliers, when their modified z-score
+// exceeds outlierScore and they deviate from the median by more than outlierRelativeDeviation and minDeviation. When low is true,
+// values lower than the median are checked as well.
+func outliers(values []float64, low bool, minDeviation float64) []bool {
+	m := median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - m)
+	}
+	mad := median(deviations)
+
+	res := make([]bool, len(values))
+	for i, v := range values {
+		if v < m && !low {
+			continue
+		}
+		d := deviations[i]
+		if d <= outlierRelativeDeviation*math.Abs(m) || d <= minDeviation {
+			continue
+		}
+		// 0.6745 is the 0.75 quantile of the standard normal distribution, which makes the score comparable to the standard z-score
+		res[i] = mad == 0 || 0.6745*d/mad > outlierScore
+	}
+	return res
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
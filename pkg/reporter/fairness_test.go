@@ -0,0 +1,110 @@
+package reporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+func Test_outliers(t *testing.T) {
+	tests := []struct {
+		name         string
+		values       []float64
+		low          bool
+		minDeviation float64
+		want         []bool
+	}{
+		{
+			name:   "uniform values",
+			values: []float64{100, 98, 102, 101},
+			want:   []bool{false, false, false, false},
+		},
+		{
+			name:   "high outlier",
+			values: []float64{10, 11, 10, 12, 300},
+			want:   []bool{false, false, false, false, true},
+		},
+		{
+			name:   "low outlier is ignored",
+			values: []float64{100, 98, 102, 10},
+			want:   []bool{false, false, false, false},
+		},
+		{
+			name:   "low outlier",
+			values: []float64{100, 98, 102, 10},
+			low:    true,
+			want:   []bool{false, false, false, true},
+		},
+		{
+			name:   "identical values except one",
+			values: []float64{0, 0, 0, 0.2},
+			want:   []bool{false, false, false, true},
+		},
+		{
+			name:         "deviation below minimal deviation",
+			values:       []float64{0, 0, 0, 0.001},
+			minDeviation: 0.01,
+			want:         []bool{false, false, false, false},
+		},
+		{
+			name:   "deviation below relative deviation",
+			values: []float64{10, 10, 10, 14},
+			want:   []bool{false, false, false, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, outliers(tt.values, tt.low, tt.minDeviation))
+		})
+	}
+}
+
+func Test_summarizeFairness(t *testing.T) {
+	worker := func(total, ioErrors int64, latency time.Duration) WorkerResultStats {
+		hist := hdrhistogram.New(0, int64(10*time.Second), 1)
+		for range total - ioErrors {
+			hist.RecordValue(int64(latency))
+		}
+		return WorkerResultStats{Counters: dnsbench.Counters{Total: total, Success: total - ioErrors, IOError: ioErrors}, Hist: hist}
+	}
+
+	t.Run("outlier worker", func(t *testing.T) {
+		summary := summarizeFairness([]WorkerResultStats{
+			worker(100, 0, 10*time.Millisecond),
+			worker(100, 50, 10*time.Millisecond),
+			worker(100, 0, 10*time.Millisecond),
+			worker(100, 0, 300*time.Millisecond),
+		}, nil)
+
+		assert.Len(t, summary.workers, 4)
+		assert.Empty(t, summary.workers[0].outliers)
+		assert.Equal(t, []string{"errors"}, summary.workers[1].outliers)
+		assert.Empty(t, summary.workers[2].outliers)
+		assert.Equal(t, []string{"p99"}, summary.workers[3].outliers)
+		assert.Equal(t, 2, summary.outlierWorkers())
+		assert.Empty(t, summary.connections)
+	})
+
+	t.Run("too few workers", func(t *testing.T) {
+		summary := summarizeFairness([]WorkerResultStats{
+			worker(100, 0, 10*time.Millisecond),
+			worker(10, 5, 300*time.Millisecond),
+		}, nil)
+
+		assert.Zero(t, summary.outlierWorkers())
+	})
+
+	t.Run("connections ordered by address", func(t *testing.T) {
+		summary := summarizeFairness(nil, map[string]*dnsbench.ConnectionStats{
+			"127.0.0.1:50002": {Hist: hdrhistogram.New(0, 1, 1)},
+			"127.0.0.1:50001": {Hist: hdrhistogram.New(0, 1, 1)},
+		})
+
+		assert.Equal(t, "127.0.0.1:50001", summary.connections[0].name)
+		assert.Equal(t, "127.0.0.1:50002", summary.connections[1].name)
+		assert.Empty(t, summary.outlierConnections())
+	})
+}
@@ -0,0 +1,353 @@
+package reporter
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// maxReportedQueries is a maximum number of queries listed in the run configuration of the HTML report.
+const maxReportedQueries = 5
+
+//go:embed htmlreport.html
+var htmlReportTemplate string
+
+var htmlReport = template.Must(template.New("report").Parse(htmlReportTemplate))
+
+// htmlReportData is rendered by the HTML report template.
+type htmlReportData struct {
+	Server        string
+	Configuration []htmlEntry
+	Summary       []htmlEntry
+	Latency       []htmlEntry
+	// Counts are the tables of the counts, the tables without counts are not rendered.
+	Counts []htmlCounts
+	Charts []htmlChart
+}
+
+type htmlCounts struct {
+	Title  string
+	Header string
+	Counts []htmlCount
+}
+
+type htmlEntry struct {
+	Name  string
+	Value string
+}
+
+type htmlCount struct {
+	Name    string
+	Count   int64
+	Percent string
+}
+
+type htmlChart struct {
+	Name string
+	// SVG is the chart rendered as inline SVG.
+	SVG template.HTML
+	// Axes is JSON encoded htmlChartAxes used to show the tooltips.
+	Axes string
+}
+
+// htmlChartAxes describes the mapping of the position within the SVG to the plotted values.
+type htmlChartAxes struct {
+	// Area is the area of the plotted data in the SVG coordinates as [left, top, right, bottom].
+	Area [4]float64 `json:"area"`
+	X    htmlAxis   `json:"x"`
+	Y    htmlAxis   `json:"y"`
+}
+
+type htmlAxis struct {
+	Label      string         `json:"label"`
+	Min        float64        `json:"min"`
+	Max        float64        `json:"max"`
+	Scale      axisScale      `json:"scale"`
+	Categories []htmlCategory `json:"categories,omitempty"`
+}
+
+type htmlCategory struct {
+	Value float64 `json:"value"`
+	Label string  `json:"label"`
+}
+
+// exportHTML writes self-contained HTML report with the charts embedded as inline SVG to the file.
+func exportHTML(path string, params reportParameters, charts []chart) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file for HTML report due to '%v'", err)
+	}
+	defer f.Close()
+
+	if err := writeHTMLReport(f, params, charts); err != nil {
+		return fmt.Errorf("failed to write HTML report due to '%v'", err)
+	}
+	return nil
+}
+
+func writeHTMLReport(w io.Writer, params reportParameters, charts []chart) error {
+	b := params.benchmark
+	data := htmlReportData{
+		Server:        b.Server,
+		Configuration: htmlConfiguration(b),
+		Summary:       htmlSummary(params),
+	}
+	if params.hist.TotalCount() > 0 {
+		data.Latency = htmlLatency(params)
+	}
+
+	var errorClasses []htmlCount
+	for _, c := range sortedErrorClasses(params.errorClasses) {
+		errorClasses = append(errorClasses, htmlCount{Name: c.String(), Count: params.errorClasses[c]})
+	}
+	var topErrors []htmlCount
+	for _, e := range params.topErrs.order {
+		topErrors = append(topErrors, htmlCount{Name: e, Count: int64(params.topErrs.m[e])})
+	}
+	data.Counts = []htmlCounts{
+		{Title: "DNS question types", Header: "Question type", Counts: sortedHTMLCounts(params.qtypeTotals, func(k string) string { return k })},
+		{Title: "DNS response codes", Header: "Response code", Counts: sortedHTMLCounts(params.codeTotals, func(k int) string { return dns.RcodeToString[k] })},
+		{Title: "DoH HTTP response status codes", Header: "Status code", Counts: sortedHTMLCounts(params.dohResponseStatusesTotals, strconv.Itoa)},
+		{Title: "Extended DNS Errors", Header: "Extended DNS Error", Counts: sortedHTMLCounts(params.edeCodes, edeCodeName)},
+		{Title: "IO errors by class", Header: "Class", Counts: withPercents(errorClasses)},
+		{Title: "Top errors", Header: "Error", Counts: withPercents(topErrors)},
+	}
+
+	for _, c := range charts {
+		chart, ok, err := renderHTMLChart(c)
+		if err != nil {
+			fmt.Fprintln(b.ErrWriter, err)
+			continue
+		}
+		if ok {
+			data.Charts = append(data.Charts, chart)
+		}
+	}
+
+	return htmlReport.Execute(w, data)
+}
+
+func htmlConfiguration(b *dnsbench.Benchmark) []htmlEntry {
+	entries := []htmlEntry{
+		{Name: "Server", Value: b.Server},
+		{Name: "Protocol", Value: htmlProtocol(b)},
+		{Name: "Query types", Value: strings.Join(b.Types, ", ")},
+		{Name: "Concurrency", Value: strconv.FormatUint(uint64(b.Concurrency), 10)},
+	}
+	if len(b.Queries) > 0 {
+		queries := strings.Join(b.Queries[:min(len(b.Queries), maxReportedQueries)], ", ")
+		if len(b.Queries) > maxReportedQueries {
+			queries += fmt.Sprintf(" and %d more", len(b.Queries)-maxReportedQueries)
+		}
+		entries = append(entries, htmlEntry{Name: "Queries", Value: queries})
+	}
+	if b.Count > 0 {
+		entries = append(entries, htmlEntry{Name: "Number of queries per worker", Value: strconv.FormatInt(b.Count, 10)})
+	}
+	if b.Duration > 0 {
+		entries = append(entries, htmlEntry{Name: "Duration", Value: b.Duration.String()})
+	}
+	if b.Rate > 0 {
+		entries = append(entries, htmlEntry{Name: "Rate limit", Value: strconv.Itoa(b.Rate) + " per second"})
+	}
+	if b.RateLimitWorker > 0 {
+		entries = append(entries, htmlEntry{Name: "Rate limit per worker", Value: strconv.Itoa(b.RateLimitWorker) + " per second"})
+	}
+	if b.TargetLatency > 0 {
+		entries = append(entries, htmlEntry{
+			Name:  "Target latency",
+			Value: fmt.Sprintf("%s at p%s", b.TargetLatency, strconv.FormatFloat(b.TargetPercentile, 'f', -1, 64)),
+		})
+	}
+	if b.QperConn > 0 {
+		entries = append(entries, htmlEntry{Name: "Queries per connection", Value: strconv.FormatInt(b.QperConn, 10)})
+	}
+	if b.Probability > 0 && b.Probability < 1 {
+		entries = append(entries, htmlEntry{Name: "Probability", Value: strconv.FormatFloat(b.Probability, 'f', -1, 64)})
+	}
+	entries = append(entries, htmlEntry{Name: "Recursion desired", Value: strconv.FormatBool(b.Recurse)})
+	if b.Edns0 > 0 {
+		entries = append(entries, htmlEntry{Name: "EDNS0 buffer size", Value: strconv.Itoa(int(b.Edns0))})
+	}
+	if b.DNSSEC {
+		entries = append(entries, htmlEntry{Name: "DNSSEC", Value: "true"})
+	}
+	if b.RequestTimeout > 0 {
+		entries = append(entries, htmlEntry{Name: "Request timeout", Value: b.RequestTimeout.String()})
+	}
+	if len(b.Warmup) > 0 {
+		entries = append(entries, htmlEntry{Name: "Warm-up", Value: b.Warmup})
+	}
+	if b.Iterations > 1 {
+		entries = append(entries, htmlEntry{Name: "Iterations", Value: strconv.Itoa(b.Iterations)})
+	}
+	return entries
+}
+
+func htmlProtocol(b *dnsbench.Benchmark) string {
+	switch {
+	case strings.HasPrefix(b.Server, "https://"):
+		protocol := "DoH"
+		if len(b.DohMethod) > 0 {
+			protocol += " " + strings.ToUpper(b.DohMethod)
+		}
+		if len(b.DohProtocol) > 0 {
+			protocol += " HTTP/" + b.DohProtocol
+		}
+		return protocol
+	case strings.HasPrefix(b.Server, "quic://"):
+		return "DoQ"
+	case b.DOT:
+		return "DoT"
+	case b.TCP:
+		return "TCP"
+	default:
+		return "UDP"
+	}
+}
+
+func htmlSummary(params reportParameters) []htmlEntry {
+	counters := params.totalCounters
+	entries := []htmlEntry{
+		{Name: "Total requests", Value: strconv.FormatInt(counters.Total, 10)},
+		{Name: "Success responses", Value: strconv.FormatInt(counters.Success, 10)},
+		{Name: "Negative responses", Value: strconv.FormatInt(counters.Negative, 10)},
+		{Name: "Error responses", Value: strconv.FormatInt(counters.Error, 10)},
+		{Name: "IO errors", Value: strconv.FormatInt(counters.IOError, 10)},
+		{Name: "ID mismatch errors", Value: strconv.FormatInt(counters.IDmismatch, 10)},
+		{Name: "Truncated responses", Value: strconv.FormatInt(counters.Truncated, 10)},
+		{Name: "Time taken for tests", Value: roundDuration(params.benchmarkDuration).String()},
+		{Name: "Questions per second", Value: fmt.Sprintf("%0.1f", float64(counters.Total)/params.benchmarkDuration.Seconds())},
+	}
+	if params.benchmark.DNSSEC {
+		entries = append(entries, htmlEntry{Name: "Domains secured using DNSSEC", Value: strconv.Itoa(len(params.authenticatedDomains))})
+	}
+	return entries
+}
+
+func htmlLatency(params reportParameters) []htmlEntry {
+	latency := func(v int64) string {
+		return roundDuration(time.Duration(v)).String()
+	}
+	hist := params.hist
+	entries := []htmlEntry{
+		{Name: "Datapoints", Value: strconv.FormatInt(hist.TotalCount(), 10)},
+		{Name: "min", Value: latency(hist.Min())},
+		{Name: "mean", Value: latency(int64(hist.Mean()))},
+		{Name: "[+/-sd]", Value: latency(int64(hist.StdDev()))},
+		{Name: "max", Value: latency(hist.Max())},
+	}
+	for _, p := range percentiles(params.benchmark) {
+		entries = append(entries, htmlEntry{Name: percentileName(p), Value: latency(hist.ValueAtQuantile(p))})
+	}
+	return entries
+}
+
+// sortedHTMLCounts returns the counts sorted by the key.
+func sortedHTMLCounts[K int | uint16 | string](counts map[K]int64, name func(K) string) []htmlCount {
+	keys := make([]K, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	res := make([]htmlCount, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, htmlCount{Name: name(k), Count: counts[k]})
+	}
+	return withPercents(res)
+}
+
+// withPercents sets the percentages of the counts from their total.
+func withPercents(counts []htmlCount) []htmlCount {
+	var total int64
+	for _, c := range counts {
+		total += c.Count
+	}
+	for i := range counts {
+		counts[i].Percent = fmt.Sprintf("%.2f%%", float64(counts[i].Count)/float64(total)*100)
+	}
+	return counts
+}
+
+func edeCodeName(code uint16) string {
+	if s, ok := dns.ExtendedErrorCodeToString[code]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown(%d)", code)
+}
+
+// renderHTMLChart renders the chart as inline SVG, it returns false when there is nothing to plot.
+func renderHTMLChart(c chart) (htmlChart, bool, error) {
+	p, err := c.plot()
+	if err != nil {
+		return htmlChart{}, false, fmt.Errorf("failed to plot %q: %w", c.name, err)
+	}
+	if p == nil {
+		return htmlChart{}, false, nil
+	}
+
+	canvas, err := draw.NewFormattedCanvas(plotSize, plotSize, "svg")
+	if err != nil {
+		return htmlChart{}, false, err
+	}
+	dc := draw.New(canvas)
+	p.Draw(dc)
+	area := p.DataCanvas(dc)
+
+	var buf bytes.Buffer
+	if _, err := canvas.WriteTo(&buf); err != nil {
+		return htmlChart{}, false, fmt.Errorf("failed to plot %q: %w", c.name, err)
+	}
+	// strip the XML declaration, so that the SVG can be embedded into HTML
+	svg := buf.Bytes()
+	if i := bytes.Index(svg, []byte("<svg")); i > 0 {
+		svg = svg[i:]
+	}
+
+	// SVG coordinates start at the top left corner, while the plot coordinates start at the bottom left corner
+	height := plotSize.Points()
+	axes := htmlChartAxes{
+		Area: [4]float64{area.Min.X.Points(), height - area.Max.Y.Points(), area.Max.X.Points(), height - area.Min.Y.Points()},
+		X:    newHTMLAxis(p.X, c.xScale),
+		Y:    newHTMLAxis(p.Y, c.yScale),
+	}
+	axesJSON, err := json.Marshal(axes)
+	if err != nil {
+		return htmlChart{}, false, err
+	}
+
+	return htmlChart{
+		Name: c.name,
+		SVG:  template.HTML(svg), // nolint:gosec // G203: the SVG is generated by the plotting library, which escapes the texts
+		Axes: string(axesJSON),
+	}, true, nil
+}
+
+func newHTMLAxis(axis plot.Axis, scale axisScale) htmlAxis {
+	if len(scale) == 0 {
+		scale = linearScale
+	}
+	res := htmlAxis{Label: axis.Label.Text, Min: axis.Min, Max: axis.Max, Scale: scale}
+	if scale == nominalScale {
+		for _, t := range axis.Tick.Marker.Ticks(axis.Min, axis.Max) {
+			if len(t.Label) > 0 {
+				res.Categories = append(res.Categories, htmlCategory{Value: t.Value, Label: t.Label})
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,105 @@
+package reporter
+
+import (
+	"math"
+	"time"
+
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// confidenceLevel is the level of the confidence intervals of the metrics of repeated iterations.
+const confidenceLevel = 0.95
+
+type iterationsSummary struct {
+	runs        []iterationRun
+	cvThreshold float64
+	// percentiles are the reported latency percentiles, latencies of the runs and their summaries are in the same order.
+	percentiles []float64
+	qps         metricSummary
+	latencies   []metricSummary
+}
+
+type iterationRun struct {
+	duration  time.Duration
+	total     int64
+	qps       float64
+	latencies []time.Duration
+}
+
+// metricSummary holds statistics of single metric across the iterations.
+type metricSummary struct {
+	mean   float64
+	stdDev float64
+	ciLow  float64
+	ciHigh float64
+	// cv is the coefficient of variation, the standard deviation divided by the mean.
+	cv       float64
+	unstable bool
+}
+
+// unstable returns whether any of the metrics varies more than the threshold between the iterations.
+func (s *iterationsSummary) unstable() bool {
+	if s.qps.unstable {
+		return true
+	}
+	for _, l := range s.latencies {
+		if l.unstable {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeIterations computes per-iteration QPS and latency percentiles and aggregates them across the iterations.
+func summarizeIterations(b *dnsbench.Benchmark, iterations []dnsbench.IterationResult) *iterationsSummary {
+	summary := iterationsSummary{cvThreshold: b.CVThreshold, percentiles: percentiles(b)}
+	var qps []float64
+	latencies := make([][]float64, len(summary.percentiles))
+	for _, it := range iterations {
+		totals := Merge(b, it.Stats)
+		duration := it.Duration
+		if totals.Warmup != nil && duration > totals.Warmup.Duration {
+			duration -= totals.Warmup.Duration
+		}
+		run := iterationRun{
+			duration: duration,
+			total:    totals.Counters.Total,
+			qps:      float64(totals.Counters.Total) / duration.Seconds(),
+		}
+		for i, p := range summary.percentiles {
+			latency := time.Duration(totals.Hist.ValueAtQuantile(p))
+			run.latencies = append(run.latencies, latency)
+			latencies[i] = append(latencies[i], float64(latency))
+		}
+		summary.runs = append(summary.runs, run)
+		qps = append(qps, run.qps)
+	}
+	summary.qps = summarizeMetric(qps, b.CVThreshold)
+	for _, l := range latencies {
+		summary.latencies = append(summary.latencies, summarizeMetric(l, b.CVThreshold))
+	}
+	return &summary
+}
+
+// summarizeMetric computes mean, sample standard deviation, confidence interval of the mean using Student's t-distribution
+// and coefficient of variation of the values.
+func summarizeMetric(values []float64, cvThreshold float64) metricSummary {
+	if len(values) == 0 {
+		return metricSummary{}
+	}
+	if len(values) == 1 {
+		return metricSummary{mean: values[0], ciLow: values[0], ciHigh: values[0]}
+	}
+	mean, stdDev := stat.MeanStdDev(values, nil)
+	n := float64(len(values))
+	t := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: n - 1}.Quantile(1 - (1-confidenceLevel)/2)
+	margin := t * stdDev / math.Sqrt(n)
+	res := metricSummary{mean: mean, stdDev: stdDev, ciLow: mean - margin, ciHigh: mean + margin}
+	if mean != 0 {
+		res.cv = stdDev / math.Abs(mean)
+	}
+	res.unstable = res.cv > cvThreshold
+	return res
+}
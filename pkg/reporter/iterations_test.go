@@ -0,0 +1,86 @@
+package reporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+func Test_summarizeMetric(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []float64
+		cvThreshold float64
+		want        metricSummary
+	}{
+		{
+			name:        "stable",
+			values:      []float64{100, 102, 98, 100},
+			cvThreshold: 0.1,
+			want:        metricSummary{mean: 100, stdDev: 1.633, ciLow: 97.402, ciHigh: 102.598, cv: 0.0163},
+		},
+		{
+			name:        "unstable",
+			values:      []float64{10, 12, 14},
+			cvThreshold: 0.1,
+			want:        metricSummary{mean: 12, stdDev: 2, ciLow: 7.032, ciHigh: 16.968, cv: 0.1667, unstable: true},
+		},
+		{
+			name:        "single iteration",
+			values:      []float64{10},
+			cvThreshold: 0.1,
+			want:        metricSummary{mean: 10, ciLow: 10, ciHigh: 10},
+		},
+		{
+			name: "no iterations",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarizeMetric(tt.values, tt.cvThreshold)
+
+			assert.InDelta(t, tt.want.mean, got.mean, 0.001)
+			assert.InDelta(t, tt.want.stdDev, got.stdDev, 0.001)
+			assert.InDelta(t, tt.want.ciLow, got.ciLow, 0.001)
+			assert.InDelta(t, tt.want.ciHigh, got.ciHigh, 0.001)
+			assert.InDelta(t, tt.want.cv, got.cv, 0.0001)
+			assert.Equal(t, tt.want.unstable, got.unstable)
+		})
+	}
+}
+
+func Test_summarizeIterations(t *testing.T) {
+	b := &dnsbench.Benchmark{HistMax: time.Second, HistPre: 1, CVThreshold: 0.1, Percentiles: []float64{99.9, 50}}
+	iteration := func(total int64, duration, warmup time.Duration, latency time.Duration) dnsbench.IterationResult {
+		hist := hdrhistogram.New(0, int64(time.Second), 1)
+		hist.RecordValue(int64(latency))
+		return dnsbench.IterationResult{
+			Duration: duration,
+			Stats: []*dnsbench.ResultStats{{
+				Hist:     hist,
+				Counters: &dnsbench.Counters{Total: total, Success: total},
+				Warmup:   &dnsbench.WarmupStats{Hist: hdrhistogram.New(0, int64(time.Second), 1), Duration: warmup},
+			}},
+		}
+	}
+
+	summary := summarizeIterations(b, []dnsbench.IterationResult{
+		iteration(100, 2*time.Second, time.Second, 10*time.Millisecond),
+		iteration(200, 2*time.Second, 0, 10*time.Millisecond),
+	})
+
+	require.Len(t, summary.runs, 2)
+	assert.InDelta(t, 100, summary.runs[0].qps, 0.001, "QPS should be computed from the measured window after the warm-up")
+	assert.Equal(t, time.Second, summary.runs[0].duration)
+	assert.InDelta(t, 100, summary.runs[1].qps, 0.001)
+	assert.InDelta(t, 100, summary.qps.mean, 0.001)
+	assert.False(t, summary.unstable())
+	assert.Equal(t, []float64{99.9, 50}, summary.percentiles, "configured percentiles should be summarized")
+	require.Len(t, summary.latencies, 2)
+	require.Len(t, summary.runs[0].latencies, 2)
+	assert.Equal(t, time.Duration(summary.latencies[0].mean), summary.runs[0].latencies[0])
+}
@@ -5,6 +5,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/miekg/dns"
 )
 
@@ -20,6 +21,8 @@ type latencyStats struct {
 	P90Ms  int64 `json:"p90Ms"`
 	P75Ms  int64 `json:"p75Ms"`
 	P50Ms  int64 `json:"p50Ms"`
+	// PercentilesMs holds the latency percentiles configured using Benchmark.Percentiles keyed by their names, for example p99.9.
+	PercentilesMs map[string]int64 `json:"percentilesMs,omitempty"`
 }
 
 type histogramPoint struct {
@@ -28,22 +31,214 @@ type histogramPoint struct {
 }
 
 type jsonResult struct {
-	TotalRequests              int64            `json:"totalRequests"`
-	TotalSuccessResponses      int64            `json:"totalSuccessResponses"`
-	TotalNegativeResponses     int64            `json:"totalNegativeResponses"`
-	TotalErrorResponses        int64            `json:"totalErrorResponses"`
-	TotalIOErrors              int64            `json:"totalIOErrors"`
-	TotalIDmismatch            int64            `json:"totalIDmismatch"`
-	TotalTruncatedResponses    int64            `json:"totalTruncatedResponses"`
-	ResponseRcodes             map[string]int64 `json:"responseRcodes,omitempty"`
-	QuestionTypes              map[string]int64 `json:"questionTypes"`
-	QueriesPerSecond           float64          `json:"queriesPerSecond"`
-	BenchmarkDurationSeconds   float64          `json:"benchmarkDurationSeconds"`
-	LatencyStats               latencyStats     `json:"latencyStats"`
-	LatencyDistribution        []histogramPoint `json:"latencyDistribution,omitempty"`
-	TotalDNSSECSecuredDomains  *int             `json:"totalDNSSECSecuredDomains,omitempty"`
-	DohHTTPResponseStatusCodes map[int]int64    `json:"dohHTTPResponseStatusCodes,omitempty"`
-	ExtendedDNSErrors          map[uint16]int64 `json:"extendedDNSErrors,omitempty"`
+	TotalRequests              int64                   `json:"totalRequests"`
+	TotalSuccessResponses      int64                   `json:"totalSuccessResponses"`
+	TotalNegativeResponses     int64                   `json:"totalNegativeResponses"`
+	TotalErrorResponses        int64                   `json:"totalErrorResponses"`
+	TotalIOErrors              int64                   `json:"totalIOErrors"`
+	TotalIDmismatch            int64                   `json:"totalIDmismatch"`
+	TotalTruncatedResponses    int64                   `json:"totalTruncatedResponses"`
+	ResponseRcodes             map[string]int64        `json:"responseRcodes,omitempty"`
+	QuestionTypes              map[string]int64        `json:"questionTypes"`
+	QueriesPerSecond           float64                 `json:"queriesPerSecond"`
+	BenchmarkDurationSeconds   float64                 `json:"benchmarkDurationSeconds"`
+	LatencyStats               latencyStats            `json:"latencyStats"`
+	LatencyDistribution        []histogramPoint        `json:"latencyDistribution,omitempty"`
+	TotalDNSSECSecuredDomains  *int                    `json:"totalDNSSECSecuredDomains,omitempty"`
+	DohHTTPResponseStatusCodes map[int]int64           `json:"dohHTTPResponseStatusCodes,omitempty"`
+	ExtendedDNSErrors          map[uint16]int64        `json:"extendedDNSErrors,omitempty"`
+	AnswerConsistency          *jsonConsistency        `json:"answerConsistency,omitempty"`
+	TTLAnalysis                *jsonTTLAnalysis        `json:"ttlAnalysis,omitempty"`
+	LatencyStatsByQtype        map[string]latencyStats `json:"latencyStatsByQuestionType,omitempty"`
+	LatencyStatsByRcode        map[string]latencyStats `json:"latencyStatsByResponseCode,omitempty"`
+	IOErrorLatencyStats        *latencyStats           `json:"ioErrorLatencyStats,omitempty"`
+	IOErrorClasses             []jsonErrorClass        `json:"ioErrorClasses,omitempty"`
+	Sizes                      *jsonSizes              `json:"sizes,omitempty"`
+	Domains                    *jsonDomains            `json:"domains,omitempty"`
+	AdaptiveRate               *jsonAdaptiveRate       `json:"adaptiveRate,omitempty"`
+	Warmup                     *jsonWarmup             `json:"warmup,omitempty"`
+	Iterations                 *jsonIterations         `json:"iterations,omitempty"`
+	WorkerStats                *jsonWorkerStats        `json:"workerStats,omitempty"`
+}
+
+type jsonErrorClass struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail,omitempty"`
+	Count  int64  `json:"count"`
+}
+
+type jsonWorkerStats struct {
+	Workers     []jsonFairnessStats `json:"workers"`
+	Connections []jsonFairnessStats `json:"connections"`
+}
+
+type jsonFairnessStats struct {
+	Worker              *int         `json:"worker,omitempty"`
+	LocalAddress        string       `json:"localAddress,omitempty"`
+	TotalRequests       int64        `json:"totalRequests"`
+	TotalErrors         int64        `json:"totalErrors"`
+	TotalIOErrors       int64        `json:"totalIOErrors"`
+	TotalErrorResponses int64        `json:"totalErrorResponses"`
+	LatencyStats        latencyStats `json:"latencyStats"`
+	Outliers            []string     `json:"outliers,omitempty"`
+}
+
+type jsonIterations struct {
+	Count            int               `json:"count"`
+	CVThreshold      float64           `json:"cvThreshold"`
+	Unstable         bool              `json:"unstable"`
+	QueriesPerSecond jsonMetricSummary `json:"queriesPerSecond"`
+	// PercentilesMs holds the summaries of the latency percentiles keyed by their names, for example p99.
+	PercentilesMs map[string]jsonMetricSummary `json:"percentilesMs"`
+	Runs          []jsonIterationRun           `json:"runs"`
+}
+
+type jsonMetricSummary struct {
+	Mean     float64 `json:"mean"`
+	StdDev   float64 `json:"stdDev"`
+	CI95Low  float64 `json:"ci95Low"`
+	CI95High float64 `json:"ci95High"`
+	CV       float64 `json:"cv"`
+	Unstable bool    `json:"unstable"`
+}
+
+type jsonIterationRun struct {
+	DurationSeconds  float64 `json:"durationSeconds"`
+	TotalRequests    int64   `json:"totalRequests"`
+	QueriesPerSecond float64 `json:"queriesPerSecond"`
+	// PercentilesMs holds the latency percentiles of the iteration keyed by their names, for example p99.
+	PercentilesMs map[string]float64 `json:"percentilesMs"`
+}
+
+type jsonWarmup struct {
+	DurationSeconds        float64      `json:"durationSeconds"`
+	TotalRequests          int64        `json:"totalRequests"`
+	TotalSuccessResponses  int64        `json:"totalSuccessResponses"`
+	TotalNegativeResponses int64        `json:"totalNegativeResponses"`
+	TotalErrorResponses    int64        `json:"totalErrorResponses"`
+	TotalIOErrors          int64        `json:"totalIOErrors"`
+	LatencyStats           latencyStats `json:"latencyStats"`
+}
+
+type jsonAdaptiveRate struct {
+	TargetLatencyMs  int64                `json:"targetLatencyMs"`
+	TargetPercentile float64              `json:"targetPercentile"`
+	TargetErrorRate  float64              `json:"targetErrorRate"`
+	StartRate        int                  `json:"startRate"`
+	FinalRate        int                  `json:"finalRate"`
+	MinRate          int                  `json:"minRate"`
+	MaxRate          int                  `json:"maxRate"`
+	Adjustments      []jsonRateAdjustment `json:"adjustments"`
+}
+
+type jsonRateAdjustment struct {
+	Timestamp        time.Time `json:"timestamp"`
+	ElapsedSeconds   float64   `json:"elapsedSeconds"`
+	PreviousRate     int       `json:"previousRate"`
+	Rate             int       `json:"rate"`
+	LatencyMs        int64     `json:"latencyMs"`
+	ErrorRate        float64   `json:"errorRate"`
+	QueriesPerSecond float64   `json:"queriesPerSecond"`
+}
+
+type jsonDomains struct {
+	Slowest []jsonDomainStats `json:"slowest"`
+	Failing []jsonDomainStats `json:"mostErrors"`
+}
+
+type jsonDomainStats struct {
+	Domain         string           `json:"domain"`
+	TotalRequests  int64            `json:"totalRequests"`
+	TotalErrors    int64            `json:"totalErrors"`
+	TotalIOErrors  int64            `json:"totalIOErrors"`
+	ResponseRcodes map[string]int64 `json:"responseRcodes"`
+	LatencyStats   latencyStats     `json:"latencyStats"`
+}
+
+type jsonSizes struct {
+	RequestSizeStats       jsonSizeStats      `json:"requestSizeStats"`
+	ResponseSizeStats      jsonSizeStats      `json:"responseSizeStats"`
+	BytesSent              int64              `json:"bytesSent"`
+	BytesReceived          int64              `json:"bytesReceived"`
+	BytesSentPerSecond     float64            `json:"bytesSentPerSecond"`
+	BytesReceivedPerSecond float64            `json:"bytesReceivedPerSecond"`
+	Amplification          map[string]float64 `json:"amplification,omitempty"`
+	LargestResponses       []jsonResponseSize `json:"largestResponses,omitempty"`
+	NearBufferSize         int64              `json:"responsesNearEdns0BufferSize"`
+}
+
+type jsonSizeStats struct {
+	MinBytes  int64   `json:"minBytes"`
+	MeanBytes float64 `json:"meanBytes"`
+	MaxBytes  int64   `json:"maxBytes"`
+	P99Bytes  int64   `json:"p99Bytes"`
+	P95Bytes  int64   `json:"p95Bytes"`
+	P50Bytes  int64   `json:"p50Bytes"`
+}
+
+type jsonResponseSize struct {
+	Domain       string `json:"domain"`
+	QuestionType string `json:"questionType"`
+	SizeBytes    int    `json:"sizeBytes"`
+}
+
+type jsonTTLAnalysis struct {
+	TotalAnswers      int64                `json:"totalAnswers"`
+	TotalQuestions    int                  `json:"totalQuestions"`
+	TTLStats          jsonTTLStats         `json:"ttlStats"`
+	CacheHits         int64                `json:"cacheHits"`
+	CacheMisses       int64                `json:"cacheMisses"`
+	PrefetchedAnswers int64                `json:"prefetchedAnswers"`
+	StaleAnswers      int64                `json:"staleAnswers"`
+	NotCachedAnswers  int64                `json:"notCachedAnswers"`
+	LatencyClusters   *jsonLatencyClusters `json:"latencyClusters,omitempty"`
+	ClampedTTLs       []jsonClampedTTL     `json:"clampedTTLs,omitempty"`
+}
+
+type jsonTTLStats struct {
+	MinSeconds int64 `json:"minSeconds"`
+	P50Seconds int64 `json:"p50Seconds"`
+	P90Seconds int64 `json:"p90Seconds"`
+	MaxSeconds int64 `json:"maxSeconds"`
+}
+
+type jsonLatencyClusters struct {
+	ThresholdMs float64 `json:"thresholdMs"`
+	Fast        int64   `json:"fast"`
+	Slow        int64   `json:"slow"`
+}
+
+type jsonClampedTTL struct {
+	Domain                  string `json:"domain"`
+	QuestionType            string `json:"questionType"`
+	AuthoritativeTTLSeconds uint32 `json:"authoritativeTTLSeconds"`
+	MinTTLSeconds           uint32 `json:"minTTLSeconds"`
+	MaxTTLSeconds           uint32 `json:"maxTTLSeconds"`
+}
+
+type jsonConsistency struct {
+	ComparedAnswers   int64             `json:"comparedAnswers"`
+	MismatchedAnswers int64             `json:"mismatchedAnswers"`
+	SkippedAnswers    int64             `json:"skippedAnswers"`
+	Servers           []jsonServerStats `json:"servers"`
+	Differences       []jsonDifference  `json:"differences"`
+}
+
+type jsonServerStats struct {
+	Server        string       `json:"server"`
+	TotalRequests int64        `json:"totalRequests"`
+	TotalIOErrors int64        `json:"totalIOErrors"`
+	LatencyStats  latencyStats `json:"latencyStats"`
+}
+
+type jsonDifference struct {
+	Server       string `json:"server"`
+	Domain       string `json:"domain"`
+	QuestionType string `json:"questionType"`
+	Kind         string `json:"kind"`
+	Count        int64  `json:"count"`
+	Expected     string `json:"expected"`
+	Actual       string `json:"actual"`
 }
 
 func (s *jsonReporter) print(params reportParameters) error {
@@ -84,36 +279,338 @@ func (s *jsonReporter) print(params reportParameters) error {
 	}
 
 	result := jsonResult{
-		TotalRequests:            params.totalCounters.Total,
-		TotalSuccessResponses:    params.totalCounters.Success,
-		TotalNegativeResponses:   params.totalCounters.Negative,
-		TotalErrorResponses:      params.totalCounters.Error,
-		TotalIOErrors:            params.totalCounters.IOError,
-		TotalIDmismatch:          params.totalCounters.IDmismatch,
-		TotalTruncatedResponses:  params.totalCounters.Truncated,
-		QueriesPerSecond:         math.Round(float64(params.totalCounters.Total)/params.benchmarkDuration.Seconds()*100) / 100,
-		BenchmarkDurationSeconds: roundDuration(params.benchmarkDuration).Seconds(),
-		ResponseRcodes:           codeTotalsMapped,
-		QuestionTypes:            params.qtypeTotals,
-		LatencyStats: latencyStats{
-			MinMs:  roundDuration(time.Duration(params.hist.Min())).Milliseconds(),
-			MeanMs: roundDuration(time.Duration(params.hist.Mean())).Milliseconds(),
-			StdMs:  roundDuration(time.Duration(params.hist.StdDev())).Milliseconds(),
-			MaxMs:  roundDuration(time.Duration(params.hist.Max())).Milliseconds(),
-			P99Ms:  roundDuration(time.Duration(params.hist.ValueAtQuantile(99))).Milliseconds(),
-			P95Ms:  roundDuration(time.Duration(params.hist.ValueAtQuantile(95))).Milliseconds(),
-			P90Ms:  roundDuration(time.Duration(params.hist.ValueAtQuantile(90))).Milliseconds(),
-			P75Ms:  roundDuration(time.Duration(params.hist.ValueAtQuantile(75))).Milliseconds(),
-			P50Ms:  roundDuration(time.Duration(params.hist.ValueAtQuantile(50))).Milliseconds(),
-		},
+		TotalRequests:              params.totalCounters.Total,
+		TotalSuccessResponses:      params.totalCounters.Success,
+		TotalNegativeResponses:     params.totalCounters.Negative,
+		TotalErrorResponses:        params.totalCounters.Error,
+		TotalIOErrors:              params.totalCounters.IOError,
+		TotalIDmismatch:            params.totalCounters.IDmismatch,
+		TotalTruncatedResponses:    params.totalCounters.Truncated,
+		QueriesPerSecond:           math.Round(float64(params.totalCounters.Total)/params.benchmarkDuration.Seconds()*100) / 100,
+		BenchmarkDurationSeconds:   roundDuration(params.benchmarkDuration).Seconds(),
+		ResponseRcodes:             codeTotalsMapped,
+		QuestionTypes:              params.qtypeTotals,
+		LatencyStats:               newLatencyStats(params.hist),
 		LatencyDistribution:        res,
 		DohHTTPResponseStatusCodes: params.dohResponseStatusesTotals,
 		ExtendedDNSErrors:          params.edeCodes,
 	}
+	result.LatencyStats.addPercentiles(params.hist, params.benchmark.Percentiles)
 	if params.benchmark.DNSSEC {
 		totalDNSSECSecuredDomains := len(params.authenticatedDomains)
 		result.TotalDNSSECSecuredDomains = &totalDNSSECSecuredDomains
 	}
 
+	if params.consistency != nil {
+		result.AnswerConsistency = newJSONConsistency(params)
+	}
+	if params.ttls != nil {
+		result.TTLAnalysis = newJSONTTLAnalysis(params)
+	}
+	if len(params.qtypeHists) > 0 {
+		result.LatencyStatsByQtype = newLatencyStatsByKey(params.qtypeHists, params.benchmark.Percentiles)
+	}
+	if len(params.rcodeHists) > 0 {
+		result.LatencyStatsByRcode = newLatencyStatsByKey(params.rcodeHists, params.benchmark.Percentiles)
+	}
+	if params.errorHist != nil && params.errorHist.TotalCount() > 0 {
+		ioErrorLatencyStats := newLatencyStats(params.errorHist)
+		ioErrorLatencyStats.addPercentiles(params.errorHist, params.benchmark.Percentiles)
+		result.IOErrorLatencyStats = &ioErrorLatencyStats
+	}
+	for _, c := range sortedErrorClasses(params.errorClasses) {
+		result.IOErrorClasses = append(result.IOErrorClasses, jsonErrorClass{Type: string(c.Type), Detail: c.Detail, Count: params.errorClasses[c]})
+	}
+	if params.sizes != nil {
+		result.Sizes = newJSONSizes(params)
+	}
+	if params.domains != nil {
+		result.Domains = newJSONDomains(params)
+	}
+	if params.benchmark.TargetLatency > 0 {
+		result.AdaptiveRate = newJSONAdaptiveRate(params)
+	}
+	if params.iterations != nil {
+		result.Iterations = newJSONIterations(params.iterations)
+	}
+	if params.fairness != nil {
+		result.WorkerStats = newJSONWorkerStats(params.fairness)
+	}
+	if params.warmup != nil {
+		result.Warmup = &jsonWarmup{
+			DurationSeconds:        roundDuration(params.warmup.Duration).Seconds(),
+			TotalRequests:          params.warmup.Counters.Total,
+			TotalSuccessResponses:  params.warmup.Counters.Success,
+			TotalNegativeResponses: params.warmup.Counters.Negative,
+			TotalErrorResponses:    params.warmup.Counters.Error,
+			TotalIOErrors:          params.warmup.Counters.IOError,
+			LatencyStats:           newLatencyStats(params.warmup.Hist),
+		}
+	}
+
 	return json.NewEncoder(params.outputWriter).Encode(result)
 }
+
+func newLatencyStats(hist *hdrhistogram.Histogram) latencyStats {
+	return latencyStats{
+		MinMs:  roundDuration(time.Duration(hist.Min())).Milliseconds(),
+		MeanMs: roundDuration(time.Duration(hist.Mean())).Milliseconds(),
+		StdMs:  roundDuration(time.Duration(hist.StdDev())).Milliseconds(),
+		MaxMs:  roundDuration(time.Duration(hist.Max())).Milliseconds(),
+		P99Ms:  roundDuration(time.Duration(hist.ValueAtQuantile(99))).Milliseconds(),
+		P95Ms:  roundDuration(time.Duration(hist.ValueAtQuantile(95))).Milliseconds(),
+		P90Ms:  roundDuration(time.Duration(hist.ValueAtQuantile(90))).Milliseconds(),
+		P75Ms:  roundDuration(time.Duration(hist.ValueAtQuantile(75))).Milliseconds(),
+		P50Ms:  roundDuration(time.Duration(hist.ValueAtQuantile(50))).Milliseconds(),
+	}
+}
+
+// addPercentiles adds the latency percentiles configured using Benchmark.Percentiles, nothing is added when the percentiles are not configured.
+func (s *latencyStats) addPercentiles(hist *hdrhistogram.Histogram, percentiles []float64) {
+	if len(percentiles) == 0 {
+		return
+	}
+	s.PercentilesMs = make(map[string]int64, len(percentiles))
+	for _, p := range percentiles {
+		s.PercentilesMs[percentileName(p)] = roundDuration(time.Duration(hist.ValueAtQuantile(p))).Milliseconds()
+	}
+}
+
+func newLatencyStatsByKey(hists map[string]*hdrhistogram.Histogram, percentiles []float64) map[string]latencyStats {
+	res := make(map[string]latencyStats, len(hists))
+	for k, v := range hists {
+		stats := newLatencyStats(v)
+		stats.addPercentiles(v, percentiles)
+		res[k] = stats
+	}
+	return res
+}
+
+func newJSONConsistency(params reportParameters) *jsonConsistency {
+	res := jsonConsistency{
+		ComparedAnswers:   params.consistency.Compared,
+		MismatchedAnswers: params.consistency.Mismatched,
+		SkippedAnswers:    params.consistency.Skipped,
+		Servers:           make([]jsonServerStats, 0, len(params.consistency.Servers)+1),
+		Differences:       make([]jsonDifference, 0, len(params.consistency.Differences)),
+	}
+	for _, s := range comparedServers(params) {
+		res.Servers = append(res.Servers, jsonServerStats{
+			Server:        s.server,
+			TotalRequests: s.counters.Total,
+			TotalIOErrors: s.counters.IOError,
+			LatencyStats:  newLatencyStats(s.hist),
+		})
+	}
+	for _, d := range sortedDifferences(params.consistency.Differences) {
+		res.Differences = append(res.Differences, jsonDifference{
+			Server:       d.key.Server,
+			Domain:       d.key.Domain,
+			QuestionType: d.key.Qtype,
+			Kind:         string(d.key.Kind),
+			Count:        d.Count,
+			Expected:     d.Expected,
+			Actual:       d.Actual,
+		})
+	}
+	return &res
+}
+
+func newJSONTTLAnalysis(params reportParameters) *jsonTTLAnalysis {
+	summary := summarizeTTLs(params.ttls)
+	res := jsonTTLAnalysis{
+		TotalAnswers:   summary.answers,
+		TotalQuestions: summary.questions,
+		TTLStats: jsonTTLStats{
+			MinSeconds: params.ttlHist.Min(),
+			P50Seconds: params.ttlHist.ValueAtQuantile(50),
+			P90Seconds: params.ttlHist.ValueAtQuantile(90),
+			MaxSeconds: params.ttlHist.Max(),
+		},
+		CacheHits:         summary.hits,
+		CacheMisses:       summary.misses,
+		PrefetchedAnswers: summary.prefetches,
+		StaleAnswers:      summary.stale,
+		NotCachedAnswers:  summary.notCached,
+	}
+	if clusters, ok := findLatencyClusters(params.hist); ok {
+		res.LatencyClusters = &jsonLatencyClusters{
+			ThresholdMs: float64(clusters.threshold.Microseconds()) / 1000,
+			Fast:        clusters.fast,
+			Slow:        clusters.slow,
+		}
+	}
+	for _, c := range summary.clamped {
+		res.ClampedTTLs = append(res.ClampedTTLs, jsonClampedTTL{
+			Domain:                  c.key.Name,
+			QuestionType:            c.key.Qtype,
+			AuthoritativeTTLSeconds: c.authoritativeTTL,
+			MinTTLSeconds:           c.minTTL,
+			MaxTTLSeconds:           c.maxTTL,
+		})
+	}
+	return &res
+}
+
+func newJSONSizes(params reportParameters) *jsonSizes {
+	sizes := params.sizes
+	res := jsonSizes{
+		RequestSizeStats:       newJSONSizeStats(sizes.RequestHist),
+		ResponseSizeStats:      newJSONSizeStats(sizes.ResponseHist),
+		BytesSent:              sizes.BytesSent,
+		BytesReceived:          sizes.BytesReceived,
+		BytesSentPerSecond:     math.Round(float64(sizes.BytesSent)/params.benchmarkDuration.Seconds()*100) / 100,
+		BytesReceivedPerSecond: math.Round(float64(sizes.BytesReceived)/params.benchmarkDuration.Seconds()*100) / 100,
+		NearBufferSize:         sizes.NearBufferSize,
+	}
+	if len(sizes.Qtypes) > 0 {
+		res.Amplification = make(map[string]float64, len(sizes.Qtypes))
+		for k, v := range sizes.Qtypes {
+			res.Amplification[k] = math.Round(v.Amplification()*100) / 100
+		}
+	}
+	for _, l := range sizes.Largest {
+		res.LargestResponses = append(res.LargestResponses, jsonResponseSize{Domain: l.Name, QuestionType: l.Qtype, SizeBytes: l.Size})
+	}
+	return &res
+}
+
+func newJSONSizeStats(hist *hdrhistogram.Histogram) jsonSizeStats {
+	return jsonSizeStats{
+		MinBytes:  hist.Min(),
+		MeanBytes: math.Round(hist.Mean()*100) / 100,
+		MaxBytes:  hist.Max(),
+		P99Bytes:  hist.ValueAtQuantile(99),
+		P95Bytes:  hist.ValueAtQuantile(95),
+		P50Bytes:  hist.ValueAtQuantile(50),
+	}
+}
+
+func newJSONDomains(params reportParameters) *jsonDomains {
+	summaries := summarizeDomains(params.domains)
+	res := jsonDomains{
+		Slowest: []jsonDomainStats{},
+		Failing: []jsonDomainStats{},
+	}
+	for _, d := range slowestDomains(summaries, params.benchmark.DomainStatsTop) {
+		res.Slowest = append(res.Slowest, newJSONDomainStats(d))
+	}
+	for _, d := range failingDomains(summaries, params.benchmark.DomainStatsTop) {
+		res.Failing = append(res.Failing, newJSONDomainStats(d))
+	}
+	return &res
+}
+
+func newJSONDomainStats(d domainSummary) jsonDomainStats {
+	codes := make(map[string]int64, len(d.Codes))
+	for k, v := range d.Codes {
+		codes[dns.RcodeToString[k]] = v
+	}
+	return jsonDomainStats{
+		Domain:         d.name,
+		TotalRequests:  d.Count,
+		TotalErrors:    d.Errors(),
+		TotalIOErrors:  d.IOError,
+		ResponseRcodes: codes,
+		LatencyStats:   newLatencyStats(d.Hist),
+	}
+}
+
+func newJSONAdaptiveRate(params reportParameters) *jsonAdaptiveRate {
+	b := params.benchmark
+	summary := summarizeRateAdjustments(b.Rate, params.rateAdjustments)
+	res := jsonAdaptiveRate{
+		TargetLatencyMs:  b.TargetLatency.Milliseconds(),
+		TargetPercentile: b.TargetPercentile,
+		TargetErrorRate:  b.TargetErrorRate,
+		StartRate:        summary.startRate,
+		FinalRate:        summary.finalRate,
+		MinRate:          summary.minRate,
+		MaxRate:          summary.maxRate,
+		Adjustments:      make([]jsonRateAdjustment, 0, len(params.rateAdjustments)),
+	}
+	for _, a := range params.rateAdjustments {
+		res.Adjustments = append(res.Adjustments, jsonRateAdjustment{
+			Timestamp:        a.Time.UTC(),
+			ElapsedSeconds:   math.Round(a.Elapsed.Seconds()*1000) / 1000,
+			PreviousRate:     a.PreviousRate,
+			Rate:             a.Rate,
+			LatencyMs:        a.Latency.Milliseconds(),
+			ErrorRate:        math.Round(a.ErrorRate*10000) / 10000,
+			QueriesPerSecond: math.Round(a.QPS*100) / 100,
+		})
+	}
+	return &res
+}
+
+func newJSONIterations(summary *iterationsSummary) *jsonIterations {
+	round := func(v float64) float64 {
+		return math.Round(v*100) / 100
+	}
+	ms := func(v float64) float64 {
+		return round(v / float64(time.Millisecond))
+	}
+	metric := func(m metricSummary, scale func(float64) float64) jsonMetricSummary {
+		return jsonMetricSummary{
+			Mean:     scale(m.mean),
+			StdDev:   scale(m.stdDev),
+			CI95Low:  scale(m.ciLow),
+			CI95High: scale(m.ciHigh),
+			CV:       math.Round(m.cv*10000) / 10000,
+			Unstable: m.unstable,
+		}
+	}
+	res := jsonIterations{
+		Count:            len(summary.runs),
+		CVThreshold:      summary.cvThreshold,
+		Unstable:         summary.unstable(),
+		QueriesPerSecond: metric(summary.qps, round),
+		PercentilesMs:    make(map[string]jsonMetricSummary, len(summary.percentiles)),
+		Runs:             make([]jsonIterationRun, 0, len(summary.runs)),
+	}
+	for i, p := range summary.percentiles {
+		res.PercentilesMs[percentileName(p)] = metric(summary.latencies[i], ms)
+	}
+	for _, r := range summary.runs {
+		run := jsonIterationRun{
+			DurationSeconds:  roundDuration(r.duration).Seconds(),
+			TotalRequests:    r.total,
+			QueriesPerSecond: round(r.qps),
+			PercentilesMs:    make(map[string]float64, len(summary.percentiles)),
+		}
+		for i, p := range summary.percentiles {
+			run.PercentilesMs[percentileName(p)] = ms(float64(r.latencies[i]))
+		}
+		res.Runs = append(res.Runs, run)
+	}
+	return &res
+}
+
+func newJSONWorkerStats(fairness *fairnessSummary) *jsonWorkerStats {
+	res := jsonWorkerStats{
+		Workers:     []jsonFairnessStats{},
+		Connections: []jsonFairnessStats{},
+	}
+	for i, w := range fairness.workers {
+		stats := newJSONFairnessStats(w)
+		stats.Worker = &i
+		res.Workers = append(res.Workers, stats)
+	}
+	for _, c := range fairness.connections {
+		stats := newJSONFairnessStats(c)
+		stats.LocalAddress = c.name
+		res.Connections = append(res.Connections, stats)
+	}
+	return &res
+}
+
+func newJSONFairnessStats(e fairnessEntry) jsonFairnessStats {
+	return jsonFairnessStats{
+		TotalRequests:       e.counters.Total,
+		TotalErrors:         e.errors(),
+		TotalIOErrors:       e.counters.IOError,
+		TotalErrorResponses: e.counters.Error,
+		LatencyStats:        newLatencyStats(e.hist),
+		Outliers:            e.outliers,
+	}
+}
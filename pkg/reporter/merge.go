@@ -1,8 +1,6 @@
 package reporter
 
 import (
-	"errors"
-	"net"
 	"sort"
 
 	"github.com/HdrHistogram/hdrhistogram-go"
@@ -11,44 +9,106 @@ import (
 
 // BenchmarkResultStats represents merged results of the dnsbench.Benchmark execution.
 type BenchmarkResultStats struct {
-	Codes                map[int]int64
-	Qtypes               map[string]int64
-	Hist                 *hdrhistogram.Histogram
-	Timings              []dnsbench.Datapoint
-	Counters             dnsbench.Counters
-	Errors               []dnsbench.ErrorDatapoint
-	GroupedErrors        map[string]int
+	Codes  map[int]int64
+	Qtypes map[string]int64
+	Hist   *hdrhistogram.Histogram
+	// Timings holds the sampled raw datapoints of the responses sorted by the start time.
+	Timings  []dnsbench.Datapoint
+	Counters dnsbench.Counters
+	// Errors holds the sampled raw datapoints of the IO errors sorted by the start time.
+	Errors []dnsbench.ErrorDatapoint
+	// GroupedErrors counts all IO errors grouped using dnsbench.ErrorGroup.
+	GroupedErrors map[string]int
+	// ErrorClasses counts all IO errors classified using dnsbench.ClassifyError.
+	ErrorClasses map[dnsbench.ErrorClass]int64
+	// TimeSeries holds merged results aggregated over time, it is nil when no time series was recorded.
+	TimeSeries           *dnsbench.TimeSeries
 	AuthenticatedDomains map[string]struct{}
 	DoHStatusCodes       map[int]int64
 	// EDECodes counts Extended DNS Error (RFC 8914) info codes received in responses.
 	EDECodes map[uint16]int64
+	// QtypeHists holds merged latency histograms keyed by query type.
+	QtypeHists map[string]*hdrhistogram.Histogram
+	// RcodeHists holds merged latency histograms keyed by response code, NOERROR responses without answers are keyed by dnsbench.NoDataOutcome.
+	RcodeHists map[string]*hdrhistogram.Histogram
+	// ErrorHist is a merged histogram of time to error of requests, which ended with IO error, it is nil when it was not recorded.
+	ErrorHist *hdrhistogram.Histogram
+	// Sizes holds merged statistics of wire sizes of requests and responses, it is nil when sizes were not recorded.
+	Sizes *dnsbench.SizeStats
+	// Domains holds merged per-question aggregates, it is nil when per-domain statistics were not enabled.
+	Domains map[dnsbench.QuestionKey]*dnsbench.DomainStats
+	// TTLs holds merged analysis of answer TTLs for each question, it is nil when TTL analysis was not enabled.
+	TTLs map[dnsbench.QuestionKey]*dnsbench.TTLStats
+	// TTLHist is a merged histogram of answer TTLs in seconds, it is nil when TTL analysis was not enabled.
+	TTLHist *hdrhistogram.Histogram
+	// Consistency holds merged results of cross-server answer consistency checking, it is nil when no servers were compared.
+	Consistency *ConsistencyResultStats
+	// RateAdjustments holds adjustments of the global rate limit done by the adaptive rate control sorted by time,
+	// it is nil when the adaptive rate control was not enabled.
+	RateAdjustments []dnsbench.RateAdjustment
+	// Warmup holds merged results of the warm-up phase, it is nil when the warm-up was not configured.
+	Warmup *dnsbench.WarmupStats
+	// Workers holds results of each worker ordered by the worker ID, results of the repeated iterations are merged by the worker ID.
+	// It is nil when per-worker statistics were not enabled.
+	Workers []WorkerResultStats
+	// Connections holds merged per-connection aggregates keyed by the local address of the connection,
+	// it is nil when per-worker statistics were not enabled.
+	Connections map[string]*dnsbench.ConnectionStats
 }
 
-// Merge takes results of the executed dnsbench.Benchmark and merges them.
+// ConsistencyResultStats represents merged results of cross-server answer consistency checking.
+type ConsistencyResultStats struct {
+	// Servers holds merged results of the compared servers keyed by the server address.
+	Servers     map[string]BenchmarkResultStats
+	Compared    int64
+	Mismatched  int64
+	Skipped     int64
+	Differences map[dnsbench.DifferenceKey]*dnsbench.Difference
+}
+
+// Merge takes results of the executed dnsbench.Benchmark and merges them. The results are expected in the order of the worker IDs
+// as returned by dnsbench.Benchmark.Run, results of the repeated iterations can be concatenated. The merged histograms use the configuration
+// with default values filled in (see dnsbench.Benchmark.WithDefaults), the configuration is used as is only when it is not valid.
 func Merge(b *dnsbench.Benchmark, stats []*dnsbench.ResultStats) BenchmarkResultStats {
+	if effective, err := b.WithDefaults(); err == nil {
+		b = effective
+	}
 	totals := BenchmarkResultStats{
 		Codes:                make(map[int]int64),
 		Qtypes:               make(map[string]int64),
 		Hist:                 hdrhistogram.New(b.HistMin.Nanoseconds(), b.HistMax.Nanoseconds(), b.HistPre),
 		GroupedErrors:        make(map[string]int),
+		ErrorClasses:         make(map[dnsbench.ErrorClass]int64),
 		AuthenticatedDomains: make(map[string]struct{}),
 		DoHStatusCodes:       make(map[int]int64),
 		EDECodes:             make(map[uint16]int64),
 	}
 
-	for _, s := range stats {
-		for _, err := range s.Errors {
-			errorString := errString(err)
-
-			if v, ok := totals.GroupedErrors[errorString]; ok {
-				totals.GroupedErrors[errorString] = v + 1
-			} else {
-				totals.GroupedErrors[errorString] = 1
-			}
+	var consistencyStats []*dnsbench.ConsistencyStats
+	var series []*dnsbench.TimeSeries
+	for i, s := range stats {
+		if s.Consistency != nil {
+			consistencyStats = append(consistencyStats, s.Consistency)
+		}
+		for k, v := range s.ErrorGroups {
+			totals.GroupedErrors[k] += int(v)
+		}
+		for k, v := range s.ErrorClasses {
+			totals.ErrorClasses[k] += v
 		}
+		series = append(series, s.TimeSeries)
+		totals.RateAdjustments = append(totals.RateAdjustments, s.RateAdjustments...)
 		totals.Errors = append(totals.Errors, s.Errors...)
 
 		totals.Hist.Merge(s.Hist)
+		totals.QtypeHists = mergeHists(b, totals.QtypeHists, s.QtypeHists)
+		totals.RcodeHists = mergeHists(b, totals.RcodeHists, s.RcodeHists)
+		if s.ErrorHist != nil {
+			if totals.ErrorHist == nil {
+				totals.ErrorHist = hdrhistogram.New(b.HistMin.Nanoseconds(), b.HistMax.Nanoseconds(), b.HistPre)
+			}
+			totals.ErrorHist.Merge(s.ErrorHist)
+		}
 		totals.Timings = append(totals.Timings, s.Timings...)
 		if s.Codes != nil {
 			for k, v := range s.Codes {
@@ -69,15 +129,23 @@ func Merge(b *dnsbench.Benchmark, stats []*dnsbench.ResultStats) BenchmarkResult
 			totals.EDECodes[k] += v
 		}
 		if s.Counters != nil {
-			totals.Counters = dnsbench.Counters{
-				Total:      totals.Counters.Total + s.Counters.Total,
-				IOError:    totals.Counters.IOError + s.Counters.IOError,
-				Success:    totals.Counters.Success + s.Counters.Success,
-				Negative:   totals.Counters.Negative + s.Counters.Negative,
-				Error:      totals.Counters.Error + s.Counters.Error,
-				IDmismatch: totals.Counters.IDmismatch + s.Counters.IDmismatch,
-				Truncated:  totals.Counters.Truncated + s.Counters.Truncated,
-			}
+			totals.Counters = mergeCounters(totals.Counters, *s.Counters)
+		}
+		if s.Warmup != nil {
+			mergeWarmup(&totals, s.Warmup)
+		}
+		if s.Sizes != nil {
+			mergeSizes(&totals, s)
+		}
+		if s.Domains != nil {
+			mergeDomains(&totals, s)
+		}
+		if s.TTLs != nil {
+			mergeTTLs(&totals, s)
+		}
+		if s.Connections != nil {
+			mergeWorker(b, &totals, i, s)
+			mergeConnections(&totals, s)
 		}
 		if b.DNSSEC {
 			for k := range s.AuthenticatedDomains {
@@ -86,6 +154,11 @@ func Merge(b *dnsbench.Benchmark, stats []*dnsbench.ResultStats) BenchmarkResult
 		}
 	}
 
+	if len(consistencyStats) > 0 {
+		totals.Consistency = mergeConsistency(b, consistencyStats)
+	}
+	totals.TimeSeries = dnsbench.MergeTimeSeries(series...)
+
 	// sort data points from the oldest to the earliest, so we can better plot time dependant graphs (like line)
 	sort.SliceStable(totals.Timings, func(i, j int) bool {
 		return totals.Timings[i].Start.Before(totals.Timings[j].Start)
@@ -95,24 +168,208 @@ func Merge(b *dnsbench.Benchmark, stats []*dnsbench.ResultStats) BenchmarkResult
 	sort.SliceStable(totals.Errors, func(i, j int) bool {
 		return totals.Errors[i].Start.Before(totals.Errors[j].Start)
 	})
+
+	sort.SliceStable(totals.RateAdjustments, func(i, j int) bool {
+		return totals.RateAdjustments[i].Time.Before(totals.RateAdjustments[j].Time)
+	})
 	return totals
 }
 
-func errString(err dnsbench.ErrorDatapoint) string {
-	var errorString string
-	var netOpErr *net.OpError
-	var resolveErr *net.DNSError
+func mergeHists(b *dnsbench.Benchmark, totals, hists map[string]*hdrhistogram.Histogram) map[string]*hdrhistogram.Histogram {
+	if hists == nil {
+		return totals
+	}
+	if totals == nil {
+		totals = make(map[string]*hdrhistogram.Histogram)
+	}
+	for k, v := range hists {
+		h, ok := totals[k]
+		if !ok {
+			h = hdrhistogram.New(b.HistMin.Nanoseconds(), b.HistMax.Nanoseconds(), b.HistPre)
+			totals[k] = h
+		}
+		h.Merge(v)
+	}
+	return totals
+}
 
-	switch {
-	case errors.As(err.Err, &resolveErr):
-		errorString = resolveErr.Err + " " + resolveErr.Name
-	case errors.As(err.Err, &netOpErr):
-		errorString = netOpErr.Op + " " + netOpErr.Net
-		if netOpErr.Addr != nil {
-			errorString += " " + netOpErr.Addr.String()
+func mergeWarmup(totals *BenchmarkResultStats, w *dnsbench.WarmupStats) {
+	if totals.Warmup == nil {
+		totals.Warmup = &dnsbench.WarmupStats{
+			Hist: hdrhistogram.New(w.Hist.LowestTrackableValue(), w.Hist.HighestTrackableValue(), int(w.Hist.SignificantFigures())),
 		}
-	default:
-		errorString = err.Err.Error()
 	}
-	return errorString
+	totals.Warmup.Hist.Merge(w.Hist)
+	totals.Warmup.Duration = max(totals.Warmup.Duration, w.Duration)
+	totals.Warmup.Counters = mergeCounters(totals.Warmup.Counters, w.Counters)
+}
+
+// mergeWorker merges results of the i-th results of the benchmark to the results of the worker, which produced them.
+func mergeWorker(b *dnsbench.Benchmark, totals *BenchmarkResultStats, i int, s *dnsbench.ResultStats) {
+	if totals.Workers == nil {
+		totals.Workers = make([]WorkerResultStats, max(1, int(b.Concurrency)))
+	}
+	w := &totals.Workers[i%len(totals.Workers)]
+	if w.Hist == nil {
+		w.Hist = hdrhistogram.New(s.Hist.LowestTrackableValue(), s.Hist.HighestTrackableValue(), int(s.Hist.SignificantFigures()))
+	}
+	w.Hist.Merge(s.Hist)
+	if s.Counters != nil {
+		w.Counters = mergeCounters(w.Counters, *s.Counters)
+	}
+}
+
+func mergeConnections(totals *BenchmarkResultStats, s *dnsbench.ResultStats) {
+	if totals.Connections == nil {
+		totals.Connections = make(map[string]*dnsbench.ConnectionStats)
+	}
+	for k, v := range s.Connections {
+		c, ok := totals.Connections[k]
+		if !ok {
+			c = &dnsbench.ConnectionStats{
+				Hist: hdrhistogram.New(v.Hist.LowestTrackableValue(), v.Hist.HighestTrackableValue(), int(v.Hist.SignificantFigures())),
+			}
+			totals.Connections[k] = c
+		}
+		c.Counters = mergeCounters(c.Counters, v.Counters)
+		c.Hist.Merge(v.Hist)
+	}
+}
+
+func mergeCounters(a, b dnsbench.Counters) dnsbench.Counters {
+	return dnsbench.Counters{
+		Total:      a.Total + b.Total,
+		IOError:    a.IOError + b.IOError,
+		Success:    a.Success + b.Success,
+		Negative:   a.Negative + b.Negative,
+		Error:      a.Error + b.Error,
+		IDmismatch: a.IDmismatch + b.IDmismatch,
+		Truncated:  a.Truncated + b.Truncated,
+	}
+}
+
+func mergeSizes(totals *BenchmarkResultStats, s *dnsbench.ResultStats) {
+	if totals.Sizes == nil {
+		totals.Sizes = &dnsbench.SizeStats{
+			RequestHist: hdrhistogram.New(s.Sizes.RequestHist.LowestTrackableValue(), s.Sizes.RequestHist.HighestTrackableValue(),
+				int(s.Sizes.RequestHist.SignificantFigures())),
+			ResponseHist: hdrhistogram.New(s.Sizes.ResponseHist.LowestTrackableValue(), s.Sizes.ResponseHist.HighestTrackableValue(),
+				int(s.Sizes.ResponseHist.SignificantFigures())),
+			Qtypes: make(map[string]*dnsbench.QtypeSizes),
+		}
+	}
+	totals.Sizes.RequestHist.Merge(s.Sizes.RequestHist)
+	totals.Sizes.ResponseHist.Merge(s.Sizes.ResponseHist)
+	totals.Sizes.BytesSent += s.Sizes.BytesSent
+	totals.Sizes.BytesReceived += s.Sizes.BytesReceived
+	totals.Sizes.NearBufferSize += s.Sizes.NearBufferSize
+	totals.Sizes.Largest = dnsbench.MergeLargest(totals.Sizes.Largest, s.Sizes.Largest)
+
+	for k, v := range s.Sizes.Qtypes {
+		q, ok := totals.Sizes.Qtypes[k]
+		if !ok {
+			q = &dnsbench.QtypeSizes{}
+			totals.Sizes.Qtypes[k] = q
+		}
+		q.Requests += v.Requests
+		q.RequestBytes += v.RequestBytes
+		q.Responses += v.Responses
+		q.ResponseBytes += v.ResponseBytes
+	}
+}
+
+func mergeDomains(totals *BenchmarkResultStats, s *dnsbench.ResultStats) {
+	if totals.Domains == nil {
+		totals.Domains = make(map[dnsbench.QuestionKey]*dnsbench.DomainStats)
+	}
+	for k, v := range s.Domains {
+		d, ok := totals.Domains[k]
+		if !ok {
+			d = &dnsbench.DomainStats{
+				Codes: make(map[int]int64),
+				Hist:  hdrhistogram.New(v.Hist.LowestTrackableValue(), v.Hist.HighestTrackableValue(), int(v.Hist.SignificantFigures())),
+			}
+			totals.Domains[k] = d
+		}
+		d.Count += v.Count
+		d.IOError += v.IOError
+		for code, c := range v.Codes {
+			d.Codes[code] += c
+		}
+		d.Hist.Merge(v.Hist)
+	}
+}
+
+func mergeTTLs(totals *BenchmarkResultStats, s *dnsbench.ResultStats) {
+	if totals.TTLs == nil {
+		totals.TTLs = make(map[dnsbench.QuestionKey]*dnsbench.TTLStats)
+		totals.TTLHist = hdrhistogram.New(s.TTLHist.LowestTrackableValue(), s.TTLHist.HighestTrackableValue(),
+			int(s.TTLHist.SignificantFigures()))
+	}
+	totals.TTLHist.Merge(s.TTLHist)
+
+	for k, v := range s.TTLs {
+		t, ok := totals.TTLs[k]
+		if !ok {
+			totals.TTLs[k] = &dnsbench.TTLStats{
+				Count:            v.Count,
+				Min:              v.Min,
+				Max:              v.Max,
+				Hits:             v.Hits,
+				Misses:           v.Misses,
+				Prefetches:       v.Prefetches,
+				Stale:            v.Stale,
+				NotCached:        v.NotCached,
+				Authoritative:    v.Authoritative,
+				AuthoritativeTTL: v.AuthoritativeTTL,
+			}
+			continue
+		}
+		if v.Count > 0 {
+			if t.Count == 0 {
+				t.Min, t.Max = v.Min, v.Max
+			}
+			t.Min = min(t.Min, v.Min)
+			t.Max = max(t.Max, v.Max)
+		}
+		t.Count += v.Count
+		t.Hits += v.Hits
+		t.Misses += v.Misses
+		t.Prefetches += v.Prefetches
+		t.Stale += v.Stale
+		t.NotCached += v.NotCached
+		if v.Authoritative {
+			t.Authoritative = true
+			t.AuthoritativeTTL = v.AuthoritativeTTL
+		}
+	}
+}
+
+func mergeConsistency(b *dnsbench.Benchmark, stats []*dnsbench.ConsistencyStats) *ConsistencyResultStats {
+	totals := ConsistencyResultStats{
+		Servers:     make(map[string]BenchmarkResultStats),
+		Differences: make(map[dnsbench.DifferenceKey]*dnsbench.Difference),
+	}
+	serverStats := make(map[string][]*dnsbench.ResultStats)
+
+	for _, s := range stats {
+		totals.Compared += s.Compared
+		totals.Mismatched += s.Mismatched
+		totals.Skipped += s.Skipped
+		for k, v := range s.Differences {
+			if d, ok := totals.Differences[k]; ok {
+				d.Count += v.Count
+				continue
+			}
+			totals.Differences[k] = &dnsbench.Difference{Count: v.Count, Expected: v.Expected, Actual: v.Actual}
+		}
+		for server, rs := range s.Servers {
+			serverStats[server] = append(serverStats[server], rs)
+		}
+	}
+
+	for server, rs := range serverStats {
+		totals.Servers[server] = Merge(b, rs)
+	}
+	return &totals
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
 	"github.com/tantalor93/dnspyre/v3/pkg/reporter"
 )
@@ -59,6 +60,9 @@ func TestMerge(t *testing.T) {
 					Err:   errors.New("test"),
 				},
 			},
+			ErrorGroups: map[string]int64{
+				"test": 2,
+			},
 			AuthenticatedDomains: map[string]struct{}{
 				"google.com.": {},
 			},
@@ -103,6 +107,9 @@ func TestMerge(t *testing.T) {
 					Err:   errors.New("test2"),
 				},
 			},
+			ErrorGroups: map[string]int64{
+				"test2": 1,
+			},
 			AuthenticatedDomains: map[string]struct{}{
 				"google.com.": {},
 			},
@@ -173,6 +180,7 @@ func TestMerge(t *testing.T) {
 			"test":  2,
 			"test2": 1,
 		},
+		ErrorClasses: map[dnsbench.ErrorClass]int64{},
 		AuthenticatedDomains: map[string]struct{}{
 			"google.com.": {},
 		},
@@ -189,6 +197,163 @@ func TestMerge(t *testing.T) {
 	assert.Equal(t, want, res)
 }
 
+func TestMerge_sizes(t *testing.T) {
+	stats := []*dnsbench.ResultStats{
+		{
+			Hist:     histogramWithValues(time.Second),
+			Counters: &dnsbench.Counters{Total: 2, Success: 2},
+			Sizes: &dnsbench.SizeStats{
+				RequestHist:   sizeHistogramWithValues(30, 31),
+				ResponseHist:  sizeHistogramWithValues(100, 1200),
+				BytesSent:     61,
+				BytesReceived: 1300,
+				Qtypes: map[string]*dnsbench.QtypeSizes{
+					"A":   {Requests: 1, RequestBytes: 30, Responses: 1, ResponseBytes: 100},
+					"TXT": {Requests: 1, RequestBytes: 31, Responses: 1, ResponseBytes: 1200},
+				},
+				Largest: []dnsbench.ResponseSize{
+					{QuestionKey: dnsbench.QuestionKey{Name: "example.org.", Qtype: "TXT"}, Size: 1200},
+					{QuestionKey: dnsbench.QuestionKey{Name: "example.org.", Qtype: "A"}, Size: 100},
+				},
+				NearBufferSize: 1,
+			},
+		},
+		{
+			Hist:     histogramWithValues(time.Second),
+			Counters: &dnsbench.Counters{Total: 1, Success: 1},
+			Sizes: &dnsbench.SizeStats{
+				RequestHist:   sizeHistogramWithValues(31),
+				ResponseHist:  sizeHistogramWithValues(1220),
+				BytesSent:     31,
+				BytesReceived: 1220,
+				Qtypes: map[string]*dnsbench.QtypeSizes{
+					"TXT": {Requests: 1, RequestBytes: 31, Responses: 1, ResponseBytes: 1220},
+				},
+				Largest: []dnsbench.ResponseSize{
+					{QuestionKey: dnsbench.QuestionKey{Name: "example.org.", Qtype: "TXT"}, Size: 1220},
+				},
+				NearBufferSize: 1,
+			},
+		},
+	}
+
+	want := &dnsbench.SizeStats{
+		RequestHist:   sizeHistogramWithValues(30, 31, 31),
+		ResponseHist:  sizeHistogramWithValues(100, 1200, 1220),
+		BytesSent:     92,
+		BytesReceived: 2520,
+		Qtypes: map[string]*dnsbench.QtypeSizes{
+			"A":   {Requests: 1, RequestBytes: 30, Responses: 1, ResponseBytes: 100},
+			"TXT": {Requests: 2, RequestBytes: 62, Responses: 2, ResponseBytes: 2420},
+		},
+		Largest: []dnsbench.ResponseSize{
+			{QuestionKey: dnsbench.QuestionKey{Name: "example.org.", Qtype: "TXT"}, Size: 1220},
+			{QuestionKey: dnsbench.QuestionKey{Name: "example.org.", Qtype: "A"}, Size: 100},
+		},
+		NearBufferSize: 2,
+	}
+
+	res := reporter.Merge(&dnsbench.Benchmark{HistMin: 0, HistMax: 5 * time.Second, HistPre: 1}, stats)
+
+	assert.Equal(t, want, res.Sizes)
+}
+
+func TestMerge_warmup(t *testing.T) {
+	stats := []*dnsbench.ResultStats{
+		{
+			Hist:     histogramWithValues(time.Second),
+			Counters: &dnsbench.Counters{Total: 1, Success: 1},
+			Warmup: &dnsbench.WarmupStats{
+				Counters: dnsbench.Counters{Total: 2, Success: 1, IOError: 1},
+				Hist:     histogramWithValues(2 * time.Second),
+				Duration: time.Second,
+			},
+		},
+		{
+			Hist:     histogramWithValues(time.Second),
+			Counters: &dnsbench.Counters{Total: 1, Success: 1},
+			Warmup: &dnsbench.WarmupStats{
+				Counters: dnsbench.Counters{Total: 1, Negative: 1},
+				Hist:     histogramWithValues(3 * time.Second),
+				Duration: 2 * time.Second,
+			},
+		},
+	}
+
+	res := reporter.Merge(&dnsbench.Benchmark{HistMin: 0, HistMax: 5 * time.Second, HistPre: 1}, stats)
+
+	require.NotNil(t, res.Warmup)
+	assert.Equal(t, dnsbench.Counters{Total: 3, Success: 1, Negative: 1, IOError: 1}, res.Warmup.Counters)
+	assert.Equal(t, histogramWithValues(2*time.Second, 3*time.Second), res.Warmup.Hist)
+	assert.Equal(t, 2*time.Second, res.Warmup.Duration)
+	assert.Equal(t, dnsbench.Counters{Total: 2, Success: 2}, res.Counters, "warm-up should not be part of the results")
+}
+
+func TestMerge_error_classes(t *testing.T) {
+	timeout := dnsbench.ErrorClass{Type: dnsbench.TimeoutError, Detail: "read"}
+	connectTimeout := dnsbench.ErrorClass{Type: dnsbench.TimeoutError, Detail: "connect"}
+	refused := dnsbench.ErrorClass{Type: dnsbench.RefusedError}
+	stats := []*dnsbench.ResultStats{
+		{
+			Hist:         histogramWithValues(),
+			ErrorClasses: map[dnsbench.ErrorClass]int64{timeout: 2, refused: 1},
+		},
+		{
+			Hist:         histogramWithValues(),
+			ErrorClasses: map[dnsbench.ErrorClass]int64{timeout: 1, connectTimeout: 1},
+		},
+	}
+
+	res := reporter.Merge(&dnsbench.Benchmark{HistMin: 0, HistMax: 5 * time.Second, HistPre: 1}, stats)
+
+	assert.Equal(t, map[dnsbench.ErrorClass]int64{timeout: 3, connectTimeout: 1, refused: 1}, res.ErrorClasses)
+	assert.EqualValues(t, 4, res.ErrorsOfType(dnsbench.TimeoutError))
+	assert.EqualValues(t, 1, res.ErrorsOfType(dnsbench.RefusedError))
+	assert.Zero(t, res.ErrorsOfType(dnsbench.TLSAlertError))
+}
+
+func TestMerge_worker_stats(t *testing.T) {
+	stats := []*dnsbench.ResultStats{
+		{
+			Hist:        histogramWithValues(time.Second),
+			Counters:    &dnsbench.Counters{Total: 1, Success: 1},
+			Connections: map[string]*dnsbench.ConnectionStats{"127.0.0.1:50001": {Counters: dnsbench.Counters{Total: 1, Success: 1}, Hist: histogramWithValues(time.Second)}},
+		},
+		{
+			Hist:        histogramWithValues(2 * time.Second),
+			Counters:    &dnsbench.Counters{Total: 2, Success: 1, IOError: 1},
+			Connections: map[string]*dnsbench.ConnectionStats{"127.0.0.1:50002": {Counters: dnsbench.Counters{Total: 2, Success: 1, IOError: 1}, Hist: histogramWithValues(2 * time.Second)}},
+		},
+		// second iteration of the first worker
+		{
+			Hist:        histogramWithValues(3 * time.Second),
+			Counters:    &dnsbench.Counters{Total: 1, Success: 1},
+			Connections: map[string]*dnsbench.ConnectionStats{"127.0.0.1:50001": {Counters: dnsbench.Counters{Total: 1, Success: 1}, Hist: histogramWithValues(3 * time.Second)}},
+		},
+	}
+
+	res := reporter.Merge(&dnsbench.Benchmark{HistMin: 0, HistMax: 5 * time.Second, HistPre: 1, Concurrency: 2, WorkerStats: true}, stats)
+
+	require.Len(t, res.Workers, 2)
+	assert.Equal(t, dnsbench.Counters{Total: 2, Success: 2}, res.Workers[0].Counters)
+	assert.Equal(t, histogramWithValues(time.Second, 3*time.Second), res.Workers[0].Hist)
+	assert.Equal(t, dnsbench.Counters{Total: 2, Success: 1, IOError: 1}, res.Workers[1].Counters)
+	assert.Equal(t, histogramWithValues(2*time.Second), res.Workers[1].Hist)
+
+	require.Len(t, res.Connections, 2)
+	assert.Equal(t, dnsbench.Counters{Total: 2, Success: 2}, res.Connections["127.0.0.1:50001"].Counters)
+	assert.Equal(t, histogramWithValues(time.Second, 3*time.Second), res.Connections["127.0.0.1:50001"].Hist)
+	assert.Equal(t, dnsbench.Counters{Total: 2, Success: 1, IOError: 1}, res.Connections["127.0.0.1:50002"].Counters)
+}
+
+func sizeHistogramWithValues(sizes ...int64) *hdrhistogram.Histogram {
+	hst := hdrhistogram.New(0, 65535, 3)
+	for _, v := range sizes {
+		hst.RecordValue(v)
+	}
+	return hst
+}
+
 func histogramWithValues(durations ...time.Duration) *hdrhistogram.Histogram {
 	hst := hdrhistogram.New(0, 5*time.Second.Nanoseconds(), 1)
 	for _, v := range durations {
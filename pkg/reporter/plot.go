@@ -5,25 +5,103 @@ import (
 	"image/color"
 	"math"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/miekg/dns"
-	"github.com/montanaflynn/stats"
 	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
 	"go-hep.org/x/hep/hplot"
 	"gonum.org/v1/gonum/stat"
 	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette/moreland"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
 	"gonum.org/v1/plot/vg/draw"
 )
 
-func plotHistogramLatency(file string, times []dnsbench.Datapoint) error {
-	if len(times) == 0 {
+// plotSize is the width and height of the plots.
+const plotSize = 6 * vg.Inch
+
+// axisScale describes how the values of the plot axis are mapped to the plotted data.
+type axisScale string
+
+const (
+	// linearScale axis plots the data values.
+	linearScale axisScale = "linear"
+	// nominalScale axis plots categories, for example the query types of box plots.
+	nominalScale axisScale = "nominal"
+	// ninesScale axis plots percentiles as the number of their nines, see latencyCDFPlot.
+	ninesScale axisScale = "nines"
+	// log10Scale axis plots decimal logarithms of the data values.
+	log10Scale axisScale = "log10"
+)
+
+// chart is a named plot of the benchmark results, the plot function returns nil plot when there is nothing to plot.
+type chart struct {
+	name string
+	plot func() (*plot.Plot, error)
+	// xScale and yScale are scales of the plot axes, linearScale is used when empty.
+	xScale, yScale axisScale
+}
+
+// charts returns the charts of the benchmark results selected by dnsbench.Benchmark.PlotCharts.
+func charts(b *dnsbench.Benchmark, totals *BenchmarkResultStats, benchStart time.Time) []chart {
+	all := []chart{
+		{name: dnsbench.LatencyHistogramChart, plot: func() (*plot.Plot, error) { return latencyHistogramPlot(totals.Timings) }},
+		{
+			name: dnsbench.LatencyBoxPlotChart, xScale: nominalScale,
+			plot: func() (*plot.Plot, error) { return latencyBoxPlot(b.Server, totals.Timings) },
+		},
+		{
+			name: dnsbench.ResponsesBarChart, xScale: nominalScale,
+			plot: func() (*plot.Plot, error) { return responsesBarChart(totals.Codes) },
+		},
+		{name: dnsbench.ThroughputLinePlotChart, plot: func() (*plot.Plot, error) { return throughputLinePlot(benchStart, totals.TimeSeries) }},
+		{name: dnsbench.LatencyLinePlotChart, plot: func() (*plot.Plot, error) { return latencyLinePlot(benchStart, totals.TimeSeries) }},
+		{name: dnsbench.ErrorRateLinePlotChart, plot: func() (*plot.Plot, error) { return errorRateLinePlot(benchStart, totals.TimeSeries) }},
+		// the rate limit is plotted only when it was adjusted by the adaptive rate control
+		{name: dnsbench.RateLinePlotChart, plot: func() (*plot.Plot, error) { return rateLinePlot(b.Rate, totals.RateAdjustments) }},
+		{name: dnsbench.LatencyCDFChart, xScale: ninesScale, plot: func() (*plot.Plot, error) { return latencyCDFPlot(totals.Hist) }},
+		{
+			name: dnsbench.LatencyHeatmapChart, yScale: log10Scale,
+			plot: func() (*plot.Plot, error) { return latencyHeatmapPlot(benchStart, totals.TimeSeries) },
+		},
+		{name: dnsbench.RcodesAreaPlotChart, plot: func() (*plot.Plot, error) { return rcodesAreaPlot(benchStart, totals.TimeSeries) }},
+		{name: dnsbench.QtypeBoxPlotChart, xScale: nominalScale, plot: func() (*plot.Plot, error) { return qtypeBoxPlot(totals.QtypeHists) }},
+	}
+	var selected []chart
+	for _, c := range all {
+		if b.PlotChart(c.name) {
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+// savePlot saves the chart to the file, the format of the file is given by the file extension.
+func savePlot(c chart, file string) error {
+	p, err := c.plot()
+	if err != nil {
+		return err
+	}
+	if p == nil {
 		// nothing to plot
 		return nil
 	}
+	if err := p.Save(plotSize, plotSize, file); err != nil {
+		return fmt.Errorf("failed to save plot %q: %w", file, err)
+	}
+	return nil
+}
+
+func latencyHistogramPlot(times []dnsbench.Datapoint) (*plot.Plot, error) {
+	if len(times) == 0 {
+		// nothing to plot
+		return nil, nil
+	}
 	var values plotter.Values
 	for _, v := range times {
 		values = append(values, float64(v.Duration.Milliseconds()))
@@ -33,7 +111,7 @@ func plotHistogramLatency(file string, times []dnsbench.Datapoint) error {
 
 	hist, err := plotter.NewHist(values, numBins(values))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	p.X.Label.Text = "Latencies (ms)"
 	p.X.Tick.Marker = hplot.Ticks{N: 5, Format: "%.0f"}
@@ -42,10 +120,7 @@ func plotHistogramLatency(file string, times []dnsbench.Datapoint) error {
 	hist.FillColor = color.RGBA{R: 175, G: 238, B: 238, A: 255}
 	p.Add(hist)
 
-	if err := p.Save(6*vg.Inch, 6*vg.Inch, file); err != nil {
-		return fmt.Errorf("failed to save plot %q: %w", file, err)
-	}
-	return nil
+	return p, nil
 }
 
 // numBins calculates number of bins for histogram.
@@ -74,10 +149,10 @@ func numBins(values plotter.Values) int {
 	return int(math.Min(50, doane))
 }
 
-func plotBoxPlotLatency(file, server string, times []dnsbench.Datapoint) error {
+func latencyBoxPlot(server string, times []dnsbench.Datapoint) (*plot.Plot, error) {
 	if len(times) == 0 {
 		// nothing to plot
-		return nil
+		return nil, nil
 	}
 	var values plotter.Values
 	for _, v := range times {
@@ -91,21 +166,29 @@ func plotBoxPlotLatency(file, server string, times []dnsbench.Datapoint) error {
 
 	boxplot, err := plotter.NewBoxPlot(vg.Length(120), 0, values)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	boxplot.FillColor = color.RGBA{R: 127, G: 188, B: 165, A: 255}
 	p.Add(boxplot)
 
-	if err := p.Save(6*vg.Inch, 6*vg.Inch, file); err != nil {
-		return fmt.Errorf("failed to save plot %q: %w", file, err)
-	}
-	return nil
+	return p, nil
 }
 
-func plotResponses(file string, rcodes map[int]int64) error {
+// rcodeColors are colors of the response codes, the response codes sorted by the value are assigned the colors in order.
+var rcodeColors = append([]color.Color{
+	color.RGBA{R: 122, G: 195, B: 106, A: 255},
+	color.RGBA{R: 241, G: 90, B: 96, A: 255},
+	color.RGBA{R: 90, G: 155, B: 212, A: 255},
+	color.RGBA{R: 250, G: 167, B: 91, A: 255},
+	color.RGBA{R: 158, G: 103, B: 171, A: 255},
+	color.RGBA{R: 206, G: 112, B: 88, A: 255},
+	color.RGBA{R: 215, G: 127, B: 180, A: 255},
+}, plotutil.DarkColors...)
+
+func responsesBarChart(rcodes map[int]int64) (*plot.Plot, error) {
 	if len(rcodes) == 0 {
 		// nothing to plot
-		return nil
+		return nil, nil
 	}
 	sortedKeys := make([]int, 0)
 	for k := range rcodes {
@@ -113,17 +196,6 @@ func plotResponses(file string, rcodes map[int]int64) error {
 	}
 	sort.Ints(sortedKeys)
 
-	colors := []color.Color{
-		color.RGBA{R: 122, G: 195, B: 106, A: 255},
-		color.RGBA{R: 241, G: 90, B: 96, A: 255},
-		color.RGBA{R: 90, G: 155, B: 212, A: 255},
-		color.RGBA{R: 250, G: 167, B: 91, A: 255},
-		color.RGBA{R: 158, G: 103, B: 171, A: 255},
-		color.RGBA{R: 206, G: 112, B: 88, A: 255},
-		color.RGBA{R: 215, G: 127, B: 180, A: 255},
-	}
-	colors = append(colors, plotutil.DarkColors...)
-
 	p := plot.New()
 	p.Title.Text = "Response code distribution"
 	p.NominalX("Response codes")
@@ -135,10 +207,10 @@ func plotResponses(file string, rcodes map[int]int64) error {
 	for _, v := range sortedKeys {
 		bar, err := plotter.NewBarChart(plotter.Values{float64(rcodes[v])}, width)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		p.Legend.Add(dns.RcodeToString[v], bar)
-		bar.Color = colors[c%len(colors)]
+		bar.Color = rcodeColors[c%len(rcodeColors)]
 		bar.Offset = off
 		p.Add(bar)
 		c++
@@ -149,37 +221,17 @@ func plotResponses(file string, rcodes map[int]int64) error {
 	p.Y.Tick.Marker = hplot.Ticks{N: 3, Format: "%.0f"}
 	p.Legend.Top = true
 
-	if err := p.Save(6*vg.Inch, 6*vg.Inch, file); err != nil {
-		return fmt.Errorf("failed to save plot %q: %w", file, err)
-	}
-	return nil
+	return p, nil
 }
 
-func plotLineThroughput(file string, benchStart time.Time, times []dnsbench.Datapoint) error {
-	if len(times) == 0 {
+func throughputLinePlot(benchStart time.Time, series *dnsbench.TimeSeries) (*plot.Plot, error) {
+	values := seriesValues(benchStart, series, func(b dnsbench.TimeBucket) (float64, bool) {
+		return float64(b.Responses) / series.Width.Seconds(), b.Responses > 0
+	})
+	if len(values) == 0 {
 		// nothing to plot
-		return nil
+		return nil, nil
 	}
-	var values plotter.XYs
-	m := make(map[int64]int64)
-
-	if len(times) != 0 {
-		for _, v := range times {
-			offset := v.Start.Unix() - benchStart.Unix()
-			if _, ok := m[offset]; !ok {
-				m[offset] = 0
-			}
-			m[offset]++
-		}
-	}
-
-	for k, v := range m {
-		values = append(values, plotter.XY{X: float64(k), Y: float64(v)})
-	}
-
-	sort.SliceStable(values, func(i, j int) bool {
-		return values[i].X < values[j].X
-	})
 
 	p := plot.New()
 	p.Title.Text = "Throughput per second"
@@ -190,7 +242,7 @@ func plotLineThroughput(file string, benchStart time.Time, times []dnsbench.Data
 
 	l, err := plotter.NewLine(values)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	l.Width = vg.Points(0.5)
 	l.FillColor = color.RGBA{R: 175, G: 238, B: 238, A: 255}
@@ -198,70 +250,31 @@ func plotLineThroughput(file string, benchStart time.Time, times []dnsbench.Data
 
 	scatter, err := plotter.NewScatter(values)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	scatter.Shape = draw.CircleGlyph{}
 	p.Add(scatter)
 
-	if err := p.Save(6*vg.Inch, 6*vg.Inch, file); err != nil {
-		return fmt.Errorf("failed to save plot %q: %w", file, err)
-	}
-	return nil
-}
-
-type latencyMeasurements struct {
-	p99 float64
-	p95 float64
-	p90 float64
-	p50 float64
+	return p, nil
 }
 
-func plotLineLatencies(file string, benchStart time.Time, times []dnsbench.Datapoint) error {
-	if len(times) == 0 {
-		// nothing to plot
-		return nil
-	}
-
-	measurements := make(map[int64]latencyMeasurements)
-	timings := make([]float64, 0)
-	last := times[0].Start.Unix() - benchStart.Unix()
-
-	for _, v := range times {
-		offset := v.Start.Unix() - benchStart.Unix()
-		if offset != last {
-			if err := collectMeasurements(timings, measurements, last); err != nil {
-				return err
+func latencyLinePlot(benchStart time.Time, series *dnsbench.TimeSeries) (*plot.Plot, error) {
+	percentile := func(q float64) func(b dnsbench.TimeBucket) (float64, bool) {
+		return func(b dnsbench.TimeBucket) (float64, bool) {
+			if b.Hist == nil {
+				return 0, false
 			}
-			last = offset
+			return float64(time.Duration(b.Hist.ValueAtQuantile(q)).Milliseconds()), true
 		}
-		timings = append(timings, float64(v.Duration.Milliseconds()))
-	}
-	if err := collectMeasurements(timings, measurements, last); err != nil {
-		return err
-	}
-
-	var p99values plotter.XYs
-	var p95values plotter.XYs
-	var p90values plotter.XYs
-	var p50values plotter.XYs
-
-	for k, v := range measurements {
-		p99values = append(p99values, plotter.XY{X: float64(k), Y: v.p99})
-		p95values = append(p95values, plotter.XY{X: float64(k), Y: v.p95})
-		p90values = append(p90values, plotter.XY{X: float64(k), Y: v.p90})
-		p50values = append(p50values, plotter.XY{X: float64(k), Y: v.p50})
 	}
-
-	less := func(xys plotter.XYs) func(i, j int) bool {
-		return func(i, j int) bool {
-			return xys[i].X < xys[j].X
-		}
+	p99values := seriesValues(benchStart, series, percentile(99))
+	if len(p99values) == 0 {
+		// nothing to plot
+		return nil, nil
 	}
-
-	sort.SliceStable(p99values, less(p99values))
-	sort.SliceStable(p95values, less(p95values))
-	sort.SliceStable(p90values, less(p90values))
-	sort.SliceStable(p50values, less(p50values))
+	p95values := seriesValues(benchStart, series, percentile(95))
+	p90values := seriesValues(benchStart, series, percentile(90))
+	p50values := seriesValues(benchStart, series, percentile(50))
 
 	p := plot.New()
 	p.Title.Text = "Response latencies"
@@ -269,104 +282,388 @@ func plotLineLatencies(file string, benchStart time.Time, times []dnsbench.Datap
 	p.Y.Label.Text = "Latency (ms)"
 
 	if err := plotLine(p, p99values, plotutil.DarkColors[0], plotutil.SoftColors[0], "p99"); err != nil {
-		return err
+		return nil, err
 	}
 	if err := plotLine(p, p95values, plotutil.DarkColors[1], plotutil.SoftColors[1], "p95"); err != nil {
-		return err
+		return nil, err
 	}
 	if err := plotLine(p, p90values, plotutil.DarkColors[2], plotutil.SoftColors[2], "p90"); err != nil {
-		return err
+		return nil, err
 	}
 	if err := plotLine(p, p50values, plotutil.DarkColors[3], plotutil.SoftColors[3], "p50"); err != nil {
-		return err
+		return nil, err
 	}
 
 	p.Legend.Top = true
 
-	if err := p.Save(6*vg.Inch, 6*vg.Inch, file); err != nil {
-		return fmt.Errorf("failed to save plot %q: %w", file, err)
-	}
-	return nil
+	return p, nil
 }
 
-func collectMeasurements(timings []float64, measurements map[int64]latencyMeasurements, offset int64) error {
-	p99, err := stats.Percentile(timings, 99)
+func errorRateLinePlot(benchStart time.Time, series *dnsbench.TimeSeries) (*plot.Plot, error) {
+	values := seriesValues(benchStart, series, func(b dnsbench.TimeBucket) (float64, bool) {
+		return float64(b.Errors) / series.Width.Seconds(), b.Errors > 0
+	})
+	if len(values) == 0 {
+		// nothing to plot
+		return nil, nil
+	}
+
+	p := plot.New()
+	p.Title.Text = "Error rate over time"
+	p.X.Label.Text = "Time of test (s)"
+	p.X.Tick.Marker = hplot.Ticks{N: 3, Format: "%.0f"}
+	p.Y.Label.Text = "Number of errors (per sec)"
+	p.Y.Tick.Marker = hplot.Ticks{N: 3, Format: "%.0f"}
+
+	l, err := plotter.NewLine(values)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	p95, err := stats.Percentile(timings, 95)
+	l.Width = vg.Points(0.5)
+
+	p.Add(l)
+
+	scatter, err := plotter.NewScatter(values)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	scatter.Color = color.RGBA{R: 238, G: 46, B: 47, A: 255}
+	scatter.Shape = draw.CircleGlyph{}
+
+	p.Add(scatter)
+
+	return p, nil
+}
+
+func rateLinePlot(startRate int, adjustments []dnsbench.RateAdjustment) (*plot.Plot, error) {
+	if len(adjustments) == 0 {
+		// nothing to plot
+		return nil, nil
 	}
-	p90, err := stats.Percentile(timings, 90)
+
+	values := plotter.XYs{{X: 0, Y: float64(startRate)}}
+	for _, a := range adjustments {
+		values = append(values, plotter.XY{X: a.Elapsed.Seconds(), Y: float64(a.Rate)})
+	}
+
+	p := plot.New()
+	p.Title.Text = "Rate limit over time"
+	p.X.Label.Text = "Time of test (s)"
+	p.X.Tick.Marker = hplot.Ticks{N: 3, Format: "%.0f"}
+	p.Y.Label.Text = "Rate limit (per sec)"
+	p.Y.Tick.Marker = hplot.Ticks{N: 3, Format: "%.0f"}
+
+	l, err := plotter.NewLine(values)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	p50, err := stats.Percentile(timings, 50)
+	// the rate is constant between the adjustments
+	l.StepStyle = plotter.PostStep
+	l.Width = vg.Points(1)
+	l.Color = color.RGBA{R: 0, G: 114, B: 178, A: 255}
+
+	p.Add(l)
+
+	return p, nil
+}
+
+func latencyCDFPlot(hist *hdrhistogram.Histogram) (*plot.Plot, error) {
+	if hist == nil || hist.TotalCount() == 0 {
+		// nothing to plot
+		return nil, nil
+	}
+
+	// X is the number of nines of the percentile, so that the tail percentiles are spread evenly, the plot ends at the percentile
+	// corresponding to a single response
+	maxNines := max(1, math.Log10(float64(hist.TotalCount())))
+	var values plotter.XYs
+	for nines := 0.0; nines < maxNines; nines += cdfStep {
+		values = append(values, plotter.XY{X: nines, Y: latencyAtPercentile(hist, 100*(1-math.Pow(10, -nines)))})
+	}
+	values = append(values, plotter.XY{X: maxNines, Y: latencyAtPercentile(hist, 100*(1-math.Pow(10, -maxNines)))})
+
+	p := plot.New()
+	p.Title.Text = "Latency by percentile"
+	p.X.Label.Text = "Percentile"
+	p.X.Tick.Marker = percentileTicks(maxNines)
+	p.Y.Label.Text = "Latency (ms)"
+	p.Y.Tick.Marker = hplot.Ticks{N: 5, Format: "%.0f"}
+	p.Y.Min = 0
+
+	l, err := plotter.NewLine(values)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	measure := latencyMeasurements{}
-	measure.p99 = p99
-	measure.p95 = p95
-	measure.p90 = p90
-	measure.p50 = p50
-	measurements[offset] = measure
-	return nil
+	l.Width = vg.Points(1)
+	l.Color = color.RGBA{R: 0, G: 114, B: 178, A: 255}
+	p.Add(l, plotter.NewGrid())
+
+	return p, nil
 }
 
-func plotErrorRate(file string, benchStart time.Time, times []dnsbench.ErrorDatapoint) error {
-	if len(times) == 0 {
+// cdfStep is a distance between the plotted points of the latency CDF in the number of nines of the percentile.
+const cdfStep = 0.05
+
+func latencyAtPercentile(hist *hdrhistogram.Histogram, percentile float64) float64 {
+	return float64(hist.ValueAtPercentile(percentile)) / float64(time.Millisecond)
+}
+
+// percentileTicks returns ticks of the percentile axis, in which the percentiles are represented by the number of their nines.
+func percentileTicks(maxNines float64) plot.ConstantTicks {
+	ticks := plot.ConstantTicks{{Value: 0, Label: "0%"}, {Value: math.Log10(2), Label: "50%"}, {Value: 1, Label: "90%"}}
+	for nines := 2; float64(nines) <= maxNines; nines++ {
+		digits := strings.Repeat("9", nines)
+		label := digits[:2]
+		if nines > 2 {
+			label += "." + digits[2:]
+		}
+		ticks = append(ticks, plot.Tick{Value: float64(nines), Label: label + "%"})
+	}
+	return ticks
+}
+
+// heatmapBins is a number of latency bins of the latency heatmap.
+const heatmapBins = 20
+
+func latencyHeatmapPlot(benchStart time.Time, series *dnsbench.TimeSeries) (*plot.Plot, error) {
+	grid := newLatencyGrid(benchStart, series)
+	if grid == nil {
 		// nothing to plot
+		return nil, nil
+	}
+
+	p := plot.New()
+	p.Title.Text = "Latency heatmap"
+	p.X.Label.Text = "Time of test (s)"
+	p.X.Tick.Marker = hplot.Ticks{N: 3, Format: "%.0f"}
+	p.Y.Label.Text = "Latency (ms)"
+	p.Y.Tick.Marker = grid.latencyTicks()
+
+	heatmap := plotter.NewHeatMap(grid, moreland.SmoothBlueRed().Palette(255))
+	// the color of the cell is proportional to the number of responses, the cells without responses are not filled
+	heatmap.Min = 0
+	p.Add(heatmap)
+
+	return p, nil
+}
+
+// latencyGrid is a plotter.GridXYZ of the number of responses in the time series buckets (columns) and logarithmic latency bins (rows).
+type latencyGrid struct {
+	// x are the middles of the time series buckets in seconds since the start of the benchmark.
+	x []float64
+	// counts are the numbers of responses indexed by the bucket and the latency bin.
+	counts [][heatmapBins]int64
+	// low and step are the decimal logarithm of the lowest latency in milliseconds and the width of the latency bins.
+	low, step float64
+}
+
+func newLatencyGrid(benchStart time.Time, series *dnsbench.TimeSeries) *latencyGrid {
+	if series == nil {
+		return nil
+	}
+	lowest, highest := int64(math.MaxInt64), int64(0)
+	for _, b := range series.Buckets {
+		if b.Hist != nil {
+			lowest = min(lowest, b.Hist.Min())
+			highest = max(highest, b.Hist.Max())
+		}
+	}
+	if highest == 0 {
 		return nil
 	}
-	var values plotter.XYs
-	m := make(map[int64]int64)
 
-	for _, v := range times {
-		offset := v.Start.Unix() - benchStart.Unix()
-		if _, ok := m[offset]; !ok {
-			m[offset] = 0
+	low := math.Log10(float64(max(lowest, int64(time.Microsecond))) / float64(time.Millisecond))
+	high := math.Log10(float64(max(highest, int64(time.Microsecond))) / float64(time.Millisecond))
+	// make sure the range is not empty, when all latencies are the same
+	high = max(high, low+0.1)
+	g := &latencyGrid{
+		counts: make([][heatmapBins]int64, len(series.Buckets)),
+		low:    low,
+		step:   (high - low) / heatmapBins,
+	}
+	for i, b := range series.Buckets {
+		g.x = append(g.x, float64(series.BucketStart(i).Unix()-benchStart.Unix())+series.Width.Seconds()/2)
+		if b.Hist == nil {
+			continue
+		}
+		for _, bar := range b.Hist.Distribution() {
+			if bar.Count == 0 {
+				continue
+			}
+			latency := float64(max((bar.From+bar.To)/2, int64(time.Microsecond))) / float64(time.Millisecond)
+			bin := min(heatmapBins-1, max(0, int((math.Log10(latency)-low)/g.step)))
+			g.counts[i][bin] += bar.Count
 		}
-		m[offset]++
 	}
+	return g
+}
+
+// Dims implements plotter.GridXYZ.
+func (g *latencyGrid) Dims() (c, r int) {
+	return len(g.x), heatmapBins
+}
 
-	for k, v := range m {
-		values = append(values, plotter.XY{X: float64(k), Y: float64(v)})
+// Z implements plotter.GridXYZ, the cells without responses are NaN.
+func (g *latencyGrid) Z(c, r int) float64 {
+	if g.counts[c][r] == 0 {
+		return math.NaN()
 	}
+	return float64(g.counts[c][r])
+}
 
-	sort.SliceStable(values, func(i, j int) bool {
-		return values[i].X < values[j].X
-	})
+// X implements plotter.GridXYZ.
+func (g *latencyGrid) X(c int) float64 {
+	return g.x[c]
+}
+
+// Y implements plotter.GridXYZ, it returns the decimal logarithm of the latency in the middle of the bin.
+func (g *latencyGrid) Y(r int) float64 {
+	return g.low + (float64(r)+0.5)*g.step
+}
+
+// latencyTicks returns ticks of the logarithmic latency axis at 1, 2 and 5 multiples of the powers of ten.
+func (g *latencyGrid) latencyTicks() plot.ConstantTicks {
+	low, high := g.low, g.low+heatmapBins*g.step
+	var ticks plot.ConstantTicks
+	for exp := math.Floor(low); exp <= math.Ceil(high); exp++ {
+		for _, m := range []float64{1, 2, 5} {
+			v := math.Log10(m) + exp
+			if v >= low && v <= high {
+				ticks = append(ticks, plot.Tick{Value: v, Label: strconv.FormatFloat(m*math.Pow(10, exp), 'g', -1, 64)})
+			}
+		}
+	}
+	return ticks
+}
+
+func rcodesAreaPlot(benchStart time.Time, series *dnsbench.TimeSeries) (*plot.Plot, error) {
+	if series == nil {
+		// nothing to plot
+		return nil, nil
+	}
+	rcodes := make(map[int]struct{})
+	for _, b := range series.Buckets {
+		for k := range b.Rcodes {
+			rcodes[k] = struct{}{}
+		}
+	}
+	if len(rcodes) == 0 {
+		// nothing to plot
+		return nil, nil
+	}
+	sortedKeys := make([]int, 0, len(rcodes))
+	for k := range rcodes {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Ints(sortedKeys)
 
 	p := plot.New()
-	p.Title.Text = "Error rate over time"
+	p.Title.Text = "Response codes over time"
 	p.X.Label.Text = "Time of test (s)"
 	p.X.Tick.Marker = hplot.Ticks{N: 3, Format: "%.0f"}
-	p.Y.Label.Text = "Number of errors (per sec)"
-	p.Y.Tick.Marker = hplot.Ticks{N: 3, Format: "%.0f"}
-
-	l, err := plotter.NewLine(values)
-	if err != nil {
-		return err
+	p.Y.Label.Text = "Number of responses (per sec)"
+	p.Y.Tick.Marker = hplot.Ticks{N: 3, Format: "%g"}
+	p.Y.Min = 0
+
+	// the areas are stacked, each area is drawn from zero to the sum of the response codes up to the plotted one,
+	// so the areas are added from the top one to be overdrawn by the lower ones
+	for i := len(sortedKeys) - 1; i >= 0; i-- {
+		values := seriesValues(benchStart, series, func(b dnsbench.TimeBucket) (float64, bool) {
+			var sum int64
+			for _, k := range sortedKeys[:i+1] {
+				sum += b.Rcodes[k]
+			}
+			return float64(sum) / series.Width.Seconds(), true
+		})
+		l, err := plotter.NewLine(values)
+		if err != nil {
+			return nil, err
+		}
+		l.Width = vg.Points(0.5)
+		l.FillColor = rcodeColors[i%len(rcodeColors)]
+		p.Add(l)
 	}
-	l.Width = vg.Points(0.5)
+	// the legend follows the order of the stacked areas from the top one
+	for i := len(sortedKeys) - 1; i >= 0; i-- {
+		p.Legend.Add(dns.RcodeToString[sortedKeys[i]], colorThumbnail{rcodeColors[i%len(rcodeColors)]})
+	}
+	p.Legend.Top = true
 
-	p.Add(l)
+	return p, nil
+}
 
-	scatter, err := plotter.NewScatter(values)
-	if err != nil {
-		return err
+func qtypeBoxPlot(hists map[string]*hdrhistogram.Histogram) (*plot.Plot, error) {
+	var qtypes []string
+	for k, v := range hists {
+		if v.TotalCount() > 0 {
+			qtypes = append(qtypes, k)
+		}
 	}
-	scatter.Color = color.RGBA{R: 238, G: 46, B: 47, A: 255}
-	scatter.Shape = draw.CircleGlyph{}
+	if len(qtypes) == 0 {
+		// nothing to plot
+		return nil, nil
+	}
+	sort.Strings(qtypes)
 
-	p.Add(scatter)
+	p := plot.New()
+	p.Title.Text = "Latencies distribution by query type"
+	p.Y.Label.Text = "Latencies (ms)"
+	p.Y.Tick.Marker = hplot.Ticks{N: 3, Format: "%.0f"}
+	p.NominalX(qtypes...)
+	// keep the boxes at the edges of the plot in the plotting area
+	p.X.Min = -0.5
+	p.X.Max = float64(len(qtypes)) - 0.5
+
+	for i, qtype := range qtypes {
+		hist := hists[qtype]
+		median := latencyAtPercentile(hist, 50)
+		boxplot, err := plotter.NewBoxPlot(vg.Length(60), float64(i), plotter.Values{median})
+		if err != nil {
+			return nil, err
+		}
+		// the box plot is computed from the histogram, the whiskers are drawn to the furthest latency within 1.5 IQR
+		// from the box, the individual outliers are not available
+		q1, q3 := latencyAtPercentile(hist, 25), latencyAtPercentile(hist, 75)
+		iqr := q3 - q1
+		boxplot.Median = median
+		boxplot.Quartile1 = q1
+		boxplot.Quartile3 = q3
+		boxplot.AdjLow = max(float64(hist.Min())/float64(time.Millisecond), q1-1.5*iqr)
+		boxplot.AdjHigh = min(float64(hist.Max())/float64(time.Millisecond), q3+1.5*iqr)
+		boxplot.Min = boxplot.AdjLow
+		boxplot.Max = boxplot.AdjHigh
+		boxplot.FillColor = plotutil.SoftColors[i%len(plotutil.SoftColors)]
+		p.Add(boxplot)
+	}
+
+	return p, nil
+}
 
-	if err := p.Save(6*vg.Inch, 6*vg.Inch, file); err != nil {
-		return fmt.Errorf("failed to save plot %q: %w", file, err)
+// colorThumbnail is a legend thumbnail filled by the color.
+type colorThumbnail struct {
+	color color.Color
+}
+
+// Thumbnail implements plot.Thumbnailer.
+func (t colorThumbnail) Thumbnail(c *draw.Canvas) {
+	c.FillPolygon(t.color, []vg.Point{
+		{X: c.Min.X, Y: c.Min.Y}, {X: c.Min.X, Y: c.Max.Y}, {X: c.Max.X, Y: c.Max.Y}, {X: c.Max.X, Y: c.Min.Y},
+	})
+}
+
+// seriesValues returns plotted values of the time series buckets, X is the start of the bucket in seconds since the start of the benchmark
+// and Y is the value of the bucket. The buckets, for which the value is not available, are skipped.
+func seriesValues(benchStart time.Time, series *dnsbench.TimeSeries, value func(b dnsbench.TimeBucket) (float64, bool)) plotter.XYs {
+	if series == nil {
+		return nil
 	}
-	return nil
+	var values plotter.XYs
+	for i, b := range series.Buckets {
+		if y, ok := value(b); ok {
+			values = append(values, plotter.XY{X: float64(series.BucketStart(i).Unix() - benchStart.Unix()), Y: y})
+		}
+	}
+	return values
 }
 
 func plotLine(p *plot.Plot, values plotter.XYs, color color.Color, fill color.Color, name string) error {
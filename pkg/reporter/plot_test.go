@@ -2,12 +2,16 @@ package reporter
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 )
 
@@ -36,106 +40,177 @@ var testErrorDatapoints = []dnsbench.ErrorDatapoint{
 	{Start: testStart.Add(7 * time.Second)},
 }
 
+var testTimeSeries = func() *dnsbench.TimeSeries {
+	ts := dnsbench.NewTimeSeries(testStart, 0, 5*time.Second)
+	for i, v := range testDatapoints {
+		ts.RecordResponse(v.Start, v.Duration)
+		ts.RecordRcode(v.Start, testDatapointRcode(i))
+	}
+	for _, v := range testErrorDatapoints {
+		ts.RecordError(v.Start)
+	}
+	return ts
+}()
+
+// testDatapointRcode returns response code of the i-th test datapoint, so that the response codes sum up to testRcodes.
+func testDatapointRcode(i int) int {
+	switch i {
+	case 2, 7:
+		return dns.RcodeNameError
+	case 8:
+		return dns.RcodeServerFailure
+	default:
+		return dns.RcodeSuccess
+	}
+}
+
+var testHist = func() *hdrhistogram.Histogram {
+	h := hdrhistogram.New(1, int64(time.Second), 3)
+	for _, v := range testDatapoints {
+		h.RecordValue(v.Duration.Nanoseconds())
+	}
+	return h
+}()
+
+var testQtypeHists = func() map[string]*hdrhistogram.Histogram {
+	hists := map[string]*hdrhistogram.Histogram{
+		"A":    hdrhistogram.New(1, int64(time.Second), 3),
+		"AAAA": hdrhistogram.New(1, int64(time.Second), 3),
+	}
+	for i, v := range testDatapoints {
+		if i%2 == 0 {
+			hists["A"].RecordValue(v.Duration.Nanoseconds())
+		} else {
+			hists["AAAA"].RecordValue(2 * v.Duration.Nanoseconds())
+		}
+	}
+	return hists
+}()
+
 var testRcodes = map[int]int64{
 	0: 8,
 	2: 1,
 	3: 2,
 }
 
-func Test_plotHistogramLatency(t *testing.T) {
-	dir := t.TempDir()
-
-	file := dir + "/histogram-latency.svg"
-	err := plotHistogramLatency(file, testDatapoints)
+func Test_latencyHistogramPlot(t *testing.T) {
+	p, err := latencyHistogramPlot(testDatapoints)
 	require.NoError(t, err)
 
-	expected, err := os.ReadFile("testdata/test-histogram-latency.svg")
-	require.NoError(t, err)
+	assertPlot(t, p, "testdata/test-histogram-latency.svg")
+}
 
-	actual, err := os.ReadFile(file)
+func Test_latencyBoxPlot(t *testing.T) {
+	p, err := latencyBoxPlot("127.0.0.1", testDatapoints)
 	require.NoError(t, err)
 
-	assert.Equal(t, expected, actual, "generated histogram latency plot does not equal to expected 'test-histogram-latency.png'")
+	assertPlot(t, p, "testdata/test-boxplot-latency.svg")
 }
 
-func Test_plotBoxPlotLatency(t *testing.T) {
-	dir := t.TempDir()
-
-	file := dir + "/boxplot-latency.svg"
-	err := plotBoxPlotLatency(file, "127.0.0.1", testDatapoints)
+func Test_responsesBarChart(t *testing.T) {
+	p, err := responsesBarChart(testRcodes)
 	require.NoError(t, err)
 
-	expected, err := os.ReadFile("testdata/test-boxplot-latency.svg")
-	require.NoError(t, err)
+	assertPlot(t, p, "testdata/test-responses-barchart.svg")
+}
 
-	actual, err := os.ReadFile(file)
+func Test_throughputLinePlot(t *testing.T) {
+	p, err := throughputLinePlot(testStart, testTimeSeries)
 	require.NoError(t, err)
 
-	assert.Equal(t, expected, actual, "generated boxplot latency plot does not equal to expected 'test-boxplot-latency.png'")
+	assertPlot(t, p, "testdata/test-throughput-lineplot.svg")
 }
 
-func Test_plotResponses(t *testing.T) {
-	dir := t.TempDir()
-
-	file := dir + "/responses-barchart.svg"
-	err := plotResponses(file, testRcodes)
+func Test_latencyLinePlot(t *testing.T) {
+	p, err := latencyLinePlot(testStart, testTimeSeries)
 	require.NoError(t, err)
 
-	expected, err := os.ReadFile("testdata/test-responses-barchart.svg")
-	require.NoError(t, err)
+	assertPlot(t, p, "testdata/test-latency-lineplot.svg")
+}
 
-	actual, err := os.ReadFile(file)
+func Test_errorRateLinePlot(t *testing.T) {
+	p, err := errorRateLinePlot(testStart, testTimeSeries)
 	require.NoError(t, err)
 
-	assert.Equal(t, expected, actual, "generated responses plot does not equal to expected 'test-responses-barchart.png'")
+	assertPlot(t, p, "testdata/test-errorrate-lineplot.svg")
 }
 
-func Test_plotLineThroughput(t *testing.T) {
-	dir := t.TempDir()
-
-	file := dir + "/throughput-lineplot.svg"
-	err := plotLineThroughput(file, testStart, testDatapoints)
+func Test_rateLinePlot(t *testing.T) {
+	adjustments := []dnsbench.RateAdjustment{
+		{Elapsed: time.Second, PreviousRate: 100, Rate: 110},
+		{Elapsed: 2 * time.Second, PreviousRate: 110, Rate: 120},
+		{Elapsed: 3 * time.Second, PreviousRate: 120, Rate: 84},
+		{Elapsed: 4 * time.Second, PreviousRate: 84, Rate: 94},
+	}
+	p, err := rateLinePlot(100, adjustments)
 	require.NoError(t, err)
 
-	expected, err := os.ReadFile("testdata/test-throughput-lineplot.svg")
+	assertPlot(t, p, "testdata/test-rate-lineplot.svg")
+}
+
+func Test_rateLinePlot_no_adjustments(t *testing.T) {
+	p, err := rateLinePlot(100, nil)
+
 	require.NoError(t, err)
+	assert.Nil(t, p)
+}
 
-	actual, err := os.ReadFile(file)
+func Test_latencyCDFPlot(t *testing.T) {
+	p, err := latencyCDFPlot(testHist)
 	require.NoError(t, err)
 
-	assert.Equal(t, expected, actual, "generated line throughput plot does not equal to expected 'test-throughput-lineplot.png'")
+	assertPlot(t, p, "testdata/test-latency-cdf.svg")
 }
 
-func Test_plotLineLatencies(t *testing.T) {
-	dir := t.TempDir()
-
-	file := dir + "/latency-lineplot.svg"
-	err := plotLineLatencies(file, testStart, testDatapoints)
+func Test_latencyHeatmapPlot(t *testing.T) {
+	p, err := latencyHeatmapPlot(testStart, testTimeSeries)
 	require.NoError(t, err)
 
-	expected, err := os.ReadFile("testdata/test-latency-lineplot.svg")
-	require.NoError(t, err)
+	assertPlot(t, p, "testdata/test-latency-heatmap.svg")
+}
 
-	actual, err := os.ReadFile(file)
+func Test_rcodesAreaPlot(t *testing.T) {
+	p, err := rcodesAreaPlot(testStart, testTimeSeries)
 	require.NoError(t, err)
 
-	assert.Equal(t, expected, actual, "generated line latencies plot does not equal to expected 'test-latency-lineplot.png'")
+	assertPlot(t, p, "testdata/test-rcodes-areaplot.svg")
 }
 
-func Test_plotErrorRate(t *testing.T) {
-	dir := t.TempDir()
-
-	file := dir + "/errorrate-lineplot.svg"
-	err := plotErrorRate(file, testStart, testErrorDatapoints)
+func Test_qtypeBoxPlot(t *testing.T) {
+	p, err := qtypeBoxPlot(testQtypeHists)
 	require.NoError(t, err)
 
-	expected, err := os.ReadFile("testdata/test-errorrate-lineplot.svg")
-	require.NoError(t, err)
+	assertPlot(t, p, "testdata/test-qtype-boxplot.svg")
+}
 
-	actual, err := os.ReadFile(file)
-	require.NoError(t, err)
+func Test_plot_no_data(t *testing.T) {
+	tests := []struct {
+		name string
+		plot func() (*plot.Plot, error)
+	}{
+		{name: "latency CDF", plot: func() (*plot.Plot, error) { return latencyCDFPlot(hdrhistogram.New(0, 0, 1)) }},
+		{name: "latency heatmap", plot: func() (*plot.Plot, error) { return latencyHeatmapPlot(testStart, nil) }},
+		{name: "rcodes area", plot: func() (*plot.Plot, error) {
+			return rcodesAreaPlot(testStart, dnsbench.NewTimeSeries(testStart, 0, time.Second))
+		}},
+		{name: "qtype boxplot", plot: func() (*plot.Plot, error) { return qtypeBoxPlot(nil) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tt.plot()
 
-	assert.Equal(t, expected, actual, "generated error rate plot does not equal to expected 'test-errorrate-lineplot.png")
+			require.NoError(t, err)
+			assert.Nil(t, p)
+		})
+	}
+}
+func Test_percentileTicks(t *testing.T) {
+	var labels []string
+	for _, tick := range percentileTicks(4.5) {
+		labels = append(labels, tick.Label)
+	}
+
+	assert.Equal(t, []string{"0%", "50%", "90%", "99%", "99.9%", "99.99%"}, labels)
 }
 
 func Test_numBins(t *testing.T) {
@@ -172,6 +247,23 @@ func Test_numBins(t *testing.T) {
 	}
 }
 
+// assertPlot asserts that the plot saved as SVG equals to the golden file.
+func assertPlot(t *testing.T, p *plot.Plot, golden string) {
+	t.Helper()
+	require.NotNil(t, p)
+
+	file := t.TempDir() + "/" + filepath.Base(golden)
+	require.NoError(t, p.Save(plotSize, plotSize, file))
+
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err)
+
+	actual, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	assert.Equal(t, expected, actual, "generated plot does not equal to expected %q", golden)
+}
+
 // dataset generates uniformorly distributed dataset.
 func dataset(length int) plotter.Values {
 	values := make(plotter.Values, length)
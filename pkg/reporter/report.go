@@ -1,14 +1,17 @@
 package reporter
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+	"github.com/tantalor93/dnspyre/v3/pkg/printutils"
 )
 
 type orderedMap struct {
@@ -19,50 +22,93 @@ type orderedMap struct {
 type reportParameters struct {
 	benchmark                 *dnsbench.Benchmark
 	outputWriter              io.Writer
+	colors                    *printutils.Printer
 	hist                      *hdrhistogram.Histogram
 	codeTotals                map[int]int64
 	totalCounters             dnsbench.Counters
 	qtypeTotals               map[string]int64
 	topErrs                   orderedMap
+	errorClasses              map[dnsbench.ErrorClass]int64
 	authenticatedDomains      map[string]struct{}
 	benchmarkDuration         time.Duration
 	dohResponseStatusesTotals map[int]int64
 	edeCodes                  map[uint16]int64
+	consistency               *ConsistencyResultStats
+	qtypeHists                map[string]*hdrhistogram.Histogram
+	rcodeHists                map[string]*hdrhistogram.Histogram
+	errorHist                 *hdrhistogram.Histogram
+	sizes                     *dnsbench.SizeStats
+	domains                   map[dnsbench.QuestionKey]*dnsbench.DomainStats
+	ttls                      map[dnsbench.QuestionKey]*dnsbench.TTLStats
+	ttlHist                   *hdrhistogram.Histogram
+	rateAdjustments           []dnsbench.RateAdjustment
+	warmup                    *dnsbench.WarmupStats
+	iterations                *iterationsSummary
+	fairness                  *fairnessSummary
 }
 
 type reportPrinter interface {
 	print(params reportParameters) error
 }
 
-// PrintReport prints formatted benchmark result to stdout, exports graphs and generates CSV output if configured.
-// If there is a fatal error while printing report, an error is returned.
+// PrintReport prints formatted benchmark result to stdout, exports graphs and generates CSV and HTML output if configured.
+// The report uses the configuration with default values filled in (see dnsbench.Benchmark.WithDefaults), so the benchmark passed
+// to dnsbench.Benchmark.Run can be reported as is. If there is a fatal error while printing report, an error is returned.
 func PrintReport(b *dnsbench.Benchmark, stats []*dnsbench.ResultStats, benchStart time.Time, benchDuration time.Duration) error {
+	b, err := b.WithDefaults()
+	if err != nil {
+		return err
+	}
 	totals := Merge(b, stats)
+	return printReport(b, &totals, benchStart, benchDuration, nil)
+}
 
-	top3errs := make(map[string]int)
-	top3errorsInOrder := make([]string, 0)
+// PrintIterationsReport prints formatted results of the benchmark repeated using dnsbench.Benchmark.RunIterations, the results of all iterations
+// are merged and the per-iteration QPS and latency percentiles are summarized with their variance across the iterations. Graphs and CSV output
+// are exported from the merged results if configured. If there is a fatal error while printing report, an error is returned.
+func PrintIterationsReport(b *dnsbench.Benchmark, iterations []dnsbench.IterationResult) error {
+	if len(iterations) == 0 {
+		return errors.New("no iterations to report")
+	}
+	b, err := b.WithDefaults()
+	if err != nil {
+		return err
+	}
+	totals, benchDuration := mergeIterations(b, iterations)
+	return printReport(b, &totals, iterations[0].Start, benchDuration, summarizeIterations(b, iterations))
+}
 
-	for i := 0; i < 3; i++ {
-		maxerr := 0
-		maxerrstr := ""
-		for k, v := range totals.GroupedErrors {
-			if _, ok := top3errs[k]; v > maxerr && !ok {
-				maxerrstr = k
-				maxerr = v
+// mergeIterations merges results of all iterations, the returned duration is the sum of the durations of the iterations.
+func mergeIterations(b *dnsbench.Benchmark, iterations []dnsbench.IterationResult) (BenchmarkResultStats, time.Duration) {
+	var stats []*dnsbench.ResultStats
+	var benchDuration, warmupDuration time.Duration
+	for _, it := range iterations {
+		stats = append(stats, it.Stats...)
+		benchDuration += it.Duration
+		var warmup time.Duration
+		for _, s := range it.Stats {
+			if s.Warmup != nil {
+				warmup = max(warmup, s.Warmup.Duration)
 			}
 		}
-		if maxerr != 0 {
-			top3errs[maxerrstr] = maxerr
-			top3errorsInOrder = append(top3errorsInOrder, maxerrstr)
-		}
+		warmupDuration += warmup
 	}
+	totals := Merge(b, stats)
+	if totals.Warmup != nil {
+		// each iteration has its own warm-up
+		totals.Warmup.Duration = warmupDuration
+	}
+	return totals, benchDuration
+}
 
+func printReport(b *dnsbench.Benchmark, totals *BenchmarkResultStats, benchStart time.Time, benchDuration time.Duration, iterations *iterationsSummary) error {
 	if len(b.PlotDir) != 0 {
 		if err := directoryExists(b.PlotDir); err != nil {
 			return fmt.Errorf("unable to plot results: %w", err)
 		}
-		if len(b.PlotFormat) == 0 {
-			b.PlotFormat = dnsbench.DefaultPlotFormat
+		format := b.PlotFormat
+		if len(format) == 0 {
+			format = dnsbench.DefaultPlotFormat
 		}
 
 		now := time.Now().Format("2006-01-02T15-04-05")
@@ -71,23 +117,10 @@ func PrintReport(b *dnsbench.Benchmark, stats []*dnsbench.ResultStats, benchStar
 			return fmt.Errorf("unable to plot results: %w", err)
 		}
 
-		if err := plotHistogramLatency(fileName(b, dir, "latency-histogram"), totals.Timings); err != nil {
-			fmt.Fprintln(b.ErrWriter, err)
-		}
-		if err := plotBoxPlotLatency(fileName(b, dir, "latency-boxplot"), b.Server, totals.Timings); err != nil {
-			fmt.Fprintln(b.ErrWriter, err)
-		}
-		if err := plotResponses(fileName(b, dir, "responses-barchart"), totals.Codes); err != nil {
-			fmt.Fprintln(b.ErrWriter, err)
-		}
-		if err := plotLineThroughput(fileName(b, dir, "throughput-lineplot"), benchStart, totals.Timings); err != nil {
-			fmt.Fprintln(b.ErrWriter, err)
-		}
-		if err := plotLineLatencies(fileName(b, dir, "latency-lineplot"), benchStart, totals.Timings); err != nil {
-			fmt.Fprintln(b.ErrWriter, err)
-		}
-		if err := plotErrorRate(fileName(b, dir, "errorrate-lineplot"), benchStart, totals.Errors); err != nil {
-			fmt.Fprintln(b.ErrWriter, err)
+		for _, c := range charts(b, totals, benchStart) {
+			if err := savePlot(c, fileName(dir, c.name, format)); err != nil {
+				fmt.Fprintln(b.ErrWriter, err)
+			}
 		}
 	}
 
@@ -111,24 +144,106 @@ func PrintReport(b *dnsbench.Benchmark, stats []*dnsbench.ResultStats, benchStar
 		writeBars(csv, totals.Hist.Distribution())
 	}
 
+	if b.DomainStatsCsv != "" && totals.Domains != nil {
+		if err := exportDomainsCsv(b.DomainStatsCsv, totals.Domains); err != nil {
+			return err
+		}
+	}
+
+	params := newReportParameters(b, totals, benchDuration, b.Writer)
+	params.iterations = iterations
+
+	if b.HTML != "" {
+		if err := exportHTML(b.HTML, params, charts(b, totals, benchStart)); err != nil {
+			return err
+		}
+	}
+
 	if b.Silent {
 		return nil
 	}
+	return printer(b).print(params)
+}
+
+// WriteJSONReport writes benchmark result formatted as JSON to the writer regardless of Benchmark.JSON, graphs and CSV files are not exported.
+func WriteJSONReport(w io.Writer, b *dnsbench.Benchmark, stats []*dnsbench.ResultStats, benchDuration time.Duration) error {
+	b, err := b.WithDefaults()
+	if err != nil {
+		return err
+	}
+	totals := Merge(b, stats)
+	reporter := jsonReporter{}
+	return reporter.print(newReportParameters(b, &totals, benchDuration, w))
+}
+
+func newReportParameters(b *dnsbench.Benchmark, totals *BenchmarkResultStats, benchDuration time.Duration, w io.Writer) reportParameters {
+	top3errs := make(map[string]int)
+	top3errorsInOrder := make([]string, 0)
+
+	for i := 0; i < 3; i++ {
+		maxerr := 0
+		maxerrstr := ""
+		for k, v := range totals.GroupedErrors {
+			if _, ok := top3errs[k]; v > maxerr && !ok {
+				maxerrstr = k
+				maxerr = v
+			}
+		}
+		if maxerr != 0 {
+			top3errs[maxerrstr] = maxerr
+			top3errorsInOrder = append(top3errorsInOrder, maxerrstr)
+		}
+	}
+
 	topErrs := orderedMap{m: top3errs, order: top3errorsInOrder}
-	params := reportParameters{
+	if totals.Warmup != nil && benchDuration > totals.Warmup.Duration {
+		// QPS and throughput are computed from the measured window after the warm-up
+		benchDuration -= totals.Warmup.Duration
+	}
+	var fairness *fairnessSummary
+	if totals.Workers != nil {
+		summary := summarizeFairness(totals.Workers, totals.Connections)
+		fairness = &summary
+	}
+	return reportParameters{
 		benchmark:                 b,
-		outputWriter:              b.Writer,
+		outputWriter:              w,
+		colors:                    printutils.NewPrinter(b.Color),
 		hist:                      totals.Hist,
 		codeTotals:                totals.Codes,
 		totalCounters:             totals.Counters,
 		qtypeTotals:               totals.Qtypes,
 		topErrs:                   topErrs,
+		errorClasses:              totals.ErrorClasses,
 		authenticatedDomains:      totals.AuthenticatedDomains,
 		benchmarkDuration:         benchDuration,
 		dohResponseStatusesTotals: totals.DoHStatusCodes,
 		edeCodes:                  totals.EDECodes,
+		consistency:               totals.Consistency,
+		qtypeHists:                totals.QtypeHists,
+		rcodeHists:                totals.RcodeHists,
+		errorHist:                 totals.ErrorHist,
+		sizes:                     totals.Sizes,
+		domains:                   totals.Domains,
+		ttls:                      totals.TTLs,
+		ttlHist:                   totals.TTLHist,
+		rateAdjustments:           totals.RateAdjustments,
+		warmup:                    totals.Warmup,
+		fairness:                  fairness,
 	}
-	return printer(b).print(params)
+}
+
+// percentiles returns the latency percentiles reported for the benchmark.
+func percentiles(b *dnsbench.Benchmark) []float64 {
+	if len(b.Percentiles) == 0 {
+		return dnsbench.DefaultPercentiles
+	}
+	return b.Percentiles
+}
+
+// percentileName returns name of the latency percentile, for example p99 or p99.9.
+func percentileName(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
 }
 
 func directoryExists(plotDir string) error {
@@ -153,8 +268,21 @@ func printer(b *dnsbench.Benchmark) reportPrinter {
 	}
 }
 
-func fileName(b *dnsbench.Benchmark, dir, name string) string {
-	return filepath.Join(dir, name+"."+b.PlotFormat)
+func fileName(dir, name, format string) string {
+	return filepath.Join(dir, name+"."+format)
+}
+
+func exportDomainsCsv(path string, domains map[dnsbench.QuestionKey]*dnsbench.DomainStats) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file for per-domain CSV export due to '%v'", err)
+	}
+	defer f.Close()
+
+	if err := writeDomainsCsv(f, domains); err != nil {
+		return fmt.Errorf("failed to export per-domain statistics to CSV due to '%v'", err)
+	}
+	return nil
 }
 
 func writeBars(f *os.File, bars []hdrhistogram.Bar) {
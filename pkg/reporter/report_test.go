@@ -2,6 +2,7 @@ package reporter_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"net"
@@ -29,6 +30,36 @@ func Test_PrintReport(t *testing.T) {
 	assert.Equal(t, readResource("successReport"), buffer.String())
 }
 
+func Test_PrintReport_not_normalized_benchmark(t *testing.T) {
+	buffer := bytes.Buffer{}
+	// benchmark as configured by the library user, the defaults are filled in only in the copy used by dnsbench.Benchmark.Run
+	b := dnsbench.Benchmark{Writer: &buffer}
+	hist := func() *hdrhistogram.Histogram {
+		h := hdrhistogram.New(0, int64(dnsbench.DefaultRequestTimeout), dnsbench.DefaultHistPrecision)
+		h.RecordValue(int64(time.Millisecond))
+		h.RecordValue(int64(3 * time.Millisecond))
+		return h
+	}
+	rs := dnsbench.ResultStats{
+		Codes:      map[int]int64{dns.RcodeSuccess: 2},
+		Qtypes:     map[string]int64{"A": 2},
+		Hist:       hist(),
+		QtypeHists: map[string]*hdrhistogram.Histogram{"A": hist()},
+		Counters:   &dnsbench.Counters{Total: 2, Success: 2},
+	}
+
+	totals := reporter.Merge(&b, []*dnsbench.ResultStats{&rs})
+	assert.EqualValues(t, 2, totals.Hist.TotalCount())
+	assert.EqualValues(t, 2, totals.QtypeHists["A"].TotalCount())
+
+	require.NoError(t, reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second))
+	assert.Contains(t, buffer.String(), "DNS timings, 2 datapoints")
+
+	buffer.Reset()
+	require.NoError(t, reporter.WriteJSONReport(&buffer, &b, []*dnsbench.ResultStats{&rs}, time.Second))
+	assert.Contains(t, buffer.String(), `"p99Ms":3`)
+}
+
 func Test_PrintReport_dnssec(t *testing.T) {
 	buffer := bytes.Buffer{}
 	b, rs := testReportData(&buffer)
@@ -65,6 +96,19 @@ func Test_PrintReport_json(t *testing.T) {
 	assert.Equal(t, readResource("jsonReport"), buffer.String())
 }
 
+func Test_WriteJSONReport(t *testing.T) {
+	output := bytes.Buffer{}
+	b, rs := testReportData(&output)
+	b.Rcodes = true
+	b.HistDisplay = true
+
+	buffer := bytes.Buffer{}
+	err := reporter.WriteJSONReport(&buffer, &b, []*dnsbench.ResultStats{&rs}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonReport"), buffer.String())
+	assert.Empty(t, output.String(), "nothing should be written to the benchmark writer")
+}
+
 func Test_PrintReport_json_dnssec(t *testing.T) {
 	buffer := bytes.Buffer{}
 	b, rs := testReportData(&buffer)
@@ -132,6 +176,408 @@ func Test_PrintReport_errors(t *testing.T) {
 	assert.Equal(t, readResource("errorReport"), buffer.String())
 }
 
+func Test_PrintReport_error_classes(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithErrorClasses(&buffer)
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("errorClassesReport"), buffer.String())
+}
+
+func Test_PrintReport_json_error_classes(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithErrorClasses(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonErrorClassesReport"), buffer.String())
+}
+
+func Test_PrintReport_consistency(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithConsistency(&buffer)
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("consistencyReport"), buffer.String())
+}
+
+func Test_PrintReport_json_consistency(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithConsistency(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonConsistencyReport"), buffer.String())
+}
+
+func Test_PrintReport_ttl(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithTTLs(&buffer)
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("ttlReport"), buffer.String())
+}
+
+func Test_PrintReport_json_ttl(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithTTLs(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonTTLReport"), buffer.String())
+}
+
+func Test_PrintReport_sizes(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithSizes(&buffer)
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("sizesReport"), buffer.String())
+}
+
+func Test_PrintReport_json_sizes(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithSizes(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonSizesReport"), buffer.String())
+}
+
+func Test_PrintReport_domains(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithDomains(&buffer)
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("domainsReport"), buffer.String())
+}
+
+func Test_PrintReport_json_domains(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithDomains(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonDomainsReport"), buffer.String())
+}
+
+func Test_PrintReport_domains_csv(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "domains.csv")
+
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithDomains(&buffer)
+	b.DomainStatsCsv = file
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+
+	csv, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("domainsCsv"), string(csv))
+}
+
+func Test_PrintReport_latency_breakdown(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithLatencyBreakdown(&buffer)
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("latencyBreakdownReport"), buffer.String())
+}
+
+func Test_PrintReport_json_latency_breakdown(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithLatencyBreakdown(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonLatencyBreakdownReport"), buffer.String())
+}
+
+func Test_PrintReport_latency_breakdown_percentiles(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithLatencyBreakdown(&buffer)
+	b.Percentiles = []float64{99.9, 50}
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), " Question type │ Count │  mean   │  p99.9  │   p50   │   max   \n")
+	assert.Contains(t, buffer.String(), " Response code │ Count │  mean   │  p99.9  │   p50   │   max   \n")
+
+	buffer.Reset()
+	b.JSON = true
+	err = reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	var res struct {
+		LatencyStatsByQuestionType map[string]struct {
+			PercentilesMs map[string]int64 `json:"percentilesMs"`
+		} `json:"latencyStatsByQuestionType"`
+		LatencyStatsByResponseCode map[string]struct {
+			PercentilesMs map[string]int64 `json:"percentilesMs"`
+		} `json:"latencyStatsByResponseCode"`
+	}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &res))
+	require.NotEmpty(t, res.LatencyStatsByQuestionType)
+	for _, v := range res.LatencyStatsByQuestionType {
+		assert.Contains(t, v.PercentilesMs, "p99.9")
+	}
+	require.NotEmpty(t, res.LatencyStatsByResponseCode)
+	for _, v := range res.LatencyStatsByResponseCode {
+		assert.Contains(t, v.PercentilesMs, "p99.9")
+	}
+}
+
+func Test_PrintReport_adaptive_rate(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithRateAdjustments(&buffer)
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("adaptiveRateReport"), buffer.String())
+}
+
+func Test_PrintReport_json_adaptive_rate(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithRateAdjustments(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonAdaptiveRateReport"), buffer.String())
+}
+
+func Test_PrintReport_warmup(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithWarmup(&buffer)
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("warmupReport"), buffer.String())
+}
+
+func Test_PrintReport_json_warmup(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportDataWithWarmup(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonWarmupReport"), buffer.String())
+}
+
+func Test_PrintReport_percentiles(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportData(&buffer)
+	b.Percentiles = []float64{99.9, 50}
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("percentilesReport"), buffer.String())
+}
+
+func Test_PrintReport_json_percentiles(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportData(&buffer)
+	b.Percentiles = []float64{99.9, 50}
+	b.JSON = true
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonPercentilesReport"), buffer.String())
+}
+
+func Test_PrintReport_worker_stats(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, stats := testWorkerStatsData(&buffer)
+
+	err := reporter.PrintReport(&b, stats, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("workerStatsReport"), buffer.String())
+}
+
+func Test_PrintReport_json_worker_stats(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, stats := testWorkerStatsData(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintReport(&b, stats, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonWorkerStatsReport"), buffer.String())
+}
+
+func Test_PrintIterationsReport(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, iterations := testIterationsData(&buffer)
+
+	err := reporter.PrintIterationsReport(&b, iterations)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("iterationsReport"), buffer.String())
+}
+
+func Test_PrintIterationsReport_json(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, iterations := testIterationsData(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintIterationsReport(&b, iterations)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonIterationsReport"), buffer.String())
+}
+
+func Test_PrintIterationsReport_percentiles(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, iterations := testIterationsData(&buffer)
+	b.Percentiles = []float64{99.9, 50}
+
+	err := reporter.PrintIterationsReport(&b, iterations)
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), "│  p99.9  │   p50   \n", "iterations should report configured percentiles")
+	assert.Contains(t, buffer.String(), "\n p99.9  │")
+	assert.NotContains(t, buffer.String(), "p95")
+}
+
+func Test_PrintIterationsReport_no_iterations(t *testing.T) {
+	b, _ := testIterationsData(io.Discard)
+
+	require.Error(t, reporter.PrintIterationsReport(&b, nil))
+}
+
+func Test_PrintComparison(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, baseline, candidate := testComparisonData(&buffer)
+
+	err := reporter.PrintComparison(&b, baseline, candidate, testCompareOptions)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("compareReport"), buffer.String())
+}
+
+func Test_PrintComparison_json(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, baseline, candidate := testComparisonData(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintComparison(&b, baseline, candidate, testCompareOptions)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("jsonCompareReport"), buffer.String())
+}
+
+func Test_PrintComparison_better(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, baseline, candidate := testComparisonData(&buffer)
+	b.JSON = true
+
+	err := reporter.PrintComparison(&b, candidate, baseline, testCompareOptions)
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), `"verdict":"better"`)
+}
+
+func Test_PrintComparison_below_min_effect(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b := dnsbench.Benchmark{HistMax: 10 * time.Second, HistPre: 3, Writer: &buffer, JSON: true}
+	// the latencies differ significantly, but the median latency changed only by 1%
+	var baselineLatencies, candidateLatencies []time.Duration
+	for i := range 5000 {
+		baselineLatencies = append(baselineLatencies, time.Duration(1000+i%100)*10*time.Microsecond)
+		candidateLatencies = append(candidateLatencies, time.Duration(1010+i%100)*10*time.Microsecond)
+	}
+	codes := map[int]int64{dns.RcodeSuccess: 5000}
+
+	err := reporter.PrintComparison(&b, testComparisonResults(&b, 0, codes, baselineLatencies...),
+		testComparisonResults(&b, 0, codes, candidateLatencies...), testCompareOptions)
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), `"belowMinEffect":true`)
+	assert.Contains(t, buffer.String(), `"verdict":"unchanged"`)
+}
+
+func Test_PrintComparison_iterations(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, iterations := testIterationsData(&buffer)
+	b.JSON = true
+	results := dnsbench.Results{Benchmark: &b, Iterations: iterations}
+
+	err := reporter.PrintComparison(&b, results, results, testCompareOptions)
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), `"verdict":"unchanged"`)
+}
+
+func Test_PrintComparison_iterations_latency(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b := dnsbench.Benchmark{HistMax: 10 * time.Second, HistPre: 3, Writer: &buffer, JSON: true}
+	codes := map[int]int64{dns.RcodeSuccess: 3}
+	iterations := func(medians ...time.Duration) dnsbench.Results {
+		res := dnsbench.Results{Benchmark: &b}
+		for _, m := range medians {
+			r := testComparisonResults(&b, 0, codes, m-time.Millisecond, m, m+time.Millisecond)
+			res.Iterations = append(res.Iterations, dnsbench.IterationResult{Duration: time.Second, Stats: r.Stats})
+		}
+		return res
+	}
+
+	err := reporter.PrintComparison(&b, iterations(10*time.Millisecond, 11*time.Millisecond, 12*time.Millisecond),
+		iterations(20*time.Millisecond, 21*time.Millisecond, 22*time.Millisecond), testCompareOptions)
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), `"method":"Welch's t-test of iteration medians"`)
+	assert.Contains(t, buffer.String(), `"verdict":"worse"`)
+}
+
+func Test_PrintComparison_iterations_constant_latency(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b := dnsbench.Benchmark{HistMax: 10 * time.Second, HistPre: 3, Writer: &buffer, JSON: true}
+	codes := map[int]int64{dns.RcodeSuccess: 3}
+	iterations := func(median time.Duration) dnsbench.Results {
+		res := dnsbench.Results{Benchmark: &b}
+		for range 3 {
+			// the medians do not vary and the duration of the iterations is unknown
+			r := testComparisonResults(&b, 0, codes, median, median, median)
+			res.Iterations = append(res.Iterations, dnsbench.IterationResult{Stats: r.Stats})
+		}
+		return res
+	}
+
+	err := reporter.PrintComparison(&b, iterations(time.Millisecond), iterations(2*time.Millisecond), testCompareOptions)
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), `"name":"Latency","method":"Welch's t-test of iteration medians","performed":true,"pValue":0`)
+	assert.Contains(t, buffer.String(), `"queriesPerSecond":{"baseline":0,"candidate":0,"delta":0}`)
+	assert.Contains(t, buffer.String(), `"verdict":"worse"`)
+}
+
+func Test_PrintComparison_rcodes_changed(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b := dnsbench.Benchmark{HistMax: 10 * time.Second, HistPre: 1, Writer: &buffer, JSON: true}
+	var latencies []time.Duration
+	for range 100 {
+		latencies = append(latencies, 10*time.Millisecond)
+	}
+
+	// NXDOMAIN ratio changed, but the ratio of error response codes did not
+	err := reporter.PrintComparison(&b,
+		testComparisonResults(&b, 0, map[int]int64{dns.RcodeSuccess: 90, dns.RcodeNameError: 10}, latencies...),
+		testComparisonResults(&b, 0, map[int]int64{dns.RcodeSuccess: 50, dns.RcodeNameError: 50}, latencies...),
+		testCompareOptions)
+	require.NoError(t, err)
+	assert.Contains(t, buffer.String(), `"name":"Response codes","method":"chi-square","performed":true`)
+	assert.Contains(t, buffer.String(), `"verdict":"changed","queriesPerSecond"`)
+}
+
+func Test_PrintComparison_invalid_options(t *testing.T) {
+	b, baseline, candidate := testComparisonData(io.Discard)
+
+	require.Error(t, reporter.PrintComparison(&b, baseline, candidate, reporter.CompareOptions{Significance: 0}))
+	require.Error(t, reporter.PrintComparison(&b, baseline, candidate, reporter.CompareOptions{Significance: 1}))
+	require.Error(t, reporter.PrintComparison(&b, baseline, candidate, reporter.CompareOptions{Significance: 0.05, MinEffect: -1}))
+}
+
 func Test_PrintReport_plot(t *testing.T) {
 	dir := t.TempDir()
 
@@ -139,6 +585,7 @@ func Test_PrintReport_plot(t *testing.T) {
 	b, rs := testReportData(&buffer)
 	b.PlotDir = dir
 	b.PlotFormat = dnsbench.DefaultPlotFormat
+	rs.QtypeHists = map[string]*hdrhistogram.Histogram{"A": rs.Hist}
 
 	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
 
@@ -163,11 +610,35 @@ func Test_PrintReport_plot(t *testing.T) {
 	assert.ElementsMatch(t, graphFiles,
 		[]string{
 			"errorrate-lineplot.svg", "latency-boxplot.svg", "latency-histogram.svg", "latency-lineplot.svg",
-			"responses-barchart.svg", "throughput-lineplot.svg",
+			"responses-barchart.svg", "throughput-lineplot.svg", "latency-cdf.svg", "latency-heatmap.svg",
+			"rcodes-areaplot.svg", "qtype-boxplot.svg",
 		},
 	)
 }
 
+func Test_PrintReport_plot_charts(t *testing.T) {
+	dir := t.TempDir()
+
+	buffer := bytes.Buffer{}
+	b, rs := testReportData(&buffer)
+	b.PlotDir = dir
+	b.PlotFormat = dnsbench.DefaultPlotFormat
+	b.PlotCharts = []string{dnsbench.LatencyCDFChart, dnsbench.ResponsesBarChart}
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+
+	require.NoError(t, err)
+
+	graphFiles, err := filepath.Glob(filepath.Join(dir, "graphs-*", "*"))
+	require.NoError(t, err)
+
+	var names []string
+	for _, v := range graphFiles {
+		names = append(names, filepath.Base(v))
+	}
+	assert.ElementsMatch(t, []string{"latency-cdf.svg", "responses-barchart.svg"}, names)
+}
+
 func Test_PrintReport_plot_error(t *testing.T) {
 	dir := t.TempDir()
 
@@ -181,6 +652,60 @@ func Test_PrintReport_plot_error(t *testing.T) {
 	require.Error(t, err)
 }
 
+func Test_PrintReport_html(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "report.html")
+
+	buffer := bytes.Buffer{}
+	b, rs := testReportData(&buffer)
+	b.HTML = file
+	b.PlotCharts = []string{dnsbench.ResponsesBarChart}
+	// the configuration is printed in the report, so the system nameserver and the default queries are not used
+	b.Server = "127.0.0.1"
+	b.Queries = []string{"example.org"}
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("successReport"), buffer.String())
+
+	html, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, readResource("htmlReport"), string(html))
+}
+
+func Test_PrintReport_html_all_charts(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "report.html")
+
+	buffer := bytes.Buffer{}
+	b, rs := testReportData(&buffer)
+	b.HTML = file
+	b.Silent = true
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, buffer.String())
+
+	html, err := os.ReadFile(file)
+	require.NoError(t, err)
+	for _, chart := range []string{
+		dnsbench.LatencyHistogramChart, dnsbench.LatencyBoxPlotChart, dnsbench.ResponsesBarChart,
+		dnsbench.ThroughputLinePlotChart, dnsbench.LatencyLinePlotChart, dnsbench.ErrorRateLinePlotChart,
+		dnsbench.LatencyCDFChart, dnsbench.LatencyHeatmapChart, dnsbench.RcodesAreaPlotChart,
+	} {
+		assert.Contains(t, string(html), `<div class="chart" id="`+chart+`"`)
+	}
+	assert.NotContains(t, string(html), `id="`+dnsbench.RateLinePlotChart+`"`)
+}
+
+func Test_PrintReport_html_error(t *testing.T) {
+	buffer := bytes.Buffer{}
+	b, rs := testReportData(&buffer)
+	b.HTML = filepath.Join(t.TempDir(), "non-existing-directory", "report.html")
+
+	err := reporter.PrintReport(&b, []*dnsbench.ResultStats{&rs}, time.Now(), time.Second)
+
+	require.Error(t, err)
+}
+
 func testReportData(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.ResultStats) {
 	b := dnsbench.Benchmark{
 		HistPre: 1,
@@ -204,6 +729,14 @@ func testReportData(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.Re
 	if err != nil {
 		panic(err)
 	}
+	ts := dnsbench.NewTimeSeries(time.Unix(0, 0), 0, time.Second)
+	ts.RecordResponse(d1.Start, d1.Duration)
+	ts.RecordRcode(d1.Start, dns.RcodeSuccess)
+	ts.RecordResponse(d2.Start, d2.Duration)
+	ts.RecordRcode(d2.Start, dns.RcodeSuccess)
+	for range 6 {
+		ts.RecordError(time.Unix(0, 0))
+	}
 	rs := dnsbench.ResultStats{
 		Codes: map[int]int64{
 			dns.RcodeSuccess: 2,
@@ -230,6 +763,12 @@ func testReportData(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.Re
 			{Start: time.Unix(0, 0), Err: errors.New("test2")},
 			{Start: time.Unix(0, 0), Err: errors.New("test2")},
 		},
+		ErrorGroups: map[string]int64{
+			"test2":               3,
+			"test":                1,
+			"read udp 8.8.8.8:53": 2,
+		},
+		TimeSeries: ts,
 	}
 	return b, rs
 }
@@ -256,10 +795,387 @@ func testReportDataWithServerDNSErrors(testOutputWriter io.Writer) (dnsbench.Ben
 			{Start: time.Unix(0, 0), Err: &net.DNSError{Err: "no such host", Name: "unknown.host.com"}},
 			{Start: time.Unix(0, 0), Err: &net.DNSError{Err: "no such host", Name: "unknown.host.com"}},
 		},
+		ErrorGroups: map[string]int64{
+			"no such host unknown.host.com": 3,
+		},
+	}
+	return b, rs
+}
+
+func testReportDataWithErrorClasses(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.ResultStats) {
+	b, rs := testReportData(testOutputWriter)
+	rs.ErrorClasses = map[dnsbench.ErrorClass]int64{
+		{Type: dnsbench.TimeoutError, Detail: "read"}:               3,
+		{Type: dnsbench.TLSAlertError, Detail: "handshake failure"}: 1,
+		{Type: dnsbench.RefusedError}:                               1,
+		{Type: dnsbench.HTTPStatusError, Detail: "503"}:             1,
+	}
+	return b, rs
+}
+
+func testReportDataWithConsistency(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.ResultStats) {
+	b := dnsbench.Benchmark{
+		Server:  "127.0.0.1:53",
+		HistPre: 1,
+		Writer:  testOutputWriter,
+	}
+	h := hdrhistogram.New(0, 0, 1)
+	h.RecordValue(5)
+	h.RecordValue(10)
+	compareHist := hdrhistogram.New(0, 0, 1)
+	compareHist.RecordValue(20)
+	compareHist.RecordValue(30)
+	rs := dnsbench.ResultStats{
+		Codes: map[int]int64{
+			dns.RcodeSuccess: 2,
+		},
+		Qtypes: map[string]int64{
+			"A": 2,
+		},
+		Hist: h,
+		Counters: &dnsbench.Counters{
+			Total:   2,
+			Success: 2,
+		},
+		Consistency: &dnsbench.ConsistencyStats{
+			Servers: map[string]*dnsbench.ResultStats{
+				"127.0.0.2:53": {
+					Hist: compareHist,
+					Counters: &dnsbench.Counters{
+						Total:   2,
+						Success: 1,
+						Error:   1,
+					},
+				},
+			},
+			Compared:   2,
+			Mismatched: 1,
+			Differences: map[dnsbench.DifferenceKey]*dnsbench.Difference{
+				{Server: "127.0.0.2:53", Domain: "example.org.", Qtype: "A", Kind: dnsbench.RcodeDifference}: {
+					Count: 1, Expected: "NOERROR", Actual: "SERVFAIL",
+				},
+			},
+		},
+	}
+	return b, rs
+}
+
+func testReportDataWithTTLs(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.ResultStats) {
+	b := dnsbench.Benchmark{
+		HistMax:     time.Second,
+		HistPre:     1,
+		Writer:      testOutputWriter,
+		TTLAnalysis: true,
+	}
+	// bimodal latency distribution, fast cached answers and slow cache misses
+	h := hdrhistogram.New(0, int64(time.Second), 1)
+	for range 8 {
+		h.RecordValue(int64(time.Millisecond))
+	}
+	for range 2 {
+		h.RecordValue(int64(40 * time.Millisecond))
+	}
+	ttlHist := hdrhistogram.New(0, 1<<31-1, 3)
+	for _, v := range []int64{300, 290, 280, 270, 260, 60, 60, 59, 58, 300} {
+		ttlHist.RecordValue(v)
+	}
+	rs := dnsbench.ResultStats{
+		Codes: map[int]int64{
+			dns.RcodeSuccess: 10,
+		},
+		Qtypes: map[string]int64{
+			"A": 10,
+		},
+		Hist: h,
+		Counters: &dnsbench.Counters{
+			Total:   10,
+			Success: 10,
+		},
+		TTLs: map[dnsbench.QuestionKey]*dnsbench.TTLStats{
+			{Name: "example.org.", Qtype: "A"}: {
+				Count: 6, Min: 260, Max: 300, Hits: 2, Misses: 1, NotCached: 2,
+			},
+			{Name: "example.com.", Qtype: "A"}: {
+				Count: 4, Min: 58, Max: 60, Hits: 2, Prefetches: 1, Stale: 1, Authoritative: true, AuthoritativeTTL: 3600,
+			},
+		},
+		TTLHist: ttlHist,
+	}
+	return b, rs
+}
+
+func testReportDataWithSizes(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.ResultStats) {
+	b := dnsbench.Benchmark{
+		HistPre: 1,
+		Writer:  testOutputWriter,
+	}
+	h := hdrhistogram.New(0, 0, 1)
+	h.RecordValue(5)
+	h.RecordValue(10)
+	h.RecordValue(10)
+	requestHist := hdrhistogram.New(0, 65535, 3)
+	responseHist := hdrhistogram.New(0, 65535, 3)
+	for _, v := range []int64{40, 41, 40} {
+		requestHist.RecordValue(v)
+	}
+	for _, v := range []int64{56, 1210, 2800} {
+		responseHist.RecordValue(v)
+	}
+	rs := dnsbench.ResultStats{
+		Codes: map[int]int64{
+			dns.RcodeSuccess: 3,
+		},
+		Qtypes: map[string]int64{
+			"A":   2,
+			"TXT": 1,
+		},
+		Hist: h,
+		Counters: &dnsbench.Counters{
+			Total:   3,
+			Success: 3,
+		},
+		Sizes: &dnsbench.SizeStats{
+			RequestHist:   requestHist,
+			ResponseHist:  responseHist,
+			BytesSent:     121,
+			BytesReceived: 4066,
+			Qtypes: map[string]*dnsbench.QtypeSizes{
+				"A":   {Requests: 2, RequestBytes: 80, Responses: 2, ResponseBytes: 1266},
+				"TXT": {Requests: 1, RequestBytes: 41, Responses: 1, ResponseBytes: 2800},
+			},
+			Largest: []dnsbench.ResponseSize{
+				{QuestionKey: dnsbench.QuestionKey{Name: "example.com.", Qtype: "TXT"}, Size: 2800},
+				{QuestionKey: dnsbench.QuestionKey{Name: "example.org.", Qtype: "A"}, Size: 1210},
+			},
+			NearBufferSize: 1,
+		},
+	}
+	return b, rs
+}
+
+func testReportDataWithDomains(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.ResultStats) {
+	b := dnsbench.Benchmark{
+		HistPre:        1,
+		Writer:         testOutputWriter,
+		DomainStats:    true,
+		DomainStatsTop: 2,
+	}
+	hist := func(values ...int64) *hdrhistogram.Histogram {
+		h := hdrhistogram.New(0, 0, 1)
+		for _, v := range values {
+			h.RecordValue(v)
+		}
+		return h
+	}
+	rs := dnsbench.ResultStats{
+		Codes: map[int]int64{
+			dns.RcodeSuccess:       5,
+			dns.RcodeServerFailure: 2,
+		},
+		Qtypes: map[string]int64{
+			"A": 8,
+		},
+		Hist: hist(2, 3, 10, 12, 14, 20, 25),
+		Counters: &dnsbench.Counters{
+			Total:   8,
+			Success: 5,
+			Error:   2,
+			IOError: 1,
+		},
+		Domains: map[dnsbench.QuestionKey]*dnsbench.DomainStats{
+			{Name: "example.org.", Qtype: "A"}: {
+				Count: 2, Codes: map[int]int64{dns.RcodeSuccess: 2}, Hist: hist(2, 3),
+			},
+			{Name: "example.com.", Qtype: "A"}: {
+				Count: 2, Codes: map[int]int64{dns.RcodeSuccess: 1, dns.RcodeServerFailure: 1}, Hist: hist(10, 12),
+			},
+			{Name: "example.com.", Qtype: "AAAA"}: {
+				Count: 1, Codes: map[int]int64{dns.RcodeSuccess: 1}, Hist: hist(14),
+			},
+			{Name: "example.net.", Qtype: "A"}: {
+				Count: 3, IOError: 1, Codes: map[int]int64{dns.RcodeSuccess: 1, dns.RcodeServerFailure: 1}, Hist: hist(20, 25),
+			},
+		},
+	}
+	return b, rs
+}
+
+func testReportDataWithLatencyBreakdown(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.ResultStats) {
+	b := dnsbench.Benchmark{
+		HistMax: 10 * time.Second,
+		HistPre: 1,
+		Writer:  testOutputWriter,
+	}
+	hist := func(values ...time.Duration) *hdrhistogram.Histogram {
+		h := hdrhistogram.New(0, int64(10*time.Second), 1)
+		for _, v := range values {
+			h.RecordValue(int64(v))
+		}
+		return h
+	}
+	rs := dnsbench.ResultStats{
+		Codes: map[int]int64{
+			dns.RcodeSuccess:       3,
+			dns.RcodeServerFailure: 1,
+		},
+		Qtypes: map[string]int64{
+			"A": 5,
+		},
+		Hist: hist(10*time.Millisecond, 20*time.Millisecond, 30*time.Millisecond, 5*time.Second),
+		QtypeHists: map[string]*hdrhistogram.Histogram{
+			"A":    hist(10*time.Millisecond, 20*time.Millisecond, 5*time.Second),
+			"AAAA": hist(30 * time.Millisecond),
+		},
+		RcodeHists: map[string]*hdrhistogram.Histogram{
+			"SERVFAIL":             hist(5 * time.Second),
+			dnsbench.NoDataOutcome: hist(30 * time.Millisecond),
+			"NOERROR":              hist(10*time.Millisecond, 20*time.Millisecond),
+		},
+		ErrorHist: hist(3 * time.Second),
+		Counters: &dnsbench.Counters{
+			Total:    5,
+			Success:  2,
+			Negative: 1,
+			Error:    1,
+			IOError:  1,
+		},
+	}
+	return b, rs
+}
+
+func testReportDataWithRateAdjustments(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.ResultStats) {
+	b, rs := testReportData(testOutputWriter)
+	b.Rate = 100
+	b.TargetLatency = 20 * time.Millisecond
+	b.TargetPercentile = 99
+	b.TargetErrorRate = 0.01
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	rs.RateAdjustments = []dnsbench.RateAdjustment{
+		{Time: start.Add(time.Second), Elapsed: time.Second, PreviousRate: 100, Rate: 110, Latency: 5 * time.Millisecond, QPS: 100},
+		{Time: start.Add(2 * time.Second), Elapsed: 2 * time.Second, PreviousRate: 110, Rate: 77, Latency: 25 * time.Millisecond, ErrorRate: 0.02, QPS: 109.5},
+		{Time: start.Add(3 * time.Second), Elapsed: 3 * time.Second, PreviousRate: 77, Rate: 87, Latency: 8 * time.Millisecond, QPS: 77},
+	}
+	return b, rs
+}
+
+func testReportDataWithWarmup(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.ResultStats) {
+	b, rs := testReportData(testOutputWriter)
+	b.Warmup = "200ms"
+	hist := hdrhistogram.New(0, int64(10*time.Second), 1)
+	hist.RecordValue(int64(50 * time.Millisecond))
+	hist.RecordValue(int64(300 * time.Millisecond))
+	rs.Warmup = &dnsbench.WarmupStats{
+		Counters: dnsbench.Counters{Total: 3, Success: 2, IOError: 1},
+		Hist:     hist,
+		Duration: 200 * time.Millisecond,
 	}
 	return b, rs
 }
 
+func testWorkerStatsData(testOutputWriter io.Writer) (dnsbench.Benchmark, []*dnsbench.ResultStats) {
+	b := dnsbench.Benchmark{
+		HistMax:     10 * time.Second,
+		HistPre:     1,
+		Writer:      testOutputWriter,
+		Concurrency: 4,
+		WorkerStats: true,
+	}
+	worker := func(addr string, total, ioErrors int64, latency time.Duration) *dnsbench.ResultStats {
+		hist := hdrhistogram.New(0, int64(10*time.Second), 1)
+		for range total - ioErrors {
+			hist.RecordValue(int64(latency))
+		}
+		counters := dnsbench.Counters{Total: total, Success: total - ioErrors, IOError: ioErrors}
+		return &dnsbench.ResultStats{
+			Codes:    map[int]int64{dns.RcodeSuccess: total - ioErrors},
+			Qtypes:   map[string]int64{"A": total},
+			Hist:     hist,
+			Counters: &counters,
+			Connections: map[string]*dnsbench.ConnectionStats{
+				addr: {Counters: counters, Hist: hist},
+			},
+		}
+	}
+	return b, []*dnsbench.ResultStats{
+		worker("127.0.0.1:50001", 100, 0, 10*time.Millisecond),
+		worker("127.0.0.1:50002", 98, 0, 11*time.Millisecond),
+		worker("127.0.0.1:50003", 102, 0, 10*time.Millisecond),
+		worker("127.0.0.1:50004", 40, 10, 300*time.Millisecond),
+	}
+}
+
+func testIterationsData(testOutputWriter io.Writer) (dnsbench.Benchmark, []dnsbench.IterationResult) {
+	b := dnsbench.Benchmark{
+		HistMax:     10 * time.Second,
+		HistPre:     1,
+		Writer:      testOutputWriter,
+		Iterations:  3,
+		CVThreshold: 0.1,
+	}
+	iteration := func(total int64, latencies ...time.Duration) dnsbench.IterationResult {
+		hist := hdrhistogram.New(0, int64(10*time.Second), 1)
+		for _, l := range latencies {
+			hist.RecordValue(int64(l))
+		}
+		return dnsbench.IterationResult{
+			Start:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			Duration: time.Second,
+			Stats: []*dnsbench.ResultStats{{
+				Codes:    map[int]int64{dns.RcodeSuccess: total},
+				Qtypes:   map[string]int64{"A": total},
+				Hist:     hist,
+				Counters: &dnsbench.Counters{Total: total, Success: total},
+			}},
+		}
+	}
+	return b, []dnsbench.IterationResult{
+		iteration(100, 10*time.Millisecond, 20*time.Millisecond),
+		iteration(110, 10*time.Millisecond, 30*time.Millisecond),
+		iteration(90, 10*time.Millisecond, 60*time.Millisecond),
+	}
+}
+
+var testCompareOptions = reporter.CompareOptions{Significance: reporter.DefaultSignificance, MinEffect: reporter.DefaultMinEffect}
+
+func testComparisonData(testOutputWriter io.Writer) (dnsbench.Benchmark, dnsbench.Results, dnsbench.Results) {
+	b := dnsbench.Benchmark{
+		HistMax: 10 * time.Second,
+		HistPre: 1,
+		Writer:  testOutputWriter,
+	}
+	var baselineLatencies, candidateLatencies []time.Duration
+	for i := range 40 {
+		baselineLatencies = append(baselineLatencies, time.Duration(10+i%10)*time.Millisecond)
+		candidateLatencies = append(candidateLatencies, time.Duration(14+i%10)*time.Millisecond)
+	}
+	return b, testComparisonResults(&b, 0, map[int]int64{dns.RcodeSuccess: 38, dns.RcodeServerFailure: 2}, baselineLatencies...),
+		testComparisonResults(&b, 10, map[int]int64{dns.RcodeSuccess: 25, dns.RcodeServerFailure: 15}, candidateLatencies...)
+}
+
+// testComparisonResults returns results of the benchmark run with the latency of each response, the response codes other than NOERROR
+// and NXDOMAIN are counted as error responses.
+func testComparisonResults(b *dnsbench.Benchmark, ioErrors int64, codes map[int]int64, latencies ...time.Duration) dnsbench.Results {
+	hist := hdrhistogram.New(0, int64(10*time.Second), b.HistPre)
+	timings := make([]dnsbench.Datapoint, 0, len(latencies))
+	for _, l := range latencies {
+		hist.RecordValue(int64(l))
+		timings = append(timings, dnsbench.Datapoint{Duration: l, Start: time.Unix(0, 0)})
+	}
+	responses := int64(len(latencies))
+	errorResponses := responses - codes[dns.RcodeSuccess] - codes[dns.RcodeNameError]
+	return dnsbench.Results{
+		Benchmark: b,
+		Duration:  time.Second,
+		Stats: []*dnsbench.ResultStats{{
+			Codes:    codes,
+			Qtypes:   map[string]int64{"A": responses + ioErrors},
+			Hist:     hist,
+			Timings:  timings,
+			Counters: &dnsbench.Counters{Total: responses + ioErrors, Success: responses - errorResponses, Error: errorResponses, IOError: ioErrors},
+		}},
+	}
+}
+
 func readResource(resource string) string {
 	open, err := os.Open("testdata/" + resource)
 	if err != nil {
@@ -0,0 +1,29 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+)
+
+// formatBytes formats number of bytes using decimal (SI) units.
+func formatBytes(b float64) string {
+	const unit = 1000
+	if b < unit {
+		return fmt.Sprintf("%.0f B", b)
+	}
+	div, exp := float64(unit), 0
+	for n := b / unit; n >= unit && exp < 3; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", b/div, "kMGT"[exp])
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -19,17 +19,17 @@ import (
 type standardReporter struct{}
 
 func (s *standardReporter) print(params reportParameters) error {
-	printProgress(params.outputWriter, params.totalCounters)
+	printProgress(params.outputWriter, params.colors, params.totalCounters)
 
 	if len(params.codeTotals) > 0 {
-		printutils.NeutralFprintf(params.outputWriter, "\nDNS response codes:\n")
+		params.colors.NeutralFprintf(params.outputWriter, "\nDNS response codes:\n")
 		for i := dns.RcodeSuccess; i <= dns.RcodeBadCookie; i++ {
-			printFn := printutils.ErrFprintf
+			printFn := params.colors.ErrFprintf
 			if i == dns.RcodeSuccess {
-				printFn = printutils.SuccessFprintf
+				printFn = params.colors.SuccessFprintf
 			}
 			if i == dns.RcodeNameError {
-				printFn = printutils.NeutralFprintf
+				printFn = params.colors.NeutralFprintf
 			}
 			if c, ok := params.codeTotals[i]; ok {
 				printFn(params.outputWriter, "\t%s:\t%d\n", dns.RcodeToString[i], c)
@@ -44,94 +44,129 @@ func (s *standardReporter) print(params reportParameters) error {
 	sort.Ints(dohResponseStatuses)
 
 	if len(params.dohResponseStatusesTotals) > 0 {
-		printutils.NeutralFprintf(params.outputWriter, "\nDoH HTTP response status codes:\n")
+		params.colors.NeutralFprintf(params.outputWriter, "\nDoH HTTP response status codes:\n")
 		for _, st := range dohResponseStatuses {
 			if st == 200 {
-				printutils.SuccessFprintf(params.outputWriter, "\t%d:\t%d\n", st, params.dohResponseStatusesTotals[st])
+				params.colors.SuccessFprintf(params.outputWriter, "\t%d:\t%d\n", st, params.dohResponseStatusesTotals[st])
 			} else {
-				printutils.ErrFprintf(params.outputWriter, "\t%d:\t%d\n", st, params.dohResponseStatusesTotals[st])
+				params.colors.ErrFprintf(params.outputWriter, "\t%d:\t%d\n", st, params.dohResponseStatusesTotals[st])
 			}
 		}
 	}
 
 	if len(params.edeCodes) > 0 {
-		printutils.NeutralFprintf(params.outputWriter, "\nExtended DNS Errors:\n")
+		params.colors.NeutralFprintf(params.outputWriter, "\nExtended DNS Errors:\n")
 		edeCodesSorted := make([]uint16, 0, len(params.edeCodes))
 		for code := range params.edeCodes {
 			edeCodesSorted = append(edeCodesSorted, code)
 		}
 		sort.Slice(edeCodesSorted, func(i, j int) bool { return edeCodesSorted[i] < edeCodesSorted[j] })
 		for _, code := range edeCodesSorted {
-			count := params.edeCodes[code]
-			var name string
-			if s, ok := dns.ExtendedErrorCodeToString[code]; ok {
-				name = s
-			} else {
-				name = fmt.Sprintf("Unknown(%d)", code)
-			}
-			printutils.ErrFprintf(params.outputWriter, "\t%s:\t%d\n", name, count)
+			params.colors.ErrFprintf(params.outputWriter, "\t%s:\t%d\n", edeCodeName(code), params.edeCodes[code])
 		}
 	}
 
 	if len(params.qtypeTotals) > 0 {
-		printutils.NeutralFprintf(params.outputWriter, "\nDNS question types:\n")
-		for k, v := range params.qtypeTotals {
-			printutils.SuccessFprintf(params.outputWriter, "\t%s:\t%d\n", k, v)
+		params.colors.NeutralFprintf(params.outputWriter, "\nDNS question types:\n")
+		for _, k := range sortedKeys(params.qtypeTotals) {
+			params.colors.SuccessFprintf(params.outputWriter, "\t%s:\t%d\n", k, params.qtypeTotals[k])
 		}
 	}
 
 	if params.benchmark.DNSSEC {
-		printutils.NeutralFprintf(params.outputWriter,
-			"\nNumber of domains secured using DNSSEC: %s\n", printutils.HighlightSprint(len(params.authenticatedDomains)))
+		params.colors.NeutralFprintf(params.outputWriter,
+			"\nNumber of domains secured using DNSSEC: %s\n", params.colors.HighlightSprint(len(params.authenticatedDomains)))
 	}
 
-	printutils.NeutralFprintf(params.outputWriter, "\nTime taken for tests:\t%s\n",
-		printutils.HighlightSprint(roundDuration(params.benchmarkDuration)))
-	printutils.NeutralFprintf(params.outputWriter, "Questions per second:\t%s\n",
-		printutils.HighlightSprintf("%0.1f", float64(params.totalCounters.Total)/params.benchmarkDuration.Seconds()))
+	if params.warmup != nil {
+		printWarmup(params)
+	}
+
+	params.colors.NeutralFprintf(params.outputWriter, "\nTime taken for tests:\t%s\n",
+		params.colors.HighlightSprint(roundDuration(params.benchmarkDuration)))
+	params.colors.NeutralFprintf(params.outputWriter, "Questions per second:\t%s\n",
+		params.colors.HighlightSprintf("%0.1f", float64(params.totalCounters.Total)/params.benchmarkDuration.Seconds()))
 
 	minHist := time.Duration(params.hist.Min())
 	mean := time.Duration(params.hist.Mean())
 	sd := time.Duration(params.hist.StdDev())
 	maxHist := time.Duration(params.hist.Max())
-	p99 := time.Duration(params.hist.ValueAtQuantile(99))
-	p95 := time.Duration(params.hist.ValueAtQuantile(95))
-	p90 := time.Duration(params.hist.ValueAtQuantile(90))
-	p75 := time.Duration(params.hist.ValueAtQuantile(75))
-	p50 := time.Duration(params.hist.ValueAtQuantile(50))
 
 	if tc := params.hist.TotalCount(); tc > 0 {
-		printutils.NeutralFprintf(params.outputWriter, "DNS timings, %s datapoints\n", printutils.HighlightSprint(tc))
-		printutils.NeutralFprintf(params.outputWriter, "\t min:\t\t%s\n", printutils.HighlightSprint(roundDuration(minHist)))
-		printutils.NeutralFprintf(params.outputWriter, "\t mean:\t\t%s\n", printutils.HighlightSprint(roundDuration(mean)))
-		printutils.NeutralFprintf(params.outputWriter, "\t [+/-sd]:\t%s\n", printutils.HighlightSprint(roundDuration(sd)))
-		printutils.NeutralFprintf(params.outputWriter, "\t max:\t\t%s\n", printutils.HighlightSprint(roundDuration(maxHist)))
-		printutils.NeutralFprintf(params.outputWriter, "\t p99:\t\t%s\n", printutils.HighlightSprint(roundDuration(p99)))
-		printutils.NeutralFprintf(params.outputWriter, "\t p95:\t\t%s\n", printutils.HighlightSprint(roundDuration(p95)))
-		printutils.NeutralFprintf(params.outputWriter, "\t p90:\t\t%s\n", printutils.HighlightSprint(roundDuration(p90)))
-		printutils.NeutralFprintf(params.outputWriter, "\t p75:\t\t%s\n", printutils.HighlightSprint(roundDuration(p75)))
-		printutils.NeutralFprintf(params.outputWriter, "\t p50:\t\t%s\n", printutils.HighlightSprint(roundDuration(p50)))
+		params.colors.NeutralFprintf(params.outputWriter, "DNS timings, %s datapoints\n", params.colors.HighlightSprint(tc))
+		params.colors.NeutralFprintf(params.outputWriter, "\t min:\t\t%s\n", params.colors.HighlightSprint(roundDuration(minHist)))
+		params.colors.NeutralFprintf(params.outputWriter, "\t mean:\t\t%s\n", params.colors.HighlightSprint(roundDuration(mean)))
+		params.colors.NeutralFprintf(params.outputWriter, "\t [+/-sd]:\t%s\n", params.colors.HighlightSprint(roundDuration(sd)))
+		params.colors.NeutralFprintf(params.outputWriter, "\t max:\t\t%s\n", params.colors.HighlightSprint(roundDuration(maxHist)))
+		for _, p := range percentiles(params.benchmark) {
+			params.colors.NeutralFprintf(params.outputWriter, "\t %s:\t\t%s\n", percentileName(p),
+				params.colors.HighlightSprint(roundDuration(time.Duration(params.hist.ValueAtQuantile(p)))))
+		}
 
 		dist := params.hist.Distribution()
 		if params.benchmark.HistDisplay && tc > 1 {
-			printutils.NeutralFprintf(params.outputWriter, "\nDNS distribution, %s datapoints\n", printutils.HighlightSprint(tc))
-			err := printBars(params.outputWriter, dist)
+			params.colors.NeutralFprintf(params.outputWriter, "\nDNS distribution, %s datapoints\n", params.colors.HighlightSprint(tc))
+			err := printBars(params.outputWriter, params.colors, dist)
 			if err != nil {
 				return err
 			}
 		}
 	}
 
+	if err := printLatencyBreakdown(params); err != nil {
+		return err
+	}
+
+	if params.sizes != nil {
+		printSizes(params)
+	}
+
+	if params.domains != nil {
+		if err := printDomains(params); err != nil {
+			return err
+		}
+	}
+
+	if params.ttls != nil {
+		printTTLAnalysis(params)
+	}
+
+	if params.consistency != nil {
+		if err := printConsistency(params); err != nil {
+			return err
+		}
+	}
+
+	if params.fairness != nil {
+		if err := printFairness(params); err != nil {
+			return err
+		}
+	}
+
+	if params.benchmark.TargetLatency > 0 {
+		printAdaptiveRate(params)
+	}
+
+	if params.iterations != nil {
+		if err := printIterations(params); err != nil {
+			return err
+		}
+	}
+
+	if len(params.errorClasses) > 0 {
+		printErrorClasses(params)
+	}
+
 	sumerrs := 0
 	for _, v := range params.topErrs.m {
 		sumerrs += v
 	}
 
 	if len(params.topErrs.m) > 0 {
-		printutils.ErrFprintf(params.outputWriter, "\nTotal Errors: %d\n", sumerrs)
-		printutils.ErrFprintf(params.outputWriter, "Top errors:\n")
+		params.colors.ErrFprintf(params.outputWriter, "\nTotal Errors: %d\n", sumerrs)
+		params.colors.ErrFprintf(params.outputWriter, "Top errors:\n")
 		for _, err := range params.topErrs.order {
-			printutils.ErrFprintf(params.outputWriter, "%s\t%d (%.2f)%%\n", err, params.topErrs.m[err],
+			params.colors.ErrFprintf(params.outputWriter, "%s\t%d (%.2f)%%\n", err, params.topErrs.m[err],
 				(float64(params.topErrs.m[err])/float64(sumerrs))*100)
 		}
 	}
@@ -139,33 +174,415 @@ func (s *standardReporter) print(params reportParameters) error {
 	return nil
 }
 
-func printProgress(w io.Writer, c dnsbench.Counters) {
-	printutils.NeutralFprintf(w, "\nTotal requests:\t\t%s\n", printutils.HighlightSprint(c.Total))
+func printErrorClasses(params reportParameters) {
+	w := params.outputWriter
+	var total int64
+	for _, v := range params.errorClasses {
+		total += v
+	}
+
+	params.colors.ErrFprintf(w, "\nIO errors by class:\n")
+	for _, c := range sortedErrorClasses(params.errorClasses) {
+		params.colors.ErrFprintf(w, "\t%s:\t%d (%.2f%%)\n", c, params.errorClasses[c], float64(params.errorClasses[c])/float64(total)*100)
+	}
+}
+
+func printWarmup(params reportParameters) {
+	w := params.outputWriter
+	warmup := params.warmup
+
+	params.colors.NeutralFprintf(w, "\nWarm-up, excluded from results:\n")
+	params.colors.NeutralFprintf(w, "\tTime taken:\t\t%s\n", params.colors.HighlightSprint(roundDuration(warmup.Duration)))
+	params.colors.NeutralFprintf(w, "\tTotal requests:\t\t%s\n", params.colors.HighlightSprint(warmup.Counters.Total))
+	if warmup.Counters.IOError > 0 {
+		params.colors.ErrFprintf(w, "\tRead/Write errors:\t%d\n", warmup.Counters.IOError)
+	}
+	if warmup.Counters.Error > 0 {
+		params.colors.ErrFprintf(w, "\tDNS error responses:\t%d\n", warmup.Counters.Error)
+	}
+	if warmup.Hist.TotalCount() > 0 {
+		params.colors.NeutralFprintf(w, "\tp50:\t\t\t%s\n", params.colors.HighlightSprint(roundDuration(time.Duration(warmup.Hist.ValueAtQuantile(50)))))
+		params.colors.NeutralFprintf(w, "\tp99:\t\t\t%s\n", params.colors.HighlightSprint(roundDuration(time.Duration(warmup.Hist.ValueAtQuantile(99)))))
+	}
+}
+
+func printIterations(params reportParameters) error {
+	w := params.outputWriter
+	summary := params.iterations
+
+	params.colors.NeutralFprintf(w, "\nIterations, %s runs:\n", params.colors.HighlightSprint(len(summary.runs)))
+	header := []string{"Iteration", "Requests", "QPS"}
+	for _, p := range summary.percentiles {
+		header = append(header, percentileName(p))
+	}
+	lines := make([][]string, 0, len(summary.runs))
+	for i, r := range summary.runs {
+		line := []string{strconv.Itoa(i + 1), strconv.FormatInt(r.total, 10), fmt.Sprintf("%.1f", r.qps)}
+		for _, l := range r.latencies {
+			line = append(line, roundDuration(l).String())
+		}
+		lines = append(lines, line)
+	}
+	if err := printTable(w, header, lines); err != nil {
+		return err
+	}
+
+	params.colors.NeutralFprintf(w, "\nVariance across iterations:\n")
+	latency := func(v float64) string {
+		return roundDuration(time.Duration(v)).String()
+	}
+	type metric struct {
+		name    string
+		summary metricSummary
+		format  func(float64) string
+	}
+	metrics := []metric{
+		{name: "QPS", summary: summary.qps, format: func(v float64) string { return fmt.Sprintf("%.1f", v) }},
+	}
+	for i, p := range summary.percentiles {
+		metrics = append(metrics, metric{name: percentileName(p), summary: summary.latencies[i], format: latency})
+	}
+	lines = make([][]string, 0, len(metrics))
+	var unstable []string
+	for _, m := range metrics {
+		lines = append(lines, []string{m.name, m.format(m.summary.mean), m.format(m.summary.stdDev),
+			m.format(m.summary.ciLow) + " - " + m.format(m.summary.ciHigh), fmt.Sprintf("%.2f%%", m.summary.cv*100)})
+		if m.summary.unstable {
+			unstable = append(unstable, m.name)
+		}
+	}
+	if err := printTable(w, []string{"Metric", "mean", "std. dev.", "95% CI", "CV"}, lines); err != nil {
+		return err
+	}
+
+	if len(unstable) > 0 {
+		params.colors.ErrFprintf(w, "Unstable results, coefficient of variation exceeds %.2f%% for: %s\n",
+			summary.cvThreshold*100, strings.Join(unstable, ", "))
+	}
+	return nil
+}
+
+func printLatencyBreakdown(params reportParameters) error {
+	w := params.outputWriter
+	if len(params.qtypeHists) > 0 {
+		params.colors.NeutralFprintf(w, "\nDNS timings per question type:\n")
+		if err := printLatencyTable(w, "Question type", qtypeBreakdown(params.qtypeHists), percentiles(params.benchmark)); err != nil {
+			return err
+		}
+	}
+	if rcodes := rcodeBreakdown(params.rcodeHists, params.errorHist); len(rcodes) > 0 {
+		params.colors.NeutralFprintf(w, "\nDNS timings per response code:\n")
+		if err := printLatencyTable(w, "Response code", rcodes, percentiles(params.benchmark)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printLatencyTable(w io.Writer, dimension string, breakdown []latencyBreakdown, percentiles []float64) error {
+	header := []string{dimension, "Count", "mean"}
+	for _, p := range percentiles {
+		header = append(header, percentileName(p))
+	}
+	header = append(header, "max")
+
+	lines := make([][]string, 0, len(breakdown))
+	for _, b := range breakdown {
+		line := []string{
+			b.key,
+			strconv.FormatInt(b.hist.TotalCount(), 10),
+			roundDuration(time.Duration(b.hist.Mean())).String(),
+		}
+		for _, p := range percentiles {
+			line = append(line, roundDuration(time.Duration(b.hist.ValueAtQuantile(p))).String())
+		}
+		lines = append(lines, append(line, roundDuration(time.Duration(b.hist.Max())).String()))
+	}
+	return printTable(w, header, lines)
+}
+
+func printSizes(params reportParameters) {
+	w := params.outputWriter
+	sizes := params.sizes
+
+	if tc := sizes.RequestHist.TotalCount(); tc > 0 {
+		params.colors.NeutralFprintf(w, "\nRequest sizes, %s datapoints\n", params.colors.HighlightSprint(tc))
+		params.colors.NeutralFprintf(w, "\t min:\t\t%s\n", params.colors.HighlightSprint(formatBytes(float64(sizes.RequestHist.Min()))))
+		params.colors.NeutralFprintf(w, "\t mean:\t\t%s\n", params.colors.HighlightSprint(formatBytes(sizes.RequestHist.Mean())))
+		params.colors.NeutralFprintf(w, "\t max:\t\t%s\n", params.colors.HighlightSprint(formatBytes(float64(sizes.RequestHist.Max()))))
+	}
+	if tc := sizes.ResponseHist.TotalCount(); tc > 0 {
+		params.colors.NeutralFprintf(w, "Response sizes, %s datapoints\n", params.colors.HighlightSprint(tc))
+		params.colors.NeutralFprintf(w, "\t min:\t\t%s\n", params.colors.HighlightSprint(formatBytes(float64(sizes.ResponseHist.Min()))))
+		params.colors.NeutralFprintf(w, "\t mean:\t\t%s\n", params.colors.HighlightSprint(formatBytes(sizes.ResponseHist.Mean())))
+		params.colors.NeutralFprintf(w, "\t max:\t\t%s\n", params.colors.HighlightSprint(formatBytes(float64(sizes.ResponseHist.Max()))))
+		params.colors.NeutralFprintf(w, "\t p99:\t\t%s\n", params.colors.HighlightSprint(formatBytes(float64(sizes.ResponseHist.ValueAtQuantile(99)))))
+		params.colors.NeutralFprintf(w, "\t p95:\t\t%s\n", params.colors.HighlightSprint(formatBytes(float64(sizes.ResponseHist.ValueAtQuantile(95)))))
+		params.colors.NeutralFprintf(w, "\t p50:\t\t%s\n", params.colors.HighlightSprint(formatBytes(float64(sizes.ResponseHist.ValueAtQuantile(50)))))
+	}
+
+	params.colors.NeutralFprintf(w, "Bytes sent:\t\t%s (%s/s)\n", params.colors.HighlightSprint(formatBytes(float64(sizes.BytesSent))),
+		formatBytes(float64(sizes.BytesSent)/params.benchmarkDuration.Seconds()))
+	params.colors.NeutralFprintf(w, "Bytes received:\t\t%s (%s/s)\n", params.colors.HighlightSprint(formatBytes(float64(sizes.BytesReceived))),
+		formatBytes(float64(sizes.BytesReceived)/params.benchmarkDuration.Seconds()))
+
+	if len(sizes.Qtypes) > 0 {
+		params.colors.NeutralFprintf(w, "Amplification (response/request size):\n")
+		for _, k := range sortedKeys(sizes.Qtypes) {
+			params.colors.NeutralFprintf(w, "\t%s:\t%s\n", k, params.colors.HighlightSprintf("%.2fx", sizes.Qtypes[k].Amplification()))
+		}
+	}
+
+	if len(sizes.Largest) > 0 {
+		params.colors.NeutralFprintf(w, "Largest responses:\n")
+		for _, l := range sizes.Largest {
+			params.colors.NeutralFprintf(w, "\t%s %s\t%s\n", l.Name, l.Qtype, params.colors.HighlightSprint(formatBytes(float64(l.Size))))
+		}
+	}
+
+	if sizes.NearBufferSize > 0 {
+		params.colors.ErrFprintf(w, "Responses close to EDNS0 buffer size:\t%d\n", sizes.NearBufferSize)
+	}
+}
+
+func printDomains(params reportParameters) error {
+	w := params.outputWriter
+	summaries := summarizeDomains(params.domains)
+
+	if slowest := slowestDomains(summaries, params.benchmark.DomainStatsTop); len(slowest) > 0 {
+		params.colors.NeutralFprintf(w, "\nSlowest domains by p95 latency:\n")
+		lines := make([][]string, 0, len(slowest))
+		for _, d := range slowest {
+			lines = append(lines, []string{
+				d.name,
+				strconv.FormatInt(d.Count, 10),
+				roundDuration(time.Duration(d.Hist.ValueAtQuantile(50))).String(),
+				roundDuration(time.Duration(d.Hist.ValueAtQuantile(95))).String(),
+				roundDuration(time.Duration(d.Hist.ValueAtQuantile(99))).String(),
+				roundDuration(time.Duration(d.Hist.Max())).String(),
+			})
+		}
+		if err := printTable(w, []string{"Domain", "Requests", "p50", "p95", "p99", "max"}, lines); err != nil {
+			return err
+		}
+	}
+
+	if failing := failingDomains(summaries, params.benchmark.DomainStatsTop); len(failing) > 0 {
+		params.colors.ErrFprintf(w, "\nDomains with most errors:\n")
+		lines := make([][]string, 0, len(failing))
+		for _, d := range failing {
+			lines = append(lines, []string{
+				d.name,
+				strconv.FormatInt(d.Count, 10),
+				strconv.FormatInt(d.Errors(), 10),
+				strconv.FormatInt(d.IOError, 10),
+				formatCodes(d.Codes),
+			})
+		}
+		if err := printTable(w, []string{"Domain", "Requests", "Errors", "IO errors", "Response codes"}, lines); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printFairness(params reportParameters) error {
+	w := params.outputWriter
+	fairness := params.fairness
+	header := []string{"", "Requests", "Errors", "p50", "p95", "p99", "Outlier"}
+
+	params.colors.NeutralFprintf(w, "\nPer-worker statistics:\n")
+	header[0] = "Worker"
+	if err := printTable(w, header, fairnessLines(fairness.workers)); err != nil {
+		return err
+	}
+	if n := fairness.outlierWorkers(); n > 0 {
+		params.colors.ErrFprintf(w, "Outlier workers:\t%d\n", n)
+	}
+
+	if len(fairness.connections) == 0 {
+		return nil
+	}
+	connections := fairness.connections
+	if len(connections) > maxReportedConnections {
+		connections = fairness.outlierConnections()
+		params.colors.NeutralFprintf(w, "\nPer-connection statistics, %s connections, only outliers are shown:\n",
+			params.colors.HighlightSprint(len(fairness.connections)))
+	} else {
+		params.colors.NeutralFprintf(w, "\nPer-connection statistics, %s connections:\n", params.colors.HighlightSprint(len(fairness.connections)))
+	}
+	if len(connections) > 0 {
+		header[0] = "Connection"
+		if err := printTable(w, header, fairnessLines(connections)); err != nil {
+			return err
+		}
+	}
+	if n := len(fairness.outlierConnections()); n > 0 {
+		params.colors.ErrFprintf(w, "Outlier connections:\t%d\n", n)
+	}
+	return nil
+}
+
+func fairnessLines(entries []fairnessEntry) [][]string {
+	lines := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		lines = append(lines, []string{
+			e.name,
+			strconv.FormatInt(e.counters.Total, 10),
+			strconv.FormatInt(e.errors(), 10),
+			roundDuration(time.Duration(e.hist.ValueAtQuantile(50))).String(),
+			roundDuration(time.Duration(e.hist.ValueAtQuantile(95))).String(),
+			roundDuration(time.Duration(e.hist.ValueAtQuantile(99))).String(),
+			strings.Join(e.outliers, ", "),
+		})
+	}
+	return lines
+}
+
+func printTTLAnalysis(params reportParameters) {
+	w := params.outputWriter
+	summary := summarizeTTLs(params.ttls)
+
+	params.colors.NeutralFprintf(w, "\nTTL analysis, %s answers for %s questions\n",
+		params.colors.HighlightSprint(summary.answers), params.colors.HighlightSprint(summary.questions))
+	if params.ttlHist.TotalCount() > 0 {
+		params.colors.NeutralFprintf(w, "\t min:\t\t%s\n", params.colors.HighlightSprint(ttlDuration(params.ttlHist.Min())))
+		params.colors.NeutralFprintf(w, "\t p50:\t\t%s\n", params.colors.HighlightSprint(ttlDuration(params.ttlHist.ValueAtQuantile(50))))
+		params.colors.NeutralFprintf(w, "\t p90:\t\t%s\n", params.colors.HighlightSprint(ttlDuration(params.ttlHist.ValueAtQuantile(90))))
+		params.colors.NeutralFprintf(w, "\t max:\t\t%s\n", params.colors.HighlightSprint(ttlDuration(params.ttlHist.Max())))
+	}
+	params.colors.SuccessFprintf(w, "Cache hits:\t\t%d\n", summary.hits)
+	params.colors.NeutralFprintf(w, "Cache misses:\t\t%d\n", summary.misses)
+	params.colors.NeutralFprintf(w, "Prefetched answers:\t%d\n", summary.prefetches)
+	if summary.stale > 0 {
+		params.colors.ErrFprintf(w, "Stale answers:\t\t%d\n", summary.stale)
+	}
+	if summary.notCached > 0 {
+		params.colors.ErrFprintf(w, "Not cached answers:\t%d\n", summary.notCached)
+	}
+
+	if clusters, ok := findLatencyClusters(params.hist); ok {
+		params.colors.NeutralFprintf(w, "Latency clusters (likely cache hits and misses):\n")
+		params.colors.NeutralFprintf(w, "\t fast (< %s):\t%s\n", roundDuration(clusters.threshold), params.colors.HighlightSprint(clusters.fast))
+		params.colors.NeutralFprintf(w, "\t slow (>= %s):\t%s\n", roundDuration(clusters.threshold), params.colors.HighlightSprint(clusters.slow))
+	}
+
+	if len(summary.clamped) > 0 {
+		params.colors.ErrFprintf(w, "TTLs differing from authoritative TTL:\n")
+		for _, c := range summary.clamped {
+			params.colors.ErrFprintf(w, "\t%s %s\tauthoritative: %s, seen: %s - %s\n", c.key.Name, c.key.Qtype,
+				ttlDuration(int64(c.authoritativeTTL)), ttlDuration(int64(c.minTTL)), ttlDuration(int64(c.maxTTL)))
+		}
+	}
+}
+
+func printAdaptiveRate(params reportParameters) {
+	w := params.outputWriter
+	b := params.benchmark
+	summary := summarizeRateAdjustments(b.Rate, params.rateAdjustments)
+
+	params.colors.NeutralFprintf(w, "\nAdaptive rate, target p%g <= %s and error rate <= %.2f%%:\n",
+		b.TargetPercentile, roundDuration(b.TargetLatency), b.TargetErrorRate*100)
+	params.colors.NeutralFprintf(w, "\tStart rate:\t%s QPS\n", params.colors.HighlightSprint(summary.startRate))
+	params.colors.NeutralFprintf(w, "\tFinal rate:\t%s QPS\n", params.colors.HighlightSprint(summary.finalRate))
+	params.colors.NeutralFprintf(w, "\tMin rate:\t%s QPS\n", params.colors.HighlightSprint(summary.minRate))
+	params.colors.NeutralFprintf(w, "\tMax rate:\t%s QPS\n", params.colors.HighlightSprint(summary.maxRate))
+	params.colors.NeutralFprintf(w, "\tIncreases:\t%s\n", params.colors.HighlightSprint(summary.increases))
+	params.colors.NeutralFprintf(w, "\tDecreases:\t%s\n", params.colors.HighlightSprint(summary.decreases))
+}
+
+func ttlDuration(ttl int64) time.Duration {
+	return time.Duration(ttl) * time.Second
+}
+
+// maxPrintedDifferences limits number of answer differences printed to the standard output.
+const maxPrintedDifferences = 20
+
+func printConsistency(params reportParameters) error {
+	w := params.outputWriter
+	c := params.consistency
+
+	params.colors.NeutralFprintf(w, "\nAnswer consistency:\n")
+	params.colors.NeutralFprintf(w, "\tCompared answers:\t%s\n", params.colors.HighlightSprint(c.Compared))
+	if c.Mismatched > 0 {
+		params.colors.ErrFprintf(w, "\tMismatched answers:\t%d\n", c.Mismatched)
+	} else {
+		params.colors.SuccessFprintf(w, "\tMismatched answers:\t%d\n", c.Mismatched)
+	}
+	if c.Skipped > 0 {
+		params.colors.NeutralFprintf(w, "\tSkipped answers:\t%d\n", c.Skipped)
+	}
+
+	params.colors.NeutralFprintf(w, "\nDNS timings per server:\n")
+	lines := make([][]string, 0, len(c.Servers)+1)
+	for _, s := range comparedServers(params) {
+		lines = append(lines, []string{
+			s.server,
+			strconv.FormatInt(s.counters.Total, 10),
+			strconv.FormatInt(s.counters.IOError, 10),
+			roundDuration(time.Duration(s.hist.ValueAtQuantile(50))).String(),
+			roundDuration(time.Duration(s.hist.ValueAtQuantile(95))).String(),
+			roundDuration(time.Duration(s.hist.ValueAtQuantile(99))).String(),
+		})
+	}
+	if err := printTable(w, []string{"Server", "Requests", "IO errors", "p50", "p95", "p99"}, lines); err != nil {
+		return err
+	}
+
+	if len(c.Differences) == 0 {
+		return nil
+	}
+
+	diffs := sortedDifferences(c.Differences)
+	params.colors.ErrFprintf(w, "\nAnswer differences:\n")
+	for i, d := range diffs {
+		if i == maxPrintedDifferences {
+			params.colors.ErrFprintf(w, "... and %d more differences, use --json to list all of them\n", len(diffs)-maxPrintedDifferences)
+			break
+		}
+		params.colors.ErrFprintf(w, "%s %s %s %s\t%d\n", d.key.Server, d.key.Domain, d.key.Qtype, d.key.Kind, d.Count)
+		params.colors.NeutralFprintf(w, "\texpected: %s\n", d.Expected)
+		params.colors.NeutralFprintf(w, "\tactual:   %s\n", d.Actual)
+	}
+	return nil
+}
+
+func printTable(w io.Writer, header []string, lines [][]string) error {
+	table := tablewriter.NewTable(w, tablewriter.WithRendition(tw.Rendition{Borders: tw.BorderNone}), tablewriter.WithHeaderAutoFormat(tw.Off))
+	table.Header(header)
+	if err := table.Bulk(lines); err != nil {
+		return err
+	}
+	return table.Render()
+}
+
+func printProgress(w io.Writer, p *printutils.Printer, c dnsbench.Counters) {
+	p.NeutralFprintf(w, "\nTotal requests:\t\t%s\n", p.HighlightSprint(c.Total))
 
 	if c.IOError > 0 {
-		printutils.ErrFprintf(w, "Read/Write errors:\t%d\n", c.IOError)
+		p.ErrFprintf(w, "Read/Write errors:\t%d\n", c.IOError)
 	}
 
 	if c.IDmismatch > 0 {
-		printutils.ErrFprintf(w, "ID mismatch errors:\t%d\n", c.IDmismatch)
+		p.ErrFprintf(w, "ID mismatch errors:\t%d\n", c.IDmismatch)
 	}
 
 	if c.Success > 0 {
-		printutils.SuccessFprintf(w, "DNS success responses:\t%d\n", c.Success)
+		p.SuccessFprintf(w, "DNS success responses:\t%d\n", c.Success)
 	}
 	if c.Negative > 0 {
-		printutils.NeutralFprintf(w, "DNS negative responses:\t%d\n", c.Negative)
+		p.NeutralFprintf(w, "DNS negative responses:\t%d\n", c.Negative)
 	}
 	if c.Error > 0 {
-		printutils.ErrFprintf(w, "DNS error responses:\t%d\n", c.Error)
+		p.ErrFprintf(w, "DNS error responses:\t%d\n", c.Error)
 	}
 
 	if c.Truncated > 0 {
-		printutils.ErrFprintf(w, "Truncated responses:\t%d\n", c.Truncated)
+		p.ErrFprintf(w, "Truncated responses:\t%d\n", c.Truncated)
 	}
 }
 
-func printBars(w io.Writer, bars []hdrhistogram.Bar) error {
+func printBars(w io.Writer, p *printutils.Printer, bars []hdrhistogram.Bar) error {
 	counts := make([]int64, 0, len(bars))
 	lines := make([][]string, 0, len(bars))
 	added := false
@@ -191,7 +608,7 @@ func printBars(w io.Writer, bars []hdrhistogram.Bar) error {
 	}
 
 	for i, l := range lines {
-		l[1] = makeBar(counts[i], maxCount)
+		l[1] = makeBar(p, counts[i], maxCount)
 	}
 
 	table := tablewriter.NewTable(w, tablewriter.WithRendition(tw.Rendition{Borders: tw.BorderNone}))
@@ -207,10 +624,10 @@ func printBars(w io.Writer, bars []hdrhistogram.Bar) error {
 	return nil
 }
 
-func makeBar(c int64, m int64) string {
+func makeBar(p *printutils.Printer, c int64, m int64) string {
 	if c == 0 {
 		return ""
 	}
 	t := int((43 * float64(c) / float64(m)) + 0.5)
-	return strings.Repeat(printutils.HighlightSprint("▄"), t)
+	return strings.Repeat(p.HighlightSprint("▄"), t)
 }
@@ -0,0 +1,116 @@
+package reporter
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+// minClusterSeparation is the minimal ratio between mean latencies of the slow and fast latency cluster,
+// for the latency distribution to be considered bimodal.
+const minClusterSeparation = 2
+
+type ttlSummary struct {
+	questions  int
+	answers    int64
+	hits       int64
+	misses     int64
+	prefetches int64
+	stale      int64
+	notCached  int64
+	clamped    []clampedTTL
+}
+
+type clampedTTL struct {
+	key              dnsbench.QuestionKey
+	authoritativeTTL uint32
+	minTTL           uint32
+	maxTTL           uint32
+}
+
+type latencyClusters struct {
+	threshold time.Duration
+	fast      int64
+	slow      int64
+}
+
+func summarizeTTLs(ttls map[dnsbench.QuestionKey]*dnsbench.TTLStats) ttlSummary {
+	summary := ttlSummary{questions: len(ttls)}
+	for k, v := range ttls {
+		summary.answers += v.Count
+		summary.hits += v.Hits
+		summary.misses += v.Misses
+		summary.prefetches += v.Prefetches
+		summary.stale += v.Stale
+		summary.notCached += v.NotCached
+		// TTL is considered clamped, when the server never returned the authoritative TTL or returned TTL higher than authoritative
+		if v.Authoritative && v.Count > 0 && (v.Max < v.AuthoritativeTTL || v.Min > v.AuthoritativeTTL) {
+			summary.clamped = append(summary.clamped, clampedTTL{key: k, authoritativeTTL: v.AuthoritativeTTL, minTTL: v.Min, maxTTL: v.Max})
+		}
+	}
+	sort.Slice(summary.clamped, func(i, j int) bool {
+		if summary.clamped[i].key.Name != summary.clamped[j].key.Name {
+			return summary.clamped[i].key.Name < summary.clamped[j].key.Name
+		}
+		return summary.clamped[i].key.Qtype < summary.clamped[j].key.Qtype
+	})
+	return summary
+}
+
+// findLatencyClusters splits latency distribution into fast and slow cluster using Otsu's method on logarithmic scale.
+// Bimodal latency distribution usually means that fast responses are served from cache and slow responses are cache misses.
+// Returns false, if the distribution is not bimodal.
+func findLatencyClusters(hist *hdrhistogram.Histogram) (latencyClusters, bool) {
+	var bars []hdrhistogram.Bar
+	for _, b := range hist.Distribution() {
+		if b.Count > 0 {
+			bars = append(bars, b)
+		}
+	}
+	if len(bars) < 2 {
+		return latencyClusters{}, false
+	}
+
+	values := make([]float64, len(bars))
+	var total int64
+	var sum float64
+	for i, b := range bars {
+		values[i] = math.Log(math.Max(float64(b.From+b.To)/2, 1))
+		total += b.Count
+		sum += float64(b.Count) * values[i]
+	}
+
+	bestIdx := -1
+	var bestVariance, bestFastMean, bestSlowMean float64
+	var fastCount int64
+	var fastSum float64
+	for i := 0; i < len(bars)-1; i++ {
+		fastCount += bars[i].Count
+		fastSum += float64(bars[i].Count) * values[i]
+		slowCount := total - fastCount
+
+		fastMean := fastSum / float64(fastCount)
+		slowMean := (sum - fastSum) / float64(slowCount)
+		variance := float64(fastCount) * float64(slowCount) * (fastMean - slowMean) * (fastMean - slowMean)
+		if variance > bestVariance {
+			bestIdx, bestVariance, bestFastMean, bestSlowMean = i, variance, fastMean, slowMean
+		}
+	}
+
+	if bestIdx < 0 || bestSlowMean-bestFastMean < math.Log(minClusterSeparation) {
+		return latencyClusters{}, false
+	}
+
+	clusters := latencyClusters{threshold: time.Duration(bars[bestIdx+1].From)}
+	for i, b := range bars {
+		if i <= bestIdx {
+			clusters.fast += b.Count
+		} else {
+			clusters.slow += b.Count
+		}
+	}
+	return clusters, true
+}
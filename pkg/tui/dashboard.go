@@ -0,0 +1,375 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+const (
+	// historySize is the number of the last intervals used for sparklines and rolling percentiles.
+	historySize = 60
+
+	// maxTopErrors limits number of the most frequent errors shown.
+	maxTopErrors = 5
+
+	// maxWorkerRows limits number of workers shown in the worker status table.
+	maxWorkerRows = 8
+
+	// rateStep is the relative change of the rate limit, when the rate limit is adjusted using keyboard.
+	rateStep = 0.1
+
+	ctrlC = 0x03
+)
+
+var (
+	sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+	// rollingWindows are the numbers of the last intervals used for computing rolling percentiles.
+	rollingWindows = []int{1, 10, historySize}
+)
+
+// Dashboard aggregates live results of the benchmark provided by dnsbench.Controller and renders them.
+// Dashboard is subscribed to the results using dnsbench.Controller.OnInterval with Dashboard.Update.
+type Dashboard struct {
+	controller *dnsbench.Controller
+	title      string
+
+	mu       sync.Mutex
+	history  []dnsbench.IntervalStats
+	elapsed  time.Duration
+	totals   dnsbench.Counters
+	hist     *hdrhistogram.Histogram
+	codes    map[int]int64
+	ede      map[uint16]int64
+	errors   map[string]int64
+	stopping bool
+	message  string
+}
+
+// New creates new Dashboard controlling the benchmark using the controller, title is shown in the header of the dashboard.
+func New(controller *dnsbench.Controller, title string) *Dashboard {
+	return &Dashboard{
+		controller: controller,
+		title:      title,
+		codes:      make(map[int]int64),
+		ede:        make(map[uint16]int64),
+		errors:     make(map[string]int64),
+	}
+}
+
+// Update adds results of the finished interval to the dashboard.
+func (d *Dashboard) Update(is dnsbench.IntervalStats) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.history = append(d.history, is)
+	if len(d.history) > historySize {
+		d.history = d.history[len(d.history)-historySize:]
+	}
+	d.elapsed = is.Elapsed
+
+	d.totals.Total += is.Counters.Total
+	d.totals.IOError += is.Counters.IOError
+	d.totals.Success += is.Counters.Success
+	d.totals.Negative += is.Counters.Negative
+	d.totals.Error += is.Counters.Error
+	d.totals.IDmismatch += is.Counters.IDmismatch
+	d.totals.Truncated += is.Counters.Truncated
+
+	if d.hist == nil {
+		d.hist = newHistogram(is.Hist)
+	}
+	d.hist.Merge(is.Hist)
+	for k, v := range is.Codes {
+		d.codes[k] += v
+	}
+	for k, v := range is.EDECodes {
+		d.ede[k] += v
+	}
+	for k, v := range is.Errors {
+		d.errors[k] += v
+	}
+}
+
+// HandleKey controls the benchmark based on the pressed key.
+func (d *Dashboard) HandleKey(key byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch key {
+	case 'p', 'P', ' ':
+		if d.controller.Paused() {
+			d.controller.Resume()
+			d.message = "benchmark resumed"
+		} else {
+			d.controller.Pause()
+			d.message = "benchmark paused"
+		}
+	case 'q', 'Q', ctrlC:
+		d.controller.Stop()
+		d.stopping = true
+		d.message = "stopping benchmark"
+	case '+', '=':
+		rate := d.controller.Rate()
+		if rate == 0 {
+			d.message = "rate is not limited"
+			return
+		}
+		d.setRate(adjustRate(rate, 1))
+	case '-', '_':
+		rate := d.controller.Rate()
+		if rate == 0 && len(d.history) > 0 {
+			// start limiting from the currently achieved rate
+			rate = int(math.Round(d.history[len(d.history)-1].QPS()))
+		}
+		if rate == 0 {
+			d.message = "no results to derive the rate limit from yet"
+			return
+		}
+		d.setRate(adjustRate(rate, -1))
+	case 'u', 'U':
+		d.setRate(0)
+	}
+}
+
+func (d *Dashboard) setRate(rate int) {
+	d.controller.SetRate(rate)
+	if rate == 0 {
+		d.message = "rate limit removed"
+		return
+	}
+	d.message = fmt.Sprintf("rate limit set to %d QPS", rate)
+}
+
+// adjustRate increases (direction 1) or decreases (direction -1) the rate by rateStep, the rate is changed at least by 1 QPS
+// and it is never decreased below 1 QPS.
+func adjustRate(rate int, direction int) int {
+	step := max(int(math.Round(float64(rate)*rateStep)), 1)
+	return max(rate+direction*step, 1)
+}
+
+// Render writes the current state of the dashboard.
+func (d *Dashboard) Render(w io.Writer) error {
+	for _, line := range d.lines() {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dashboard) lines() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var sb strings.Builder
+	d.writeHeader(&sb)
+	if len(d.history) == 0 {
+		sb.WriteString("\nWaiting for the first results...\n")
+	} else {
+		d.writeSparklines(&sb)
+		d.writePercentiles(&sb)
+		d.writeCodes(&sb)
+		d.writeErrors(&sb)
+	}
+	d.writeWorkers(&sb)
+	sb.WriteString("\nKeys: [p] pause/resume  [+/-] adjust rate limit  [u] remove rate limit  [q] stop and print report\n")
+	if d.message != "" {
+		sb.WriteString(d.message + "\n")
+	}
+	return strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+}
+
+func (d *Dashboard) writeHeader(sb *strings.Builder) {
+	title := "dnspyre"
+	if d.title != "" {
+		title += " - " + d.title
+	}
+	state := "running"
+	switch {
+	case d.stopping:
+		state = "stopping"
+	case d.controller.Paused():
+		state = "paused"
+	}
+	rate := "unlimited"
+	if r := d.controller.Rate(); r > 0 {
+		rate = fmt.Sprintf("%d QPS", r)
+	}
+	fmt.Fprintf(sb, "%s\n", title)
+	fmt.Fprintf(sb, "Elapsed: %s  State: %s  Rate limit: %s\n", d.elapsed.Round(time.Second), state, rate)
+	fmt.Fprintf(sb, "Total requests: %d  success: %d  negative: %d  error: %d  IO error: %d  ID mismatch: %d  truncated: %d\n",
+		d.totals.Total, d.totals.Success, d.totals.Negative, d.totals.Error, d.totals.IOError, d.totals.IDmismatch, d.totals.Truncated)
+}
+
+func (d *Dashboard) writeSparklines(sb *strings.Builder) {
+	qps := make([]float64, 0, len(d.history))
+	p99 := make([]float64, 0, len(d.history))
+	for i := range d.history {
+		qps = append(qps, d.history[i].QPS())
+		p99 = append(p99, float64(d.history[i].Hist.ValueAtQuantile(99)))
+	}
+	last := d.history[len(d.history)-1]
+	fmt.Fprintf(sb, "\nQPS  %s %.1f\n", sparkline(qps), last.QPS())
+	fmt.Fprintf(sb, "p99  %s %s\n", sparkline(p99), formatLatency(last.Hist, 99))
+}
+
+func (d *Dashboard) writePercentiles(sb *strings.Builder) {
+	sb.WriteString("\n")
+	tw := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Latency\tp50\tp90\tp95\tp99\tmax")
+	for _, n := range rollingWindows {
+		if n > len(d.history) && n > 1 {
+			break
+		}
+		var window time.Duration
+		hist := newHistogram(d.hist)
+		for i := len(d.history) - n; i < len(d.history); i++ {
+			window += d.history[i].Duration
+			hist.Merge(d.history[i].Hist)
+		}
+		writePercentilesRow(tw, "last "+window.Round(time.Second).String(), hist)
+	}
+	writePercentilesRow(tw, "total", d.hist)
+	tw.Flush()
+}
+
+func writePercentilesRow(w io.Writer, name string, hist *hdrhistogram.Histogram) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, formatLatency(hist, 50), formatLatency(hist, 90),
+		formatLatency(hist, 95), formatLatency(hist, 99), formatLatency(hist, 100))
+}
+
+func (d *Dashboard) writeCodes(sb *strings.Builder) {
+	if len(d.codes) > 0 {
+		sb.WriteString("\nResponse codes:\n")
+		var total int64
+		codes := make([]int, 0, len(d.codes))
+		for k, v := range d.codes {
+			codes = append(codes, k)
+			total += v
+		}
+		sort.Ints(codes)
+		tw := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+		for _, code := range codes {
+			name, ok := dns.RcodeToString[code]
+			if !ok {
+				name = fmt.Sprintf("Unknown(%d)", code)
+			}
+			fmt.Fprintf(tw, "  %s\t%d\t(%.1f%%)\n", name, d.codes[code], 100*float64(d.codes[code])/float64(total))
+		}
+		tw.Flush()
+	}
+
+	if len(d.ede) > 0 {
+		sb.WriteString("\nExtended DNS Errors:\n")
+		codes := make([]uint16, 0, len(d.ede))
+		for k := range d.ede {
+			codes = append(codes, k)
+		}
+		sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+		tw := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+		for _, code := range codes {
+			name, ok := dns.ExtendedErrorCodeToString[code]
+			if !ok {
+				name = fmt.Sprintf("Unknown(%d)", code)
+			}
+			fmt.Fprintf(tw, "  %s\t%d\n", name, d.ede[code])
+		}
+		tw.Flush()
+	}
+}
+
+func (d *Dashboard) writeErrors(sb *strings.Builder) {
+	if len(d.errors) == 0 {
+		return
+	}
+	errs := make([]string, 0, len(d.errors))
+	for k := range d.errors {
+		errs = append(errs, k)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		if d.errors[errs[i]] != d.errors[errs[j]] {
+			return d.errors[errs[i]] > d.errors[errs[j]]
+		}
+		return errs[i] < errs[j]
+	})
+	if len(errs) > maxTopErrors {
+		errs = errs[:maxTopErrors]
+	}
+	sb.WriteString("\nTop errors:\n")
+	tw := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+	for _, e := range errs {
+		fmt.Fprintf(tw, "  %d\t%s\n", d.errors[e], e)
+	}
+	tw.Flush()
+}
+
+func (d *Dashboard) writeWorkers(sb *strings.Builder) {
+	workers := d.controller.Workers()
+	if len(workers) == 0 {
+		return
+	}
+	states := make(map[dnsbench.WorkerState]int)
+	for _, w := range workers {
+		states[w.State]++
+	}
+	summary := make([]string, 0, len(states))
+	for s := dnsbench.WorkerStarting; s <= dnsbench.WorkerDone; s++ {
+		if states[s] > 0 {
+			summary = append(summary, fmt.Sprintf("%s: %d", s, states[s]))
+		}
+	}
+	fmt.Fprintf(sb, "\nWorkers: %d (%s)\n", len(workers), strings.Join(summary, ", "))
+
+	tw := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ID\tState\tRequests\tIO errors\tLast latency")
+	for _, w := range workers[:min(len(workers), maxWorkerRows)] {
+		fmt.Fprintf(tw, "  %d\t%s\t%d\t%d\t%s\n", w.ID, w.State, w.Requests, w.IOErrors, w.LastLatency.Round(10*time.Microsecond))
+	}
+	tw.Flush()
+	if len(workers) > maxWorkerRows {
+		fmt.Fprintf(sb, "  ... and %d more workers\n", len(workers)-maxWorkerRows)
+	}
+}
+
+// sparkline renders values as a line of block characters, the values are scaled between 0 and the maximal value.
+func sparkline(values []float64) string {
+	var maxValue float64
+	for _, v := range values {
+		maxValue = max(maxValue, v)
+	}
+	runes := make([]rune, 0, len(values))
+	for _, v := range values {
+		idx := 0
+		if maxValue > 0 {
+			idx = int(math.Round(v / maxValue * float64(len(sparkBlocks)-1)))
+		}
+		runes = append(runes, sparkBlocks[idx])
+	}
+	return string(runes)
+}
+
+func formatLatency(hist *hdrhistogram.Histogram, quantile float64) string {
+	if hist.TotalCount() == 0 {
+		return "-"
+	}
+	if quantile == 100 {
+		return time.Duration(hist.Max()).Round(10 * time.Microsecond).String()
+	}
+	return time.Duration(hist.ValueAtQuantile(quantile)).Round(10 * time.Microsecond).String()
+}
+
+// newHistogram creates empty histogram with the same parameters as the given histogram.
+func newHistogram(h *hdrhistogram.Histogram) *hdrhistogram.Histogram {
+	return hdrhistogram.New(h.LowestTrackableValue(), h.HighestTrackableValue(), int(h.SignificantFigures()))
+}
@@ -0,0 +1,134 @@
+package tui
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tantalor93/dnspyre/v3/pkg/dnsbench"
+)
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   string
+	}{
+		{
+			name: "no values",
+			want: "",
+		},
+		{
+			name:   "zero values",
+			values: []float64{0, 0},
+			want:   "▁▁",
+		},
+		{
+			name:   "scaled values",
+			values: []float64{0, 50, 100},
+			want:   "▁▅█",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sparkline(tt.values))
+		})
+	}
+}
+
+func TestAdjustRate(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      int
+		direction int
+		want      int
+	}{
+		{name: "increase", rate: 100, direction: 1, want: 110},
+		{name: "decrease", rate: 100, direction: -1, want: 90},
+		{name: "increase small rate", rate: 1, direction: 1, want: 2},
+		{name: "decrease to minimal rate", rate: 1, direction: -1, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, adjustRate(tt.rate, tt.direction))
+		})
+	}
+}
+
+func TestDashboard_HandleKey(t *testing.T) {
+	c := dnsbench.NewController()
+	d := New(c, "127.0.0.1:53")
+
+	d.HandleKey('p')
+	assert.True(t, c.Paused())
+	d.HandleKey('p')
+	assert.False(t, c.Paused())
+
+	d.HandleKey('+')
+	assert.Equal(t, 0, c.Rate(), "unlimited rate should not be increased")
+	d.HandleKey('-')
+	assert.Equal(t, 0, c.Rate(), "rate limit cannot be derived without results")
+
+	d.Update(testIntervalStats(time.Second, 100))
+	d.HandleKey('-')
+	assert.Equal(t, 90, c.Rate(), "rate limit should be derived from the current rate")
+	d.HandleKey('+')
+	assert.Equal(t, 99, c.Rate())
+	d.HandleKey('u')
+	assert.Equal(t, 0, c.Rate())
+
+	d.HandleKey('q')
+	assert.True(t, d.stopping)
+}
+
+func TestDashboard_Render(t *testing.T) {
+	c := dnsbench.NewController()
+	d := New(c, "127.0.0.1:53")
+
+	buf := bytes.Buffer{}
+	require.NoError(t, d.Render(&buf))
+	assert.Contains(t, buf.String(), "Waiting for the first results...")
+
+	for i := 0; i < 12; i++ {
+		d.Update(testIntervalStats(time.Duration(i+1)*time.Second, 10))
+	}
+	buf.Reset()
+	require.NoError(t, d.Render(&buf))
+	out := buf.String()
+
+	assert.Contains(t, out, "dnspyre - 127.0.0.1:53")
+	assert.Contains(t, out, "Elapsed: 12s  State: running  Rate limit: unlimited")
+	assert.Contains(t, out, "Total requests: 120  success: 96  negative: 0  error: 12  IO error: 12")
+	assert.Contains(t, out, "QPS  ████████████ 10.0")
+	assert.Contains(t, out, "last 1s   5ms  8ms  9ms  9ms  9ms")
+	assert.Contains(t, out, "last 10s  5ms  9ms  9ms  9ms  9ms")
+	assert.NotContains(t, out, "last 1m0s", "window longer than the collected history should not be shown")
+	assert.Contains(t, out, "total     5ms  9ms  9ms  9ms  9ms")
+	assert.Contains(t, out, "NOERROR   96  (88.9%)")
+	assert.Contains(t, out, "SERVFAIL  12  (11.1%)")
+	assert.Contains(t, out, "Network Error  12")
+	assert.Contains(t, out, "12  i/o timeout")
+}
+
+func testIntervalStats(elapsed time.Duration, total int64) dnsbench.IntervalStats {
+	hist := hdrhistogram.New(0, time.Second.Nanoseconds(), 3)
+	ioErrors := total / 10
+	servfails := total / 10
+	for i := int64(0); i < total-ioErrors; i++ {
+		hist.RecordValue((time.Duration(i+1) * time.Millisecond).Nanoseconds())
+	}
+	return dnsbench.IntervalStats{
+		Start:    time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC).Add(elapsed - time.Second),
+		Duration: time.Second,
+		Elapsed:  elapsed,
+		Counters: dnsbench.Counters{Total: total, Success: total - ioErrors - servfails, Error: servfails, IOError: ioErrors},
+		Hist:     hist,
+		Codes:    map[int]int64{dns.RcodeSuccess: total - ioErrors - servfails, dns.RcodeServerFailure: servfails},
+		EDECodes: map[uint16]int64{dns.ExtendedErrorCodeNetworkError: servfails},
+		Errors:   map[string]int64{"i/o timeout": ioErrors},
+	}
+}
@@ -0,0 +1,2 @@
+// Package tui provides live terminal dashboard showing results of the running dnsbench.Benchmark and controlling it using keyboard.
+package tui
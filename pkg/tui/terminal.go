@@ -0,0 +1,121 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	// refreshInterval is how often the dashboard is redrawn, so the worker statuses are shown even between the reporting intervals.
+	refreshInterval = 250 * time.Millisecond
+
+	// keyPollInterval is how long the reading of the keys waits for the input, before checking whether the dashboard was closed.
+	keyPollInterval = 50 * time.Millisecond
+
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+	hideCursor     = "\x1b[?25l"
+	showCursor     = "\x1b[?25h"
+	cursorHome     = "\x1b[H"
+	clearLine      = "\x1b[K"
+	clearScreenEnd = "\x1b[J"
+)
+
+// CheckTerminal checks whether the dashboard can be shown, the dashboard requires both input and output to be interactive terminal.
+func CheckTerminal(in, out *os.File) error {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return errors.New("live dashboard requires interactive terminal")
+	}
+	return nil
+}
+
+// Run shows the dashboard in the alternate screen of the terminal until the context is cancelled. The keys read from in are
+// handled by Dashboard.HandleKey. The reading of the keys is stopped and the terminal is restored, when Run returns.
+func (d *Dashboard) Run(ctx context.Context, in, out *os.File) error {
+	fd := int(in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to switch terminal to raw mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	fmt.Fprint(out, enterAltScreen+hideCursor)
+	defer fmt.Fprint(out, showCursor+exitAltScreen)
+
+	keys := make(chan byte)
+	readCtx, stopReading := context.WithCancel(ctx)
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		readKeys(readCtx, in, keys)
+	}()
+	defer func() {
+		stopReading()
+		<-readDone
+	}()
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := d.draw(out); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-keys:
+			if !ok {
+				// input was closed, the benchmark can be still stopped using signals
+				keys = nil
+				continue
+			}
+			d.HandleKey(key)
+		case <-ticker.C:
+		}
+	}
+}
+
+// draw redraws the whole screen, lines are overwritten instead of clearing the screen first to avoid flickering.
+func (d *Dashboard) draw(w io.Writer) error {
+	buf := bytes.Buffer{}
+	buf.WriteString(cursorHome)
+	for _, line := range d.lines() {
+		buf.WriteString(line + clearLine + "\r\n")
+	}
+	buf.WriteString(clearScreenEnd)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// readKeys sends the keys read from in to the keys channel until the context is cancelled or the input is closed.
+func readKeys(ctx context.Context, in io.Reader, keys chan<- byte) {
+	defer close(keys)
+	buf := make([]byte, 16)
+	for {
+		ready, err := waitForInput(in, keyPollInterval)
+		if err != nil || ctx.Err() != nil {
+			return
+		}
+		if !ready {
+			continue
+		}
+		n, err := in.Read(buf)
+		for _, key := range buf[:n] {
+			select {
+			case keys <- key:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
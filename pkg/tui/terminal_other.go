@@ -0,0 +1,14 @@
+//go:build !unix
+
+package tui
+
+import (
+	"io"
+	"time"
+)
+
+// waitForInput considers the input always ready, the reading of the keys is blocked until the next key is pressed
+// or the input is closed.
+func waitForInput(io.Reader, time.Duration) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package tui
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// waitForInput waits until the input is ready to be read or the timeout elapses, so that the reading of the keys does not block
+// after the dashboard was closed. Inputs without file descriptor are always considered ready.
+func waitForInput(in io.Reader, timeout time.Duration) (bool, error) {
+	f, ok := in.(interface{ Fd() uintptr })
+	if !ok {
+		return true, nil
+	}
+	fds := []unix.PollFd{{Fd: int32(f.Fd()), Events: unix.POLLIN}} // nolint:gosec // G115: file descriptors fit into int32
+	n, err := unix.Poll(fds, int(timeout.Milliseconds()))
+	if errors.Is(err, unix.EINTR) {
+		return false, nil
+	}
+	return n > 0, err
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package tui
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readKeys(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	keys := make(chan byte)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readKeys(ctx, r, keys)
+	}()
+
+	_, err = w.Write([]byte("q"))
+	require.NoError(t, err)
+	assert.Equal(t, byte('q'), <-keys)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reading of the keys should stop, when the context is cancelled, even if no key is pressed")
+	}
+	_, ok := <-keys
+	assert.False(t, ok, "keys channel should be closed")
+}